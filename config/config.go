@@ -2,6 +2,7 @@ package config
 
 import (
 	"image/color"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -10,6 +11,9 @@ var Conf Config
 
 const DYNAMIC_INT_SCALING = 0
 
+// DEFAULT_EPOCH is where deterministic guest clocks start when none is set
+var DEFAULT_EPOCH = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 type FirmwareColor = int
 
 const (
@@ -70,12 +74,14 @@ var ColorNameToId = map[string]FirmwareColor{
 }
 
 type Config struct {
-	General General
-	Ui      Ui
-	Profile Profile
-	Gb      Gb
-	Gba     Gba
-	Nds     NdsConfig
+	General  General
+	Ui       Ui
+	Profile  Profile
+	Headless Headless
+	Link     Link
+	Gb       Gb
+	Gba      Gba
+	Nds      NdsConfig
 }
 
 type General struct {
@@ -88,8 +94,15 @@ type General struct {
 	Vsync               bool
 	DisableSaves        bool
 	Logger              bool
+	Debugger            bool // debugger console on stdin, only set by flags
+	GdbPort             int  // gdb stub port, only set by flags
 	IntegerScaling      bool
 	IntegerScalingRatio int
+	RewindBufferSize    int               // megabytes, zero disables rewind
+	RewindInterval      int               // frames between rewind snapshots
+	Patches             map[string]string // rom file name to patch, patches beside roms are found without it
+	Deterministic       bool              // same input, same frames and audio, see Epoch
+	Epoch               time.Time         // guest clocks start here in deterministic mode
 	Keyboard            GeneralKeyboard
 	Controller          GeneralController
 }
@@ -105,6 +118,12 @@ type GeneralKeyboard struct {
 	Down       []ebiten.Key
 	Fullscreen []ebiten.Key
 	Quit       []ebiten.Key
+	SaveState  []ebiten.Key
+	LoadState  []ebiten.Key
+	NextSlot   []ebiten.Key
+	PrevSlot   []ebiten.Key
+	Rewind     []ebiten.Key
+	Capture    []ebiten.Key
 }
 
 type GeneralController struct {
@@ -118,6 +137,12 @@ type GeneralController struct {
 	Down       []ebiten.StandardGamepadButton
 	Fullscreen []ebiten.StandardGamepadButton
 	Quit       []ebiten.StandardGamepadButton
+	SaveState  []ebiten.StandardGamepadButton
+	LoadState  []ebiten.StandardGamepadButton
+	NextSlot   []ebiten.StandardGamepadButton
+	PrevSlot   []ebiten.StandardGamepadButton
+	Rewind     []ebiten.StandardGamepadButton
+	Capture    []ebiten.StandardGamepadButton
 }
 
 type Ui struct {
@@ -135,19 +160,63 @@ type Profile struct {
 	EndTick   int64
 }
 
+// Headless is only set by flags, events use the same syntax as the flags
+type Headless struct {
+	ScriptPath    string
+	Frames        int
+	ScreenshotDir string
+	Inputs        []string // frame:buttons[:hold]
+	Screenshots   []string // frame[:file]
+	Expects       []string // frame:hash
+	Searches      []string // frame:op[:value]
+	SearchWidth   int
+	SearchSigned  bool
+	Movie         string // played from power on or its state
+	Record        string // recorded from power on
+	Capture       string // picture and sound, a .y4m file or a png directory
+}
+
+// Link is only set by flags, addresses are tcp host:port or unix:path
+type Link struct {
+	Listen  string
+	Connect string
+}
+
 type Gb struct {
 	Palette          [4]color.Color
+	Printer          GbPrinter
+	Camera           GbCamera
 	KeyboardConfig   EmulatorKeyboard
 	ControllerConfig EmulatorController
 }
 
+type GbPrinter struct {
+	Enabled   bool // plugged into the link port instead of a cable
+	Directory string
+}
+
+type GbCamera struct {
+	Path string // png or directory of pngs seen by the pocket camera
+}
+
 type Gba struct {
 	IdleOptimize           bool
 	SoundClockUpdateCycles int
+	Rtc                    GbaRtc
+	Solar                  GbaSolar
 	KeyboardConfig         EmulatorKeyboard
 	ControllerConfig       EmulatorController
 }
 
+type GbaRtc struct {
+	AdditionalHours int
+}
+
+// GbaSolar is the sunlight on boktai carts, changed by the solar hotkeys
+type GbaSolar struct {
+	Level int // 0 - 10
+}
+
 type NdsConfig struct {
 	Screen           NdsScreen
 	Firmware         NdsFirmware
@@ -155,10 +224,44 @@ type NdsConfig struct {
 	Export           NdsExport
 	Bios             NdsBios
 	Jit              NdsJit
+	Slot2            NdsSlot2
+	Wifi             NdsWifi
+	Mic              NdsMic
 	KeyboardConfig   EmulatorKeyboard
 	ControllerConfig EmulatorController
 }
 
+type Slot2Device = int
+
+const (
+	SLOT2_NONE Slot2Device = iota
+	SLOT2_GBA
+	SLOT2_RUMBLE
+	SLOT2_EXPANSION
+	SLOT2_GUITAR
+)
+
+// NdsSlot2 is what is plugged into the gba slot
+type NdsSlot2 struct {
+	Device     Slot2Device
+	GbaRomPath string // gba cart, saves are shared with the gba emulator
+}
+
+// NdsWifi puts the wifi on an air shared by guac instances on this machine,
+// the air is "udp:host:port" or "unix:dir". Every instance needs its own mac,
+// one is made from the process id when Mac is empty
+type NdsWifi struct {
+	Air string
+	Mac string
+}
+
+// NdsMic is what the microphone hears, the wav plays on a loop or when the
+// mic key is pressed
+type NdsMic struct {
+	WavPath string
+	Loop    bool
+}
+
 type NdsBios struct {
 	Arm7Path string
 	Arm9Path string
@@ -215,6 +318,15 @@ type EmulatorKeyboard struct {
 	SizingToggle   []ebiten.Key
 	RotationToggle []ebiten.Key
 	ExportScene    []ebiten.Key
+	SolarUp        []ebiten.Key
+	SolarDown      []ebiten.Key
+	GripGreen      []ebiten.Key
+	GripRed        []ebiten.Key
+	GripYellow     []ebiten.Key
+	GripBlue       []ebiten.Key
+	Mic            []ebiten.Key
+	Blow           []ebiten.Key
+	Lid            []ebiten.Key
 }
 
 type EmulatorController struct {
@@ -236,4 +348,13 @@ type EmulatorController struct {
 	SizingToggle   []ebiten.StandardGamepadButton
 	RotationToggle []ebiten.StandardGamepadButton
 	ExportScene    []ebiten.StandardGamepadButton
+	SolarUp        []ebiten.StandardGamepadButton
+	SolarDown      []ebiten.StandardGamepadButton
+	GripGreen      []ebiten.StandardGamepadButton
+	GripRed        []ebiten.StandardGamepadButton
+	GripYellow     []ebiten.StandardGamepadButton
+	GripBlue       []ebiten.StandardGamepadButton
+	Mic            []ebiten.StandardGamepadButton
+	Blow           []ebiten.StandardGamepadButton
+	Lid            []ebiten.StandardGamepadButton
 }
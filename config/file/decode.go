@@ -59,6 +59,15 @@ func (c *Config) decodeGeneral() {
 	c.config.General.DisableSaves = c.General.DisableSaves
 	c.config.General.IntegerScaling = c.General.IntegerScaling
 	c.config.General.IntegerScalingRatio = c.General.IntegerScalingRatio
+	c.config.General.RewindBufferSize = c.General.RewindBufferSize
+	c.config.General.RewindInterval = c.General.RewindInterval
+	c.config.General.Patches = c.General.Patches
+	c.config.General.Deterministic = c.General.Deterministic
+	c.config.General.Epoch = c.General.Epoch
+
+	if c.config.General.Epoch.IsZero() {
+		c.config.General.Epoch = config.DEFAULT_EPOCH
+	}
 
 	in := &c.General.Keyboard
 	confKey := &c.config.General.Keyboard
@@ -74,6 +83,12 @@ func (c *Config) decodeGeneral() {
 		&in.Down,
 		&in.Fullscreen,
 		&in.Quit,
+		&in.SaveState,
+		&in.LoadState,
+		&in.NextSlot,
+		&in.PrevSlot,
+		&in.Rewind,
+		&in.Capture,
 	}
 
 	outputsKeys := []*[]ebiten.Key{
@@ -87,6 +102,12 @@ func (c *Config) decodeGeneral() {
 		&confKey.Down,
 		&confKey.Fullscreen,
 		&confKey.Quit,
+		&confKey.SaveState,
+		&confKey.LoadState,
+		&confKey.NextSlot,
+		&confKey.PrevSlot,
+		&confKey.Rewind,
+		&confKey.Capture,
 	}
 
 	for i := range len(tomls) {
@@ -111,6 +132,12 @@ func (c *Config) decodeGeneral() {
 		&in.Down,
 		&in.Fullscreen,
 		&in.Quit,
+		&in.SaveState,
+		&in.LoadState,
+		&in.NextSlot,
+		&in.PrevSlot,
+		&in.Rewind,
+		&in.Capture,
 	}
 
 	outputs := []*[]ebiten.StandardGamepadButton{
@@ -124,6 +151,12 @@ func (c *Config) decodeGeneral() {
 		&conf.Down,
 		&conf.Fullscreen,
 		&conf.Quit,
+		&conf.SaveState,
+		&conf.LoadState,
+		&conf.NextSlot,
+		&conf.PrevSlot,
+		&conf.Rewind,
+		&conf.Capture,
 	}
 
 	for i := range len(tomls) {
@@ -184,6 +217,14 @@ func (c *Config) decodeGb() {
 		c.config.Gb.Palette[2] = pals[2]
 		c.config.Gb.Palette[3] = pals[3]
 	}
+
+	c.config.Gb.Printer.Enabled = c.Gb.Printer.Enabled
+	c.config.Gb.Printer.Directory = c.Gb.Printer.Directory
+	if c.config.Gb.Printer.Directory == "" {
+		c.config.Gb.Printer.Directory = "./prints/"
+	}
+
+	c.config.Gb.Camera.Path = c.Gb.Camera.Path
 }
 
 func (c *Config) decodeGba() {
@@ -192,6 +233,8 @@ func (c *Config) decodeGba() {
 
 	c.config.Gba.IdleOptimize = c.Gba.IdleOptimize
 	c.config.Gba.SoundClockUpdateCycles = c.Gba.SoundClockUpdateCycles
+	c.config.Gba.Rtc.AdditionalHours = c.Gba.Rtc.AdditionalHours
+	c.config.Gba.Solar.Level = max(0, min(c.Gba.Solar.Level, 10))
 }
 
 func (c *Config) decodeNds() {
@@ -208,6 +251,37 @@ func (c *Config) decodeNds() {
 
 	c.config.Nds.Rtc.AdditionalHours = c.Nds.Rtc.AdditionalHours
 
+	if utils.IsFile(c.Nds.Slot2.GbaRomPath) {
+		c.config.Nds.Slot2.GbaRomPath = c.Nds.Slot2.GbaRomPath
+	}
+
+	switch strings.ToLower(c.Nds.Slot2.Device) {
+	case "gba":
+		c.config.Nds.Slot2.Device = config.SLOT2_GBA
+	case "rumble":
+		c.config.Nds.Slot2.Device = config.SLOT2_RUMBLE
+	case "expansion":
+		c.config.Nds.Slot2.Device = config.SLOT2_EXPANSION
+	case "guitar":
+		c.config.Nds.Slot2.Device = config.SLOT2_GUITAR
+	case "":
+		// a gba rom without a device is a gba cart
+		if c.config.Nds.Slot2.GbaRomPath != "" {
+			c.config.Nds.Slot2.Device = config.SLOT2_GBA
+		}
+	default:
+		c.config.Nds.Slot2.Device = config.SLOT2_NONE
+	}
+
+	c.config.Nds.Wifi.Air = c.Nds.Wifi.Air
+	c.config.Nds.Wifi.Mac = c.Nds.Wifi.Mac
+
+	if utils.IsFile(c.Nds.Mic.WavPath) {
+		c.config.Nds.Mic.WavPath = c.Nds.Mic.WavPath
+	}
+
+	c.config.Nds.Mic.Loop = c.Nds.Mic.Loop
+
 	// if utils.IsDirectory(c.Nds.Export.Directory) {
 	// need to create directory if empty
 	c.config.Nds.Export.Directory = c.Nds.Export.Directory
@@ -343,6 +417,15 @@ func (c *Config) decodeKeyboard(in *EmulatorInput, conf *config.EmulatorKeyboard
 		&in.SizingToggle,
 		&in.RotationToggle,
 		&in.ExportScene,
+		&in.SolarUp,
+		&in.SolarDown,
+		&in.GripGreen,
+		&in.GripRed,
+		&in.GripYellow,
+		&in.GripBlue,
+		&in.Mic,
+		&in.Blow,
+		&in.Lid,
 	}
 
 	outputs := []*[]ebiten.Key{
@@ -364,6 +447,15 @@ func (c *Config) decodeKeyboard(in *EmulatorInput, conf *config.EmulatorKeyboard
 		&conf.SizingToggle,
 		&conf.RotationToggle,
 		&conf.ExportScene,
+		&conf.SolarUp,
+		&conf.SolarDown,
+		&conf.GripGreen,
+		&conf.GripRed,
+		&conf.GripYellow,
+		&conf.GripBlue,
+		&conf.Mic,
+		&conf.Blow,
+		&conf.Lid,
 	}
 
 	for i := range len(tomls) {
@@ -390,6 +482,15 @@ func (c *Config) decodeController(in *EmulatorInput, conf *config.EmulatorContro
 		&in.X,
 		&in.Y,
 		&in.Hinge,
+		&in.SolarUp,
+		&in.SolarDown,
+		&in.GripGreen,
+		&in.GripRed,
+		&in.GripYellow,
+		&in.GripBlue,
+		&in.Mic,
+		&in.Blow,
+		&in.Lid,
 	}
 
 	outputs := []*[]ebiten.StandardGamepadButton{
@@ -406,6 +507,15 @@ func (c *Config) decodeController(in *EmulatorInput, conf *config.EmulatorContro
 		&conf.X,
 		&conf.Y,
 		&conf.Hinge,
+		&conf.SolarUp,
+		&conf.SolarDown,
+		&conf.GripGreen,
+		&conf.GripRed,
+		&conf.GripYellow,
+		&conf.GripBlue,
+		&conf.Mic,
+		&conf.Blow,
+		&conf.Lid,
 	}
 
 	for i := range len(tomls) {
@@ -50,7 +50,12 @@ func (c *Config) encodeGeneral() {
 		IntegerScaling:      c.config.General.IntegerScaling,
 		IntegerScalingRatio: c.config.General.IntegerScalingRatio,
 		// rompath
-		DisableSaves: c.config.General.DisableSaves,
+		DisableSaves:     c.config.General.DisableSaves,
+		RewindBufferSize: c.config.General.RewindBufferSize,
+		RewindInterval:   c.config.General.RewindInterval,
+		Patches:          c.config.General.Patches,
+		Deterministic:    c.config.General.Deterministic,
+		Epoch:            c.config.General.Epoch,
 	}
 
 	file := &c.General.Keyboard
@@ -67,6 +72,12 @@ func (c *Config) encodeGeneral() {
 		&file.Down,
 		&file.Fullscreen,
 		&file.Quit,
+		&file.SaveState,
+		&file.LoadState,
+		&file.NextSlot,
+		&file.PrevSlot,
+		&file.Rewind,
+		&file.Capture,
 	}
 
 	confKeys := []*[]ebiten.Key{
@@ -80,6 +91,12 @@ func (c *Config) encodeGeneral() {
 		&conf.Down,
 		&conf.Fullscreen,
 		&conf.Quit,
+		&conf.SaveState,
+		&conf.LoadState,
+		&conf.NextSlot,
+		&conf.PrevSlot,
+		&conf.Rewind,
+		&conf.Capture,
 	}
 
 	for i := range confKeys {
@@ -103,6 +120,12 @@ func (c *Config) encodeGeneral() {
 		&file.Down,
 		&file.Fullscreen,
 		&file.Quit,
+		&file.SaveState,
+		&file.LoadState,
+		&file.NextSlot,
+		&file.PrevSlot,
+		&file.Rewind,
+		&file.Capture,
 	}
 
 	confButtons := []*[]ebiten.StandardGamepadButton{
@@ -116,6 +139,12 @@ func (c *Config) encodeGeneral() {
 		&confB.Down,
 		&confB.Fullscreen,
 		&confB.Quit,
+		&confB.SaveState,
+		&confB.LoadState,
+		&confB.NextSlot,
+		&confB.PrevSlot,
+		&confB.Rewind,
+		&confB.Capture,
 	}
 
 	for i := range confButtons {
@@ -157,6 +186,11 @@ func (c *Config) encodeGb() {
 		"0x" + utils.ColorToHex(c.config.Gb.Palette[2]),
 		"0x" + utils.ColorToHex(c.config.Gb.Palette[3]),
 	}
+
+	c.Gb.Printer.Enabled = c.config.Gb.Printer.Enabled
+	c.Gb.Printer.Directory = c.config.Gb.Printer.Directory
+
+	c.Gb.Camera.Path = c.config.Gb.Camera.Path
 }
 
 func (c *Config) encodeGba() {
@@ -164,6 +198,8 @@ func (c *Config) encodeGba() {
 	c.encodeController(&c.Gba.Controller, &c.config.Gba.ControllerConfig)
 	c.Gba.IdleOptimize = c.config.Gba.IdleOptimize
 	c.Gba.SoundClockUpdateCycles = c.config.Gba.SoundClockUpdateCycles
+	c.Gba.Rtc.AdditionalHours = c.config.Gba.Rtc.AdditionalHours
+	c.Gba.Solar.Level = c.config.Gba.Solar.Level
 }
 
 func (c *Config) encodeNds() {
@@ -180,6 +216,32 @@ func (c *Config) encodeNds() {
 
 	c.Nds.Rtc.AdditionalHours = c.config.Nds.Rtc.AdditionalHours
 
+	if utils.IsFile(c.config.Nds.Slot2.GbaRomPath) {
+		c.Nds.Slot2.GbaRomPath = c.config.Nds.Slot2.GbaRomPath
+	}
+
+	switch c.config.Nds.Slot2.Device {
+	case config.SLOT2_GBA:
+		c.Nds.Slot2.Device = "gba"
+	case config.SLOT2_RUMBLE:
+		c.Nds.Slot2.Device = "rumble"
+	case config.SLOT2_EXPANSION:
+		c.Nds.Slot2.Device = "expansion"
+	case config.SLOT2_GUITAR:
+		c.Nds.Slot2.Device = "guitar"
+	default:
+		c.Nds.Slot2.Device = "none"
+	}
+
+	c.Nds.Wifi.Air = c.config.Nds.Wifi.Air
+	c.Nds.Wifi.Mac = c.config.Nds.Wifi.Mac
+
+	if utils.IsFile(c.config.Nds.Mic.WavPath) {
+		c.Nds.Mic.WavPath = c.config.Nds.Mic.WavPath
+	}
+
+	c.Nds.Mic.Loop = c.config.Nds.Mic.Loop
+
 	// if utils.IsDirectory(c.Nds.Export.Directory) {
 	c.Nds.Export.Directory = c.config.Nds.Export.Directory
 	//}
@@ -263,6 +325,15 @@ func (c *Config) encodeKeyboard(file *EmulatorInput, conf *config.EmulatorKeyboa
 		&file.SizingToggle,
 		&file.RotationToggle,
 		&file.ExportScene,
+		&file.SolarUp,
+		&file.SolarDown,
+		&file.GripGreen,
+		&file.GripRed,
+		&file.GripYellow,
+		&file.GripBlue,
+		&file.Mic,
+		&file.Blow,
+		&file.Lid,
 	}
 
 	confs := []*[]ebiten.Key{
@@ -284,6 +355,15 @@ func (c *Config) encodeKeyboard(file *EmulatorInput, conf *config.EmulatorKeyboa
 		&conf.SizingToggle,
 		&conf.RotationToggle,
 		&conf.ExportScene,
+		&conf.SolarUp,
+		&conf.SolarDown,
+		&conf.GripGreen,
+		&conf.GripRed,
+		&conf.GripYellow,
+		&conf.GripBlue,
+		&conf.Mic,
+		&conf.Blow,
+		&conf.Lid,
 	}
 
 	for i := range len(confs) {
@@ -314,6 +394,15 @@ func (c *Config) encodeController(file *EmulatorInput, conf *config.EmulatorCont
 		&file.SizingToggle,
 		&file.RotationToggle,
 		&file.ExportScene,
+		&file.SolarUp,
+		&file.SolarDown,
+		&file.GripGreen,
+		&file.GripRed,
+		&file.GripYellow,
+		&file.GripBlue,
+		&file.Mic,
+		&file.Blow,
+		&file.Lid,
 	}
 
 	confs := []*[]ebiten.StandardGamepadButton{
@@ -335,6 +424,15 @@ func (c *Config) encodeController(file *EmulatorInput, conf *config.EmulatorCont
 		&conf.SizingToggle,
 		&conf.RotationToggle,
 		&conf.ExportScene,
+		&conf.SolarUp,
+		&conf.SolarDown,
+		&conf.GripGreen,
+		&conf.GripRed,
+		&conf.GripYellow,
+		&conf.GripBlue,
+		&conf.Mic,
+		&conf.Blow,
+		&conf.Lid,
 	}
 
 	for i := range len(confs) {
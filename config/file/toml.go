@@ -2,6 +2,7 @@ package file
 
 import (
 	_ "embed"
+	"time"
 
 	"github.com/aabalke/guac/config"
 )
@@ -22,17 +23,22 @@ type Config struct {
 }
 
 type General struct {
-	Muted               bool         `toml:"muted"`
-	TargetFps           int          `toml:"target_fps"`
-	ShowFps             bool         `toml:"show_fps"`
-	InitFullscreen      bool         `toml:"fullscreen"`
-	Vsync               bool         `toml:"vsync_enabled"`
-	RomPath             string       `toml:"rom_path"`
-	IntegerScaling      bool         `toml:"integer_scaling"`
-	IntegerScalingRatio int          `toml:"integer_scaling_ratio"`
-	DisableSaves        bool         `toml:"disable_saves"`
-	Keyboard            GeneralInput `toml:"keyboard"`
-	Controller          GeneralInput `toml:"controller"`
+	Muted               bool              `toml:"muted"`
+	TargetFps           int               `toml:"target_fps"`
+	ShowFps             bool              `toml:"show_fps"`
+	InitFullscreen      bool              `toml:"fullscreen"`
+	Vsync               bool              `toml:"vsync_enabled"`
+	RomPath             string            `toml:"rom_path"`
+	IntegerScaling      bool              `toml:"integer_scaling"`
+	IntegerScalingRatio int               `toml:"integer_scaling_ratio"`
+	DisableSaves        bool              `toml:"disable_saves"`
+	RewindBufferSize    int               `toml:"rewind_buffer_size"`
+	RewindInterval      int               `toml:"rewind_interval"`
+	Patches             map[string]string `toml:"patches"`
+	Deterministic       bool              `toml:"deterministic"`
+	Epoch               time.Time         `toml:"epoch"`
+	Keyboard            GeneralInput      `toml:"keyboard"`
+	Controller          GeneralInput      `toml:"controller"`
 }
 
 type GeneralInput struct {
@@ -46,6 +52,12 @@ type GeneralInput struct {
 	Down       []string `toml:"down"`
 	Fullscreen []string `toml:"fullscreen"`
 	Quit       []string `toml:"quit"`
+	SaveState  []string `toml:"save_state"`
+	LoadState  []string `toml:"load_state"`
+	NextSlot   []string `toml:"next_slot"`
+	PrevSlot   []string `toml:"prev_slot"`
+	Rewind     []string `toml:"rewind"`
+	Capture    []string `toml:"capture"`
 }
 
 type Ui struct {
@@ -65,17 +77,38 @@ type Profile struct {
 
 type Gb struct {
 	Palette    []string      `toml:"dmg_palette"`
+	Printer    GbPrinter     `toml:"printer"`
+	Camera     GbCamera      `toml:"camera"`
 	Keyboard   EmulatorInput `toml:"keyboard"`
 	Controller EmulatorInput `toml:"controller"`
 }
 
+type GbPrinter struct {
+	Enabled   bool   `toml:"enabled"`
+	Directory string `toml:"directory"`
+}
+
+type GbCamera struct {
+	Path string `toml:"path"`
+}
+
 type Gba struct {
 	IdleOptimize           bool          `toml:"idle_optimize"`
 	SoundClockUpdateCycles int           `toml:"sound_clock_update_cycles"`
+	Rtc                    GbaRtc        `toml:"rtc"`
+	Solar                  GbaSolar      `toml:"solar"`
 	Keyboard               EmulatorInput `toml:"keyboard"`
 	Controller             EmulatorInput `toml:"controller"`
 }
 
+type GbaRtc struct {
+	AdditionalHours int `toml:"additional_hours"`
+}
+
+type GbaSolar struct {
+	Level int `toml:"level"`
+}
+
 type Nds struct {
 	Keyboard   EmulatorInput `toml:"keyboard"`
 	Controller EmulatorInput `toml:"controller"`
@@ -85,6 +118,24 @@ type Nds struct {
 	Screen     NdsScreen     `toml:"screen"`
 	Firmware   NdsFirmware   `toml:"firmware"`
 	Jit        NdsJit        `toml:"jit"`
+	Slot2      NdsSlot2      `toml:"slot2"`
+	Wifi       NdsWifi       `toml:"wifi"`
+	Mic        NdsMic        `toml:"mic"`
+}
+
+type NdsMic struct {
+	WavPath string `toml:"wav_path"`
+	Loop    bool   `toml:"loop"`
+}
+
+type NdsWifi struct {
+	Air string `toml:"air"`
+	Mac string `toml:"mac"`
+}
+
+type NdsSlot2 struct {
+	Device     string `toml:"device"`
+	GbaRomPath string `toml:"gba_rom_path"`
 }
 
 type NdsBios struct {
@@ -142,4 +193,13 @@ type EmulatorInput struct {
 	SizingToggle   []string `toml:"sizing_toggle"`
 	RotationToggle []string `toml:"rotation_toggle"`
 	ExportScene    []string `toml:"export_scene"`
+	SolarUp        []string `toml:"solar_up"`
+	SolarDown      []string `toml:"solar_down"`
+	GripGreen      []string `toml:"grip_green"`
+	GripRed        []string `toml:"grip_red"`
+	GripYellow     []string `toml:"grip_yellow"`
+	GripBlue       []string `toml:"grip_blue"`
+	Mic            []string `toml:"mic"`
+	Blow           []string `toml:"blow"`
+	Lid            []string `toml:"lid"`
 }
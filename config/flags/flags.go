@@ -2,10 +2,24 @@ package flags
 
 import (
 	"flag"
+	"strings"
+	"time"
 
 	"github.com/aabalke/guac/config"
 )
 
+// list is a flag which can be given more than once
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
 func Decode() {
 	var (
 		romPath  = flag.String("r", "", "rom path")
@@ -15,8 +29,45 @@ func Decode() {
 		logger   = flag.Bool("l", false, "logger")
 		showfps  = flag.Bool("show-fps", false, "show fps")
 		headless = flag.Bool("headless", false, "headless")
+		debug    = flag.Bool("debug", false, "arm debugger console on stdin (gba, nds)")
+		gdb      = flag.Int("gdb", 0, "gdb stub port on localhost (gba, nds)")
+
+		deterministic = flag.Bool("deterministic", false, "same input gives the same frames and audio")
+
+		script      = flag.String("script", "", "headless toml script")
+		frames      = flag.Int("frames", 0, "headless frames to run, 0 runs forever")
+		outDir      = flag.String("out", "", "headless screenshot directory")
+		inputs      list
+		screenshots list
+		expects     list
+
+		searches     list
+		searchWidth  = flag.Int("search-width", 1, "headless ram search bytes, 1, 2 or 4")
+		searchSigned = flag.Bool("search-signed", false, "headless ram search of signed values")
+
+		moviePath  = flag.String("movie", "", "headless input movie to play")
+		recordPath = flag.String("record", "", "headless input movie to record from power on")
+		capture    = flag.String("capture", "", "headless picture and sound, a .y4m (and .wav) or a png directory")
+
+		linkListen  = flag.String("link-listen", "", "link cable, wait for a peer on host:port or unix:path")
+		linkConnect = flag.String("link", "", "link cable, connect to a peer on host:port or unix:path")
 	)
 
+	flag.Var(&inputs, "input", "headless input, frame:buttons[:hold] e.g. 60:a+start:2")
+	flag.Var(&screenshots, "screenshot", "headless screenshot, frame[:file]")
+	flag.Var(&expects, "expect", "headless framebuffer hash, frame:hash")
+	flag.Var(&searches, "search", "headless ram search, frame:op[:value] e.g. 120:dec")
+
+	flag.Func("epoch", "deterministic guest clock start, rfc3339 e.g. 2000-01-01T00:00:00Z", func(v string) error {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return err
+		}
+
+		config.Conf.General.Epoch = t
+		return nil
+	})
+
 	flag.Parse()
 
 	flag.Visit(func(f *flag.Flag) {
@@ -33,8 +84,44 @@ func Decode() {
 			config.Conf.General.Muted = *mute
 		case "l":
 			config.Conf.General.Logger = *logger
+		case "debug":
+			config.Conf.General.Debugger = *debug
+		case "gdb":
+			config.Conf.General.GdbPort = *gdb
 		case "show-fps":
 			config.Conf.General.ShowFps = *showfps
+		case "deterministic":
+			config.Conf.General.Deterministic = *deterministic
+		case "script":
+			// a script is only run headless
+			config.Conf.General.Headless = true
+			config.Conf.Headless.ScriptPath = *script
+		case "frames":
+			config.Conf.Headless.Frames = *frames
+		case "out":
+			config.Conf.Headless.ScreenshotDir = *outDir
+		case "input":
+			config.Conf.Headless.Inputs = inputs
+		case "screenshot":
+			config.Conf.Headless.Screenshots = screenshots
+		case "expect":
+			config.Conf.Headless.Expects = expects
+		case "search":
+			config.Conf.Headless.Searches = searches
+		case "search-width":
+			config.Conf.Headless.SearchWidth = *searchWidth
+		case "search-signed":
+			config.Conf.Headless.SearchSigned = *searchSigned
+		case "movie":
+			config.Conf.Headless.Movie = *moviePath
+		case "record":
+			config.Conf.Headless.Record = *recordPath
+		case "capture":
+			config.Conf.Headless.Capture = *capture
+		case "link-listen":
+			config.Conf.Link.Listen = *linkListen
+		case "link":
+			config.Conf.Link.Connect = *linkConnect
 		}
 	})
 }
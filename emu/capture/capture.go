@@ -0,0 +1,223 @@
+// capture writes what a console puts out, frame by frame, at its own
+// resolution and frame rate. Pictures go to a png per frame in a directory,
+// which is lossless, or to a single 4:4:4 y4m file. Sound goes to a wav
+// beside them.
+//
+//	game/000000.png game/000001.png ... game/audio.wav
+//	game.y4m game.wav
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aabalke/guac/emu/wav"
+)
+
+// Rate is frames per second as a fraction, consoles don't run at whole rates
+type Rate struct {
+	Num, Den int
+}
+
+type Recorder struct {
+	dir string // png frames, empty when writing y4m
+
+	file *os.File
+	y4m  *bufio.Writer
+	yuv  []uint8
+
+	audio *wav.Writer
+	png   png.Encoder
+
+	w, h   int
+	frames int
+
+	sampled uint32 // cursor in the apu's ring, apart from the player's
+	samples []int16
+}
+
+// IsY4m reports if path is recorded to a y4m file rather than a directory
+func IsY4m(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".y4m")
+}
+
+// New starts recording to path, a .y4m file or a directory for pngs. Frames
+// are w by h, sound is stereo at sampleRate.
+func New(path string, w, h int, fps Rate, sampleRate int) (*Recorder, error) {
+	r := &Recorder{
+		w:   w,
+		h:   h,
+		png: png.Encoder{CompressionLevel: png.BestSpeed},
+	}
+
+	audioPath := filepath.Join(path, "audio.wav")
+
+	if IsY4m(path) {
+		audioPath = strings.TrimSuffix(path, filepath.Ext(path)) + ".wav"
+
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+
+		r.file = f
+		r.y4m = bufio.NewWriter(f)
+		r.yuv = make([]uint8, w*h*3)
+
+		fmt.Fprintf(r.y4m, "YUV4MPEG2 W%d H%d F%d:%d Ip A1:1 C444\n", w, h, fps.Num, fps.Den)
+	} else {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+
+		r.dir = path
+	}
+
+	a, err := wav.Create(audioPath, uint32(sampleRate), 2)
+	if err != nil {
+		if r.file != nil {
+			r.file.Close()
+		}
+
+		return nil, err
+	}
+
+	r.audio = a
+
+	return r, nil
+}
+
+// Frame adds a picture. A y4m keeps the size it started with, other sizes
+// are cropped or padded with black.
+func (r *Recorder) Frame(img *image.RGBA) error {
+	r.frames++
+
+	if r.y4m == nil {
+		return r.writePng(img)
+	}
+
+	r.toYuv(img)
+
+	if _, err := r.y4m.WriteString("FRAME\n"); err != nil {
+		return err
+	}
+
+	_, err := r.y4m.Write(r.yuv)
+	return err
+}
+
+func (r *Recorder) writePng(img *image.RGBA) error {
+	f, err := os.Create(filepath.Join(r.dir, fmt.Sprintf("%06d.png", r.frames-1)))
+	if err != nil {
+		return err
+	}
+
+	if err := r.png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// toYuv fills the y, u and v planes with bt.601 studio range values
+func (r *Recorder) toYuv(img *image.RGBA) {
+	clear(r.yuv)
+
+	plane := r.w * r.h
+	ys, us, vs := r.yuv[:plane], r.yuv[plane:2*plane], r.yuv[2*plane:]
+
+	b := img.Bounds()
+	w, h := min(r.w, b.Dx()), min(r.h, b.Dy())
+
+	for y := range r.h {
+		for x := range r.w {
+			// black, where the image doesn't reach
+			R, G, B := 0, 0, 0
+
+			if x < w && y < h {
+				i := img.PixOffset(b.Min.X+x, b.Min.Y+y)
+				R, G, B = int(img.Pix[i]), int(img.Pix[i+1]), int(img.Pix[i+2])
+			}
+
+			i := y*r.w + x
+			ys[i] = uint8((66*R+129*G+25*B+128)>>8 + 16)
+			us[i] = uint8((-38*R-74*G+112*B+128)>>8 + 128)
+			vs[i] = uint8((112*R-94*G-18*B+128)>>8 + 128)
+		}
+	}
+}
+
+// Audio adds samples, interleaved left and right
+func (r *Recorder) Audio(samples []int16) error {
+	return r.audio.Write(samples)
+}
+
+// Record adds a frame and the sound made during it, see Sound
+func (r *Recorder) Record(img *image.RGBA, ring []int16, write uint32) error {
+	if err := r.Frame(img); err != nil {
+		return err
+	}
+
+	return r.Sound(ring, write)
+}
+
+// Sound adds the samples of an apu's ring buffer made since the last call.
+// The ring is left and right samples as the apus mix them, its length a
+// power of two, and write is where the next sample goes.
+func (r *Recorder) Sound(ring []int16, write uint32) error {
+	mask := uint32(len(ring) - 1)
+
+	r.samples = r.samples[:0]
+	for ; r.sampled&mask != write&mask; r.sampled++ {
+		r.samples = append(r.samples, ring[r.sampled&mask]<<6)
+	}
+
+	r.sampled &= mask
+
+	return r.Audio(r.samples)
+}
+
+// SkipSound drops the samples made so far, Sound starts from write. It is
+// called when recording starts and when a state moves the ring, a nil r is
+// not recording.
+func (r *Recorder) SkipSound(write uint32) {
+	if r != nil {
+		r.sampled = write
+	}
+}
+
+// Frames is the pictures added so far
+func (r *Recorder) Frames() int {
+	return r.frames
+}
+
+// Stop closes r and returns the frames recorded, a nil r recorded none
+func (r *Recorder) Stop() (int, error) {
+	if r == nil {
+		return 0, nil
+	}
+
+	return r.frames, r.Close()
+}
+
+func (r *Recorder) Close() error {
+	err := r.audio.Close()
+
+	if r.y4m != nil {
+		if ferr := r.y4m.Flush(); err == nil {
+			err = ferr
+		}
+
+		if cerr := r.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
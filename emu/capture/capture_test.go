@@ -0,0 +1,115 @@
+package capture
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aabalke/guac/emu/wav"
+)
+
+func TestY4m(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.y4m")
+
+	r, err := New(path, 2, 1, Rate{16777216, 280896}, 32768)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// white then black, the second frame is too small and padded
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	copy(img.Pix, []uint8{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0xFF})
+
+	r.Frame(img)
+	r.Frame(image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	r.Audio([]int16{1, 2, 3, 4})
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := "YUV4MPEG2 W2 H1 F16777216:280896 Ip A1:1 C444\n"
+	want := header +
+		"FRAME\n" + string([]uint8{235, 16, 128, 128, 128, 128}) +
+		"FRAME\n" + string([]uint8{16, 16, 128, 128, 128, 128})
+
+	if !bytes.Equal(b, []byte(want)) {
+		t.Fatalf("y4m %q", b)
+	}
+
+	a, err := wav.ReadFile(filepath.Join(filepath.Dir(path), "run.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Rate != 32768 || len(a.Samples) != 2 {
+		t.Fatalf("wav rate %d, %d samples", a.Rate, len(a.Samples))
+	}
+}
+
+func TestPng(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+
+	r, err := New(dir, 2, 2, Rate{60, 1}, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		r.Frame(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"000000.png", "000001.png", "audio.wav"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if r.Frames() != 2 {
+		t.Fatalf("%d frames", r.Frames())
+	}
+}
+
+func TestSound(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+
+	r, err := New(dir, 1, 1, Rate{60, 1}, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring := []int16{1, 2, 3, 4, 5, 6, 7, 8}
+
+	// the ring wraps, then a state moves it back and the stale samples
+	// between are dropped
+	r.SkipSound(6)
+	r.Sound(ring, 10)
+	r.SkipSound(4)
+	r.Sound(ring, 6)
+
+	if n, err := r.Stop(); n != 0 || err != nil {
+		t.Fatalf("stop: %d frames, %v", n, err)
+	}
+
+	a, err := wav.ReadFile(filepath.Join(dir, "audio.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read back mixed to mono, 7 and 8, 1 and 2, 5 and 6
+	if want := []int16{480, 96, 352}; !slices.Equal(a.Samples, want) {
+		t.Fatalf("samples %v, expected %v", a.Samples, want)
+	}
+}
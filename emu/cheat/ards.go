@@ -0,0 +1,213 @@
+package cheat
+
+import "fmt"
+
+type ards struct {
+	code    [][2]uint32
+	counter uint32 // kept across frames for C5 codes
+}
+
+// compileArds takes action replay ds codes, "XXXXXXXX YYYYYYYY". The data
+// of E codes follows on the next lines.
+func compileArds(lines []string) (program, error) {
+	code, err := words(lines, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(code); i++ {
+		a, v := code[i][0], code[i][1]
+
+		switch op := a >> 28; {
+		case op == 0xE:
+			n := int(v+7) / 8
+			if i+n >= len(code) {
+				return nil, fmt.Errorf("copy is missing %d lines: %w", n, ErrSyntax)
+			}
+			i += n
+
+		case op == 0xC || op == 0xD:
+			// C4 points the offset at its own line in the action replay's
+			// memory, the codes are not in the console's memory here
+			switch a >> 24 {
+			case 0xC0, 0xC5, 0xC6,
+				0xD0, 0xD1, 0xD2, 0xD3, 0xD4, 0xD5, 0xD6, 0xD7,
+				0xD8, 0xD9, 0xDA, 0xDB, 0xDC:
+			default:
+				return nil, fmt.Errorf("%08X %08X: %w", a, v, ErrUnsupported)
+			}
+		}
+	}
+
+	return &ards{code: code}, nil
+}
+
+func (p *ards) run(b Bus) {
+	var (
+		c      = p.code
+		offset uint32
+		data   uint32
+		conds  []bool // results of open ifs
+
+		loop      = -1 // line of the running C0, -1 when none
+		loopCount uint32
+		loopConds int
+	)
+
+	active := func() bool {
+		for _, ok := range conds {
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	// next ends a loop body, the loop runs again until its count is done
+	next := func(i int) int {
+		if loop >= 0 && loopCount != 0 {
+			loopCount--
+			conds = conds[:loopConds]
+			return loop
+		}
+
+		loop = -1
+		return i
+	}
+
+	for i, steps := 0, 0; i < len(c) && steps < MAX_STEPS; i, steps = i+1, steps+1 {
+		a, v := c[i][0], c[i][1]
+		op := a >> 28
+		addr := a & 0x0FFF_FFFF
+
+		if op >= 0x3 && op <= 0xA || a>>24 == 0xC5 {
+			if !active() {
+				conds = append(conds, false)
+				continue
+			}
+
+			if addr == 0 {
+				addr = offset
+			}
+
+			var ok bool
+
+			switch op {
+			case 0xC:
+				// XXXXYYYY, runs when the counter and y is x
+				p.counter++
+				ok = p.counter&(v&0xFFFF) == v>>16
+			case 0x3:
+				ok = v > b.Read32(addr)
+			case 0x4:
+				ok = v < b.Read32(addr)
+			case 0x5:
+				ok = v == b.Read32(addr)
+			case 0x6:
+				ok = v != b.Read32(addr)
+			default:
+				// ZZZZYYYY, the half word is masked by not z
+				m := uint32(b.Read16(addr)) &^ (v >> 16)
+				y := v & 0xFFFF
+
+				switch op {
+				case 0x7:
+					ok = y > m
+				case 0x8:
+					ok = y < m
+				case 0x9:
+					ok = y == m
+				case 0xA:
+					ok = y != m
+				}
+			}
+
+			conds = append(conds, ok)
+			continue
+		}
+
+		switch a >> 24 {
+		case 0xD0:
+			if n := len(conds); n != 0 {
+				conds = conds[:n-1]
+			}
+			continue
+
+		case 0xD1:
+			i = next(i)
+			continue
+
+		case 0xD2:
+			if j := next(i); j != i {
+				i = j
+				continue
+			}
+
+			offset, data, conds = 0, 0, conds[:0]
+			continue
+		}
+
+		if !active() {
+			if op == 0xE {
+				i += int(v+7) / 8
+			}
+
+			continue
+		}
+
+		switch op {
+		case 0x0:
+			b.Write32(addr+offset, v)
+		case 0x1:
+			b.Write16(addr+offset, uint16(v))
+		case 0x2:
+			b.Write8(addr+offset, uint8(v))
+		case 0xB:
+			offset = b.Read32(addr + offset)
+
+		case 0xE:
+			// the bytes follow eight a line, each word little endian
+			for k := range v {
+				w := c[i+1+int(k/8)][k/4%2]
+				b.Write8(addr+offset+k, uint8(w>>(8*(k%4))))
+			}
+			i += int(v+7) / 8
+
+		case 0xF:
+			for k := range v {
+				b.Write8(addr+k, b.Read8(offset+k))
+			}
+
+		default:
+			switch a >> 24 {
+			case 0xC0:
+				loop, loopCount, loopConds = i, v, len(conds)
+			case 0xC6:
+				b.Write32(v, offset)
+			case 0xD3:
+				offset = v
+			case 0xD4:
+				data += v
+			case 0xD5:
+				data = v
+			case 0xD6:
+				b.Write32(v+offset, data)
+				offset += 4
+			case 0xD7:
+				b.Write16(v+offset, uint16(data))
+				offset += 2
+			case 0xD8:
+				b.Write8(v+offset, uint8(data))
+				offset++
+			case 0xD9:
+				data = b.Read32(v + offset)
+			case 0xDA:
+				data = uint32(b.Read16(v + offset))
+			case 0xDB:
+				data = uint32(b.Read8(v + offset))
+			case 0xDC:
+				offset += v
+			}
+		}
+	}
+}
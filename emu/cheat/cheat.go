@@ -0,0 +1,297 @@
+// cheat parses and applies cheat codes.
+//
+// Cheats for a rom are kept beside it in a .cht toml file. Each Cheat has a
+// Format and its code lines as they are printed, spaces and dashes are
+// ignored. An Engine compiles the enabled cheats of a console, codes which
+// patch the rom are applied once by Set and undone by the next Set, the rest
+// run every frame from Apply through the console's Bus.
+//
+// Formats are
+//
+//	gb   game genie "ABC-DEF-GHI" and gameshark "01VVAAAA"
+//	gba  gameshark and action replay v1, v2 and v3, encrypted, and
+//	     codebreaker, decrypted
+//	nds  action replay ds, with conditionals and loops
+package cheat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aabalke/guac/emu/cpu"
+	"github.com/aabalke/guac/emu/rom"
+)
+
+type Format string
+
+const (
+	GAME_GENIE  Format = "gamegenie"   // gb
+	GAMESHARK   Format = "gameshark"   // gb
+	GSA_V1      Format = "gsa1"        // gba gameshark and action replay v1, v2
+	GSA_V3      Format = "gsa3"        // gba action replay v3
+	CODEBREAKER Format = "codebreaker" // gba
+	ARDS        Format = "ards"        // nds action replay
+)
+
+// Formats are the cheat formats of each console
+var Formats = map[rom.Console][]Format{
+	rom.GB:  {GAME_GENIE, GAMESHARK},
+	rom.GBA: {GSA_V1, GSA_V3, CODEBREAKER},
+	rom.NDS: {ARDS},
+}
+
+// lines run per frame at most, a runaway loop gives up instead of hanging
+const MAX_STEPS = 1 << 20
+
+var (
+	ErrFormat      = errors.New("format is not supported by the console")
+	ErrUnsupported = errors.New("code type is not supported")
+	ErrSyntax      = errors.New("code is not hex of the expected length")
+)
+
+type Cheat struct {
+	Name    string   `toml:"name"`
+	Format  Format   `toml:"format"`
+	Enabled bool     `toml:"enabled"`
+	Codes   []string `toml:"codes"`
+}
+
+type file struct {
+	Cheats []Cheat `toml:"cheat"`
+}
+
+// Load reads a .cht file, a missing file has no cheats
+func Load(path string) ([]Cheat, error) {
+	var f file
+
+	if _, err := toml.DecodeFile(path, &f); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return f.Cheats, nil
+}
+
+func Save(path string, cheats []Cheat) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := toml.NewEncoder(f).Encode(file{Cheats: cheats}); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Bus is the memory cheats read and write, at the addresses the cpu uses
+type Bus interface {
+	Read8(addr uint32) uint8
+	Read16(addr uint32) uint16
+	Read32(addr uint32) uint32
+	Write8(addr uint32, v uint8)
+	Write16(addr uint32, v uint16)
+	Write32(addr uint32, v uint32)
+}
+
+// program is a compiled cheat run every frame
+type program interface {
+	run(b Bus)
+}
+
+// Engine applies the cheats of one console
+type Engine struct {
+	Console rom.Console
+	Bus     Bus
+
+	// Rom is patched in place by rom patch codes
+	Rom []uint8
+
+	programs []program
+	original map[int]uint8 // rom bytes under the patches
+}
+
+func NewEngine(console rom.Console, bus Bus, romData []uint8) *Engine {
+	return &Engine{
+		Console:  console,
+		Bus:      bus,
+		Rom:      romData,
+		original: map[int]uint8{},
+	}
+}
+
+// Set replaces the cheats in use with the enabled ones. A cheat which does
+// not compile is left out and its error returned, the rest still apply.
+func (e *Engine) Set(cheats []Cheat) error {
+	for off, v := range e.original {
+		e.Rom[off] = v
+	}
+
+	clear(e.original)
+	e.programs = e.programs[:0]
+
+	var errs []error
+
+	for _, c := range cheats {
+		if !c.Enabled {
+			continue
+		}
+
+		if err := e.compile(c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Apply runs the cheats, once a frame
+func (e *Engine) Apply() {
+	for _, p := range e.programs {
+		p.run(e.Bus)
+	}
+}
+
+// Active reports if any cheat is running every frame
+func (e *Engine) Active() bool {
+	return len(e.programs) != 0
+}
+
+func (e *Engine) compile(c Cheat) error {
+	supported := false
+	for _, f := range Formats[e.Console] {
+		supported = supported || f == c.Format
+	}
+
+	if !supported {
+		return fmt.Errorf("%q: %w", c.Format, ErrFormat)
+	}
+
+	var (
+		p       program
+		patches []patch
+		err     error
+	)
+
+	switch c.Format {
+	case GAME_GENIE:
+		patches, err = compileGameGenie(c.Codes, e.Rom)
+	case GAMESHARK:
+		p, err = compileGameShark(c.Codes)
+	case GSA_V1:
+		p, patches, err = compileGsa(c.Codes, false)
+	case GSA_V3:
+		p, patches, err = compileGsa(c.Codes, true)
+	case CODEBREAKER:
+		p, patches, err = compileCodeBreaker(c.Codes)
+	case ARDS:
+		p, err = compileArds(c.Codes)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// patches are checked before any is made, a cheat applies whole
+	for _, pt := range patches {
+		if pt.off < 0 || pt.off >= len(e.Rom) {
+			return fmt.Errorf("rom patch at %X is outside the rom", pt.off)
+		}
+	}
+
+	for _, pt := range patches {
+		if _, ok := e.original[pt.off]; !ok {
+			e.original[pt.off] = e.Rom[pt.off]
+		}
+
+		e.Rom[pt.off] = pt.v
+	}
+
+	if p != nil {
+		e.programs = append(e.programs, p)
+	}
+
+	return nil
+}
+
+// patch is a rom byte replaced while the cheat is on, off is in the rom
+type patch struct {
+	off int
+	v   uint8
+}
+
+// patch16 is a little endian rom patch at bus address addr
+func patch16(addr, base uint32, v uint16) []patch {
+	off := int(addr - base)
+	return []patch{{off, uint8(v)}, {off + 1, uint8(v >> 8)}}
+}
+
+// digits is a code line without separators, it must be n hex digits long
+func digits(line string, n ...int) (string, error) {
+	s := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', ':':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(line))
+
+	for _, l := range n {
+		if len(s) != l {
+			continue
+		}
+
+		if _, err := strconv.ParseUint(s, 16, 64); err != nil {
+			break
+		}
+
+		return s, nil
+	}
+
+	return "", fmt.Errorf("%q: %w", line, ErrSyntax)
+}
+
+// words splits code lines into an address and a value, the value is the
+// last vlen hex digits of the line
+func words(lines []string, vlen int) ([][2]uint32, error) {
+	out := make([][2]uint32, 0, len(lines))
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		s, err := digits(line, 8+vlen)
+		if err != nil {
+			return nil, err
+		}
+
+		a, _ := strconv.ParseUint(s[:8], 16, 32)
+		v, _ := strconv.ParseUint(s[8:], 16, 32)
+		out = append(out, [2]uint32{uint32(a), uint32(v)})
+	}
+
+	return out, nil
+}
+
+// ArmBus is the Bus of an arm cpu, Arm9 picks the nds arm9 memory map
+type ArmBus struct {
+	Mem  cpu.MemoryInterface
+	Arm9 bool
+}
+
+func (b ArmBus) Read8(addr uint32) uint8   { return uint8(b.Mem.Read8(addr, b.Arm9)) }
+func (b ArmBus) Read16(addr uint32) uint16 { return uint16(b.Mem.Read16(addr, b.Arm9)) }
+func (b ArmBus) Read32(addr uint32) uint32 { return b.Mem.Read32(addr, b.Arm9) }
+
+func (b ArmBus) Write8(addr uint32, v uint8)   { b.Mem.Write8(addr, v, b.Arm9) }
+func (b ArmBus) Write16(addr uint32, v uint16) { b.Mem.Write16(addr, v, b.Arm9) }
+func (b ArmBus) Write32(addr uint32, v uint32) { b.Mem.Write32(addr, v, b.Arm9) }
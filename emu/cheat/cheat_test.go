@@ -0,0 +1,347 @@
+package cheat
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aabalke/guac/emu/rom"
+)
+
+// mem is a little endian bus over a sparse address space
+type mem map[uint32]uint8
+
+func (m mem) Read8(addr uint32) uint8   { return m[addr] }
+func (m mem) Read16(addr uint32) uint16 { return uint16(m[addr]) | uint16(m[addr+1])<<8 }
+func (m mem) Read32(addr uint32) uint32 {
+	return uint32(m.Read16(addr)) | uint32(m.Read16(addr+2))<<16
+}
+
+func (m mem) Write8(addr uint32, v uint8) { m[addr] = v }
+func (m mem) Write16(addr uint32, v uint16) {
+	m[addr], m[addr+1] = uint8(v), uint8(v>>8)
+}
+func (m mem) Write32(addr uint32, v uint32) {
+	m.Write16(addr, uint16(v))
+	m.Write16(addr+2, uint16(v>>16))
+}
+
+func encryptGsa(a, v uint32, seeds *[4]uint32) (uint32, uint32) {
+	var sum uint32
+
+	for range 32 {
+		sum += 0x9E3779B9
+		a += ((v << 4) + seeds[0]) ^ (v + sum) ^ ((v >> 5) + seeds[1])
+		v += ((a << 4) + seeds[2]) ^ (a + sum) ^ ((a >> 5) + seeds[3])
+	}
+
+	return a, v
+}
+
+// gsa encrypts decrypted code pairs into lines
+func gsa(seeds *[4]uint32, code ...uint32) []string {
+	var lines []string
+
+	for i := 0; i < len(code); i += 2 {
+		a, v := encryptGsa(code[i], code[i+1], seeds)
+		lines = append(lines, fmt.Sprintf("%08X %08X", a, v))
+	}
+
+	return lines
+}
+
+func gameGenie(v uint8, addr uint16, cmp uint8) string {
+	c := cmp ^ 0xBA
+	c = c<<2 | c>>6
+
+	return fmt.Sprintf("%02X%X-%X%X%X-%X0%X",
+		v, addr>>8&0xF, addr>>4&0xF, addr&0xF, addr>>12^0xF, c>>4, c&0xF)
+}
+
+func TestGameGenie(t *testing.T) {
+	data := make([]uint8, 4*GB_BANK_SIZE)
+	data[0x0150] = 0x11
+	data[1*GB_BANK_SIZE+0x0123] = 0x3C
+	data[2*GB_BANK_SIZE+0x0123] = 0x00
+	data[3*GB_BANK_SIZE+0x0123] = 0x3C
+
+	e := NewEngine(rom.GB, mem{}, data)
+
+	err := e.Set([]Cheat{{
+		Name:    "lives",
+		Format:  GAME_GENIE,
+		Enabled: true,
+		Codes:   []string{gameGenie(0x99, 0x4123, 0x3C), gameGenie(0x22, 0x0150, 0)[:7]},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data[GB_BANK_SIZE+0x123] != 0x99 || data[2*GB_BANK_SIZE+0x123] != 0x00 || data[3*GB_BANK_SIZE+0x123] != 0x99 {
+		t.Fatalf("banks hold %02X %02X %02X", data[GB_BANK_SIZE+0x123], data[2*GB_BANK_SIZE+0x123], data[3*GB_BANK_SIZE+0x123])
+	}
+
+	if data[0x0150] != 0x22 {
+		t.Fatalf("code without compare wrote %02X", data[0x0150])
+	}
+
+	if err := e.Set(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if data[GB_BANK_SIZE+0x123] != 0x3C || data[0x0150] != 0x11 {
+		t.Fatal("rom was not restored")
+	}
+}
+
+func TestGameShark(t *testing.T) {
+	m := mem{GB_SVBK: 1}
+	e := NewEngine(rom.GB, m, nil)
+
+	err := e.Set([]Cheat{{
+		Format:  GAMESHARK,
+		Enabled: true,
+		Codes:   []string{"010238CD", "93FF00D0"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m[0xD000] = 0
+	e.Apply()
+
+	if m[0xCD38] != 0x02 || m[0xD000] != 0xFF || m[GB_SVBK] != 1 {
+		t.Fatalf("wrote %02X %02X, svbk %d", m[0xCD38], m[0xD000], m[GB_SVBK])
+	}
+
+	if err := e.Set([]Cheat{{Format: GAMESHARK, Enabled: true, Codes: []string{"45000000"}}}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("unknown type: %v", err)
+	}
+}
+
+func TestGsaV1(t *testing.T) {
+	data := make([]uint8, 0x1000)
+	m := mem{}
+	e := NewEngine(rom.GBA, m, data)
+
+	codes := gsa(&gsaSeedsV1,
+		0x2200_0010, 0x1234_5678, // 32 bit write
+		0xD200_0020, 0x0000_0001, // if [2000020] == 1
+		0x1200_0030, 0x0000_BEEF, // 16 bit write
+		0x6000_0100, 0x0000_4E70, // rom patch at 8000200
+	)
+
+	if err := e.Set([]Cheat{{Format: GSA_V1, Enabled: true, Codes: codes}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data[0x200] != 0x70 || data[0x201] != 0x4E {
+		t.Fatalf("rom patch wrote %02X %02X", data[0x200], data[0x201])
+	}
+
+	e.Apply()
+
+	if m.Read32(0x200_0010) != 0x1234_5678 || m.Read16(0x200_0030) != 0 {
+		t.Fatalf("wrote %08X %04X", m.Read32(0x200_0010), m.Read16(0x200_0030))
+	}
+
+	m.Write16(0x200_0020, 1)
+	e.Apply()
+
+	if m.Read16(0x200_0030) != 0xBEEF {
+		t.Fatal("conditional write did not run")
+	}
+
+	if err := e.Set([]Cheat{{Format: ARDS, Enabled: true}}); !errors.Is(err, ErrFormat) {
+		t.Fatalf("nds format on gba: %v", err)
+	}
+}
+
+func arV3Code(addr uint32, typ uint8) uint32 {
+	return uint32(typ&0x7F)<<25 | uint32(typ>>7)<<24 | addr>>4&0x00F0_0000 | addr&0x000F_FFFF
+}
+
+func TestArV3(t *testing.T) {
+	data := make([]uint8, 0x1000)
+	m := mem{}
+	e := NewEngine(rom.GBA, m, data)
+
+	codes := gsa(&gsaSeedsV3,
+		arV3Code(0x300_1000, 0x02), 0x0003_ABCD, // 16 bit fill, 4 half words
+		arV3Code(0x200_0000, 0x8A), 0x0000_0005, // if [2000000] == 5, block
+		arV3Code(0x200_0010, 0x00), 0x0000_0011,
+		0, AR_ELSE<<24,
+		arV3Code(0x200_0010, 0x00), 0x0000_0022,
+		0, AR_END_IF<<24,
+		arV3Code(0x200_0020, 0x84), 0x0000_0001, // 32 bit add
+		0, 0x1800_0080, // rom patch at 8000100
+		0x0000_1234, 0,
+	)
+
+	if err := e.Set([]Cheat{{Format: GSA_V3, Enabled: true, Codes: codes}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data[0x100] != 0x34 || data[0x101] != 0x12 {
+		t.Fatalf("rom patch wrote %02X %02X", data[0x100], data[0x101])
+	}
+
+	e.Apply()
+
+	if m.Read16(0x300_1006) != 0xABCD || m.Read16(0x300_1008) != 0 {
+		t.Fatal("fill wrote the wrong count")
+	}
+
+	if m[0x200_0010] != 0x22 {
+		t.Fatalf("else wrote %02X", m[0x200_0010])
+	}
+
+	m[0x200_0000] = 5
+	e.Apply()
+
+	if m[0x200_0010] != 0x11 || m.Read32(0x200_0020) != 2 {
+		t.Fatalf("if wrote %02X, add %d", m[0x200_0010], m.Read32(0x200_0020))
+	}
+}
+
+func TestCodeBreaker(t *testing.T) {
+	m := mem{GBA_KEYINPUT: 0xFF, GBA_KEYINPUT + 1: 0x03}
+	e := NewEngine(rom.GBA, m, nil)
+
+	codes := []string{
+		"0000ABCD 0007", // master
+		"83000010 1234",
+		"D0000020 0001", // a held
+		"33000012 0056",
+		"43000020 0100", // slide from 0x100, 3 times, +1 every 4 bytes
+		"00010003 0004",
+		"53000040 0003", // 6 bytes
+		"11223344 5566",
+	}
+
+	if err := e.Set([]Cheat{{Format: CODEBREAKER, Enabled: true, Codes: codes}}); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Apply()
+
+	if m.Read16(0x300_0010) != 0x1234 || m[0x300_0012] != 0 {
+		t.Fatalf("wrote %04X %02X", m.Read16(0x300_0010), m[0x300_0012])
+	}
+
+	for k, want := range []uint16{0x100, 0x101, 0x102, 0} {
+		if got := m.Read16(0x300_0020 + uint32(k)*4); got != want {
+			t.Fatalf("slide %d wrote %04X", k, got)
+		}
+	}
+
+	for k, want := range []uint8{0x11, 0x22, 0x33, 0x44, 0x55, 0x66} {
+		if got := m[0x300_0040+uint32(k)]; got != want {
+			t.Fatalf("super byte %d is %02X", k, got)
+		}
+	}
+
+	m[GBA_KEYINPUT] = 0xFE
+	e.Apply()
+
+	if m[0x300_0012] != 0x56 {
+		t.Fatal("key conditional did not run")
+	}
+
+	if err := e.Set([]Cheat{{Format: CODEBREAKER, Enabled: true, Codes: []string{"9ABCDEF0 1234"}}}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("encrypted codes: %v", err)
+	}
+}
+
+func TestArds(t *testing.T) {
+	m := mem{}
+	e := NewEngine(rom.NDS, m, nil)
+
+	codes := []string{
+		"0210 0000 0000 0001",
+		"52100000 00000001",   // if word == 1
+		"  92100004 FF000042", // if half & ff == 42, nested
+		"  22100008 00000077",
+		"  D0000000 00000000",
+		"D2000000 00000000",
+		"D3000000 02200000", // offset
+		"D5000000 CAFE0000", // data
+		"C0000000 00000003", // 4 words
+		"D6000000 00000000",
+		"D2000000 00000000",
+		"E2300000 0000000A", // copy 10 bytes
+		"44332211 88776655",
+		"0000AA99 00000000",
+		"62100000 00000001", // if word != 1
+		"02100010 DEADBEEF",
+		"D2000000 00000000",
+	}
+
+	if err := e.Set([]Cheat{{Format: ARDS, Enabled: true, Codes: codes}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Write16(0x210_0004, 0x1242)
+	e.Apply()
+
+	if m.Read32(0x210_0000) != 1 || m[0x210_0008] != 0x77 || m.Read32(0x210_0010) != 0 {
+		t.Fatalf("wrote %08X %02X %08X", m.Read32(0x210_0000), m[0x210_0008], m.Read32(0x210_0010))
+	}
+
+	for k := range uint32(5) {
+		want := uint32(0xCAFE0000)
+		if k == 4 {
+			want = 0
+		}
+
+		if got := m.Read32(0x220_0000 + k*4); got != want {
+			t.Fatalf("loop word %d is %08X", k, got)
+		}
+	}
+
+	var copied []uint8
+	for k := range uint32(11) {
+		copied = append(copied, m[0x230_0000+k])
+	}
+
+	if want := []uint8{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0}; !reflect.DeepEqual(copied, want) {
+		t.Fatalf("copied % X", copied)
+	}
+}
+
+func TestArdsUnsupported(t *testing.T) {
+	e := NewEngine(rom.NDS, mem{}, nil)
+
+	codes := []string{"C4000000 00000000", "02000000 00000001"}
+	if err := e.Set([]Cheat{{Format: ARDS, Enabled: true, Codes: codes}}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("c4 code: %v", err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.nds.cht")
+
+	if cheats, err := Load(path); err != nil || cheats != nil {
+		t.Fatalf("missing file: %v %v", cheats, err)
+	}
+
+	want := []Cheat{
+		{Name: "max money", Format: ARDS, Enabled: true, Codes: []string{"02100000 0098967F"}},
+		{Name: "walk through walls", Format: ARDS, Codes: []string{"12100010 00000001", "D2000000 00000000"}},
+	}
+
+	if err := Save(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("loaded %+v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package cheat
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	GB_BANK_SIZE = 0x4000
+	GB_SVBK      = 0xFF70
+)
+
+// compileGameGenie patches the rom. "VVA-AAA" always replaces the byte,
+// "VVA-AAA-CxC" only where the rom holds the compare value. The switchable
+// area is patched in every bank.
+func compileGameGenie(lines []string, data []uint8) ([]patch, error) {
+	var patches []patch
+
+	for _, line := range lines {
+		s, err := digits(line, 6, 9)
+		if err != nil {
+			return nil, err
+		}
+
+		d := make([]uint32, len(s))
+		for i := range s {
+			v, _ := strconv.ParseUint(s[i:i+1], 16, 8)
+			d[i] = uint32(v)
+		}
+
+		var (
+			v    = uint8(d[0]<<4 | d[1])
+			addr = (d[5]^0xF)<<12 | d[2]<<8 | d[3]<<4 | d[4]
+			cmp  = -1
+		)
+
+		if addr >= 0x8000 {
+			return nil, fmt.Errorf("%q: address %04X is not in the rom", line, addr)
+		}
+
+		if len(d) == 9 {
+			c := uint8(d[6]<<4 | d[8])
+			cmp = int((c>>2 | c<<6) ^ 0xBA)
+		}
+
+		offs := []int{int(addr)}
+		if addr >= GB_BANK_SIZE {
+			offs = offs[:0]
+			for bank := GB_BANK_SIZE; bank < len(data); bank += GB_BANK_SIZE {
+				offs = append(offs, bank+int(addr)-GB_BANK_SIZE)
+			}
+		}
+
+		for _, off := range offs {
+			if off >= len(data) || (cmp >= 0 && int(data[off]) != cmp) {
+				continue
+			}
+
+			patches = append(patches, patch{off, v})
+		}
+	}
+
+	return patches, nil
+}
+
+type gameShark struct {
+	writes []gsWrite
+}
+
+type gsWrite struct {
+	bank uint8 // wram bank of d000 - dfff, 0 keeps the current one
+	v    uint8
+	addr uint16
+}
+
+// compileGameShark writes "TTVVLLHH" every frame, type 01 to the address as
+// mapped and 8x / 9x to wram bank x
+func compileGameShark(lines []string) (program, error) {
+	p := &gameShark{}
+
+	for _, line := range lines {
+		s, err := digits(line, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		n, _ := strconv.ParseUint(s, 16, 32)
+
+		w := gsWrite{
+			v:    uint8(n >> 16),
+			addr: uint16(n>>8)&0xFF | uint16(n&0xFF)<<8,
+		}
+
+		switch t := uint8(n >> 24); {
+		case t == 0x01:
+		case t&0xE8 == 0x80:
+			w.bank = t & 7
+		default:
+			return nil, fmt.Errorf("%q: type %02X: %w", line, t, ErrUnsupported)
+		}
+
+		p.writes = append(p.writes, w)
+	}
+
+	return p, nil
+}
+
+func (p *gameShark) run(b Bus) {
+	for _, w := range p.writes {
+		if w.bank == 0 || w.addr < 0xD000 || w.addr >= 0xE000 {
+			b.Write8(uint32(w.addr), w.v)
+			continue
+		}
+
+		svbk := b.Read8(GB_SVBK)
+		b.Write8(GB_SVBK, w.bank)
+		b.Write8(uint32(w.addr), w.v)
+		b.Write8(GB_SVBK, svbk)
+	}
+}
@@ -0,0 +1,477 @@
+package cheat
+
+import (
+	"fmt"
+)
+
+const (
+	GBA_ROM      = 0x800_0000
+	GBA_KEYINPUT = 0x400_0130
+	GBA_IO       = 0x400_0000
+
+	GSA_DEADFACE = 0xDEADFACE
+)
+
+var (
+	gsaSeedsV1 = [4]uint32{0x09F4FBBD, 0x9681884A, 0x352027E9, 0xF3DEE5A7}
+	gsaSeedsV3 = [4]uint32{0x7AA9648F, 0x7FAE6994, 0xC0EFAAD5, 0x42712C57}
+)
+
+// decryptGsa undoes the tea encryption of gameshark and action replay codes
+func decryptGsa(a, v uint32, seeds *[4]uint32) (uint32, uint32) {
+	sum := uint32(0xC6EF3720)
+
+	for range 32 {
+		v -= ((a << 4) + seeds[2]) ^ (a + sum) ^ ((a >> 5) + seeds[3])
+		a -= ((v << 4) + seeds[0]) ^ (v + sum) ^ ((v >> 5) + seeds[1])
+		sum -= 0x9E3779B9
+	}
+
+	return a, v
+}
+
+func compileGsa(lines []string, v3 bool) (program, []patch, error) {
+	code, err := words(lines, 8)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seeds := &gsaSeedsV1
+	if v3 {
+		seeds = &gsaSeedsV3
+	}
+
+	for i := range code {
+		code[i][0], code[i][1] = decryptGsa(code[i][0], code[i][1], seeds)
+
+		if code[i][0] == GSA_DEADFACE {
+			return nil, nil, fmt.Errorf("deadface seed change: %w", ErrUnsupported)
+		}
+	}
+
+	if v3 {
+		return compileArV3(code)
+	}
+
+	return compileGsaV1(code)
+}
+
+type gsaV1 struct {
+	code [][2]uint32
+}
+
+// compileGsaV1 takes the decrypted v1 and v2 codes. Type 6 patches the rom,
+// button (8) and hook (F) codes are dropped.
+func compileGsaV1(code [][2]uint32) (program, []patch, error) {
+	var (
+		p       = &gsaV1{}
+		patches []patch
+	)
+
+	for i := 0; i < len(code); i++ {
+		a, v := code[i][0], code[i][1]
+
+		switch a >> 28 {
+		case 0x0, 0x1, 0x2, 0xD, 0xE:
+			p.code = append(p.code, code[i])
+
+		case 0x3:
+			// the addresses follow two a line
+			n := int(a&0xFFFF+1) / 2
+			if i+n >= len(code) {
+				return nil, nil, fmt.Errorf("group write is missing addresses: %w", ErrSyntax)
+			}
+
+			p.code = append(p.code, code[i:i+n+1]...)
+			i += n
+
+		case 0x6:
+			addr := GBA_ROM + (a<<1)&0x1FF_FFFE
+			patches = append(patches, patch16(addr, GBA_ROM, uint16(v))...)
+
+		case 0x8, 0xF:
+
+		default:
+			return nil, nil, fmt.Errorf("%08X %08X: %w", a, v, ErrUnsupported)
+		}
+	}
+
+	return p, patches, nil
+}
+
+func (p *gsaV1) run(b Bus) {
+	c := p.code
+
+	for i := 0; i < len(c); i++ {
+		a, v := c[i][0], c[i][1]
+		addr := a & 0x0FFF_FFFF
+
+		switch a >> 28 {
+		case 0x0:
+			b.Write8(addr, uint8(v))
+		case 0x1:
+			b.Write16(addr, uint16(v))
+		case 0x2:
+			b.Write32(addr, v)
+		case 0x3:
+			n := int(a & 0xFFFF)
+			for k := range n {
+				b.Write32(c[i+1+k/2][k%2], v)
+			}
+			i += (n + 1) / 2
+
+		case 0xD:
+			// the next line runs when equal
+			if b.Read16(addr) != uint16(v) {
+				i++
+			}
+
+		case 0xE:
+			// E0zzvvvv 0aaaaaaa, the next zz lines run when equal
+			if b.Read16(v&0x0FFF_FFFF) != uint16(a) {
+				i += int(a >> 16 & 0xFF)
+			}
+		}
+	}
+}
+
+const (
+	// action replay v3 comparisons, bits 3 - 5 of the type
+	AR_EQ = 1 + iota
+	AR_NE
+	AR_LT
+	AR_GT
+	AR_LTU
+	AR_GTU
+	AR_AND
+
+	// what a failed comparison skips, bits 6 - 7
+	AR_SKIP_1     = 0
+	AR_SKIP_2     = 1
+	AR_SKIP_BLOCK = 2
+	AR_SKIP_ALL   = 3
+
+	// special codes have a zero address and the kind in the value
+	AR_ELSE   = 0x40
+	AR_END_IF = 0x60
+)
+
+type arV3 struct {
+	code [][2]uint32
+}
+
+// arV3Addr unpacks the address and type of a decrypted code
+func arV3Addr(a uint32) (addr uint32, typ uint8) {
+	addr = (a&0x00F0_0000)<<4 | a&0x000F_FFFF
+	typ = uint8(a>>25&0x7F | a>>17&0x80)
+	return
+}
+
+// compileArV3 takes the decrypted v3 codes, writes, pointer writes, adds,
+// io writes, comparisons and rom patches
+func compileArV3(code [][2]uint32) (program, []patch, error) {
+	var (
+		p       = &arV3{}
+		patches []patch
+	)
+
+	for i := 0; i < len(code); i++ {
+		a, v := code[i][0], code[i][1]
+
+		if a == 0 {
+			switch k := v >> 24; k {
+			case 0x00, AR_ELSE, AR_END_IF:
+				p.code = append(p.code, code[i])
+
+			case 0x18, 0x1A, 0x1C, 0x1E:
+				// 00000000 18aaaaaa, 0000vvvv 00000000
+				if i+1 >= len(code) {
+					return nil, nil, fmt.Errorf("rom patch is missing its value: %w", ErrSyntax)
+				}
+
+				addr := GBA_ROM + (v&0xFF_FFFF)<<1
+				patches = append(patches, patch16(addr, GBA_ROM, uint16(code[i+1][0]))...)
+				i++
+
+			default:
+				return nil, nil, fmt.Errorf("special %08X: %w", v, ErrUnsupported)
+			}
+
+			continue
+		}
+
+		switch _, t := arV3Addr(a); t {
+		case 0x00, 0x02, 0x04, // writes
+			0x40, 0x42, 0x44, // pointer writes
+			0x80, 0x82, 0x84, // adds
+			0xC6, 0xC7: // io writes
+			p.code = append(p.code, code[i])
+
+		case 0xC4:
+			// hook
+
+		default:
+			if t>>3&7 == 0 {
+				return nil, nil, fmt.Errorf("type %02X: %w", t, ErrUnsupported)
+			}
+
+			p.code = append(p.code, code[i])
+		}
+	}
+
+	return p, patches, nil
+}
+
+func (p *arV3) run(b Bus) {
+	var (
+		c     = p.code
+		block []bool // comparisons of open blocks
+	)
+
+	active := func() bool {
+		for _, ok := range block {
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i := 0; i < len(c); i++ {
+		a, v := c[i][0], c[i][1]
+
+		if a == 0 {
+			switch v >> 24 {
+			case AR_ELSE:
+				if n := len(block); n != 0 {
+					block[n-1] = !block[n-1]
+				}
+			case AR_END_IF:
+				if n := len(block); n != 0 {
+					block = block[:n-1]
+				}
+			}
+
+			continue
+		}
+
+		addr, t := arV3Addr(a)
+
+		if !active() {
+			// blocks nest inside skipped blocks
+			if t>>3&7 != 0 && t>>6 == AR_SKIP_BLOCK {
+				block = append(block, false)
+			}
+			continue
+		}
+
+		if t>>3&7 == 0 {
+			arV3Write(b, addr, t, v)
+			continue
+		}
+
+		ok := arV3Compare(b, addr, t, v)
+
+		switch t >> 6 {
+		case AR_SKIP_1:
+			if !ok {
+				i++
+			}
+		case AR_SKIP_2:
+			if !ok {
+				i += 2
+			}
+		case AR_SKIP_BLOCK:
+			block = append(block, ok)
+		case AR_SKIP_ALL:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func arV3Write(b Bus, addr uint32, t uint8, v uint32) {
+	switch t {
+	case 0x00: // 8 bit fill, zzzzzzvv
+		for k := range v>>8 + 1 {
+			b.Write8(addr+k, uint8(v))
+		}
+	case 0x02: // 16 bit fill, zzzzvvvv
+		for k := range v>>16 + 1 {
+			b.Write16(addr+k*2, uint16(v))
+		}
+	case 0x04:
+		b.Write32(addr, v)
+
+	case 0x40: // [[a] + z] = vv
+		b.Write8(b.Read32(addr)+v>>8, uint8(v))
+	case 0x42: // [[a] + z * 2] = vvvv
+		b.Write16(b.Read32(addr)+v>>16*2, uint16(v))
+	case 0x44:
+		b.Write32(b.Read32(addr), v)
+
+	case 0x80:
+		b.Write8(addr, b.Read8(addr)+uint8(v))
+	case 0x82:
+		b.Write16(addr, b.Read16(addr)+uint16(v))
+	case 0x84:
+		b.Write32(addr, b.Read32(addr)+v)
+
+	case 0xC6:
+		b.Write16(GBA_IO|addr&0xFF_FFFF, uint16(v))
+	case 0xC7:
+		b.Write32(GBA_IO|addr&0xFF_FFFF, v)
+	}
+}
+
+func arV3Compare(b Bus, addr uint32, t uint8, v uint32) bool {
+	var (
+		m    uint32
+		sign uint32
+	)
+
+	switch t & 6 {
+	case 0:
+		m, v, sign = uint32(b.Read8(addr)), v&0xFF, 0x80
+	case 2:
+		m, v, sign = uint32(b.Read16(addr)), v&0xFFFF, 0x8000
+	default:
+		m, sign = b.Read32(addr), 0x8000_0000
+	}
+
+	switch t >> 3 & 7 {
+	case AR_EQ:
+		return m == v
+	case AR_NE:
+		return m != v
+	case AR_LT:
+		return m^sign < v^sign
+	case AR_GT:
+		return m^sign > v^sign
+	case AR_LTU:
+		return m < v
+	case AR_GTU:
+		return m > v
+	default:
+		return m&v != 0
+	}
+}
+
+type codeBreaker struct {
+	code [][2]uint32
+}
+
+// compileCodeBreaker takes decrypted "TAAAAAAA VVVV" codes, the 9 seed code
+// of encrypted lists is not supported
+func compileCodeBreaker(lines []string) (program, []patch, error) {
+	code, err := words(lines, 4)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := &codeBreaker{}
+
+	for i := 0; i < len(code); i++ {
+		a, v := code[i][0], code[i][1]
+
+		switch a >> 28 {
+		case 0x0, 0x1:
+			// master and hook
+
+		case 0x4:
+			// slide, the step is on the next line
+			if i+1 >= len(code) {
+				return nil, nil, fmt.Errorf("slide is missing its step: %w", ErrSyntax)
+			}
+
+			p.code = append(p.code, code[i:i+2]...)
+			i++
+
+		case 0x5:
+			// the bytes follow six a line
+			n := (int(v)*2 + 5) / 6
+			if i+n >= len(code) {
+				return nil, nil, fmt.Errorf("super code is missing bytes: %w", ErrSyntax)
+			}
+
+			p.code = append(p.code, code[i:i+n+1]...)
+			i += n
+
+		case 0x9:
+			return nil, nil, fmt.Errorf("encrypted codes, use decrypted ones: %w", ErrUnsupported)
+
+		case 0xD:
+			if a != 0xD000_0020 {
+				return nil, nil, fmt.Errorf("%08X %04X: %w", a, v, ErrUnsupported)
+			}
+
+			p.code = append(p.code, code[i])
+
+		default:
+			p.code = append(p.code, code[i])
+		}
+	}
+
+	return p, nil, nil
+}
+
+func (p *codeBreaker) run(b Bus) {
+	c := p.code
+
+	for i := 0; i < len(c); i++ {
+		a, v := c[i][0], uint16(c[i][1])
+		addr := a & 0x0FFF_FFFF
+
+		skip := false
+
+		switch a >> 28 {
+		case 0x2:
+			b.Write16(addr, b.Read16(addr)|v)
+		case 0x3:
+			b.Write8(addr, uint8(v))
+		case 0x4:
+			// iiiinnnn ssss, n writes of v + k * i every s bytes
+			step := c[i+1]
+			inc, n := uint16(step[0]>>16), int(step[0]&0xFFFF)
+			for k := range n {
+				b.Write16(addr+uint32(k)*step[1], v+uint16(k)*inc)
+			}
+			i++
+		case 0x5:
+			n := int(v) * 2
+			for k := range n {
+				line := c[i+1+k/6]
+				if k%6 < 4 {
+					b.Write8(addr+uint32(k), uint8(line[0]>>(24-8*(k%6))))
+				} else {
+					b.Write8(addr+uint32(k), uint8(line[1]>>(8-8*(k%6-4))))
+				}
+			}
+			i += (n + 5) / 6
+		case 0x6:
+			b.Write16(addr, b.Read16(addr)&v)
+		case 0x7:
+			skip = b.Read16(addr) != v
+		case 0x8:
+			b.Write16(addr, v)
+		case 0xA:
+			skip = b.Read16(addr) == v
+		case 0xB:
+			skip = int16(b.Read16(addr)) <= int16(v)
+		case 0xC:
+			skip = int16(b.Read16(addr)) >= int16(v)
+		case 0xD:
+			// keys held, keyinput is active low
+			skip = ^b.Read16(GBA_KEYINPUT)&v != v
+		case 0xE:
+			b.Write16(addr, b.Read16(addr)+v)
+		case 0xF:
+			skip = b.Read16(addr)&v == 0
+		}
+
+		if skip {
+			i++
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// clock is the wall time the guest real time clocks read. It is the host's
+// until seeded, a seeded clock starts at the seed and only moves as frames
+// are emulated, so runs with the same seed read the same times.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	mu     sync.Mutex
+	seeded bool
+	now    time.Time
+)
+
+func Now() time.Time {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !seeded {
+		return time.Now()
+	}
+
+	return now
+}
+
+// Seed fixes the clock at t, whole seconds as rtcs keep no less
+func Seed(t time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	seeded = true
+	now = t.Truncate(time.Second)
+}
+
+// Unseed returns the clock to host time
+func Unseed() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	seeded = false
+}
+
+func Seeded() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return seeded
+}
+
+// Advance moves a seeded clock by an emulated frame, host time moves itself
+func Advance(d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if seeded {
+		now = now.Add(d)
+	}
+}
@@ -0,0 +1,32 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeed(t *testing.T) {
+	defer Unseed()
+
+	seed := time.Date(2005, 3, 7, 12, 0, 0, 0, time.UTC)
+	Seed(seed.Add(time.Millisecond))
+
+	if got := Now(); !got.Equal(seed) {
+		t.Fatalf("seeded %v, expected %v", got, seed)
+	}
+
+	for range 61 {
+		Advance(time.Second / 60)
+	}
+
+	if got := Now().Truncate(time.Second); !got.Equal(seed.Add(time.Second)) {
+		t.Fatalf("after 61 frames %v", got)
+	}
+
+	Unseed()
+	Advance(time.Hour)
+
+	if d := time.Since(Now()); d < 0 || d > time.Minute {
+		t.Fatalf("unseeded is %v from host time", d)
+	}
+}
@@ -47,6 +47,7 @@ func generate(exportPath string, cfg CpuConfig) {
 
 	for _, v := range [...]string{
 		"cpu",
+		"state",
 		"cache",
 		"exceptions",
 		"jit",
@@ -6,6 +6,7 @@ import (
 	"unsafe"
 
 	"github.com/aabalke/guac/emu/cpu"
+	"github.com/aabalke/guac/emu/debugger"
 )
 
 type Cpu struct {
@@ -21,6 +22,10 @@ type Cpu struct {
 
 	Jit        *Jit
 	jitEnabled bool
+
+	// Interpreter keeps compiled blocks from running while a debugger
+	// checks every instruction
+	Interpreter bool
 }
 
 const (
@@ -120,6 +125,89 @@ func (c *Cpu) Execute() (int, bool) {
 	return c.DecodeARM()
 }
 
+// Regs copies the registers for debuggers, the banks of the current mode are
+// filled from R
+func (c *Cpu) Regs() debugger.Regs {
+	reg := &c.Reg
+
+	r := debugger.Regs{
+		R:    reg.R,
+		CPSR: reg.CPSR.Get(),
+		SP:   reg.SP,
+		LR:   reg.LR,
+		FIQ:  reg.FIQ,
+		USR:  reg.USR,
+	}
+
+	for i := range reg.SPSR {
+		r.SPSR[i] = reg.SPSR[i].Get()
+	}
+
+	bank := BANK_ID[reg.CPSR.Mode]
+	r.SP[bank] = reg.R[SP]
+	r.LR[bank] = reg.R[LR]
+
+	if reg.CPSR.Mode == MODE_FIQ {
+		copy(r.FIQ[:], reg.R[8:13])
+	} else {
+		copy(r.USR[:], reg.R[8:13])
+	}
+
+	return r
+}
+
+// SetReg writes a register for debuggers, 0 - 15 are r0 - r15 of the current
+// mode and 16 is the cpsr. Changing the mode swaps banks like msr.
+func (c *Cpu) SetReg(i int, v uint32) {
+	reg := &c.Reg
+
+	switch {
+	case i < 16:
+		reg.R[i] = v
+	case i == 16:
+		curr, next := reg.CPSR.Mode, v&0x1F
+		if _, ok := BANK_ID[next]; !ok {
+			return
+		}
+
+		if curr != next {
+			c.switchBank(curr, next)
+		}
+
+		reg.CPSR.Set(v)
+	default:
+		return
+	}
+
+	// refetch, pc or thumb state may have changed
+	c.PcPtr = nil
+	c.PcOff = 0
+	c.isBranching = true
+}
+
+func (c *Cpu) switchBank(curr, next uint32) {
+	reg := &c.Reg
+	r := &reg.R
+
+	if curr != MODE_FIQ {
+		copy(reg.USR[:], r[8:13])
+	} else {
+		copy(reg.FIQ[:], r[8:13])
+	}
+
+	reg.SP[BANK_ID[curr]] = r[SP]
+	reg.LR[BANK_ID[curr]] = r[LR]
+
+	if next != MODE_FIQ {
+		copy(r[8:13], reg.USR[:])
+	} else {
+		copy(r[8:13], reg.FIQ[:])
+	}
+
+	r[SP] = reg.SP[BANK_ID[next]]
+	r[LR] = reg.LR[BANK_ID[next]]
+}
+
 type Reg struct {
 	R    [16]uint32
 	SP   [6]uint32
@@ -244,7 +332,7 @@ func (cpu *Cpu) GetOpArm() (uint32, int) {
 		cpu.isBranching = false
 		cpu.PcOff = 0
 
-		if cpu.jitEnabled {
+		if cpu.jitEnabled && !cpu.Interpreter {
 			pc := r[PC]
 			if finalOp, length, ok := cpu.jitFunction(pc, false); ok {
 				return finalOp, length
@@ -282,7 +370,7 @@ func (cpu *Cpu) GetOpThumb() (uint16, int) {
 	if cpu.isBranching {
 		cpu.isBranching = false
 		cpu.PcOff = 0
-		if cpu.jitEnabled {
+		if cpu.jitEnabled && !cpu.Interpreter {
 			pc := r[PC]
 			if finalOp, length, ok := cpu.jitFunction(pc, true); ok {
 				return uint16(finalOp), length
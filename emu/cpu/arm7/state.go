@@ -0,0 +1,22 @@
+// Code generated by '_gen'
+package arm7
+
+import (
+	"github.com/aabalke/guac/emu/state"
+)
+
+func (c *Cpu) SaveState(e *state.Encoder) {
+	e.Encode(&c.Reg, c.Irq, &c.Halted)
+}
+
+func (c *Cpu) LoadState(d *state.Decoder) {
+	d.Decode(&c.Reg, c.Irq, &c.Halted)
+
+	// cached op pointer may point into memory that has been remapped,
+	// refetch from pc on the next instruction
+	c.PcPtr = nil
+	c.PcOff = 0
+	c.isBranching = true
+
+	c.Jit.InvalidateAll()
+}
@@ -91,6 +91,18 @@ func (j *Jit) InvalidatePage(addr uint32) {
 	j.invalidPages = append(j.invalidPages, page)
 }
 
+// InvalidateAll drops every compiled block, used when memory is replaced
+// wholesale (save state load)
+func (j *Jit) InvalidateAll() {
+	for i := range j.Pages {
+		if j.Pages[i] != nil {
+			j.InvalidatePage(uint32(i) << j.PageShift)
+		}
+	}
+
+	j.DeletePages()
+}
+
 func (j *Jit) DeletePages() {
 	if len(j.invalidPages) == 0 {
 		return
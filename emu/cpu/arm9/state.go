@@ -0,0 +1,24 @@
+// Code generated by '_gen'
+package arm9
+
+import (
+	"github.com/aabalke/guac/emu/state"
+)
+
+func (c *Cpu) SaveState(e *state.Encoder) {
+	e.Encode(&c.Reg, c.Irq, &c.Halted)
+	e.Encode(&c.LowVector, c.Cp15)
+}
+
+func (c *Cpu) LoadState(d *state.Decoder) {
+	d.Decode(&c.Reg, c.Irq, &c.Halted)
+	d.Decode(&c.LowVector, c.Cp15)
+
+	// cached op pointer may point into memory that has been remapped,
+	// refetch from pc on the next instruction
+	c.PcPtr = nil
+	c.PcOff = 0
+	c.isBranching = true
+
+	c.Jit.InvalidateAll()
+}
@@ -9,6 +9,7 @@ const (
 	IRQ_TMR2 = 5
 	IRQ_TMR3 = 6
 	IRQ_RTC  = 7 // arm7 only
+	IRQ_SIO  = 7 // gba only
 	IRQ_DMA0 = 8
 	IRQ_DMA1 = 9
 	IRQ_DMA2 = 10
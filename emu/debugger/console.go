@@ -0,0 +1,308 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const CONSOLE_HELP = `numbers are hex
+  c                   continue every cpu
+  s                   step one instruction
+  n                   step over calls
+  p                   pause
+  b <addr>            add breakpoint
+  d <addr>            delete breakpoint
+  w <addr> [len] [r|w|rw]
+                      add watchpoint, len defaults to 4 and access to rw
+  uw <addr>           delete watchpoints at addr
+  l                   list breakpoints and watchpoints
+  r                   registers and banks
+  x <addr> [count]    memory words
+  dis [addr] [count]  disassemble, addr defaults to pc
+  cpu [name]          select cpu`
+
+// Console is a command line front end for debuggers. Lines are read from in
+// on their own goroutine and run by Poll, which the core calls from its update
+// so commands never race the cpu.
+type Console struct {
+	Cpus []*Debugger
+
+	cur   int
+	out   io.Writer
+	lines chan string
+}
+
+func NewConsole(in io.Reader, out io.Writer, cpus ...*Debugger) *Console {
+	c := &Console{
+		Cpus:  cpus,
+		out:   out,
+		lines: make(chan string, 16),
+	}
+
+	for _, d := range cpus {
+		d.OnStop = append(d.OnStop, c.stopped)
+	}
+
+	go c.read(in)
+
+	fmt.Fprintf(out, "Debugger: type help for commands\n")
+
+	return c
+}
+
+func (c *Console) read(in io.Reader) {
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		c.lines <- s.Text()
+	}
+}
+
+// Poll runs every queued command
+func (c *Console) Poll() {
+	for {
+		select {
+		case line := <-c.lines:
+			if err := c.Run(line); err != nil {
+				fmt.Fprintf(c.out, "%v\n", err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *Console) Close() error {
+	return nil
+}
+
+// Stopped reports if any cpu is stopped
+func (c *Console) Stopped() bool {
+	for _, d := range c.Cpus {
+		if d.Stopped() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Console) stopped(d *Debugger) {
+	for i := range c.Cpus {
+		if c.Cpus[i] == d {
+			c.cur = i
+		}
+	}
+
+	s := d.Stop()
+
+	switch s.Reason {
+	case WATCHPOINT:
+		fmt.Fprintf(c.out, "%s: watchpoint %s %08X\n", d.Name, s.Access, s.Addr)
+	default:
+		fmt.Fprintf(c.out, "%s: %s\n", d.Name, s.Reason)
+	}
+
+	fmt.Fprintf(c.out, "%s\n", d.Disasm(s.Pc, 1)[0])
+}
+
+func parseHex(s string) (uint32, error) {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad number %q", s)
+	}
+
+	return uint32(v), nil
+}
+
+// args parses the hex arguments of a command, missing ones keep their value
+func args(fields []string, vs ...*uint32) error {
+	for i, f := range fields {
+		if i >= len(vs) {
+			break
+		}
+
+		v, err := parseHex(f)
+		if err != nil {
+			return err
+		}
+
+		*vs[i] = v
+	}
+
+	return nil
+}
+
+// Run runs a single command line
+func (c *Console) Run(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	d := c.Cpus[c.cur]
+	cmd, fields := fields[0], fields[1:]
+
+	switch cmd {
+	case "help", "h", "?":
+		fmt.Fprintf(c.out, "%s\n", CONSOLE_HELP)
+
+	case "c", "continue":
+		for _, d := range c.Cpus {
+			d.Continue()
+		}
+
+	case "s", "step":
+		d.Step()
+
+	case "n", "next":
+		d.StepOver()
+
+	case "p", "pause":
+		d.Break()
+
+	case "b", "break":
+		if len(fields) == 0 {
+			return fmt.Errorf("b <addr>")
+		}
+
+		var addr uint32
+		if err := args(fields, &addr); err != nil {
+			return err
+		}
+
+		d.AddBreakpoint(addr)
+
+	case "d", "delete":
+		if len(fields) == 0 {
+			return fmt.Errorf("d <addr>")
+		}
+
+		var addr uint32
+		if err := args(fields, &addr); err != nil {
+			return err
+		}
+
+		if !d.RemoveBreakpoint(addr) {
+			return fmt.Errorf("no breakpoint at %08X", addr)
+		}
+
+	case "w", "watch":
+		if len(fields) == 0 {
+			return fmt.Errorf("w <addr> [len] [r|w|rw]")
+		}
+
+		w := Watchpoint{Len: 4}
+
+		if n := len(fields); n == 3 || (n == 2 && strings.Trim(fields[1], "rw") == "") {
+			switch fields[n-1] {
+			case "r":
+				w.Access = READ
+			case "w":
+				w.Access = WRITE
+			case "rw":
+				w.Access = READ | WRITE
+			default:
+				return fmt.Errorf("bad access %q", fields[n-1])
+			}
+
+			fields = fields[:n-1]
+		}
+
+		if err := args(fields, &w.Addr, &w.Len); err != nil {
+			return err
+		}
+
+		return d.AddWatchpoint(w)
+
+	case "uw", "unwatch":
+		if len(fields) == 0 {
+			return fmt.Errorf("uw <addr>")
+		}
+
+		var addr uint32
+		if err := args(fields, &addr); err != nil {
+			return err
+		}
+
+		if !d.RemoveWatchpoint(addr, 0) {
+			return fmt.Errorf("no watchpoint at %08X", addr)
+		}
+
+	case "l", "list":
+		for _, addr := range d.Breakpoints() {
+			fmt.Fprintf(c.out, "break %08X\n", addr)
+		}
+
+		for _, w := range d.Watchpoints() {
+			fmt.Fprintf(c.out, "watch %08X-%08X %s\n", w.Addr, w.Addr+w.Len-1, w.Access)
+		}
+
+	case "r", "regs":
+		r := d.Cpu.Regs()
+		fmt.Fprintf(c.out, "%s\n", r.String())
+
+	case "x":
+		if len(fields) == 0 {
+			return fmt.Errorf("x <addr> [count]")
+		}
+
+		var addr, count uint32 = 0, 16
+		if err := args(fields, &addr, &count); err != nil {
+			return err
+		}
+
+		addr &^= 3
+
+		for i := range count {
+			if i&3 == 0 {
+				fmt.Fprintf(c.out, "%08X:", addr+i*4)
+			}
+
+			fmt.Fprintf(c.out, " %08X", d.Peek(addr+i*4, 4))
+
+			if i&3 == 3 || i == count-1 {
+				fmt.Fprintf(c.out, "\n")
+			}
+		}
+
+	case "dis":
+		addr, count := d.Cpu.Regs().R[15], uint32(8)
+		if err := args(fields, &addr, &count); err != nil {
+			return err
+		}
+
+		for _, l := range d.Disasm(addr, int(count)) {
+			fmt.Fprintf(c.out, "%s\n", l)
+		}
+
+	case "cpu":
+		if len(fields) == 0 {
+			for i, d := range c.Cpus {
+				mark := " "
+				if i == c.cur {
+					mark = "*"
+				}
+				fmt.Fprintf(c.out, "%s %s\n", mark, d.Name)
+			}
+			return nil
+		}
+
+		for i, d := range c.Cpus {
+			if d.Name == fields[0] {
+				c.cur = i
+				return nil
+			}
+		}
+
+		return fmt.Errorf("no cpu %q", fields[0])
+
+	default:
+		return fmt.Errorf("unknown command %q, type help for commands", cmd)
+	}
+
+	return nil
+}
@@ -0,0 +1,412 @@
+// debugger stops an arm cpu at breakpoints and watchpoints and steps it.
+//
+// Every cpu has its own Debugger. While a Debugger is Active the core calls
+// Check before each instruction and runs the cpu through the interpreter, so
+// compiled jit blocks can not run past a breakpoint. Memory handlers call
+// Watch while it is Watching. Once a Debugger has stopped the core stops
+// running until it is resumed with Continue, Step or StepOver.
+//
+// A Debugger is only used from the emulation goroutine, front ends like the
+// Console queue their commands and run them from the core's update.
+package debugger
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sync/atomic"
+
+	"github.com/aabalke/guac/emu/cpu"
+)
+
+type Reason uint8
+
+const (
+	NONE Reason = iota
+	PAUSE
+	BREAKPOINT
+	WATCHPOINT
+	STEP
+)
+
+var reasons = [...]string{"none", "pause", "breakpoint", "watchpoint", "step"}
+
+func (r Reason) String() string {
+	return reasons[r]
+}
+
+type Access uint8
+
+const (
+	READ Access = 1 << iota
+	WRITE
+)
+
+func (a Access) String() string {
+	switch a {
+	case READ:
+		return "r"
+	case WRITE:
+		return "w"
+	default:
+		return "rw"
+	}
+}
+
+// Watchpoint stops on accesses overlapping Addr up to Addr+Len
+type Watchpoint struct {
+	Addr, Len uint32
+	Access    Access
+}
+
+func (w Watchpoint) overlaps(addr, size uint32) bool {
+	return addr < w.Addr+w.Len && w.Addr < addr+size
+}
+
+// Stop is why a Debugger stopped, Addr, Access and the Watchpoint hit are
+// set for watchpoints
+type Stop struct {
+	Reason     Reason
+	Pc         uint32
+	Addr       uint32
+	Access     Access
+	Watchpoint Watchpoint
+}
+
+// REG_CPSR is the register index of the cpsr for SetReg
+const REG_CPSR = 16
+
+// Cpu is what a Debugger needs from an arm core
+type Cpu interface {
+	Regs() Regs
+	SetReg(i int, v uint32)
+}
+
+// Frontend drives debuggers from outside the emulation goroutine, Poll is
+// called from the core's update and runs whatever the frontend queued
+type Frontend interface {
+	Poll()
+	Close() error
+}
+
+// Attach starts the frontends for cpus, a console on stdin and a gdb stub
+// on localhost when port is set
+func Attach(console bool, port int, cpus ...*Debugger) []Frontend {
+	var fronts []Frontend
+
+	if console {
+		fronts = append(fronts, NewConsole(os.Stdin, os.Stdout, cpus...))
+	}
+
+	if port != 0 {
+		g, err := ListenGdb(fmt.Sprintf("127.0.0.1:%d", port), cpus...)
+		if err != nil {
+			log.Printf("Gdb: %v\n", err)
+		} else {
+			fronts = append(fronts, g)
+		}
+	}
+
+	return fronts
+}
+
+type Debugger struct {
+	Name string
+	Cpu  Cpu
+	Mem  cpu.MemoryInterface
+	Arm9 bool
+
+	// OnStop is called by frontends once the cpu stopped
+	OnStop []func(d *Debugger)
+
+	active   atomic.Bool
+	watching atomic.Bool
+
+	breakpoints map[uint32]struct{}
+	watchpoints []Watchpoint
+
+	stopped bool
+	stop    Stop
+	pending *Stop
+
+	// skip lets the instruction at the stopped pc run once when resuming
+	skip bool
+	step bool
+
+	over    uint32
+	overing bool
+	peeking bool
+}
+
+func New(name string, c Cpu, mem cpu.MemoryInterface, arm9 bool) *Debugger {
+	return &Debugger{
+		Name:        name,
+		Cpu:         c,
+		Mem:         mem,
+		Arm9:        arm9,
+		breakpoints: map[uint32]struct{}{},
+	}
+}
+
+// Active reports if Check has to be called before each instruction, the
+// core must not run jit blocks while it is set. A nil Debugger is never
+// active.
+func (d *Debugger) Active() bool {
+	return d != nil && d.active.Load()
+}
+
+// Watching reports if memory handlers have to call Watch
+func (d *Debugger) Watching() bool {
+	return d != nil && d.watching.Load()
+}
+
+func (d *Debugger) Stopped() bool {
+	return d != nil && d.stopped
+}
+
+// Stop returns why the debugger last stopped
+func (d *Debugger) Stop() Stop {
+	return d.stop
+}
+
+func (d *Debugger) update() {
+	d.watching.Store(len(d.watchpoints) != 0)
+	d.active.Store(len(d.breakpoints) != 0 || len(d.watchpoints) != 0 ||
+		d.stopped || d.pending != nil || d.step || d.overing)
+}
+
+// Check is called with the pc of the next instruction, it reports if the cpu
+// has to stop before running it
+func (d *Debugger) Check(pc uint32) bool {
+	if d.stopped {
+		return true
+	}
+
+	if d.pending != nil {
+		s := *d.pending
+		s.Pc = pc
+		d.halt(s)
+		return true
+	}
+
+	if d.skip {
+		d.skip = false
+		return false
+	}
+
+	if d.step {
+		d.step = false
+		d.halt(Stop{Reason: STEP, Pc: pc})
+		return true
+	}
+
+	if d.overing && pc == d.over {
+		d.overing = false
+		d.halt(Stop{Reason: STEP, Pc: pc})
+		return true
+	}
+
+	if _, ok := d.breakpoints[pc]; ok {
+		d.halt(Stop{Reason: BREAKPOINT, Pc: pc})
+		return true
+	}
+
+	return false
+}
+
+// Watch is called by memory handlers on every access while Watching. A hit
+// stops the cpu once the accessing instruction has finished.
+func (d *Debugger) Watch(addr, size uint32, access Access) {
+	if d.peeking || d.pending != nil || d.stopped {
+		return
+	}
+
+	for _, w := range d.watchpoints {
+		if w.Access&access != 0 && w.overlaps(addr, size) {
+			d.pending = &Stop{Reason: WATCHPOINT, Addr: addr, Access: access, Watchpoint: w}
+			d.update()
+			return
+		}
+	}
+}
+
+func (d *Debugger) halt(s Stop) {
+	d.stopped = true
+	d.stop = s
+	d.pending = nil
+	d.step = false
+	d.overing = false
+	d.update()
+
+	for _, f := range d.OnStop {
+		f(d)
+	}
+}
+
+// Break stops the cpu right away, it is only called between instructions so
+// a halted cpu can be stopped as well
+func (d *Debugger) Break() {
+	if d.stopped {
+		return
+	}
+
+	d.halt(Stop{Reason: PAUSE, Pc: d.Cpu.Regs().R[15]})
+}
+
+func (d *Debugger) resume() {
+	if d.stopped {
+		d.skip = true
+	}
+
+	d.stopped = false
+	d.pending = nil
+}
+
+// Continue runs until the next breakpoint or watchpoint
+func (d *Debugger) Continue() {
+	d.resume()
+	d.update()
+}
+
+// Step runs a single instruction
+func (d *Debugger) Step() {
+	d.resume()
+	d.step = true
+	d.update()
+}
+
+// StepOver steps a single instruction, calls are run until they return to
+// the instruction after them
+func (d *Debugger) StepOver() {
+	r := d.Cpu.Regs()
+	pc := r.R[15]
+
+	var (
+		size uint32
+		call bool
+	)
+
+	if r.Thumb() {
+		size, call = IsCallThumb(uint16(d.Peek(pc, 2)), uint16(d.Peek(pc+2, 2)))
+	} else {
+		size, call = IsCallArm(d.Peek(pc, 4))
+	}
+
+	if !call {
+		d.Step()
+		return
+	}
+
+	d.resume()
+	d.over = pc + size
+	d.overing = true
+	d.update()
+}
+
+// Peek reads memory without triggering watchpoints. Reads of io registers
+// can still have side effects.
+func (d *Debugger) Peek(addr, size uint32) uint32 {
+	d.peeking = true
+	defer func() { d.peeking = false }()
+
+	switch size {
+	case 1:
+		return d.Mem.Read8(addr, d.Arm9)
+	case 2:
+		return d.Mem.Read16(addr&^1, d.Arm9)
+	default:
+		return d.Mem.Read32(addr&^3, d.Arm9)
+	}
+}
+
+// Poke writes memory without triggering watchpoints
+func (d *Debugger) Poke(addr, v, size uint32) {
+	d.peeking = true
+	defer func() { d.peeking = false }()
+
+	switch size {
+	case 1:
+		d.Mem.Write8(addr, uint8(v), d.Arm9)
+	case 2:
+		d.Mem.Write16(addr&^1, uint16(v), d.Arm9)
+	default:
+		d.Mem.Write32(addr&^3, v, d.Arm9)
+	}
+}
+
+func (d *Debugger) AddBreakpoint(addr uint32) {
+	d.breakpoints[addr] = struct{}{}
+	d.update()
+}
+
+func (d *Debugger) RemoveBreakpoint(addr uint32) bool {
+	_, ok := d.breakpoints[addr]
+	delete(d.breakpoints, addr)
+	d.update()
+	return ok
+}
+
+// Breakpoints returns the breakpoint addresses sorted
+func (d *Debugger) Breakpoints() []uint32 {
+	return slices.Sorted(maps.Keys(d.breakpoints))
+}
+
+func (d *Debugger) AddWatchpoint(w Watchpoint) error {
+	if w.Len == 0 {
+		return fmt.Errorf("watchpoint at %08X has no length", w.Addr)
+	}
+
+	if w.Access == 0 {
+		w.Access = READ | WRITE
+	}
+
+	d.watchpoints = append(d.watchpoints, w)
+	d.update()
+	return nil
+}
+
+// RemoveWatchpoint removes every watchpoint starting at addr with access
+func (d *Debugger) RemoveWatchpoint(addr uint32, access Access) bool {
+	n := len(d.watchpoints)
+	d.watchpoints = slices.DeleteFunc(d.watchpoints, func(w Watchpoint) bool {
+		return w.Addr == addr && (access == 0 || w.Access == access)
+	})
+	d.update()
+	return len(d.watchpoints) != n
+}
+
+func (d *Debugger) Watchpoints() []Watchpoint {
+	return slices.Clone(d.watchpoints)
+}
+
+// Disasm disassembles count instructions from addr in the current cpu state
+func (d *Debugger) Disasm(addr uint32, count int) []string {
+	thumb := d.Cpu.Regs().Thumb()
+
+	lines := make([]string, 0, count)
+
+	for range count {
+		if thumb {
+			op := uint16(d.Peek(addr, 2))
+			next := uint16(d.Peek(addr+2, 2))
+
+			if s, ok := DisasmThumbLong(op, next, addr); ok {
+				lines = append(lines, fmt.Sprintf("%08X: %04X%04X %s", addr, op, next, s))
+				addr += 4
+				continue
+			}
+
+			lines = append(lines, fmt.Sprintf("%08X: %04X     %s", addr, op, DisasmThumb(op, addr)))
+			addr += 2
+			continue
+		}
+
+		op := d.Peek(addr, 4)
+		lines = append(lines, fmt.Sprintf("%08X: %08X %s", addr, op, DisasmArm(op, addr)))
+		addr += 4
+	}
+
+	return lines
+}
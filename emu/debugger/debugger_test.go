@@ -0,0 +1,406 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// testCpu runs a fake program where every instruction is 4 bytes, so tests
+// can drive Check like a core would
+type testCpu struct {
+	regs Regs
+	mem  [0x100]byte
+}
+
+func (c *testCpu) Regs() Regs { return c.regs }
+
+func (c *testCpu) SetReg(i int, v uint32) {
+	if i == REG_CPSR {
+		c.regs.CPSR = v
+		return
+	}
+
+	c.regs.R[i] = v
+}
+
+func (c *testCpu) Read8(addr uint32, _ bool) uint32 { return uint32(c.mem[addr&0xFF]) }
+func (c *testCpu) Read16(addr uint32, _ bool) uint32 {
+	return uint32(binary.LittleEndian.Uint16(c.mem[addr&0xFF:]))
+}
+func (c *testCpu) Read32(addr uint32, _ bool) uint32 {
+	return binary.LittleEndian.Uint32(c.mem[addr&0xFF:])
+}
+func (c *testCpu) Write8(addr uint32, v uint8, _ bool) { c.mem[addr&0xFF] = v }
+func (c *testCpu) Write16(addr uint32, v uint16, _ bool) {
+	binary.LittleEndian.PutUint16(c.mem[addr&0xFF:], v)
+}
+func (c *testCpu) Write32(addr uint32, v uint32, _ bool) {
+	binary.LittleEndian.PutUint32(c.mem[addr&0xFF:], v)
+}
+func (c *testCpu) ReadPtr(uint32, bool) (unsafe.Pointer, bool)  { return nil, false }
+func (c *testCpu) WritePtr(uint32, bool) (unsafe.Pointer, bool) { return nil, false }
+
+// run steps until the debugger stops or n instructions ran
+func (c *testCpu) run(d *Debugger, n int) {
+	for range n {
+		if d.Check(c.regs.R[15]) {
+			return
+		}
+
+		c.regs.R[15] += 4
+	}
+}
+
+func newTestDebugger() (*Debugger, *testCpu) {
+	c := &testCpu{}
+	c.regs.CPSR = MODE_SYS
+	return New("arm7", c, c, false), c
+}
+
+func TestBreakpoint(t *testing.T) {
+	d, c := newTestDebugger()
+
+	if d.Active() {
+		t.Fatal("active without breakpoints")
+	}
+
+	d.AddBreakpoint(0x10)
+	c.run(d, 100)
+
+	if !d.Stopped() || c.regs.R[15] != 0x10 || d.Stop().Reason != BREAKPOINT {
+		t.Fatalf("stopped %t at %08X, expected breakpoint at 00000010", d.Stopped(), c.regs.R[15])
+	}
+
+	// continuing has to run past the breakpoint it stopped at
+	d.Continue()
+	c.run(d, 4)
+
+	if d.Stopped() || c.regs.R[15] != 0x20 {
+		t.Fatalf("stopped %t at %08X after continue", d.Stopped(), c.regs.R[15])
+	}
+
+	d.RemoveBreakpoint(0x10)
+	if d.Active() {
+		t.Fatal("active after removing breakpoints")
+	}
+}
+
+func TestStep(t *testing.T) {
+	d, c := newTestDebugger()
+
+	d.Break()
+	c.run(d, 1)
+
+	if !d.Stopped() || d.Stop().Reason != PAUSE {
+		t.Fatal("did not pause")
+	}
+
+	for i := range 3 {
+		d.Step()
+		c.run(d, 100)
+
+		if want := uint32(4 * (i + 1)); c.regs.R[15] != want || d.Stop().Reason != STEP {
+			t.Fatalf("step %d at %08X, expected %08X", i, c.regs.R[15], want)
+		}
+	}
+}
+
+func TestStepOver(t *testing.T) {
+	d, c := newTestDebugger()
+
+	// bl 0x40 at 0x0, the fake cpu does not branch so step over has to stop
+	// at the instruction after the call
+	binary.LittleEndian.PutUint32(c.mem[0:], 0xEB00_000E)
+	binary.LittleEndian.PutUint32(c.mem[4:], 0xE1A0_0000)
+
+	d.Break()
+	c.run(d, 1)
+
+	d.StepOver()
+	if d.over != 4 || !d.overing {
+		t.Fatalf("step over of bl set %08X %t", d.over, d.overing)
+	}
+
+	c.run(d, 100)
+	if c.regs.R[15] != 4 || d.Stop().Reason != STEP {
+		t.Fatalf("stepped over to %08X", c.regs.R[15])
+	}
+
+	// not a call, same as step
+	d.StepOver()
+	c.run(d, 100)
+	if c.regs.R[15] != 8 {
+		t.Fatalf("stepped over to %08X, expected 00000008", c.regs.R[15])
+	}
+}
+
+func TestWatchpoint(t *testing.T) {
+	d, c := newTestDebugger()
+
+	if err := d.AddWatchpoint(Watchpoint{Addr: 0x80, Len: 4, Access: WRITE}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.Watching() || !d.Active() {
+		t.Fatal("not watching")
+	}
+
+	d.Watch(0x80, 4, READ)
+	d.Watch(0x7C, 4, WRITE)
+	c.run(d, 1)
+	if d.Stopped() {
+		t.Fatal("stopped on access outside the watchpoint")
+	}
+
+	// peeks are never watched
+	d.Peek(0x80, 4)
+
+	d.Watch(0x82, 2, WRITE)
+	c.run(d, 100)
+
+	s := d.Stop()
+	if !d.Stopped() || s.Reason != WATCHPOINT || s.Addr != 0x82 || s.Pc != 4 {
+		t.Fatalf("stop %+v, expected watchpoint at 00000082 from pc 00000004", s)
+	}
+}
+
+func TestDisasmArm(t *testing.T) {
+	tests := []struct {
+		op, pc uint32
+		want   string
+	}{
+		{0xE3A0_0001, 0, "mov r0, #1"},
+		{0xE091_0002, 0, "adds r0, r1, r2"},
+		{0x1352_0000, 0, "cmpne r2, #0"},
+		{0xE1A0_1102, 0, "mov r1, r2, lsl #2"},
+		{0xE59F_0010, 0, "ldr r0, [pc, #0x10]"},
+		{0xE5A1_0004, 0, "str r0, [r1, #4]!"},
+		{0xE92D_4010, 0, "push {r4, lr}"},
+		{0xE8BD_8010, 0, "pop {r4, pc}"},
+		{0xE891_000F, 0, "ldmia r1, {r0-r3}"},
+		{0xEB00_000E, 0x0800_0000, "bl 0x08000040"},
+		{0xEAFF_FFFE, 0x0800_0000, "b 0x08000000"},
+		{0xE12F_FF1E, 0, "bx lr"},
+		{0xE10F_0000, 0, "mrs r0, cpsr"},
+		{0xE129_F000, 0, "msr cpsr_cf, r0"},
+		{0xE002_0190, 0, "mul r2, r0, r1"},
+		{0xE1D0_00B2, 0, "ldrh r0, [r0, #2]"},
+		{0xEE11_0F10, 0, "mrc p15, 0, r0, c1, c0, 0"},
+		{0xEF00_0005, 0, "swi #5"},
+		{0xE16F_1F12, 0, "clz r1, r2"},
+	}
+
+	for _, tt := range tests {
+		if got := DisasmArm(tt.op, tt.pc); got != tt.want {
+			t.Errorf("%08X: %q, expected %q", tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestDisasmThumb(t *testing.T) {
+	tests := []struct {
+		op   uint16
+		pc   uint32
+		want string
+	}{
+		{0x2001, 0, "movs r0, #1"},
+		{0x0088, 0, "lsls r0, r1, #2"},
+		{0x1888, 0, "adds r0, r1, r2"},
+		{0x4348, 0, "muls r0, r1"},
+		{0x4770, 0, "bx lr"},
+		{0x4788, 0, "blx r1"},
+		{0x4801, 0x0800_0002, "ldr r0, [pc, #4] ; 0x08000008"},
+		{0x6848, 0, "ldr r0, [r1, #4]"},
+		{0x5E88, 0, "ldrsh r0, [r1, r2]"},
+		{0xB510, 0, "push {r4, lr}"},
+		{0xBD10, 0, "pop {r4, pc}"},
+		{0xD0FE, 0x0800_0000, "beq 0x08000000"},
+		{0xE7FE, 0x0800_0000, "b 0x08000000"},
+		{0xDF05, 0, "swi #5"},
+		{0xC907, 0, "ldmia r1!, {r0-r2}"},
+	}
+
+	for _, tt := range tests {
+		if got := DisasmThumb(tt.op, tt.pc); got != tt.want {
+			t.Errorf("%04X: %q, expected %q", tt.op, got, tt.want)
+		}
+	}
+
+	if got, _ := DisasmThumbLong(0xF000, 0xF81E, 0x0800_0000); got != "bl 0x08000040" {
+		t.Errorf("bl pair: %q", got)
+	}
+}
+
+func TestConsole(t *testing.T) {
+	d, c := newTestDebugger()
+
+	var out bytes.Buffer
+	con := NewConsole(strings.NewReader(""), &out, d)
+
+	for _, line := range []string{"b 10", "w 80 2 w", "r"} {
+		if err := con.Run(line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+	}
+
+	if bps := d.Breakpoints(); len(bps) != 1 || bps[0] != 0x10 {
+		t.Fatalf("breakpoints %X", bps)
+	}
+
+	if wps := d.Watchpoints(); len(wps) != 1 || wps[0] != (Watchpoint{Addr: 0x80, Len: 2, Access: WRITE}) {
+		t.Fatalf("watchpoints %+v", wps)
+	}
+
+	c.run(d, 100)
+
+	if !con.Stopped() || !strings.Contains(out.String(), "arm7: breakpoint") {
+		t.Fatalf("console did not report the stop:\n%s", out.String())
+	}
+
+	if err := con.Run("bogus"); err == nil {
+		t.Fatal("expected error for unknown command")
+	}
+}
+
+// gdbClient talks to a Gdb over localhost, polling it like a core would
+type gdbClient struct {
+	t    *testing.T
+	g    *Gdb
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *gdbClient) recv() string {
+	c.t.Helper()
+
+	for range 1000 {
+		c.g.Poll()
+
+		c.conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+
+		b, err := c.r.ReadByte()
+		if err != nil {
+			continue
+		}
+
+		if b != '$' {
+			continue
+		}
+
+		c.conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		data, err := c.r.ReadString('#')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+
+		c.r.Discard(2)
+		return data[:len(data)-1]
+	}
+
+	c.t.Fatal("no reply")
+	return ""
+}
+
+// resume sends a packet which resumes the cpu and polls until it ran
+func (c *gdbClient) resume(data string) {
+	c.t.Helper()
+	fmt.Fprintf(c.conn, "$%s#%02x", data, checksum(data))
+
+	for range 1000 {
+		if c.g.Poll(); c.g.running {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	c.t.Fatalf("%s did not resume", data)
+}
+
+func (c *gdbClient) send(data string) string {
+	c.t.Helper()
+	fmt.Fprintf(c.conn, "$%s#%02x", data, checksum(data))
+	return c.recv()
+}
+
+func TestGdb(t *testing.T) {
+	d, c := newTestDebugger()
+
+	g, err := ListenGdb("127.0.0.1:0", d)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer g.Close()
+
+	conn, err := net.Dial("tcp", g.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := &gdbClient{t: t, g: g, conn: conn, r: bufio.NewReader(conn)}
+
+	if got := client.send("?"); got != "T05thread:1;" {
+		t.Fatalf("? replied %q", got)
+	}
+
+	if !d.Stopped() {
+		t.Fatal("attaching did not stop the cpu")
+	}
+
+	c.regs.R[1] = 0x1234_5678
+	if got := client.send("p1"); got != "78563412" {
+		t.Fatalf("p1 replied %q", got)
+	}
+
+	if got := client.send("P2=efbeadde"); got != "OK" || c.regs.R[2] != 0xDEAD_BEEF {
+		t.Fatalf("P2 replied %q, r2 %08X", got, c.regs.R[2])
+	}
+
+	if got := client.send("M40,2:abcd"); got != "OK" || c.mem[0x40] != 0xAB || c.mem[0x41] != 0xCD {
+		t.Fatalf("M replied %q", got)
+	}
+
+	if got := client.send("m40,2"); got != "abcd" {
+		t.Fatalf("m replied %q", got)
+	}
+
+	if got := client.send("Z0,10,4"); got != "OK" {
+		t.Fatalf("Z0 replied %q", got)
+	}
+
+	client.resume("c")
+	c.run(d, 100)
+
+	if got := client.recv(); got != "T05thread:1;" || c.regs.R[15] != 0x10 {
+		t.Fatalf("continue replied %q at %08X", got, c.regs.R[15])
+	}
+
+	if got := client.send("Z2,80,4"); got != "OK" {
+		t.Fatalf("Z2 replied %q", got)
+	}
+
+	client.resume("c")
+	d.Watch(0x80, 4, WRITE)
+	c.run(d, 100)
+
+	if got := client.recv(); got != "T05watch:80;thread:1;" {
+		t.Fatalf("watchpoint replied %q", got)
+	}
+
+	if got := client.send("D"); got != "OK" {
+		t.Fatalf("D replied %q", got)
+	}
+
+	if d.Stopped() || d.Active() {
+		t.Fatal("detaching left the cpu stopped")
+	}
+}
@@ -0,0 +1,620 @@
+package debugger
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// instructions are classified by masks and formats, in the same order the
+// arm7 and arm9 decoders use
+
+var (
+	CONDS = [16]string{
+		"eq", "ne", "cs", "cc", "mi", "pl", "vs", "vc",
+		"hi", "ls", "ge", "lt", "gt", "le", "", "nv",
+	}
+
+	ALU_OPS = [16]string{
+		"and", "eor", "sub", "rsb", "add", "adc", "sbc", "rsc",
+		"tst", "teq", "cmp", "cmn", "orr", "mov", "bic", "mvn",
+	}
+
+	SHIFTS = [4]string{"lsl", "lsr", "asr", "ror"}
+
+	THUMB_ALU_OPS = [16]string{
+		"and", "eor", "lsl", "lsr", "asr", "adc", "sbc", "ror",
+		"tst", "neg", "cmp", "cmn", "orr", "mul", "bic", "mvn",
+	}
+)
+
+func isOpFormat(op, mask, format uint32) bool {
+	return op&mask == format
+}
+
+func reg(r uint32) string {
+	switch r &= 0xF; r {
+	case 13:
+		return "sp"
+	case 14:
+		return "lr"
+	case 15:
+		return "pc"
+	default:
+		return fmt.Sprintf("r%d", r)
+	}
+}
+
+// regList formats a register list, ranges of three or more are joined
+func regList(list uint32) string {
+	var parts []string
+
+	for i := uint32(0); i < 16; i++ {
+		if list&(1<<i) == 0 {
+			continue
+		}
+
+		j := i
+		for j+1 < 16 && list&(1<<(j+1)) != 0 {
+			j++
+		}
+
+		switch {
+		case j-i >= 2:
+			parts = append(parts, reg(i)+"-"+reg(j))
+		case j-i == 1:
+			parts = append(parts, reg(i), reg(j))
+		default:
+			parts = append(parts, reg(i))
+		}
+
+		i = j
+	}
+
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func hex(v uint32) string {
+	if v < 10 {
+		return fmt.Sprintf("#%d", v)
+	}
+
+	return fmt.Sprintf("#0x%X", v)
+}
+
+func signExtend(v uint32, bits int) int32 {
+	shift := 32 - bits
+	return int32(v<<shift) >> shift
+}
+
+// IsCallArm reports if op is a call, which step over runs until it returns
+func IsCallArm(op uint32) (size uint32, call bool) {
+	switch {
+	case op>>28 == 0xF:
+		return 4, isOpFormat(op, 0x0E00_0000, 0x0A00_0000) // blx imm
+	case isOpFormat(op, 0x0F00_0000, 0x0B00_0000): // bl
+		return 4, true
+	case isOpFormat(op, 0x0FFF_FFF0, 0x012F_FF30): // blx reg
+		return 4, true
+	}
+
+	return 4, false
+}
+
+// IsCallThumb reports if op is a call, next is the halfword after it which is
+// needed for the two halves of bl and blx
+func IsCallThumb(op, next uint16) (size uint32, call bool) {
+	switch {
+	case op&0xF800 == 0xF000 && next&0xE800 == 0xE800:
+		return 4, true
+	case op&0xFF80 == 0x4780: // blx reg
+		return 2, true
+	}
+
+	return 2, false
+}
+
+// DisasmArm disassembles an arm instruction at pc
+func DisasmArm(op, pc uint32) string {
+	cond := CONDS[op>>28]
+
+	if op>>28 == 0xF {
+		switch {
+		case isOpFormat(op, 0x0E00_0000, 0x0A00_0000):
+			offset := signExtend(op&0xFF_FFFF, 24)<<2 | int32((op>>24)&1)<<1
+			return fmt.Sprintf("blx 0x%08X", pc+8+uint32(offset))
+		case isOpFormat(op, 0x0D70_F000, 0x0550_F000):
+			return "pld " + armAddress(op)
+		}
+
+		return "undefined"
+	}
+
+	switch {
+	case isOpFormat(op, 0x0FFF_FFD0, 0x012F_FF10):
+		if op&0x20 != 0 {
+			return "blx" + cond + " " + reg(op)
+		}
+		return "bx" + cond + " " + reg(op)
+
+	case isOpFormat(op, 0x0FFF_0FF0, 0x016F_0F10):
+		return fmt.Sprintf("clz%s %s, %s", cond, reg(op>>12), reg(op))
+
+	case isOpFormat(op, 0x0F90_0FF0, 0x0100_0050):
+		names := [4]string{"qadd", "qsub", "qdadd", "qdsub"}
+		return fmt.Sprintf("%s%s %s, %s, %s", names[(op>>21)&3], cond, reg(op>>12), reg(op), reg(op>>16))
+
+	case isOpFormat(op, 0x0FF0_00F0, 0x0120_0070):
+		return "bkpt " + hex((op>>4)&0xFFF0|op&0xF)
+
+	case isOpFormat(op, 0x0FC0_00F0, 0x0000_0090):
+		s := sFlag(op)
+		if op&(1<<21) != 0 {
+			return fmt.Sprintf("mla%s%s %s, %s, %s, %s", s, cond, reg(op>>16), reg(op), reg(op>>8), reg(op>>12))
+		}
+		return fmt.Sprintf("mul%s%s %s, %s, %s", s, cond, reg(op>>16), reg(op), reg(op>>8))
+
+	case isOpFormat(op, 0x0F80_00F0, 0x0080_0090):
+		names := [4]string{"umull", "umlal", "smull", "smlal"}
+		name := names[(op>>21)&3]
+		return fmt.Sprintf("%s%s%s %s, %s, %s, %s", name, sFlag(op), cond, reg(op>>12), reg(op>>16), reg(op), reg(op>>8))
+
+	case isOpFormat(op, 0x0FB0_0FF0, 0x0100_0090):
+		b := ""
+		if op&(1<<22) != 0 {
+			b = "b"
+		}
+		return fmt.Sprintf("swp%s%s %s, %s, [%s]", b, cond, reg(op>>12), reg(op), reg(op>>16))
+
+	case isOpFormat(op, 0x0F90_0090, 0x0100_0080):
+		return armSignedMul(op, cond)
+
+	case isOpFormat(op, 0x0E00_0090, 0x0000_0090) && op&0x60 != 0:
+		return armHalf(op, cond)
+
+	case isOpFormat(op, 0x0FBF_0FFF, 0x010F_0000):
+		return fmt.Sprintf("mrs%s %s, %s", cond, reg(op>>12), psrName(op))
+
+	case isOpFormat(op, 0x0FB0_FFF0, 0x0120_F000):
+		return fmt.Sprintf("msr%s %s_%s, %s", cond, psrName(op), psrFields(op), reg(op))
+
+	case isOpFormat(op, 0x0FB0_F000, 0x0320_F000):
+		imm := bits.RotateLeft32(op&0xFF, -int((op>>8)&0xF)*2)
+		return fmt.Sprintf("msr%s %s_%s, %s", cond, psrName(op), psrFields(op), hex(imm))
+
+	case isOpFormat(op, 0x0C00_0000, 0x0000_0000):
+		return armAlu(op, cond)
+
+	case isOpFormat(op, 0x0E00_0010, 0x0600_0010):
+		return "undefined"
+
+	case isOpFormat(op, 0x0C00_0000, 0x0400_0000):
+		name := "str"
+		if op&(1<<20) != 0 {
+			name = "ldr"
+		}
+		if op&(1<<22) != 0 {
+			name += "b"
+		}
+		if op&(1<<24) == 0 && op&(1<<21) != 0 {
+			name += "t"
+		}
+		return fmt.Sprintf("%s%s %s, %s", name, cond, reg(op>>12), armAddress(op))
+
+	case isOpFormat(op, 0x0E00_0000, 0x0800_0000):
+		return armBlock(op, cond)
+
+	case isOpFormat(op, 0x0E00_0000, 0x0A00_0000):
+		name := "b"
+		if op&(1<<24) != 0 {
+			name = "bl"
+		}
+		offset := signExtend(op&0xFF_FFFF, 24) << 2
+		return fmt.Sprintf("%s%s 0x%08X", name, cond, pc+8+uint32(offset))
+
+	case isOpFormat(op, 0x0E00_0000, 0x0C00_0000):
+		name := "stc"
+		if op&(1<<20) != 0 {
+			name = "ldc"
+		}
+		offset := (op & 0xFF) << 2
+		return fmt.Sprintf("%s%s p%d, c%d, [%s, %s]", name, cond, (op>>8)&0xF, (op>>12)&0xF, reg(op>>16), hex(offset))
+
+	case isOpFormat(op, 0x0F00_0010, 0x0E00_0010):
+		name := "mcr"
+		if op&(1<<20) != 0 {
+			name = "mrc"
+		}
+		return fmt.Sprintf("%s%s p%d, %d, %s, c%d, c%d, %d", name, cond, (op>>8)&0xF, (op>>21)&7, reg(op>>12), (op>>16)&0xF, op&0xF, (op>>5)&7)
+
+	case isOpFormat(op, 0x0F00_0010, 0x0E00_0000):
+		return fmt.Sprintf("cdp%s p%d, %d, c%d, c%d, c%d, %d", cond, (op>>8)&0xF, (op>>20)&0xF, (op>>12)&0xF, (op>>16)&0xF, op&0xF, (op>>5)&7)
+
+	case isOpFormat(op, 0x0F00_0000, 0x0F00_0000):
+		return "swi" + cond + " " + hex(op&0xFF_FFFF)
+	}
+
+	return "undefined"
+}
+
+func sFlag(op uint32) string {
+	if op&(1<<20) != 0 {
+		return "s"
+	}
+	return ""
+}
+
+func psrName(op uint32) string {
+	if op&(1<<22) != 0 {
+		return "spsr"
+	}
+	return "cpsr"
+}
+
+func psrFields(op uint32) string {
+	var b strings.Builder
+	for i, c := range "csxf" {
+		if op&(1<<(16+i)) != 0 {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// shifter formats the second operand of alu instructions
+func shifter(op uint32) string {
+	if op&(1<<25) != 0 {
+		return hex(bits.RotateLeft32(op&0xFF, -int((op>>8)&0xF)*2))
+	}
+
+	return shiftedReg(op)
+}
+
+func shiftedReg(op uint32) string {
+	rm := reg(op)
+	shift := (op >> 5) & 3
+
+	if op&(1<<4) != 0 {
+		return fmt.Sprintf("%s, %s %s", rm, SHIFTS[shift], reg(op>>8))
+	}
+
+	amount := (op >> 7) & 0x1F
+
+	switch {
+	case amount == 0 && shift == 0:
+		return rm
+	case amount == 0 && shift == 3:
+		return rm + ", rrx"
+	case amount == 0:
+		amount = 32
+	}
+
+	return fmt.Sprintf("%s, %s #%d", rm, SHIFTS[shift], amount)
+}
+
+func armAlu(op uint32, cond string) string {
+	opcode := (op >> 21) & 0xF
+	name := ALU_OPS[opcode]
+
+	switch opcode {
+	case 0x8, 0x9, 0xA, 0xB:
+		return fmt.Sprintf("%s%s %s, %s", name, cond, reg(op>>16), shifter(op))
+	case 0xD, 0xF:
+		return fmt.Sprintf("%s%s%s %s, %s", name, sFlag(op), cond, reg(op>>12), shifter(op))
+	}
+
+	return fmt.Sprintf("%s%s%s %s, %s, %s", name, sFlag(op), cond, reg(op>>12), reg(op>>16), shifter(op))
+}
+
+// armAddress formats the address of single data transfers
+func armAddress(op uint32) string {
+	var (
+		rn    = reg(op >> 16)
+		pre   = op&(1<<24) != 0
+		up    = op&(1<<23) != 0
+		wb    = op&(1<<21) != 0
+		sign  = ""
+		index string
+	)
+
+	if !up {
+		sign = "-"
+	}
+
+	if op&(1<<25) == 0 {
+		offset := op & 0xFFF
+		if offset == 0 {
+			return "[" + rn + "]"
+		}
+		index = "#" + sign + strings.TrimPrefix(hex(offset), "#")
+	} else {
+		index = sign + shiftedReg(op)
+	}
+
+	return address(rn, index, pre, wb)
+}
+
+func address(rn, index string, pre, wb bool) string {
+	switch {
+	case !pre:
+		return fmt.Sprintf("[%s], %s", rn, index)
+	case wb:
+		return fmt.Sprintf("[%s, %s]!", rn, index)
+	default:
+		return fmt.Sprintf("[%s, %s]", rn, index)
+	}
+}
+
+func armHalf(op uint32, cond string) string {
+	var name string
+
+	switch sh, load := (op>>5)&3, op&(1<<20) != 0; {
+	case load && sh == 1:
+		name = "ldrh"
+	case load && sh == 2:
+		name = "ldrsb"
+	case load && sh == 3:
+		name = "ldrsh"
+	case sh == 1:
+		name = "strh"
+	case sh == 2:
+		name = "ldrd"
+	default:
+		name = "strd"
+	}
+
+	var (
+		rn    = reg(op >> 16)
+		sign  = ""
+		index string
+	)
+
+	if op&(1<<23) == 0 {
+		sign = "-"
+	}
+
+	if op&(1<<22) != 0 {
+		index = "#" + sign + strings.TrimPrefix(hex((op>>4)&0xF0|op&0xF), "#")
+	} else {
+		index = sign + reg(op)
+	}
+
+	return fmt.Sprintf("%s%s %s, %s", name, cond, reg(op>>12), address(rn, index, op&(1<<24) != 0, op&(1<<21) != 0))
+}
+
+func armSignedMul(op uint32, cond string) string {
+	xy := "b"
+	if op&(1<<5) != 0 {
+		xy = "t"
+	}
+
+	y := "b"
+	if op&(1<<6) != 0 {
+		y = "t"
+	}
+
+	rd, rn, rs, rm := reg(op>>16), reg(op>>12), reg(op>>8), reg(op)
+
+	switch (op >> 21) & 3 {
+	case 0:
+		return fmt.Sprintf("smla%s%s%s %s, %s, %s, %s", xy, y, cond, rd, rm, rs, rn)
+	case 1:
+		if op&(1<<5) != 0 {
+			return fmt.Sprintf("smulw%s%s %s, %s, %s", y, cond, rd, rm, rs)
+		}
+		return fmt.Sprintf("smlaw%s%s %s, %s, %s, %s", y, cond, rd, rm, rs, rn)
+	case 2:
+		return fmt.Sprintf("smlal%s%s%s %s, %s, %s, %s", xy, y, cond, rn, rd, rm, rs)
+	default:
+		return fmt.Sprintf("smul%s%s%s %s, %s, %s", xy, y, cond, rd, rm, rs)
+	}
+}
+
+func armBlock(op uint32, cond string) string {
+	var (
+		load = op&(1<<20) != 0
+		wb   = op&(1<<21) != 0
+		rn   = (op >> 16) & 0xF
+		list = regList(op & 0xFFFF)
+	)
+
+	if op&(1<<22) != 0 {
+		list += "^"
+	}
+
+	mode := [4]string{"da", "ia", "db", "ib"}[(op>>23)&3]
+
+	switch {
+	case rn == 13 && wb && load && mode == "ia":
+		return "pop" + cond + " " + list
+	case rn == 13 && wb && !load && mode == "db":
+		return "push" + cond + " " + list
+	}
+
+	name := "stm"
+	if load {
+		name = "ldm"
+	}
+
+	base := reg(rn)
+	if wb {
+		base += "!"
+	}
+
+	return fmt.Sprintf("%s%s%s %s, %s", name, mode, cond, base, list)
+}
+
+// DisasmThumb disassembles a thumb instruction at pc. The halves of bl and
+// blx are shown on their own, use DisasmThumbLong for the pair.
+func DisasmThumb(op uint16, pc uint32) string {
+	o := uint32(op)
+
+	rd := reg(o & 7)
+	rs := reg(o >> 3 & 7)
+
+	switch {
+	case o&0xF800 == 0x1800:
+		name := "add"
+		if o&(1<<9) != 0 {
+			name = "sub"
+		}
+		if o&(1<<10) != 0 {
+			return fmt.Sprintf("%ss %s, %s, #%d", name, rd, rs, (o>>6)&7)
+		}
+		return fmt.Sprintf("%ss %s, %s, %s", name, rd, rs, reg((o>>6)&7))
+
+	case o&0xE000 == 0x0000:
+		shift := (o >> 11) & 3
+		amount := (o >> 6) & 0x1F
+		if amount == 0 && shift != 0 {
+			amount = 32
+		}
+		return fmt.Sprintf("%ss %s, %s, #%d", SHIFTS[shift], rd, rs, amount)
+
+	case o&0xE000 == 0x2000:
+		name := [4]string{"movs", "cmp", "adds", "subs"}[(o>>11)&3]
+		return fmt.Sprintf("%s %s, %s", name, reg(o>>8&7), hex(o&0xFF))
+
+	case o&0xFC00 == 0x4000:
+		name := THUMB_ALU_OPS[(o>>6)&0xF]
+		switch name {
+		case "tst", "cmp", "cmn":
+		default:
+			name += "s"
+		}
+		return fmt.Sprintf("%s %s, %s", name, reg(o&7), reg(o>>3&7))
+
+	case o&0xFC00 == 0x4400:
+		hd := o&7 | (o>>4)&8
+		hs := (o >> 3) & 0xF
+		switch (o >> 8) & 3 {
+		case 0:
+			return fmt.Sprintf("add %s, %s", reg(hd), reg(hs))
+		case 1:
+			return fmt.Sprintf("cmp %s, %s", reg(hd), reg(hs))
+		case 2:
+			return fmt.Sprintf("mov %s, %s", reg(hd), reg(hs))
+		default:
+			if o&(1<<7) != 0 {
+				return "blx " + reg(hs)
+			}
+			return "bx " + reg(hs)
+		}
+
+	case o&0xF800 == 0x4800:
+		offset := (o & 0xFF) << 2
+		return fmt.Sprintf("ldr %s, [pc, %s] ; 0x%08X", reg(o>>8&7), hex(offset), (pc+4)&^3+offset)
+
+	case o&0xF200 == 0x5000:
+		name := [4]string{"str", "strb", "ldr", "ldrb"}[(o>>10)&3]
+		return fmt.Sprintf("%s %s, [%s, %s]", name, reg(o&7), reg(o>>3&7), reg(o>>6&7))
+
+	case o&0xF200 == 0x5200:
+		name := [4]string{"strh", "ldrsb", "ldrh", "ldrsh"}[(o>>10)&3]
+		return fmt.Sprintf("%s %s, [%s, %s]", name, reg(o&7), reg(o>>3&7), reg(o>>6&7))
+
+	case o&0xE000 == 0x6000:
+		imm := (o >> 6) & 0x1F
+		name := "str"
+		if o&(1<<11) != 0 {
+			name = "ldr"
+		}
+		if o&(1<<12) != 0 {
+			name += "b"
+		} else {
+			imm <<= 2
+		}
+		return fmt.Sprintf("%s %s, [%s, %s]", name, reg(o&7), reg(o>>3&7), hex(imm))
+
+	case o&0xF000 == 0x8000:
+		name := "strh"
+		if o&(1<<11) != 0 {
+			name = "ldrh"
+		}
+		return fmt.Sprintf("%s %s, [%s, %s]", name, reg(o&7), reg(o>>3&7), hex((o>>6)&0x1F<<1))
+
+	case o&0xF000 == 0x9000:
+		name := "str"
+		if o&(1<<11) != 0 {
+			name = "ldr"
+		}
+		return fmt.Sprintf("%s %s, [sp, %s]", name, reg(o>>8&7), hex((o&0xFF)<<2))
+
+	case o&0xF000 == 0xA000:
+		if o&(1<<11) != 0 {
+			return fmt.Sprintf("add %s, sp, %s", reg(o>>8&7), hex((o&0xFF)<<2))
+		}
+		return fmt.Sprintf("add %s, pc, %s ; 0x%08X", reg(o>>8&7), hex((o&0xFF)<<2), (pc+4)&^3+(o&0xFF)<<2)
+
+	case o&0xFF00 == 0xB000:
+		if o&(1<<7) != 0 {
+			return "sub sp, " + hex((o&0x7F)<<2)
+		}
+		return "add sp, " + hex((o&0x7F)<<2)
+
+	case o&0xF600 == 0xB400:
+		list := o & 0xFF
+		if o&(1<<11) != 0 {
+			if o&(1<<8) != 0 {
+				list |= 1 << 15
+			}
+			return "pop " + regList(list)
+		}
+		if o&(1<<8) != 0 {
+			list |= 1 << 14
+		}
+		return "push " + regList(list)
+
+	case o&0xFF00 == 0xBE00:
+		return "bkpt " + hex(o&0xFF)
+
+	case o&0xF000 == 0xC000:
+		name := "stmia"
+		if o&(1<<11) != 0 {
+			name = "ldmia"
+		}
+		return fmt.Sprintf("%s %s!, %s", name, reg(o>>8&7), regList(o&0xFF))
+
+	case o&0xFF00 == 0xDF00:
+		return "swi " + hex(o&0xFF)
+
+	case o&0xFF00 == 0xDE00:
+		return "undefined"
+
+	case o&0xF000 == 0xD000:
+		offset := signExtend(o&0xFF, 8) << 1
+		return fmt.Sprintf("b%s 0x%08X", CONDS[(o>>8)&0xF], pc+4+uint32(offset))
+
+	case o&0xF800 == 0xE000:
+		offset := signExtend(o&0x7FF, 11) << 1
+		return fmt.Sprintf("b 0x%08X", pc+4+uint32(offset))
+
+	case o&0xF800 == 0xF000:
+		return "bl.hi " + hex(o&0x7FF)
+
+	case o&0xF800 == 0xF800:
+		return "bl.lo " + hex(o&0x7FF)
+
+	case o&0xF800 == 0xE800:
+		return "blx.lo " + hex(o&0x7FF)
+	}
+
+	return "undefined"
+}
+
+// DisasmThumbLong disassembles the two halves of a thumb bl or blx at pc, ok
+// is false if hi and lo are not a pair
+func DisasmThumbLong(hi, lo uint16, pc uint32) (string, bool) {
+	if hi&0xF800 != 0xF000 || lo&0xE800 != 0xE800 {
+		return "", false
+	}
+
+	offset := signExtend(uint32(hi&0x7FF), 11)<<12 | int32(lo&0x7FF)<<1
+	target := pc + 4 + uint32(offset)
+
+	if lo&0xF800 == 0xE800 {
+		return fmt.Sprintf("blx 0x%08X", target&^3), true
+	}
+
+	return fmt.Sprintf("bl 0x%08X", target), true
+}
@@ -0,0 +1,677 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	hexenc "encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// gdb numbers the cpsr 25, after the fpa registers it does not use
+	GDB_REG_CPSR = 25
+
+	// while stopped Poll waits this long for the next packet, gdb sends
+	// them one at a time
+	GDB_IDLE = 10 * time.Millisecond
+
+	GDB_PACKET_SIZE = 0x1000
+)
+
+const GDB_TARGET_XML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+<architecture>arm</architecture>
+<feature name="org.gnu.gdb.arm.core">
+<reg name="r0" bitsize="32" type="uint32"/>
+<reg name="r1" bitsize="32" type="uint32"/>
+<reg name="r2" bitsize="32" type="uint32"/>
+<reg name="r3" bitsize="32" type="uint32"/>
+<reg name="r4" bitsize="32" type="uint32"/>
+<reg name="r5" bitsize="32" type="uint32"/>
+<reg name="r6" bitsize="32" type="uint32"/>
+<reg name="r7" bitsize="32" type="uint32"/>
+<reg name="r8" bitsize="32" type="uint32"/>
+<reg name="r9" bitsize="32" type="uint32"/>
+<reg name="r10" bitsize="32" type="uint32"/>
+<reg name="r11" bitsize="32" type="uint32"/>
+<reg name="r12" bitsize="32" type="uint32"/>
+<reg name="sp" bitsize="32" type="data_ptr"/>
+<reg name="lr" bitsize="32"/>
+<reg name="pc" bitsize="32" type="code_ptr"/>
+<reg name="cpsr" bitsize="32" regnum="25"/>
+</feature>
+</target>
+`
+
+type gdbRequest struct {
+	conn      net.Conn
+	data      string
+	attach    bool
+	detach    bool
+	interrupt bool
+}
+
+// Gdb serves the gdb remote serial protocol, so arm-none-eabi-gdb can attach
+// with "target remote". Every cpu is a thread numbered from 1 in the order
+// given. Stopping is all stop, a stopped cpu stops the whole console.
+//
+// Packets are read on their own goroutine and run by Poll like the Console.
+// Breakpoints and watchpoints are set on every cpu, gdb has no notion of the
+// cpus having their own address spaces.
+type Gdb struct {
+	Cpus []*Debugger
+
+	ln    net.Listener
+	reqs  chan gdbRequest
+	noAck atomic.Bool
+
+	conn    net.Conn
+	running bool // gdb is waiting for a stop reply
+	gThread int  // cpu of register and memory packets
+	cThread int  // cpu stepped by s, -1 for any
+}
+
+// ListenGdb starts serving gdb on addr, e.g. "localhost:2345"
+func ListenGdb(addr string, cpus ...*Debugger) (*Gdb, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gdb{
+		Cpus:    cpus,
+		ln:      ln,
+		reqs:    make(chan gdbRequest, 16),
+		cThread: -1,
+	}
+
+	for _, d := range cpus {
+		d.OnStop = append(d.OnStop, g.stopped)
+	}
+
+	log.Printf("Gdb: listening on %s\n", ln.Addr())
+
+	go g.accept()
+
+	return g, nil
+}
+
+func (g *Gdb) Addr() net.Addr {
+	return g.ln.Addr()
+}
+
+func (g *Gdb) Close() error {
+	return g.ln.Close()
+}
+
+func (g *Gdb) accept() {
+	for {
+		conn, err := g.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		// one gdb at a time
+		g.read(conn)
+	}
+}
+
+// read queues every packet of a connection until it is closed
+func (g *Gdb) read(conn net.Conn) {
+	defer conn.Close()
+
+	g.reqs <- gdbRequest{conn: conn, attach: true}
+
+	r := bufio.NewReader(conn)
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			g.reqs <- gdbRequest{conn: conn, detach: true}
+			return
+		}
+
+		switch b {
+		case 0x03:
+			g.reqs <- gdbRequest{conn: conn, interrupt: true}
+			continue
+		case '$':
+		default:
+			// acks
+			continue
+		}
+
+		data, err := r.ReadString('#')
+		if err != nil {
+			g.reqs <- gdbRequest{conn: conn, detach: true}
+			return
+		}
+
+		var sum [2]byte
+		if _, err := r.Read(sum[:1]); err != nil {
+			continue
+		}
+		if _, err := r.Read(sum[1:]); err != nil {
+			continue
+		}
+
+		data = data[:len(data)-1]
+
+		if !g.noAck.Load() {
+			if want, err := strconv.ParseUint(string(sum[:]), 16, 8); err != nil || uint8(want) != checksum(data) {
+				conn.Write([]byte{'-'})
+				continue
+			}
+
+			conn.Write([]byte{'+'})
+		}
+
+		g.reqs <- gdbRequest{conn: conn, data: data}
+	}
+}
+
+func checksum(data string) uint8 {
+	var sum uint8
+	for i := range len(data) {
+		sum += data[i]
+	}
+
+	return sum
+}
+
+func (g *Gdb) send(data string) {
+	if g.conn == nil {
+		return
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('$')
+
+	for i := range len(data) {
+		switch c := data[i]; c {
+		case '#', '$', '}', '*':
+			b.WriteByte('}')
+			b.WriteByte(c ^ 0x20)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	fmt.Fprintf(&b, "#%02x", checksum(b.String()[1:]))
+
+	g.conn.Write(b.Bytes())
+}
+
+func (g *Gdb) stopped(d *Debugger) {
+	if !g.running {
+		return
+	}
+
+	g.running = false
+	g.send(g.stopReply(d))
+}
+
+func (g *Gdb) thread(d *Debugger) int {
+	for i := range g.Cpus {
+		if g.Cpus[i] == d {
+			return i + 1
+		}
+	}
+
+	return 1
+}
+
+func (g *Gdb) stopReply(d *Debugger) string {
+	s := d.Stop()
+
+	if s.Reason != WATCHPOINT {
+		return fmt.Sprintf("T05thread:%x;", g.thread(d))
+	}
+
+	kind := "awatch"
+	switch s.Watchpoint.Access {
+	case WRITE:
+		kind = "watch"
+	case READ:
+		kind = "rwatch"
+	}
+
+	return fmt.Sprintf("T05%s:%x;thread:%x;", kind, s.Addr, g.thread(d))
+}
+
+// Poll runs every queued packet, while the console is stopped it keeps
+// serving gdb until it goes idle
+func (g *Gdb) Poll() {
+	for {
+		select {
+		case req := <-g.reqs:
+			g.handle(req)
+			continue
+		default:
+		}
+
+		if g.conn == nil || g.running {
+			return
+		}
+
+		select {
+		case req := <-g.reqs:
+			g.handle(req)
+		case <-time.After(GDB_IDLE):
+			return
+		}
+	}
+}
+
+func (g *Gdb) handle(req gdbRequest) {
+	switch {
+	case req.attach:
+		g.conn = req.conn
+		g.running = false
+		g.gThread = 0
+		g.cThread = -1
+		g.noAck.Store(false)
+
+		for _, d := range g.Cpus {
+			d.Break()
+		}
+
+		return
+
+	case req.conn != g.conn:
+		return
+
+	case req.detach:
+		g.detach()
+		return
+
+	case req.interrupt:
+		if g.running {
+			g.Cpus[max(0, g.cThread)].Break()
+		}
+		return
+	}
+
+	if reply, ok := g.packet(req.data); ok {
+		g.send(reply)
+	}
+}
+
+func (g *Gdb) detach() {
+	g.conn = nil
+	g.running = false
+
+	for _, d := range g.Cpus {
+		for _, addr := range d.Breakpoints() {
+			d.RemoveBreakpoint(addr)
+		}
+		for _, w := range d.Watchpoints() {
+			d.RemoveWatchpoint(w.Addr, w.Access)
+		}
+		d.Continue()
+	}
+}
+
+// resume continues every cpu, step is the index of a cpu to single step
+// instead or -1
+func (g *Gdb) resume(step int) {
+	g.running = true
+
+	for i, d := range g.Cpus {
+		if i == step {
+			d.Step()
+			continue
+		}
+
+		d.Continue()
+	}
+}
+
+// packet runs a packet and returns its reply, ok is false for packets which
+// reply once the cpu stops
+func (g *Gdb) packet(data string) (reply string, ok bool) {
+	if data == "" {
+		return "", true
+	}
+
+	d := g.Cpus[g.gThread]
+	cmd, args := data[0], data[1:]
+
+	switch cmd {
+	case '?':
+		for _, d := range g.Cpus {
+			if d.Stopped() {
+				return g.stopReply(d), true
+			}
+		}
+		return "S05", true
+
+	case 'q':
+		return g.query(args), true
+
+	case 'Q':
+		if args == "StartNoAckMode" {
+			g.noAck.Store(true)
+			return "OK", true
+		}
+		return "", true
+
+	case 'H':
+		if len(args) < 2 {
+			return "E01", true
+		}
+
+		t, err := parseThread(args[1:], len(g.Cpus))
+		if err != nil {
+			return "E01", true
+		}
+
+		switch args[0] {
+		case 'g':
+			g.gThread = max(0, t)
+		case 'c':
+			g.cThread = t
+		}
+		return "OK", true
+
+	case 'T':
+		if t, err := parseThread(args, len(g.Cpus)); err != nil || t < 0 {
+			return "E01", true
+		}
+		return "OK", true
+
+	case 'g':
+		r := d.Cpu.Regs()
+
+		var b []byte
+		for _, v := range r.R {
+			b = binary.LittleEndian.AppendUint32(b, v)
+		}
+		b = binary.LittleEndian.AppendUint32(b, r.CPSR)
+
+		return hexenc.EncodeToString(b), true
+
+	case 'G':
+		b, err := hexenc.DecodeString(args)
+		if err != nil || len(b) < 17*4 {
+			return "E01", true
+		}
+
+		for i := range 16 {
+			d.Cpu.SetReg(i, binary.LittleEndian.Uint32(b[i*4:]))
+		}
+		d.Cpu.SetReg(REG_CPSR, binary.LittleEndian.Uint32(b[16*4:]))
+
+		return "OK", true
+
+	case 'p':
+		n, err := strconv.ParseUint(args, 16, 32)
+		if err != nil {
+			return "E01", true
+		}
+
+		r := d.Cpu.Regs()
+
+		var v uint32
+		switch {
+		case n < 16:
+			v = r.R[n]
+		case n == GDB_REG_CPSR:
+			v = r.CPSR
+		default:
+			return "E01", true
+		}
+
+		return hexenc.EncodeToString(binary.LittleEndian.AppendUint32(nil, v)), true
+
+	case 'P':
+		reg, val, found := strings.Cut(args, "=")
+		n, err := strconv.ParseUint(reg, 16, 32)
+		b, err2 := hexenc.DecodeString(val)
+		if !found || err != nil || err2 != nil || len(b) != 4 {
+			return "E01", true
+		}
+
+		v := binary.LittleEndian.Uint32(b)
+
+		switch {
+		case n < 16:
+			d.Cpu.SetReg(int(n), v)
+		case n == GDB_REG_CPSR:
+			d.Cpu.SetReg(REG_CPSR, v)
+		default:
+			return "E01", true
+		}
+
+		return "OK", true
+
+	case 'm':
+		addr, length, err := addrLen(args)
+		if err != nil || length > GDB_PACKET_SIZE/2 {
+			return "E01", true
+		}
+
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = uint8(d.Peek(addr+uint32(i), 1))
+		}
+
+		return hexenc.EncodeToString(b), true
+
+	case 'M':
+		head, body, _ := strings.Cut(args, ":")
+		addr, length, err := addrLen(head)
+		b, err2 := hexenc.DecodeString(body)
+		if err != nil || err2 != nil || len(b) != int(length) {
+			return "E01", true
+		}
+
+		for i, v := range b {
+			d.Poke(addr+uint32(i), uint32(v), 1)
+		}
+
+		return "OK", true
+
+	case 'Z', 'z':
+		return g.breakpoint(cmd == 'Z', args), true
+
+	case 'c':
+		g.resume(-1)
+		return "", false
+
+	case 's':
+		step := g.cThread
+		if step < 0 {
+			step = g.gThread
+		}
+
+		g.resume(step)
+		return "", false
+
+	case 'v':
+		return g.vPacket(args)
+
+	case 'D':
+		g.send("OK")
+		g.detach()
+		return "", false
+
+	case 'k':
+		g.detach()
+		return "", false
+	}
+
+	return "", true
+}
+
+func (g *Gdb) query(args string) string {
+	switch {
+	case strings.HasPrefix(args, "Supported"):
+		return fmt.Sprintf("PacketSize=%x;qXfer:features:read+;QStartNoAckMode+;vContSupported+", GDB_PACKET_SIZE)
+
+	case strings.HasPrefix(args, "Xfer:features:read:target.xml:"):
+		_, offLen, _ := strings.Cut(args, "target.xml:")
+		off, length, err := addrLen(offLen)
+		if err != nil {
+			return "E01"
+		}
+
+		if int(off) >= len(GDB_TARGET_XML) {
+			return "l"
+		}
+
+		end := min(len(GDB_TARGET_XML), int(off+length))
+		chunk := GDB_TARGET_XML[off:end]
+		if end == len(GDB_TARGET_XML) {
+			return "l" + chunk
+		}
+
+		return "m" + chunk
+
+	case args == "Attached":
+		return "1"
+
+	case args == "C":
+		return fmt.Sprintf("QC%x", g.gThread+1)
+
+	case args == "fThreadInfo":
+		ids := make([]string, len(g.Cpus))
+		for i := range g.Cpus {
+			ids[i] = strconv.FormatInt(int64(i+1), 16)
+		}
+		return "m" + strings.Join(ids, ",")
+
+	case args == "sThreadInfo":
+		return "l"
+
+	case strings.HasPrefix(args, "ThreadExtraInfo,"):
+		t, err := parseThread(strings.TrimPrefix(args, "ThreadExtraInfo,"), len(g.Cpus))
+		if err != nil || t < 0 {
+			return "E01"
+		}
+		return hexenc.EncodeToString([]byte(g.Cpus[t].Name))
+	}
+
+	return ""
+}
+
+func (g *Gdb) vPacket(args string) (string, bool) {
+	switch {
+	case args == "Cont?":
+		return "vCont;c;C;s;S", true
+
+	case strings.HasPrefix(args, "Cont;"):
+		step := -1
+
+		for _, action := range strings.Split(args[len("Cont;"):], ";") {
+			act, thread, hasThread := strings.Cut(action, ":")
+			if act == "" || (act[0] != 's' && act[0] != 'S') {
+				continue
+			}
+
+			step = g.gThread
+			if hasThread {
+				t, err := parseThread(thread, len(g.Cpus))
+				if err != nil {
+					return "E01", true
+				}
+				step = max(0, t)
+			}
+		}
+
+		g.resume(step)
+		return "", false
+	}
+
+	return "", true
+}
+
+func (g *Gdb) breakpoint(insert bool, args string) string {
+	parts := strings.Split(args, ",")
+	if len(parts) < 3 {
+		return "E01"
+	}
+
+	addr, err := strconv.ParseUint(parts[1], 16, 32)
+	kind, err2 := strconv.ParseUint(parts[2], 16, 32)
+	if err != nil || err2 != nil {
+		return "E01"
+	}
+
+	var access Access
+
+	switch parts[0] {
+	case "0", "1":
+		for _, d := range g.Cpus {
+			if insert {
+				d.AddBreakpoint(uint32(addr))
+			} else {
+				d.RemoveBreakpoint(uint32(addr))
+			}
+		}
+		return "OK"
+	case "2":
+		access = WRITE
+	case "3":
+		access = READ
+	case "4":
+		access = READ | WRITE
+	default:
+		return ""
+	}
+
+	for _, d := range g.Cpus {
+		if !insert {
+			d.RemoveWatchpoint(uint32(addr), access)
+			continue
+		}
+
+		if err := d.AddWatchpoint(Watchpoint{Addr: uint32(addr), Len: uint32(kind), Access: access}); err != nil {
+			return "E01"
+		}
+	}
+
+	return "OK"
+}
+
+// parseThread returns the cpu index of a thread id, -1 for all threads
+func parseThread(s string, n int) (int, error) {
+	switch s {
+	case "-1", "0":
+		return -1, nil
+	}
+
+	t, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || t == 0 || int(t) > n {
+		return 0, fmt.Errorf("bad thread %q", s)
+	}
+
+	return int(t) - 1, nil
+}
+
+func addrLen(s string) (addr, length uint32, err error) {
+	a, l, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("bad address %q", s)
+	}
+
+	av, err := strconv.ParseUint(a, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lv, err := strconv.ParseUint(l, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return uint32(av), uint32(lv), nil
+}
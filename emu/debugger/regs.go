@@ -0,0 +1,120 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MODE_USR = 0x10
+	MODE_FIQ = 0x11
+	MODE_IRQ = 0x12
+	MODE_SWI = 0x13
+	MODE_ABT = 0x17
+	MODE_UND = 0x1B
+	MODE_SYS = 0x1F
+)
+
+// banks are indexed like arm7.BANK_ID
+var BANK_NAMES = [6]string{"usr", "fiq", "irq", "svc", "abt", "und"}
+
+var MODE_NAMES = map[uint32]string{
+	MODE_USR: "usr",
+	MODE_FIQ: "fiq",
+	MODE_IRQ: "irq",
+	MODE_SWI: "svc",
+	MODE_ABT: "abt",
+	MODE_UND: "und",
+	MODE_SYS: "sys",
+}
+
+// Regs is a copy of a cpu's registers. R holds the registers of the current
+// mode, the banks hold every mode including the current one.
+type Regs struct {
+	R    [16]uint32
+	CPSR uint32
+	SPSR [6]uint32
+	SP   [6]uint32
+	LR   [6]uint32
+	FIQ  [5]uint32 // r8 - r12 of fiq mode
+	USR  [5]uint32 // r8 - r12 of every other mode
+}
+
+func (r Regs) Thumb() bool {
+	return r.CPSR&(1<<5) != 0
+}
+
+func (r Regs) Mode() uint32 {
+	return r.CPSR & 0x1F
+}
+
+// Flags formats the cpsr flags, set flags are upper case
+func Flags(psr uint32) string {
+	var b strings.Builder
+
+	for i, c := range "NZCVQ" {
+		if psr&(1<<(31-i)) != 0 {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune(c + 'a' - 'A')
+		}
+	}
+
+	b.WriteByte(' ')
+
+	for i, c := range "IFT" {
+		if psr&(1<<(7-i)) != 0 {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune(c + 'a' - 'A')
+		}
+	}
+
+	mode, ok := MODE_NAMES[psr&0x1F]
+	if !ok {
+		mode = fmt.Sprintf("%02X", psr&0x1F)
+	}
+
+	return b.String() + " " + mode
+}
+
+func (r *Regs) String() string {
+	var b strings.Builder
+
+	for i, v := range r.R {
+		switch i {
+		case 13:
+			fmt.Fprintf(&b, "sp  %08X", v)
+		case 14:
+			fmt.Fprintf(&b, "lr  %08X", v)
+		case 15:
+			fmt.Fprintf(&b, "pc  %08X", v)
+		default:
+			fmt.Fprintf(&b, "r%-2d %08X", i, v)
+		}
+
+		if i&3 == 3 {
+			b.WriteByte('\n')
+		} else {
+			b.WriteString("  ")
+		}
+	}
+
+	fmt.Fprintf(&b, "cpsr %08X %s\n", r.CPSR, Flags(r.CPSR))
+
+	fmt.Fprintf(&b, "bank sp       lr       spsr\n")
+	for i, name := range BANK_NAMES {
+		if i == 0 {
+			// user and system mode have no spsr
+			fmt.Fprintf(&b, "%-4s %08X %08X\n", name, r.SP[i], r.LR[i])
+			continue
+		}
+
+		fmt.Fprintf(&b, "%-4s %08X %08X %08X %s\n", name, r.SP[i], r.LR[i], r.SPSR[i], Flags(r.SPSR[i]))
+	}
+
+	fmt.Fprintf(&b, "fiq  r8-r12 %08X\n", r.FIQ)
+	fmt.Fprintf(&b, "usr  r8-r12 %08X", r.USR)
+
+	return b.String()
+}
@@ -30,6 +30,8 @@ type Apu struct {
 	streamLen    int
 	buffSize     uint32
 
+	sampleRate int `state:"-"` // a sample every cpuFreq / sndFrequency cycles
+
 	fsCounter uint32
 	fsStep    uint8
 
@@ -83,6 +85,7 @@ func NewApu(audioContext *oto.Context, cpuFreq, sampleRate, sampleCnt int) *Apu
 	a := &Apu{
 		WritePointer: 0x200,
 		sndFrequency: sampleRate,
+		sampleRate:   cpuFreq / (cpuFreq / sampleRate),
 		streamLen:    (2 * 2 * sampleRate / 60) - (2*2*sampleRate/60)%4,
 		buffSize:     uint32(sampleCnt * 16 * 2),
 	}
@@ -263,3 +266,8 @@ func (a *Apu) PowerOn() {
 func clip(v int32) int16 {
 	return min(SAMP_MAX, max(SAMP_MIN, int16(v)))
 }
+
+// SampleRate is the rate samples are made at, not quite sndFrequency
+func (a *Apu) SampleRate() int {
+	return a.sampleRate
+}
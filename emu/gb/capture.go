@@ -0,0 +1,45 @@
+package gb
+
+import (
+	"log"
+
+	"github.com/aabalke/guac/emu/capture"
+)
+
+// FRAME_RATE is the emulated frame rate, audio and video stay in step at it
+var FRAME_RATE = capture.Rate{Num: CPU_SPEED, Den: CYCLES_PER_FRAME}
+
+// StartCapture records the picture and sound of every frame to path, see
+// capture.New
+func (gb *GameBoy) StartCapture(path string) error {
+	r, err := capture.New(path, width, height, FRAME_RATE, gb.Apu.SampleRate())
+	if err != nil {
+		return err
+	}
+
+	r.SkipSound(gb.Apu.WritePointer)
+	gb.Capture = r
+
+	return nil
+}
+
+// captureFrame adds the frame just drawn and its sound
+func (gb *GameBoy) captureFrame() {
+	if gb.Capture == nil {
+		return
+	}
+
+	err := gb.Capture.Record(gb.Screenshot(), gb.Apu.SoundBuffer, gb.Apu.WritePointer)
+	if err != nil {
+		log.Printf("Capture Failed: %v\n", err)
+		gb.StopCapture()
+	}
+}
+
+// StopCapture ends recording and returns the frames recorded
+func (gb *GameBoy) StopCapture() (int, error) {
+	n, err := gb.Capture.Stop()
+	gb.Capture = nil
+
+	return n, err
+}
@@ -0,0 +1,240 @@
+package cartridge
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+const (
+	CAMERA_W = 128
+	CAMERA_H = 112
+
+	// the captured image is written to ram bank 0 as 16 x 14 tiles
+	CAMERA_IMAGE_ADDR = 0x100
+
+	CAMERA_REGS      = 0x36
+	CAMERA_SHOOT     = 0x00
+	CAMERA_GAIN      = 0x01
+	CAMERA_EXPOSURE  = 0x02 // msb, lsb in 03
+	CAMERA_EDGE      = 0x04
+	CAMERA_DITHER    = 0x06 // 4 x 4 matrix of 3 thresholds
+	CAMERA_RAM_BANKS = 0x10
+)
+
+// Sensor supplies the image seen by the camera, 0 is black and 255 white
+type Sensor interface {
+	Capture() *[CAMERA_H][CAMERA_W]uint8
+}
+
+// Camera is the pocket camera mapper. Ram bank 0x10 maps the registers of
+// the M64282FP sensor in A000 - A07F. Writing 1 to A000 captures an image,
+// which is processed like the sensor does, with gain, edge enhancement,
+// exposure and the dither matrix, and written to ram like any photo, so it
+// is saved with the rest of the ram.
+type Camera struct {
+	Cartridge *Cartridge
+
+	RamEnabled bool
+	Bank1      uint8
+	Bank2      uint8
+
+	RomBase2, RamBase uint32
+
+	Regs [CAMERA_REGS]uint8
+
+	// Sensor is set by the emulator, a test pattern is used without one
+	Sensor Sensor
+}
+
+func NewCamera(c *Cartridge) *Camera {
+
+	fmt.Printf("Cartridge POCKET CAMERA\n")
+
+	m := &Camera{
+		Cartridge: c,
+		Bank1:     1,
+	}
+
+	m.UpdateAddrs()
+
+	return m
+}
+
+func (m *Camera) registers() bool {
+	return m.Bank2&CAMERA_RAM_BANKS != 0
+}
+
+func (m *Camera) Read(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask]
+	case addr < 0x8000:
+		return m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask]
+	case m.registers():
+		// only the shoot register is readable, the capture is done at once
+		// so it is never busy
+		if addr&0x7F == CAMERA_SHOOT {
+			return m.Regs[CAMERA_SHOOT] &^ 1
+		}
+		return 0
+	default:
+		// ram is always readable, only writes need enabling
+		return m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask]
+	}
+}
+
+func (m *Camera) ReadPtr(addr uint16) unsafe.Pointer {
+	switch {
+	case addr < 0x4000:
+		return unsafe.Pointer(&m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask])
+	case addr < 0x8000:
+		return unsafe.Pointer(&m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask])
+	default:
+		return nil
+	}
+}
+
+func (m *Camera) Write(addr uint16, v uint8) {
+
+	switch {
+	case addr < 0x2000:
+		m.RamEnabled = v&0xF == 0xA
+
+	case addr < 0x4000:
+		m.Bank1 = v & 0x3F
+		m.UpdateAddrs()
+
+	case addr < 0x6000:
+		m.Bank2 = v & 0x1F
+		m.UpdateAddrs()
+
+	case addr < 0x8000:
+		return
+
+	case m.registers():
+		reg := addr & 0x7F
+		if reg >= CAMERA_REGS {
+			return
+		}
+
+		m.Regs[reg] = v
+
+		if reg == CAMERA_SHOOT && v&1 != 0 {
+			m.capture()
+		}
+
+	case m.RamEnabled:
+		m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask] = v
+	}
+}
+
+func (m *Camera) UpdateAddrs() {
+	m.RomBase2 = uint32(m.Bank1) << 14
+	m.RamBase = uint32(m.Bank2&0xF) << 13
+}
+
+func (m *Camera) Save() {}
+
+var (
+	// edge enhancement ratio, bits 4 - 6 of register 4
+	cameraEdgeRatio = [8]float64{0.5, 0.75, 1, 1.25, 2, 3, 4, 5}
+
+	// the gain is 14 dB and 1.5 dB a step, relative to the 20 dB the camera
+	// rom starts with
+	cameraGain = func() (g [32]float64) {
+		for i := range g {
+			g[i] = math.Pow(10, (float64(i)*1.5-6)/20)
+		}
+		return g
+	}()
+)
+
+// capture processes the sensor image into 2bpp tiles in ram bank 0
+func (m *Camera) capture() {
+
+	var src *[CAMERA_H][CAMERA_W]uint8
+	if m.Sensor != nil {
+		src = m.Sensor.Capture()
+	}
+
+	if src == nil {
+		src = testPattern()
+	}
+
+	var (
+		gain     = cameraGain[m.Regs[CAMERA_GAIN]&0x1F]
+		edge     = m.Regs[CAMERA_GAIN]&0xE0 == 0xE0
+		ratio    = cameraEdgeRatio[(m.Regs[CAMERA_EDGE]>>4)&0x7]
+		invert   = m.Regs[CAMERA_EDGE]&0x08 != 0
+		exposure = float64(uint16(m.Regs[CAMERA_EXPOSURE])<<8 | uint16(m.Regs[CAMERA_EXPOSURE+1]))
+	)
+
+	pixel := func(x, y int) float64 {
+		x = max(0, min(x, CAMERA_W-1))
+		y = max(0, min(y, CAMERA_H-1))
+		return float64(src[y][x]) * gain
+	}
+
+	ram := m.Cartridge.RamData[CAMERA_IMAGE_ADDR:]
+	clear(ram[:CAMERA_W*CAMERA_H/4])
+
+	for y := range CAMERA_H {
+		for x := range CAMERA_W {
+
+			v := pixel(x, y)
+
+			if edge {
+				v += 4 * v * ratio
+				v -= (pixel(x-1, y) + pixel(x+1, y) + pixel(x, y-1) + pixel(x, y+1)) * ratio
+			}
+
+			v = v * exposure / 0x1000
+
+			if invert {
+				v = 255 - v
+			}
+
+			c := uint8(max(0, min(v, 255)))
+
+			thresholds := m.Regs[CAMERA_DITHER+((y&3)*4+(x&3))*3:]
+
+			var shade uint8
+			switch {
+			case c < thresholds[0]:
+				shade = 3
+			case c < thresholds[1]:
+				shade = 2
+			case c < thresholds[2]:
+				shade = 1
+			}
+
+			tile := (y/8)*(CAMERA_W/8) + x/8
+			i := tile*16 + (y&7)*2
+			bit := uint8(0x80) >> (x & 7)
+
+			if shade&1 != 0 {
+				ram[i] |= bit
+			}
+
+			if shade&2 != 0 {
+				ram[i+1] |= bit
+			}
+		}
+	}
+
+	m.Cartridge.Dirty = true
+}
+
+// testPattern is a diagonal gradient for captures without a sensor
+func testPattern() *[CAMERA_H][CAMERA_W]uint8 {
+	var img [CAMERA_H][CAMERA_W]uint8
+
+	for y := range CAMERA_H {
+		for x := range CAMERA_W {
+			img[y][x] = uint8((x + y) * 255 / (CAMERA_W + CAMERA_H - 2))
+		}
+	}
+
+	return &img
+}
@@ -2,16 +2,20 @@ package cartridge
 
 import (
 	"fmt"
-	"os"
 	"slices"
 	"strings"
+	"sync"
+
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
 )
 
 type Cartridge struct {
-	Title     string
-	RomPath   string
-	SavPath   string
-	Data      []uint8
+	Title     string  `state:"-"`
+	RomPath   string  `state:"-"`
+	SavPath   string  `state:"-"`
+	RtcPath   string  `state:"-"`
+	Data      []uint8 `state:"-"`
 	RamData   []uint8
 	Type      uint8
 	RomSize   int
@@ -21,10 +25,16 @@ type Cartridge struct {
 	Mbc       Mbc
 	ColorMode bool
 
+	// Dirty is set by mbcs which change save data outside of ram writes,
+	// like flash and eeprom
+	Dirty bool `state:"-"`
+
 	RomBank uint8
 	RamBank uint8
 	RomMask uint32
 	RamMask uint32
+
+	checksum func() uint32
 }
 
 const (
@@ -34,31 +44,44 @@ const (
 	SUM  = 0x14D
 )
 
-func NewCartridge(rompath, savpath string) *Cartridge {
+// NewCartridge takes the rom as loaded, saves are named after the patch when
+// it is patched
+func NewCartridge(img *rom.Image) *Cartridge {
 
 	c := &Cartridge{
-		RomPath: rompath,
-		SavPath: savpath,
+		RomPath: img.Path,
+		SavPath: img.Name() + ".save",
+		RtcPath: img.Name() + ".rtc",
 	}
 
-	buf, err := os.ReadFile(rompath)
-	if err != nil {
-		panic(err)
-	}
+	buf := img.Data
 
 	c.ParseHeader(buf)
 
+	if mmm01 := c.Type >= 0x0B && c.Type <= 0x0D; mmm01 {
+		c.RomSize = len(buf)
+		c.RomMask = uint32(c.RomSize - 1)
+	}
+
 	c.Data = make([]uint8, c.RomSize)
 	copy(c.Data, buf)
 
+	c.checksum = sync.OnceValue(func() uint32 {
+		return state.Checksum(c.Data)
+	})
+
 	if c.RamSize != 0 {
 
-		buf, err = ReadRam(savpath)
+		buf, err := ReadRam(c.SavPath)
 		if err != nil {
 			buf = make([]uint8, c.RamSize)
+
+			if mbc6 := c.Type == 0x20; mbc6 {
+				fill(buf[MBC6_RAM_SIZE:], 0xFF)
+			}
 		}
 
-		c.RamData = make([]uint8, c.RomSize)
+		c.RamData = make([]uint8, c.RamSize)
 		copy(c.RamData, buf)
 	}
 
@@ -75,6 +98,18 @@ func NewCartridge(rompath, savpath string) *Cartridge {
 		c.Mbc = NewMbc3(c)
 	case 0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E:
 		c.Mbc = NewMbc5(c)
+	case 0x0B, 0x0C, 0x0D:
+		c.Mbc = NewMmm01(c)
+	case 0x20:
+		c.Mbc = NewMbc6(c)
+	case 0x22:
+		c.Mbc = NewMbc7(c)
+	case 0xFC:
+		c.Mbc = NewCamera(c)
+	case 0xFE:
+		c.Mbc = NewHuc3(c)
+	case 0xFF:
+		c.Mbc = NewHuc1(c)
 	default:
 		panic(fmt.Sprintf("UNSUPPORTED CART MAP TYPE %X", c.Type))
 	}
@@ -126,11 +161,19 @@ var ramMask = [...]uint32{
 var validRamCodes = []uint8{
 	0x02, 0x03, 0x08, 0x09, 0x0C,
 	0x0D, 0x10, 0x12, 0x13, 0x1A,
-	0x1B, 0x1D, 0x1E, 0x22, 0xFF,
+	0x1B, 0x1D, 0x1E, 0x22, 0xFC,
+	0xFE, 0xFF,
 }
 
 func (c *Cartridge) ParseHeader(buf []uint8) {
 
+	// mmm01 multicarts boot the menu in the last 32k, with its own header
+	if n := len(buf); n > 1<<15 && n&(n-1) == 0 {
+		if t := buf[n-(1<<15)+TYPE]; t >= 0x0B && t <= 0x0D {
+			buf = buf[n-(1<<15):]
+		}
+	}
+
 	c.Type = buf[TYPE]
 	c.Title = strings.Trim(string(buf[0x134:0x143]), string(byte(0)))
 	c.RomSize = romSize[buf[ROM]]
@@ -151,6 +194,15 @@ func (c *Cartridge) ParseHeader(buf []uint8) {
 		c.RamMask = (1 << 18) - 1
 	}
 
+	switch c.Type {
+	case 0x20:
+		c.RamSize = MBC6_RAM_SIZE + MBC6_FLASH_SIZE
+		c.RamMask = MBC6_RAM_SIZE - 1
+	case 0x22:
+		c.RamSize = MBC7_EEPROM_SIZE
+		c.RamMask = MBC7_EEPROM_SIZE - 1
+	}
+
 	if flag := buf[0x143]; flag == 0x80 || flag == 0xC0 {
 		c.ColorMode = true
 	}
@@ -0,0 +1,113 @@
+package cartridge
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Infrared is the led and sensor of another cart, it is set by the emulator.
+// The sensor never sees light without one.
+type Infrared interface {
+	SetLed(on bool)
+	Light() bool
+}
+
+// irRead is the sensor read, bit 0 is light seen
+func irRead(ir Infrared) uint8 {
+	if ir != nil && ir.Light() {
+		return 0xC1
+	}
+
+	return 0xC0
+}
+
+func irLed(ir Infrared, on bool) {
+	if ir != nil {
+		ir.SetLed(on)
+	}
+}
+
+// Huc1 is like a mbc1 with an infrared led and sensor in place of ram when
+// 0x0E is written to 0000 - 1FFF
+type Huc1 struct {
+	Cartridge *Cartridge
+	Ir        Infrared
+
+	IrMode bool
+	IrLed  bool
+	Bank1  uint8
+	Bank2  uint8
+
+	RomBase2, RamBase uint32
+}
+
+func NewHuc1(c *Cartridge) *Huc1 {
+
+	fmt.Printf("Cartridge HUC1\n")
+
+	m := &Huc1{
+		Cartridge: c,
+		Bank1:     1,
+	}
+
+	m.UpdateAddrs()
+
+	return m
+}
+
+func (m *Huc1) Read(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask]
+	case addr < 0x8000:
+		return m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask]
+	case m.IrMode:
+		return irRead(m.Ir)
+	default:
+		return m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask]
+	}
+}
+
+func (m *Huc1) ReadPtr(addr uint16) unsafe.Pointer {
+	switch {
+	case addr < 0x4000:
+		return unsafe.Pointer(&m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask])
+	case addr < 0x8000:
+		return unsafe.Pointer(&m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask])
+	default:
+		return nil
+	}
+}
+
+func (m *Huc1) Write(addr uint16, v uint8) {
+
+	switch {
+	case addr < 0x2000:
+		m.IrMode = v&0xF == 0xE
+
+	case addr < 0x4000:
+		m.Bank1 = max(1, v&0x3F)
+		m.UpdateAddrs()
+
+	case addr < 0x6000:
+		m.Bank2 = v & 0x3
+		m.UpdateAddrs()
+
+	case addr < 0x8000:
+		return
+
+	case m.IrMode:
+		m.IrLed = v&1 != 0
+		irLed(m.Ir, m.IrLed)
+
+	default:
+		m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask] = v
+	}
+}
+
+func (m *Huc1) UpdateAddrs() {
+	m.RomBase2 = uint32(m.Bank1) << 14
+	m.RamBase = uint32(m.Bank2) << 13
+}
+
+func (m *Huc1) Save() {}
@@ -0,0 +1,253 @@
+package cartridge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+
+	"github.com/aabalke/guac/emu/clock"
+)
+
+// rtc uses the sameboy huc3 format (17 byte)
+// last unix second, minutes, days, alarm minutes, alarm days, alarm enabled
+
+// huc3 modes, written to 0000 - 1FFF
+const (
+	HUC3_RAM_READ = 0x0
+	HUC3_RAM      = 0xA
+	HUC3_RTC_CMD  = 0xB
+	HUC3_RTC_READ = 0xC
+	HUC3_RTC_SEM  = 0xD
+	HUC3_IR       = 0xE
+)
+
+// Huc3 has a clock counting minutes of the day and days, accessed a nibble
+// at a time with commands written in A000 - BFFF. The infrared led and
+// sensor are the same as the huc1's.
+type Huc3 struct {
+	Cartridge *Cartridge
+	Ir        Infrared
+
+	Mode  uint8
+	Bank1 uint8
+	Bank2 uint8
+	IrLed bool
+
+	RomBase2, RamBase uint32
+
+	Minutes      uint16
+	Days         uint16
+	AlarmMinutes uint16
+	AlarmDays    uint16
+	AlarmEnabled bool
+
+	Idx   uint8
+	Flags uint8
+	ReadV uint8
+
+	last int64 // unix second of the last minute counted
+}
+
+func NewHuc3(c *Cartridge) *Huc3 {
+
+	fmt.Printf("Cartridge HUC3\n")
+
+	m := &Huc3{
+		Cartridge: c,
+		Bank1:     1,
+	}
+
+	if buf, err := ReadRam(c.RtcPath); err != nil || len(buf) < 17 {
+		m.last = clock.Now().Unix()
+	} else {
+		m.Parse(buf)
+	}
+
+	m.UpdateAddrs()
+
+	return m
+}
+
+func (m *Huc3) Parse(buf []byte) {
+	m.last = int64(binary.LittleEndian.Uint64(buf[0:]))
+	m.Minutes = binary.LittleEndian.Uint16(buf[8:])
+	m.Days = binary.LittleEndian.Uint16(buf[10:])
+	m.AlarmMinutes = binary.LittleEndian.Uint16(buf[12:])
+	m.AlarmDays = binary.LittleEndian.Uint16(buf[14:])
+	m.AlarmEnabled = buf[16]&1 != 0
+
+	m.UpdateSince()
+}
+
+func (m *Huc3) Save() {
+
+	buf := make([]uint8, 17)
+
+	m.UpdateSince()
+
+	binary.LittleEndian.PutUint64(buf[0:], uint64(rtcStamp(m.last)))
+	binary.LittleEndian.PutUint16(buf[8:], m.Minutes)
+	binary.LittleEndian.PutUint16(buf[10:], m.Days)
+	binary.LittleEndian.PutUint16(buf[12:], m.AlarmMinutes)
+	binary.LittleEndian.PutUint16(buf[14:], m.AlarmDays)
+	if m.AlarmEnabled {
+		buf[16] = 1
+	}
+
+	WriteRam(m.Cartridge.RtcPath, buf)
+}
+
+func (m *Huc3) Read(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask]
+	case addr < 0x8000:
+		return m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask]
+	}
+
+	switch m.Mode {
+	case HUC3_RAM_READ, HUC3_RAM:
+		if m.Cartridge.RamData == nil {
+			return 0xFF
+		}
+		return m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask]
+	case HUC3_RTC_READ:
+		if m.Flags == 2 {
+			return 1
+		}
+		return m.ReadV
+	case HUC3_RTC_SEM:
+		// always ready
+		return 1
+	case HUC3_IR:
+		return irRead(m.Ir)
+	default:
+		return 0xFF
+	}
+}
+
+func (m *Huc3) ReadPtr(addr uint16) unsafe.Pointer {
+	switch {
+	case addr < 0x4000:
+		return unsafe.Pointer(&m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask])
+	case addr < 0x8000:
+		return unsafe.Pointer(&m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask])
+	default:
+		return nil
+	}
+}
+
+func (m *Huc3) Write(addr uint16, v uint8) {
+
+	switch {
+	case addr < 0x2000:
+		m.Mode = v & 0xF
+
+	case addr < 0x4000:
+		m.Bank1 = v & 0x7F
+		m.UpdateAddrs()
+
+	case addr < 0x6000:
+		m.Bank2 = v & 0x3
+		m.UpdateAddrs()
+
+	case addr < 0x8000:
+		return
+
+	default:
+		switch m.Mode {
+		case HUC3_RAM:
+			if m.Cartridge.RamData != nil {
+				m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask] = v
+			}
+		case HUC3_RTC_CMD:
+			m.command(v)
+		case HUC3_IR:
+			m.IrLed = v&1 != 0
+			irLed(m.Ir, m.IrLed)
+		}
+	}
+}
+
+// command runs a rtc command, the high nibble is the command and the low
+// nibble is the argument. The index selects a nibble of the registers,
+// 0 - 2 minutes, 3 - 6 days and 58 - 5F the alarm
+func (m *Huc3) command(v uint8) {
+
+	arg := v & 0xF
+
+	switch v >> 4 {
+	case 1:
+		m.UpdateSince()
+
+		switch {
+		case m.Idx < 3:
+			m.ReadV = uint8(m.Minutes>>(m.Idx*4)) & 0xF
+		case m.Idx < 7:
+			m.ReadV = uint8(m.Days>>((m.Idx-3)*4)) & 0xF
+		}
+
+		m.Idx++
+
+	case 2, 3:
+		m.UpdateSince()
+
+		switch {
+		case m.Idx < 3:
+			setNibble(&m.Minutes, m.Idx, arg)
+		case m.Idx < 7:
+			setNibble(&m.Days, m.Idx-3, arg)
+		case m.Idx >= 0x58 && m.Idx <= 0x5A:
+			setNibble(&m.AlarmMinutes, m.Idx-0x58, arg)
+		case m.Idx >= 0x5B && m.Idx <= 0x5E:
+			setNibble(&m.AlarmDays, m.Idx-0x5B, arg)
+		case m.Idx == 0x5F:
+			m.AlarmEnabled = arg&1 != 0
+		}
+
+		if v>>4 == 3 {
+			m.Idx++
+		}
+
+	case 4:
+		m.Idx = m.Idx&0xF0 | arg
+
+	case 5:
+		m.Idx = m.Idx&0x0F | arg<<4
+
+	case 6:
+		m.Flags = arg
+	}
+}
+
+func setNibble(r *uint16, i, v uint8) {
+	*r &^= 0xF << (i * 4)
+	*r |= uint16(v) << (i * 4)
+}
+
+func (m *Huc3) UpdateAddrs() {
+	m.RomBase2 = uint32(m.Bank1) << 14
+	m.RamBase = uint32(m.Bank2) << 13
+}
+
+// UpdateSince counts the whole minutes since last, the remaining seconds
+// are kept for the next update. A last ahead of the clock counts from now
+func (m *Huc3) UpdateSince() {
+	now := clock.Now().Unix()
+
+	if now < m.last {
+		m.last = now
+		return
+	}
+
+	minutes := (now - m.last) / 60
+	if minutes <= 0 {
+		return
+	}
+
+	m.last += minutes * 60
+
+	total := int64(m.Minutes) + minutes
+	m.Minutes = uint16(total % (60 * 24))
+	m.Days += uint16(total / (60 * 24))
+}
@@ -14,6 +14,11 @@ type Mbc interface {
 	Save()
 }
 
+// Rumbler is a mbc with a rumble motor
+type Rumbler interface {
+	Rumbling() bool
+}
+
 func ReadRam(path string) ([]uint8, error) {
 
 	f, err := os.Open(path)
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 	"unsafe"
+
+	"github.com/aabalke/guac/emu/clock"
 )
 
 // rtc uses https://bgb.bircd.org/rtcsave.html
@@ -42,8 +44,8 @@ func NewMbc3(c *Cartridge) *Mbc3 {
 		MBC30:     c.RomSize > 1<<21 || c.RamSize > 1<<15,
 	}
 
-	if buf, err := ReadRam(c.RomPath + ".rtc"); err != nil {
-		m.last = time.Now().Unix()
+	if buf, err := ReadRam(c.RtcPath); err != nil {
+		m.last = clock.Now().Unix()
 	} else {
 		m.Parse(buf)
 	}
@@ -86,9 +88,9 @@ func (m *Mbc3) Save() {
 	buf[28] = uint8(m.latchedtime[2])
 	buf[32] = uint8(m.latchedtime[3])
 	buf[36] = uint8(m.latchedtime[4])
-	binary.LittleEndian.PutUint32(buf[40:], uint32(m.last))
+	binary.LittleEndian.PutUint32(buf[40:], uint32(rtcStamp(m.last)))
 
-	WriteRam(m.Cartridge.RomPath+".rtc", buf)
+	WriteRam(m.Cartridge.RtcPath, buf)
 }
 
 func (m *Mbc3) Read(addr uint16) uint8 {
@@ -181,14 +183,26 @@ func (m *Mbc3) UpdateAddrs() {
 	m.RamBase = uint32(m.Bank2) << 13
 }
 
+// UpdateSince counts the seconds since last. A last ahead of the clock, an
+// .rtc from host time read by a seeded clock, counts nothing
 func (m *Mbc3) UpdateSince() {
 	last := m.last
-	now := time.Now().Unix()
-	delta := (now - last)
+	now := clock.Now().Unix()
+	delta := max(now-last, 0)
 	m.last = now
 	m.UpdateTime(uint32(delta))
 }
 
+// rtcStamp is the time saved beside the rtc registers. A seeded clock is not
+// the host's, so the registers are stamped with host time and the next
+// session counts from now rather than from the seed
+func rtcStamp(last int64) int64 {
+	if clock.Seeded() {
+		return time.Now().Unix()
+	}
+	return last
+}
+
 func (m *Mbc3) UpdateTime(cnt uint32) {
 
 	if halted := (m.time[4]>>6)&1 != 0; halted {
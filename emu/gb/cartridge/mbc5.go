@@ -13,15 +13,23 @@ type Mbc5 struct {
 	Bank1, Bank2, Bank3 uint8
 
 	RomBase, RomBase2, RamBase uint32
+
+	// rumble carts use bit 3 of the ram bank for the motor
+	HasRumble, Rumble bool
 }
 
 func NewMbc5(c *Cartridge) *Mbc5 {
 
-	fmt.Printf("Cartridge MBC5\n")
-
 	m := &Mbc5{
 		Cartridge: c,
 		Bank1:     1,
+		HasRumble: c.Type >= 0x1C && c.Type <= 0x1E,
+	}
+
+	if m.HasRumble {
+		fmt.Printf("Cartridge MBC5 RUMBLE\n")
+	} else {
+		fmt.Printf("Cartridge MBC5\n")
 	}
 
 	m.UpdateAddrs()
@@ -70,7 +78,12 @@ func (m *Mbc5) Write(addr uint16, v uint8) {
 		m.UpdateAddrs()
 
 	case addr < 0x6000:
-		m.Bank3 = v & 0xF
+		if m.HasRumble {
+			m.Bank3 = v & 0x7
+			m.Rumble = v&0x8 != 0
+		} else {
+			m.Bank3 = v & 0xF
+		}
 		m.UpdateAddrs()
 
 	case addr < 0x8000:
@@ -88,3 +101,7 @@ func (m *Mbc5) UpdateAddrs() {
 }
 
 func (m *Mbc5) Save() {}
+
+func (m *Mbc5) Rumbling() bool {
+	return m.Rumble
+}
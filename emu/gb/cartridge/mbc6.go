@@ -0,0 +1,242 @@
+package cartridge
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const (
+	MBC6_RAM_SIZE   = 1 << 15
+	MBC6_FLASH_SIZE = 1 << 20
+
+	// the flash is erased in 128k sectors
+	MBC6_SECTOR_SIZE = 1 << 17
+)
+
+// flash command states
+const (
+	FLASH_READY = iota
+	FLASH_UNLOCK1
+	FLASH_UNLOCK2
+	FLASH_ERASE
+	FLASH_ERASE_UNLOCK1
+	FLASH_ERASE_UNLOCK2
+	FLASH_PROGRAM
+)
+
+// Mbc6 has two 8k rom windows at 4000 and 6000 which map rom or flash, and
+// two 4k ram windows at A000 and B000. The flash is stored after the ram,
+// so both are saved together.
+type Mbc6 struct {
+	Cartridge *Cartridge
+
+	RamEnabled   bool
+	FlashEnabled bool
+	FlashWrite   bool
+
+	RomBank  [2]uint8
+	IsFlash  [2]bool
+	RamBank  [2]uint8
+	FlashCmd int
+	FlashId  bool
+}
+
+func NewMbc6(c *Cartridge) *Mbc6 {
+
+	fmt.Printf("Cartridge MBC6\n")
+
+	m := &Mbc6{
+		Cartridge: c,
+		RomBank:   [2]uint8{2, 3},
+	}
+
+	return m
+}
+
+func (m *Mbc6) flash() []uint8 {
+	return m.Cartridge.RamData[MBC6_RAM_SIZE:]
+}
+
+func (m *Mbc6) romAddr(addr uint16) uint32 {
+	i := (addr >> 13) & 1
+	return uint32(m.RomBank[i])<<13 | uint32(addr&0x1FFF)
+}
+
+func (m *Mbc6) ramAddr(addr uint16) uint32 {
+	i := (addr >> 12) & 1
+	return (uint32(m.RamBank[i])<<12 | uint32(addr&0xFFF)) & (MBC6_RAM_SIZE - 1)
+}
+
+func (m *Mbc6) Read(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask]
+
+	case addr < 0x8000:
+		i := (addr >> 13) & 1
+
+		if !m.IsFlash[i] {
+			return m.Cartridge.Data[m.romAddr(addr)&m.Cartridge.RomMask]
+		}
+
+		if !m.FlashEnabled {
+			return 0xFF
+		}
+
+		if m.FlashId {
+			// macronix mx29f008
+			return [2]uint8{0xC2, 0x81}[addr&1]
+		}
+
+		return m.flash()[m.romAddr(addr)&(MBC6_FLASH_SIZE-1)]
+
+	case m.RamEnabled:
+		return m.Cartridge.RamData[m.ramAddr(addr)]
+
+	default:
+		return 0xFF
+	}
+}
+
+func (m *Mbc6) ReadPtr(addr uint16) unsafe.Pointer {
+	switch {
+	case addr < 0x4000:
+		return unsafe.Pointer(&m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask])
+	case addr < 0x8000 && !m.IsFlash[(addr>>13)&1]:
+		return unsafe.Pointer(&m.Cartridge.Data[m.romAddr(addr)&m.Cartridge.RomMask])
+	default:
+		return nil
+	}
+}
+
+func (m *Mbc6) Write(addr uint16, v uint8) {
+
+	switch {
+	case addr < 0x0400:
+		m.RamEnabled = v&0xF == 0xA
+
+	case addr < 0x0800:
+		m.RamBank[0] = v & 0x7
+
+	case addr < 0x0C00:
+		m.RamBank[1] = v & 0x7
+
+	case addr < 0x1000:
+		m.FlashEnabled = v&1 != 0
+
+	case addr < 0x2000:
+		if addr == 0x1000 {
+			m.FlashWrite = v&1 != 0
+		}
+
+	case addr < 0x4000:
+		i := (addr >> 12) & 1
+
+		if isSelect := addr&0x800 != 0; isSelect {
+			m.IsFlash[i] = v == 0x08
+			return
+		}
+
+		m.RomBank[i] = v & 0x7F
+
+	case addr < 0x8000:
+		if m.IsFlash[(addr>>13)&1] && m.FlashEnabled {
+			m.writeFlash(m.romAddr(addr)&(MBC6_FLASH_SIZE-1), v)
+		}
+
+	case m.RamEnabled:
+		m.Cartridge.RamData[m.ramAddr(addr)] = v
+	}
+}
+
+// writeFlash runs the jedec command sequences
+func (m *Mbc6) writeFlash(addr uint32, v uint8) {
+	cmdAddr := addr & 0x7FFF
+
+	if v == 0xF0 {
+		m.FlashCmd = FLASH_READY
+		m.FlashId = false
+		return
+	}
+
+	switch m.FlashCmd {
+	case FLASH_READY:
+		if cmdAddr == 0x5555 && v == 0xAA {
+			m.FlashCmd = FLASH_UNLOCK1
+		}
+
+	case FLASH_UNLOCK1:
+		m.FlashCmd = FLASH_READY
+		if cmdAddr == 0x2AAA && v == 0x55 {
+			m.FlashCmd = FLASH_UNLOCK2
+		}
+
+	case FLASH_UNLOCK2:
+		m.FlashCmd = FLASH_READY
+
+		if cmdAddr != 0x5555 {
+			return
+		}
+
+		switch v {
+		case 0x80:
+			m.FlashCmd = FLASH_ERASE
+		case 0x90:
+			m.FlashId = true
+		case 0xA0:
+			m.FlashCmd = FLASH_PROGRAM
+		}
+
+	case FLASH_ERASE:
+		m.FlashCmd = FLASH_READY
+		if cmdAddr == 0x5555 && v == 0xAA {
+			m.FlashCmd = FLASH_ERASE_UNLOCK1
+		}
+
+	case FLASH_ERASE_UNLOCK1:
+		m.FlashCmd = FLASH_READY
+		if cmdAddr == 0x2AAA && v == 0x55 {
+			m.FlashCmd = FLASH_ERASE_UNLOCK2
+		}
+
+	case FLASH_ERASE_UNLOCK2:
+		m.FlashCmd = FLASH_READY
+
+		if !m.FlashWrite {
+			return
+		}
+
+		flash := m.flash()
+
+		switch {
+		case v == 0x10 && cmdAddr == 0x5555:
+			fill(flash, 0xFF)
+		case v == 0x30:
+			start := addr &^ (MBC6_SECTOR_SIZE - 1)
+			fill(flash[start:start+MBC6_SECTOR_SIZE], 0xFF)
+		default:
+			return
+		}
+
+		m.Cartridge.Dirty = true
+
+	case FLASH_PROGRAM:
+		m.FlashCmd = FLASH_READY
+
+		if !m.FlashWrite {
+			return
+		}
+
+		// programming only clears bits
+		m.flash()[addr] &= v
+		m.Cartridge.Dirty = true
+	}
+}
+
+func fill(b []uint8, v uint8) {
+	for i := range b {
+		b[i] = v
+	}
+}
+
+func (m *Mbc6) Save() {}
@@ -0,0 +1,329 @@
+package cartridge
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const (
+	// 93LC56, 128 16 bit words
+	MBC7_EEPROM_SIZE = 256
+
+	// accelerometer reading when level, and the change for 1g
+	MBC7_ACCEL_CENTER = 0x81D0
+	MBC7_ACCEL_G      = 0x70
+)
+
+// Mbc7 has an accelerometer and a serial eeprom in place of ram. Both are
+// registers in A000 - AFFF once ram is enabled twice, at 0000 and 4000.
+//
+// Tilt is set by the host, -1 - 1 on both axes, positive x is tilting right
+// and positive y is tilting down.
+type Mbc7 struct {
+	Cartridge *Cartridge
+
+	RamEnabled  bool
+	RamEnabled2 bool
+	Bank1       uint8
+
+	RomBase2 uint32
+
+	Tilt    [2]float64
+	Accel   [2]uint16
+	Latched bool
+
+	Eeprom Eeprom93
+}
+
+func NewMbc7(c *Cartridge) *Mbc7 {
+
+	fmt.Printf("Cartridge MBC7\n")
+
+	m := &Mbc7{
+		Cartridge: c,
+		Bank1:     1,
+		Accel:     [2]uint16{0x8000, 0x8000},
+		Eeprom:    Eeprom93{Do: true},
+	}
+
+	m.UpdateAddrs()
+
+	return m
+}
+
+func (m *Mbc7) Read(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask]
+	case addr < 0x8000:
+		return m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask]
+	case addr < 0xB000 && m.RamEnabled && m.RamEnabled2:
+		return m.readReg(addr)
+	default:
+		return 0xFF
+	}
+}
+
+func (m *Mbc7) readReg(addr uint16) uint8 {
+	switch (addr >> 4) & 0xF {
+	case 2:
+		return uint8(m.Accel[0])
+	case 3:
+		return uint8(m.Accel[0] >> 8)
+	case 4:
+		return uint8(m.Accel[1])
+	case 5:
+		return uint8(m.Accel[1] >> 8)
+	case 6:
+		return 0
+	case 8:
+		return m.Eeprom.Read()
+	default:
+		return 0xFF
+	}
+}
+
+func (m *Mbc7) ReadPtr(addr uint16) unsafe.Pointer {
+	switch {
+	case addr < 0x4000:
+		return unsafe.Pointer(&m.Cartridge.Data[uint32(addr)&m.Cartridge.RomMask])
+	case addr < 0x8000:
+		return unsafe.Pointer(&m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask])
+	default:
+		return nil
+	}
+}
+
+func (m *Mbc7) Write(addr uint16, v uint8) {
+
+	switch {
+	case addr < 0x2000:
+		m.RamEnabled = v&0xF == 0xA
+
+	case addr < 0x4000:
+		m.Bank1 = v & 0x7F
+		m.UpdateAddrs()
+
+	case addr < 0x6000:
+		m.RamEnabled2 = v == 0x40
+
+	case addr < 0x8000:
+		return
+
+	case addr < 0xB000 && m.RamEnabled && m.RamEnabled2:
+		m.writeReg(addr, v)
+	}
+}
+
+func (m *Mbc7) writeReg(addr uint16, v uint8) {
+	switch (addr >> 4) & 0xF {
+	case 0:
+		if v == 0x55 {
+			m.Accel = [2]uint16{0x8000, 0x8000}
+			m.Latched = false
+		}
+
+	case 1:
+		if v == 0xAA && !m.Latched {
+			m.Accel[0] = accel(m.Tilt[0])
+			m.Accel[1] = accel(m.Tilt[1])
+			m.Latched = true
+		}
+
+	case 8:
+		if m.Eeprom.Write(v, m.Cartridge.RamData) {
+			m.Cartridge.Dirty = true
+		}
+	}
+}
+
+func accel(tilt float64) uint16 {
+	tilt = max(-1, min(tilt, 1))
+	return uint16(MBC7_ACCEL_CENTER + int(tilt*MBC7_ACCEL_G))
+}
+
+func (m *Mbc7) UpdateAddrs() {
+	m.RomBase2 = uint32(m.Bank1) << 14
+}
+
+func (m *Mbc7) Save() {}
+
+// Eeprom93 is a 93LC56 microwire eeprom in 16 bit mode. Commands are a start
+// bit, a 2 bit opcode and an 8 bit address shifted in msb first on di while
+// cs is high, data is shifted out msb first on do after a dummy 0.
+type Eeprom93 struct {
+	Cs, Clk, Di, Do bool
+
+	Shift uint32
+	Bits  int
+	Done  bool // waiting for cs to go low
+
+	Reading  bool
+	ReadData uint16
+	ReadBits int
+
+	WriteEnabled bool
+}
+
+const (
+	EEPROM93_CMD_BITS  = 11 // start, opcode and address
+	EEPROM93_DATA_BITS = EEPROM93_CMD_BITS + 16
+)
+
+func (e *Eeprom93) Read() uint8 {
+	v := uint8(0)
+
+	if e.Cs {
+		v |= 0x80
+	}
+
+	if e.Clk {
+		v |= 0x40
+	}
+
+	if e.Di {
+		v |= 0x02
+	}
+
+	if e.Do {
+		v |= 0x01
+	}
+
+	return v
+}
+
+// Write sets the pins and reports if the words in data changed
+func (e *Eeprom93) Write(v uint8, data []uint8) bool {
+	cs := v&0x80 != 0
+	clk := v&0x40 != 0
+	di := v&0x02 != 0
+
+	rising := clk && !e.Clk
+
+	e.Clk, e.Di = clk, di
+
+	if !cs {
+		if e.Cs {
+			e.Shift, e.Bits = 0, 0
+			e.Done = false
+			e.Reading = false
+			e.Do = true
+		}
+
+		e.Cs = false
+		return false
+	}
+
+	e.Cs = true
+
+	if !rising || e.Done {
+		return false
+	}
+
+	if e.Reading {
+		e.Do = e.ReadData&0x8000 != 0
+		e.ReadData <<= 1
+		e.ReadBits--
+
+		if e.ReadBits == 0 {
+			e.Reading = false
+			e.Done = true
+		}
+
+		return false
+	}
+
+	// waiting for the start bit
+	if e.Bits == 0 && !di {
+		return false
+	}
+
+	e.Shift <<= 1
+	if di {
+		e.Shift |= 1
+	}
+	e.Bits++
+
+	return e.command(data)
+}
+
+func (e *Eeprom93) command(data []uint8) bool {
+	if e.Bits < EEPROM93_CMD_BITS {
+		return false
+	}
+
+	cmd := e.Shift >> (e.Bits - EEPROM93_CMD_BITS)
+	op := (cmd >> 8) & 3
+	addr := cmd & 0xFF
+
+	word := int(addr&0x7F) << 1
+	value := uint16(e.Shift)
+
+	switch op {
+	case 0b10: // read
+		e.ReadData = uint16(data[word])<<8 | uint16(data[word+1])
+		e.ReadBits = 16
+		e.Reading = true
+		e.Do = false
+		return false
+
+	case 0b01: // write
+		if e.Bits < EEPROM93_DATA_BITS {
+			return false
+		}
+
+		e.Done = true
+		e.Do = true
+
+		if !e.WriteEnabled {
+			return false
+		}
+
+		data[word], data[word+1] = uint8(value>>8), uint8(value)
+		return true
+
+	case 0b11: // erase
+		e.Done = true
+		e.Do = true
+
+		if !e.WriteEnabled {
+			return false
+		}
+
+		data[word], data[word+1] = 0xFF, 0xFF
+		return true
+	}
+
+	switch (addr >> 6) & 3 {
+	case 0b11: // write enable
+		e.WriteEnabled = true
+	case 0b00: // write disable
+		e.WriteEnabled = false
+	case 0b10: // erase all
+		if e.WriteEnabled {
+			fill(data[:MBC7_EEPROM_SIZE], 0xFF)
+			e.Done = true
+			e.Do = true
+			return true
+		}
+	case 0b01: // write all
+		if e.Bits < EEPROM93_DATA_BITS {
+			return false
+		}
+
+		e.Done = true
+		e.Do = true
+
+		if !e.WriteEnabled {
+			return false
+		}
+
+		for i := 0; i < MBC7_EEPROM_SIZE; i += 2 {
+			data[i], data[i+1] = uint8(value>>8), uint8(value)
+		}
+		return true
+	}
+
+	e.Done = true
+	return false
+}
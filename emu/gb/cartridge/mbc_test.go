@@ -0,0 +1,303 @@
+package cartridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aabalke/guac/emu/clock"
+)
+
+func newTestCart(romBanks, ramSize int) *Cartridge {
+	c := &Cartridge{
+		Data:    make([]uint8, romBanks<<14),
+		RamData: make([]uint8, ramSize),
+		RomMask: uint32(romBanks<<14) - 1,
+		RamMask: uint32(max(ramSize, 1)) - 1,
+	}
+
+	for i := range c.Data {
+		c.Data[i] = uint8(i >> 14)
+	}
+
+	return c
+}
+
+// eeprom93 shifts a command into the mbc7 eeprom msb first and returns the
+// bits shifted out
+func eeprom93(m *Mbc7, bits uint32, n, out int) uint32 {
+	const cs, clk = 0x80, 0x40
+
+	m.Write(0xA080, 0)
+	m.Write(0xA080, cs)
+
+	for i := n - 1; i >= 0; i-- {
+		di := uint8(bits>>i&1) << 1
+		m.Write(0xA080, cs|di)
+		m.Write(0xA080, cs|clk|di)
+	}
+
+	var v uint32
+	for range out {
+		m.Write(0xA080, cs)
+		m.Write(0xA080, cs|clk)
+		v = v<<1 | uint32(m.Read(0xA080)&1)
+	}
+
+	m.Write(0xA080, 0)
+
+	return v
+}
+
+func TestMbc7Eeprom(t *testing.T) {
+	c := newTestCart(4, MBC7_EEPROM_SIZE)
+	m := NewMbc7(c)
+
+	m.Write(0x0000, 0x0A)
+	m.Write(0x4000, 0x40)
+
+	eeprom93(m, 0b100_1100_0000, 11, 0)            // write enable
+	eeprom93(m, 0b101_0000_0101<<16|0xBEEF, 27, 0) // write word 5
+	if got := eeprom93(m, 0b110_0000_0101, 11, 16); got != 0xBEEF {
+		t.Fatalf("eeprom read %04X, expected BEEF", got)
+	}
+
+	if !c.Dirty {
+		t.Fatal("eeprom write not saved")
+	}
+
+	if c.RamData[10] != 0xBE || c.RamData[11] != 0xEF {
+		t.Fatalf("eeprom word stored as %02X", c.RamData[10:12])
+	}
+}
+
+func TestMbc7Accel(t *testing.T) {
+	m := NewMbc7(newTestCart(4, MBC7_EEPROM_SIZE))
+
+	m.Write(0x0000, 0x0A)
+	m.Write(0x4000, 0x40)
+
+	m.Tilt = [2]float64{1, 0}
+	m.Write(0xA000, 0x55)
+	m.Write(0xA010, 0xAA)
+
+	x := uint16(m.Read(0xA030))<<8 | uint16(m.Read(0xA020))
+	y := uint16(m.Read(0xA050))<<8 | uint16(m.Read(0xA040))
+
+	if x != MBC7_ACCEL_CENTER+MBC7_ACCEL_G || y != MBC7_ACCEL_CENTER {
+		t.Fatalf("accel %04X %04X", x, y)
+	}
+}
+
+func TestMbc6Flash(t *testing.T) {
+	c := newTestCart(8, MBC6_RAM_SIZE+MBC6_FLASH_SIZE)
+	fill(c.RamData[MBC6_RAM_SIZE:], 0xFF)
+	m := NewMbc6(c)
+
+	m.Write(0x0C00, 1) // flash enable
+	m.Write(0x1000, 1) // flash write enable
+	m.Write(0x2800, 0x08)
+	m.Write(0x2000, 0x02) // flash bank 2 at 4000
+	m.Write(0x3800, 0x08)
+	m.Write(0x3000, 0x01) // flash bank 1 at 6000
+
+	m.Write(0x5555, 0xAA) // 5555
+	m.Write(0x6AAA, 0x55) // 2AAA
+	m.Write(0x5555, 0xA0)
+	m.Write(0x4010, 0x5A)
+
+	if got := m.Read(0x4010); got != 0x5A {
+		t.Fatalf("flash program read %02X", got)
+	}
+
+	if !c.Dirty || c.RamData[MBC6_RAM_SIZE+2<<13|0x10] != 0x5A {
+		t.Fatal("flash not stored in save")
+	}
+}
+
+func TestMmm01(t *testing.T) {
+	m := NewMmm01(newTestCart(64, 0))
+
+	if m.Read(0x0000) != 62 || m.Read(0x4000) != 63 {
+		t.Fatal("unmapped mmm01 does not boot the menu")
+	}
+
+	// second 256k game, 16 banks
+	m.Write(0x2000, 0x10)
+	m.Write(0x6000, 0b1000<<2) // bit 4 fixed, the game writes the low 4
+	m.Write(0x0000, 0x40)      // lock
+
+	if m.Read(0x0000) != 16 || m.Read(0x4000) != 17 {
+		t.Fatalf("locked mmm01 maps %d %d", m.Read(0x0000), m.Read(0x4000))
+	}
+
+	m.Write(0x2000, 0x03)
+	if got := m.Read(0x4000); got != 19 {
+		t.Fatalf("game bank 3 maps %d", got)
+	}
+
+	m.Write(0x2000, 0x1F)
+	if got := m.Read(0x4000); got != 31 {
+		t.Fatalf("game bank 15 maps %d", got)
+	}
+}
+
+func TestHuc3Rtc(t *testing.T) {
+	m := &Huc3{Cartridge: newTestCart(4, 1<<13), Bank1: 1, last: time.Now().Unix()}
+	m.UpdateAddrs()
+
+	m.Write(0x0000, HUC3_RTC_CMD)
+
+	// write 0x123 minutes and 0x45 days from index 0
+	m.Write(0xA000, 0x40)
+	m.Write(0xA000, 0x50)
+	for _, v := range []uint8{3, 2, 1, 5, 4, 0, 0} {
+		m.Write(0xA000, 0x30|v)
+	}
+
+	if m.Minutes != 0x123 || m.Days != 0x45 {
+		t.Fatalf("huc3 clock %X minutes %X days", m.Minutes, m.Days)
+	}
+
+	m.Write(0xA000, 0x40)
+	m.Write(0xA000, 0x10)
+
+	m.Write(0x0000, HUC3_RTC_READ)
+	if got := m.Read(0xA000); got != 3 {
+		t.Fatalf("huc3 read nibble %X", got)
+	}
+}
+
+func TestRtcLastAheadOfClock(t *testing.T) {
+	clock.Seed(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	defer clock.Unseed()
+
+	host := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	m3 := &Mbc3{Cartridge: newTestCart(4, 1<<13), last: host}
+	m3.time = [5]uint32{1, 2, 3, 4, 0}
+	m3.UpdateSince()
+
+	if m3.time != [5]uint32{1, 2, 3, 4, 0} {
+		t.Fatalf("mbc3 rtc moved to %v", m3.time)
+	}
+
+	h := &Huc3{Cartridge: newTestCart(4, 1<<13), last: host, Minutes: 10}
+	h.UpdateSince()
+	clock.Advance(time.Minute)
+	h.UpdateSince()
+
+	if h.Minutes != 11 || h.Days != 0 {
+		t.Fatalf("huc3 clock %d minutes %d days", h.Minutes, h.Days)
+	}
+}
+
+// facingCarts are two infrared carts pointed at each other
+type facingCarts struct{ a, b bool }
+
+type irEnd struct {
+	leds  *facingCarts
+	first bool
+}
+
+func (e irEnd) SetLed(on bool) {
+	if e.first {
+		e.leds.a = on
+	} else {
+		e.leds.b = on
+	}
+}
+
+func (e irEnd) Light() bool {
+	if e.first {
+		return e.leds.b
+	}
+	return e.leds.a
+}
+
+func TestInfrared(t *testing.T) {
+	leds := &facingCarts{}
+
+	h1 := &Huc1{Cartridge: newTestCart(4, 1<<13), Ir: irEnd{leds, true}}
+	h3 := &Huc3{Cartridge: newTestCart(4, 1<<13), Ir: irEnd{leds, false}}
+
+	h1.Write(0x0000, 0x0E)
+	h3.Write(0x0000, HUC3_IR)
+
+	if h1.Read(0xA000) != 0xC0 || h3.Read(0xA000) != 0xC0 {
+		t.Fatal("light seen with both leds off")
+	}
+
+	h1.Write(0xA000, 1)
+	if got := h3.Read(0xA000); got != 0xC1 {
+		t.Fatalf("huc3 read %X with the huc1 led on", got)
+	}
+
+	h1.Write(0xA000, 0)
+	h3.Write(0xA000, 1)
+	if got := h1.Read(0xA000); got != 0xC1 {
+		t.Fatalf("huc1 read %X with the huc3 led on", got)
+	}
+}
+
+func TestMbc5Rumble(t *testing.T) {
+	c := newTestCart(4, 1<<13)
+	c.Type = 0x1C
+	m := NewMbc5(c)
+
+	m.Write(0x4000, 0x0B)
+
+	if !m.Rumbling() || m.Bank3 != 3 {
+		t.Fatalf("rumble %t ram bank %d", m.Rumbling(), m.Bank3)
+	}
+}
+
+type flatSensor uint8
+
+func (s flatSensor) Capture() *[CAMERA_H][CAMERA_W]uint8 {
+	var img [CAMERA_H][CAMERA_W]uint8
+	for y := range img {
+		for x := range img[y] {
+			img[y][x] = uint8(s)
+		}
+	}
+	return &img
+}
+
+func TestCameraCapture(t *testing.T) {
+	c := newTestCart(64, 1<<17)
+	m := NewCamera(c)
+
+	capture := func(level uint8) []uint8 {
+		m.Sensor = flatSensor(level)
+
+		m.Write(0x4000, 0x10)
+		m.Write(0xA001, 0x04) // 20 dB
+		m.Write(0xA002, 0x10) // exposure 1
+		m.Write(0xA003, 0x00)
+		for i := range 16 {
+			m.Write(0xA006+uint16(i*3), 0x40)
+			m.Write(0xA007+uint16(i*3), 0x80)
+			m.Write(0xA008+uint16(i*3), 0xC0)
+		}
+		m.Write(0xA000, 0x01)
+
+		if m.Read(0xA000)&1 != 0 {
+			t.Fatal("camera busy after capture")
+		}
+
+		m.Write(0x4000, 0x00)
+		return []uint8{m.Read(0xA100), m.Read(0xA101), m.Read(0xAEFE), m.Read(0xAEFF)}
+	}
+
+	if got := capture(0x00); got[0] != 0xFF || got[1] != 0xFF || got[3] != 0xFF {
+		t.Fatalf("black capture %02X", got)
+	}
+
+	if got := capture(0x60); got[0] != 0x00 || got[1] != 0xFF {
+		t.Fatalf("dark grey capture %02X", got)
+	}
+
+	if got := capture(0xFF); got[0] != 0x00 || got[1] != 0x00 || got[3] != 0x00 {
+		t.Fatalf("white capture %02X", got)
+	}
+}
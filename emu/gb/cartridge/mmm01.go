@@ -0,0 +1,151 @@
+package cartridge
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Mmm01 is a multicart mapper. It boots unmapped, with the menu in the last
+// 32k of the rom. The menu selects a game by writing the outer banks, then
+// locks the mapping by setting bit 6 at 0000, after which the game sees a
+// mbc1 with only the banks of its own rom.
+type Mmm01 struct {
+	Cartridge *Cartridge
+
+	Locked     bool
+	RamEnabled bool
+
+	RomLow, RomMid, RomHigh uint8
+	RamLow, RamHigh         uint8
+
+	// masked bits of RomLow and RamLow are not writable once locked
+	RomBankMask uint8
+	RamBankMask uint8
+
+	Mbc1Mode  bool
+	ModeFixed bool
+
+	RomBase, RomBase2, RamBase uint32
+}
+
+func NewMmm01(c *Cartridge) *Mmm01 {
+
+	fmt.Printf("Cartridge MMM01\n")
+
+	m := &Mmm01{
+		Cartridge: c,
+	}
+
+	m.UpdateAddrs()
+
+	return m
+}
+
+func (m *Mmm01) Read(addr uint16) uint8 {
+	switch {
+	case addr < 0x4000:
+		return m.Cartridge.Data[(m.RomBase|uint32(addr))&m.Cartridge.RomMask]
+	case addr < 0x8000:
+		return m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask]
+	case m.RamEnabled && m.Cartridge.RamData != nil:
+		return m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask]
+	default:
+		return 0xFF
+	}
+}
+
+func (m *Mmm01) ReadPtr(addr uint16) unsafe.Pointer {
+	switch {
+	case addr < 0x4000:
+		return unsafe.Pointer(&m.Cartridge.Data[(m.RomBase|uint32(addr))&m.Cartridge.RomMask])
+	case addr < 0x8000:
+		return unsafe.Pointer(&m.Cartridge.Data[(m.RomBase2|uint32(addr-0x4000))&m.Cartridge.RomMask])
+	default:
+		return nil
+	}
+}
+
+func (m *Mmm01) Write(addr uint16, v uint8) {
+
+	switch {
+	case addr < 0x2000:
+		m.RamEnabled = v&0xF == 0xA
+
+		if !m.Locked {
+			m.RamBankMask = (v >> 4) & 0x3
+			m.Locked = v&0x40 != 0
+		}
+
+	case addr < 0x4000:
+		low := v & 0x1F
+		if m.Locked {
+			mask := m.RomBankMask << 1
+			low = m.RomLow&mask | low&^mask
+		} else {
+			m.RomMid = (v >> 5) & 0x3
+		}
+
+		m.RomLow = low
+
+	case addr < 0x6000:
+		low := v & 0x3
+
+		if m.Locked {
+			low = m.RamLow&m.RamBankMask | low&^m.RamBankMask
+		} else {
+			m.ModeFixed = v&0x40 != 0
+			m.RomHigh = (v >> 4) & 0x3
+			m.RamHigh = (v >> 2) & 0x3
+		}
+
+		m.RamLow = low
+
+	case addr < 0x8000:
+		if !m.Locked {
+			m.RomBankMask = (v >> 2) & 0xF
+		}
+
+		if !m.ModeFixed {
+			m.Mbc1Mode = v&1 != 0
+		}
+
+	case m.RamEnabled && m.Cartridge.RamData != nil:
+		m.Cartridge.RamData[(m.RamBase|uint32(addr-0xA000))&m.Cartridge.RamMask] = v
+		return
+	}
+
+	m.UpdateAddrs()
+}
+
+func (m *Mmm01) UpdateAddrs() {
+
+	if !m.Locked {
+		last := uint32(len(m.Cartridge.Data)) >> 14
+		m.RomBase = (last - 2) << 14
+		m.RomBase2 = (last - 1) << 14
+		m.RamBase = 0
+		return
+	}
+
+	outer := uint32(m.RomHigh)<<7 | uint32(m.RomMid)<<5
+	mask := m.RomBankMask << 1
+
+	low := m.RomLow
+
+	// like mbc1, only the bits the game can write are checked for zero
+	if low&^mask == 0 {
+		low |= 1
+	}
+
+	m.RomBase = (outer | uint32(m.RomLow&mask)) << 14
+	m.RomBase2 = (outer | uint32(low)) << 14
+
+	ramBank := uint32(m.RamHigh)<<2 | uint32(m.RamLow)
+	if !m.Mbc1Mode {
+		ramBank &^= uint32(^m.RamBankMask & 0x3)
+	}
+
+	m.RamBase = ramBank << 13
+}
+
+func (m *Mmm01) Save() {}
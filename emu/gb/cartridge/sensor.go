@@ -0,0 +1,131 @@
+package cartridge
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ImageSensor feeds png files to the camera in place of a webcam. The path
+// is a png, or a directory of pngs which are cycled through a capture at a
+// time. Images are cropped to the sensor shape and scaled to fit.
+type ImageSensor struct {
+	paths  []string
+	images map[string]*[CAMERA_H][CAMERA_W]uint8
+	next   int
+}
+
+func NewImageSensor(path string) *ImageSensor {
+
+	s := &ImageSensor{
+		images: map[string]*[CAMERA_H][CAMERA_W]uint8{},
+	}
+
+	if path == "" {
+		return s
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Printf("Camera image %s could not be opened: %v\n", path, err)
+		return s
+	}
+
+	if !info.IsDir() {
+		s.paths = []string{path}
+		return s
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		fmt.Printf("Camera directory %s could not be read: %v\n", path, err)
+		return s
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".png") {
+			s.paths = append(s.paths, filepath.Join(path, e.Name()))
+		}
+	}
+
+	slices.Sort(s.paths)
+
+	return s
+}
+
+func (s *ImageSensor) Capture() *[CAMERA_H][CAMERA_W]uint8 {
+	if len(s.paths) == 0 {
+		return nil
+	}
+
+	path := s.paths[s.next%len(s.paths)]
+	s.next++
+
+	if img, ok := s.images[path]; ok {
+		return img
+	}
+
+	img, err := loadSensorImage(path)
+	if err != nil {
+		fmt.Printf("Camera image %s could not be decoded: %v\n", path, err)
+	}
+
+	// failed images are cached too, so they are not decoded every frame
+	s.images[path] = img
+
+	return img
+}
+
+func loadSensorImage(path string) (*[CAMERA_H][CAMERA_W]uint8, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	src, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return SensorImage(src), nil
+}
+
+// SensorImage crops the center of src to the sensor shape and scales it
+// down to greyscale
+func SensorImage(src image.Image) *[CAMERA_H][CAMERA_W]uint8 {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// crop the longer side
+	if w*CAMERA_H > h*CAMERA_W {
+		cw := h * CAMERA_W / CAMERA_H
+		b.Min.X += (w - cw) / 2
+		w = cw
+	} else {
+		ch := w * CAMERA_H / CAMERA_W
+		b.Min.Y += (h - ch) / 2
+		h = ch
+	}
+
+	var img [CAMERA_H][CAMERA_W]uint8
+
+	if w == 0 || h == 0 {
+		return &img
+	}
+
+	for y := range CAMERA_H {
+		for x := range CAMERA_W {
+			sx := b.Min.X + x*w/CAMERA_W
+			sy := b.Min.Y + y*h/CAMERA_H
+			img[y][x] = color.GrayModel.Convert(src.At(sx, sy)).(color.Gray).Y
+		}
+	}
+
+	return &img
+}
@@ -0,0 +1,27 @@
+package cartridge
+
+import (
+	"github.com/aabalke/guac/emu/clock"
+	"github.com/aabalke/guac/emu/state"
+)
+
+// RomChecksum identifies the rom in save states, it is computed on first use
+func (c *Cartridge) RomChecksum() uint32 {
+	return c.checksum()
+}
+
+func (c *Cartridge) SaveState(e *state.Encoder) {
+	e.Encode(c, c.Mbc)
+}
+
+func (c *Cartridge) LoadState(d *state.Decoder) {
+	d.Decode(c, c.Mbc)
+
+	// the rtc is paused while not running, it continues from the saved time
+	switch m := c.Mbc.(type) {
+	case *Mbc3:
+		m.last = clock.Now().Unix()
+	case *Huc3:
+		m.last = clock.Now().Unix()
+	}
+}
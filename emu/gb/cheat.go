@@ -0,0 +1,41 @@
+package gb
+
+import (
+	"github.com/aabalke/guac/emu/cheat"
+	"github.com/aabalke/guac/emu/rom"
+)
+
+// cheatBus is the cpu's view of memory for cheats, wider accesses are split
+// into little endian bytes
+type cheatBus struct {
+	gb *GameBoy
+}
+
+func (b cheatBus) Read8(addr uint32) uint8 { return b.gb.Read(uint16(addr)) }
+func (b cheatBus) Read16(addr uint32) uint16 {
+	return uint16(b.Read8(addr)) | uint16(b.Read8(addr+1))<<8
+}
+func (b cheatBus) Read32(addr uint32) uint32 {
+	return uint32(b.Read16(addr)) | uint32(b.Read16(addr+2))<<16
+}
+
+func (b cheatBus) Write8(addr uint32, v uint8) { b.gb.Write(uint16(addr), v) }
+func (b cheatBus) Write16(addr uint32, v uint16) {
+	b.Write8(addr, uint8(v))
+	b.Write8(addr+1, uint8(v>>8))
+}
+func (b cheatBus) Write32(addr uint32, v uint32) {
+	b.Write16(addr, uint16(v))
+	b.Write16(addr+2, uint16(v>>16))
+}
+
+func (gb *GameBoy) newCheats() *cheat.Engine {
+	return cheat.NewEngine(rom.GB, cheatBus{gb}, gb.Cartridge.Data)
+}
+
+// SetCheats replaces the cheats in use. The rom checksum is taken first so
+// game genie patches do not change which save states fit the rom.
+func (gb *GameBoy) SetCheats(cheats []cheat.Cheat) error {
+	gb.Cartridge.RomChecksum()
+	return gb.Cheats.Set(cheats)
+}
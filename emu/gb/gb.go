@@ -1,12 +1,21 @@
 package gb
 
 import (
+	"image"
 	"image/color"
 	"unsafe"
 
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/capture"
+	"github.com/aabalke/guac/emu/cheat"
+	"github.com/aabalke/guac/emu/clock"
 	"github.com/aabalke/guac/emu/gb/apu"
 	"github.com/aabalke/guac/emu/gb/cartridge"
+	"github.com/aabalke/guac/emu/link"
+	"github.com/aabalke/guac/emu/movie"
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
+	"github.com/aabalke/guac/input"
 	"github.com/aabalke/guac/utils"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/oto"
@@ -27,7 +36,7 @@ type GameBoy struct {
 	// Palette [][]uint8
 	Palette *[4]color.Color
 
-	DrawOptions ebiten.DrawImageOptions
+	DrawOptions ebiten.DrawImageOptions `state:"-"`
 
 	Color     bool
 	bgPalette ColorPalette
@@ -54,15 +63,27 @@ type GameBoy struct {
 
 	Joypad uint8
 
+	buttons input.Buttons `state:"-"` // held, for movies
+
 	Image      *ebiten.Image
-	Pixels     []byte
+	Pixels     []byte `state:"-"` // aliases Screen
 	Screen     [height][width]uint32
 	spMinx     [width]int32
 	bgPriority [height][width]bool
 	pixelDrawn [width]bool
 
-	Paused bool
-	Muted  bool
+	Frame uint64
+
+	Paused    bool `state:"-"`
+	Muted     bool `state:"-"`
+	Rewinding bool `state:"-"`
+
+	Rewind     *state.Rewind
+	Cheats     *cheat.Engine
+	movie.Deck `state:"-"`
+
+	PatchErr error // the rom's patch failed, it runs unpatched
+	Capture  *capture.Recorder
 
 	Apu *apu.Apu
 
@@ -92,15 +113,22 @@ const (
 func NewGameBoy(path string, ctx *oto.Context) *GameBoy {
 	img := ebiten.NewImage(width, height)
 
+	game, err := rom.Load(path, config.Conf.General.Patches)
+	if err != nil {
+		panic(err)
+	}
+
 	gb := &GameBoy{
 		Image:     img,
 		Cpu:       NewCpu(),
 		Clock:     CPU_SPEED, // t cycle count
 		Joypad:    0xFF,
-		Cartridge: cartridge.NewCartridge(path, path+".save"),
+		Cartridge: cartridge.NewCartridge(game),
 		Palette:   &config.Conf.Gb.Palette,
 		Scheduler: NewScheduler(),
 		Apu:       apu.NewApu(ctx, CPU_SPEED, SND_FREQ, SND_SAMPLES),
+		Rewind:    newRewind(),
+		PatchErr:  game.PatchErr,
 	}
 
 	// ebiten engine requires a slice, Screen is easier to edit as an array of arrays
@@ -125,6 +153,25 @@ func NewGameBoy(path string, ctx *oto.Context) *GameBoy {
 
 	initMemory(gb)
 
+	gb.Cheats = gb.newCheats()
+	gb.Deck = movie.NewDeck(gb, state.GB, gb.Cartridge.RomChecksum)
+
+	gb.MemoryBus.Serial.Port = newSerialDevice(gb.Cartridge.Title)
+
+	if camera, ok := gb.Cartridge.Mbc.(*cartridge.Camera); ok {
+		camera.Sensor = cartridge.NewImageSensor(config.Conf.Gb.Camera.Path)
+	}
+
+	// infrared carts see each other over the link cable
+	if port, ok := gb.MemoryBus.Serial.Port.(*link.Port); ok {
+		switch m := gb.Cartridge.Mbc.(type) {
+		case *cartridge.Huc1:
+			m.Ir = port
+		case *cartridge.Huc3:
+			m.Ir = port
+		}
+	}
+
 	if config.Conf.General.Logger {
 		L = NewLogger("./loggy", gb)
 	}
@@ -162,6 +209,11 @@ func (gb *GameBoy) GetPixels() []byte {
 	return gb.Pixels
 }
 
+// Screenshot returns a copy of the current frame
+func (gb *GameBoy) Screenshot() *image.RGBA {
+	return utils.NewScreenshot(width, height, gb.Pixels)
+}
+
 const (
 	CYCLES_PER_FRAME        = 70224
 	CYCLES_PER_END_SCANLINE = CYCLES_PER_FRAME / 154
@@ -175,6 +227,16 @@ func (gb *GameBoy) Update(stdFps bool) {
 		return
 	}
 
+	// rewinding would put the run out of step with a movie
+	if gb.Rewinding && gb.Rewind != nil && gb.Movie == nil {
+		gb.rewindStep()
+		return
+	}
+
+	clock.Advance(FRAME_TIME)
+	gb.StepMovie()
+	gb.Cheats.Apply()
+
 	gb.Scheduler.schedule(EVENT_END_FRAME, CYCLES_PER_FRAME)
 	gb.Scheduler.schedule(EVENT_END_SCANLINE, CYCLES_PER_END_SCANLINE)
 	gb.Scheduler.schedule(EVENT_VBK, CYCLES_PER_VBLANK)
@@ -200,6 +262,14 @@ func (gb *GameBoy) Update(stdFps bool) {
 		}
 
 		if done := gb.handleEvent(nextEvent, stdFps); done {
+			gb.Frame++
+			if config.Conf.General.Deterministic && gb.Frame%SAVE_FRAMES == 0 {
+				gb.SaveRam()
+			}
+
+			gb.captureFrame()
+
+			gb.captureRewind()
 			return
 		}
 	}
@@ -317,6 +387,12 @@ func (gb *GameBoy) handleEvent(event ScheduledEvent, stdFps bool) bool {
 		}
 		gb.scheduleWaveClock(event.InitCycle)
 
+	case EVENT_SERIAL:
+		gb.MemoryBus.Serial.finish(gb)
+
+	case EVENT_SERIAL_POLL:
+		gb.MemoryBus.Serial.poll(gb)
+
 	case EVENT_SND_FRAME_SEQ:
 		// I believe this is based on div, and will need to be reset based on div falling edge
 		// see polling version, but confirm
@@ -471,10 +547,19 @@ func (gb *GameBoy) Close() {
 	gb.Muted = true
 	gb.Paused = true
 	gb.Apu.Close()
+	gb.StopCapture()
+
+	if config.Conf.General.Deterministic {
+		gb.SaveRam()
+	}
 
 	if L != nil {
 		L.Close()
 	}
+
+	if p := gb.MemoryBus.Serial.Port; p != nil {
+		p.Close()
+	}
 }
 
 func (gb *GameBoy) Draw(screen *ebiten.Image) {
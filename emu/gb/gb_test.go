@@ -2,44 +2,39 @@ package gb
 
 import (
 	"fmt"
-	"io/fs"
-	"os"
-	"path/filepath"
-	"slices"
-	"strings"
 	"testing"
-	"time"
 
 	"github.com/aabalke/guac/config"
 	"github.com/aabalke/guac/config/file"
+	"github.com/aabalke/guac/emu/testroms"
+	"github.com/aabalke/guac/input"
 )
 
-type TestResult struct {
-	Name   string
-	Passed bool
+var GB_ROMS = []string{".gb", ".gbc"}
+
+func newTestGameBoy(t *testing.T, rom string) *GameBoy {
+	file.Decode()
+	config.Conf.General.Headless = true
+	config.Conf.General.RewindBufferSize = 0
+
+	gb := NewGameBoy(rom, nil)
+	t.Cleanup(gb.Close)
+
+	return gb
 }
 
 func TestMooneye(t *testing.T) {
 	// https://github.com/Gekkio/mooneye-test-suite
-	directory := `C:\dev\repos\emulators\roms\gb_test_roms\game-boy-test-roms-v7.0\mooneye-test-suite`
-	t.Logf("Mooneye Test Suite %s\n", time.Now().Format(time.RFC3339))
-
-	perRomHandler := func(file string, results *[]TestResult) {
+	testroms.Run(t, "Mooneye Test Suite", "mooneye-test-suite", GB_ROMS, func(t *testing.T, rom string) testroms.Result {
 		finish := false
 		passed := false
 
-		gb := NewGameBoy(file, nil)
+		gb := newTestGameBoy(t, rom)
 
 		gb.InstInjectionFunc = func(gb *GameBoy, op uint8) {
-			if op == 0x40 {
-				passed =
-					gb.Cpu.b == 3 &&
-						gb.Cpu.c == 5 &&
-						gb.Cpu.d == 8 &&
-						gb.Cpu.e == 13 &&
-						gb.Cpu.h == 21 &&
-						gb.Cpu.l == 34
-
+			if op == testroms.MOONEYE_DONE {
+				c := gb.Cpu
+				passed = testroms.Mooneye(c.b, c.c, c.d, c.e, c.h, c.l)
 				finish = true
 			}
 		}
@@ -52,126 +47,88 @@ func TestMooneye(t *testing.T) {
 			}
 		}
 
-		gb.Close()
-
-		p, _ := filepath.Rel(directory, file)
-
-		name := strings.TrimSuffix(p, ".gb")
-
-		*results = append(*results, TestResult{
-			Name:   name,
-			Passed: passed,
-		})
-
-		if !passed {
-			t.Errorf("Failed %s", file)
+		if !finish {
+			return testroms.Result{Detail: "timed out"}
 		}
-	}
 
-	testDirOfRoms("Mooneye Acceptance Tests", "testing_mooneye.md", directory, perRomHandler, t)
+		return testroms.Result{Passed: passed}
+	})
 }
 
 func TestGbMicroTest(t *testing.T) {
 	// https://github.com/aappleby/GBMicrotest
-	directory := `C:\dev\repos\emulators\roms\gb_test_roms\game-boy-test-roms-v7.0\gbmicrotest`
-	t.Logf("GBMicrotest Test Suite %s\n", time.Now().Format(time.RFC3339))
-
-	perRomHandler := func(file string, results *[]TestResult) {
-		gb := NewGameBoy(file, nil)
+	testroms.Run(t, "GBMicrotest Test Suite", "gbmicrotest", GB_ROMS, func(t *testing.T, rom string) testroms.Result {
+		gb := newTestGameBoy(t, rom)
 
 		for range 60 * 2 {
 			gb.Update(false)
 		}
 
-		passed := gb.Read(0xFF82) == 0x1
-
-		gb.Close()
-
-		p, _ := filepath.Rel(directory, file)
-
-		name := strings.TrimSuffix(p, ".gb")
-
-		*results = append(*results, TestResult{
-			Name:   name,
-			Passed: passed,
-		})
-
-		if !passed {
-			t.Errorf("Failed %s", file)
+		return testroms.Result{
+			Passed: gb.Read(0xFF82) == 0x1,
+			Detail: fmt.Sprintf("result %02X, expected %02X", gb.Read(0xFF80), gb.Read(0xFF81)),
 		}
-	}
-
-	testDirOfRoms("GBMicrotest Tests", "testing_gbmicrotest.md", directory, perRomHandler, t)
+	})
 }
 
-func testDirOfRoms(title, outputFile, directory string, perRomHandler func(string, *[]TestResult), t *testing.T) {
-	file.Decode()
-
-	config.Conf.General.Headless = true
-
-	files, err := FindFiles(directory, ".gb")
-	if err != nil {
-		panic(err)
-	}
+func TestBlargg(t *testing.T) {
+	// https://github.com/retrio/gb-test-roms
+	testroms.Run(t, "Blargg Test Suite", "blargg", GB_ROMS, func(t *testing.T, rom string) testroms.Result {
+		gb := newTestGameBoy(t, rom)
 
-	var results []TestResult
-
-	for _, file := range files {
-		perRomHandler(file, &results)
-	}
-
-	err = WriteMarkdown(title, results, outputFile)
-	if err != nil {
-		t.Fatal(err)
-	}
-}
+		serial := testroms.Serial{}
 
-func FindFiles(root, ext string) ([]string, error) {
-	var files []string
-
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+		// older tests only print through the link port, complete every
+		// transfer they start so output is never blocked
+		gb.InstInjectionFunc = func(gb *GameBoy, op uint8) {
+			s := &gb.MemoryBus.Serial
+			if s.Enabled && s.IsMaster {
+				serial.Write(s.Sb)
+				s.Sb = 0xFF
+				s.Enabled = false
+				gb.Scheduler.cancel(EVENT_SERIAL)
+				gb.SetIrq(IRQ_SER)
+			}
 		}
 
-		if d.IsDir() {
-			return nil
-		}
+		for range 60 * 60 {
+			gb.Update(false)
+
+			if done, passed := serial.Done(); done {
+				return testroms.Result{Passed: passed, Detail: serial.Text()}
+			}
 
-		if filepath.Ext(path) == ext {
-			files = append(files, path)
+			if done, passed, text := testroms.Blargg(gb.Read); done {
+				return testroms.Result{Passed: passed, Detail: text}
+			}
 		}
 
-		return nil
+		return testroms.Result{Detail: "timed out " + serial.Text()}
 	})
-
-	return files, err
 }
 
-func WriteMarkdown(header string, results []TestResult, filename string) error {
-	passed, total := 0, len(results)
-	for _, r := range results {
-		if r.Passed {
-			passed++
+// menu driven or screen only roms, compared against a reference screenshot
+func TestScreens(t *testing.T) {
+	testroms.Run(t, "GB Screen Tests", "gb-screens", GB_ROMS, func(t *testing.T, rom string) testroms.Result {
+		s, err := testroms.LoadSidecar(rom, 60*10)
+		if err != nil {
+			t.Fatal(err)
 		}
-	}
 
-	var b strings.Builder
-	fmt.Fprintf(&b, "# %s\n\n", header)
-	fmt.Fprintf(&b, "Results generated %s\n\n", time.Now().Format(time.RFC3339))
-	fmt.Fprintf(&b, "Passing %d/%d %02d%%\n\n", passed, total, (passed*100)/total)
+		gb := newTestGameBoy(t, rom)
 
-	slices.SortFunc(results, func(a, b TestResult) int {
-		return strings.Compare(a.Name, b.Name)
-	})
+		for frame := 1; frame <= s.Frames; frame++ {
+			var b input.Buttons
+			if held := s.Buttons(frame); held != "" {
+				if b, err = input.ParseButtons(held); err != nil {
+					t.Fatal(err)
+				}
+			}
 
-	for _, r := range results {
-		if r.Passed {
-			fmt.Fprintf(&b, "👍 %s\n", r.Name)
-		} else {
-			fmt.Fprintf(&b, "❌ %s\n", r.Name)
+			gb.SetButtons(b)
+			gb.Update(false)
 		}
-	}
 
-	return os.WriteFile(filename, []byte(b.String()), 0o644)
+		return testroms.Reference(t, rom, gb.Screenshot())
+	})
 }
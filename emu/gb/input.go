@@ -1,64 +1,69 @@
 package gb
 
 import (
-	"slices"
+	"math"
 
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/gb/cartridge"
+	"github.com/aabalke/guac/input"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-func (gb *GameBoy) InputHandler(keys []ebiten.Key, buttons []ebiten.StandardGamepadButton) {
-	var (
-		keyConfig    = config.Conf.Gb.KeyboardConfig
-		buttonConfig = config.Conf.Gb.ControllerConfig
-		k            = &gb.Joypad
-	)
+// stick travel ignored by the accelerometer
+const TILT_DEADZONE = 0.1
 
-	*k = 0xFF
+func (gb *GameBoy) InputHandler(keys []ebiten.Key, buttons []ebiten.StandardGamepadButton, mouse *input.Mouse) {
+	gb.SetButtons(input.Held(keys, buttons,
+		&config.Conf.Gb.KeyboardConfig,
+		&config.Conf.Gb.ControllerConfig,
+	))
 
-	for _, key := range keys {
-		switch {
-		case slices.Contains(keyConfig.A, key):
-			*k &^= 1 << 4
-		case slices.Contains(keyConfig.B, key):
-			*k &^= 1 << 5
-		case slices.Contains(keyConfig.Select, key):
-			*k &^= 1 << 6
-		case slices.Contains(keyConfig.Start, key):
-			*k &^= 1 << 7
-		case slices.Contains(keyConfig.Right, key):
-			*k &^= 1 << 0
-		case slices.Contains(keyConfig.Left, key):
-			*k &^= 1 << 1
-		case slices.Contains(keyConfig.Up, key):
-			*k &^= 1 << 2
-		case slices.Contains(keyConfig.Down, key):
-			*k &^= 1 << 3
+	gb.cartInput(mouse)
+}
+
+// cartInput feeds the left stick, or the mouse dragged from the center of
+// the window, into the mbc7 accelerometer and the rumble back out to the
+// gamepads
+func (gb *GameBoy) cartInput(mouse *input.Mouse) {
+	ids := ebiten.AppendGamepadIDs(nil)
+
+	if m, ok := gb.Cartridge.Mbc.(*cartridge.Mbc7); ok {
+		m.Tilt = [2]float64{}
+
+		if mouse != nil && mouse.Dragged {
+			w, h := ebiten.WindowSize()
+			if w > 0 && h > 0 {
+				m.Tilt[0] = float64(mouse.X-w/2) / float64(w/2)
+				m.Tilt[1] = float64(mouse.Y-h/2) / float64(h/2)
+			}
 		}
-	}
 
-	for _, button := range buttons {
-		switch {
-		case slices.Contains(buttonConfig.A, button):
-			*k &^= 1 << 4
-		case slices.Contains(buttonConfig.B, button):
-			*k &^= 1 << 5
-		case slices.Contains(buttonConfig.Select, button):
-			*k &^= 1 << 6
-		case slices.Contains(buttonConfig.Start, button):
-			*k &^= 1 << 7
-		case slices.Contains(buttonConfig.Right, button):
-			*k &^= 1 << 0
-		case slices.Contains(buttonConfig.Left, button):
-			*k &^= 1 << 1
-		case slices.Contains(buttonConfig.Up, button):
-			*k &^= 1 << 2
-		case slices.Contains(buttonConfig.Down, button):
-			*k &^= 1 << 3
+		for _, id := range ids {
+			x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+			y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+			if math.Abs(x) < TILT_DEADZONE && math.Abs(y) < TILT_DEADZONE {
+				continue
+			}
+
+			m.Tilt = [2]float64{x, y}
+			break
 		}
 	}
 
-	if *k != 0xFF {
+	if r, ok := gb.Cartridge.Mbc.(cartridge.Rumbler); ok && r.Rumbling() {
+		input.Rumble()
+	}
+}
+
+// SetButtons sets the buttons held for the next frame, R, L, X and Y are
+// ignored
+func (gb *GameBoy) SetButtons(b input.Buttons) {
+	gb.buttons = b
+
+	// high nibble is a, b, select, start. low nibble is the dpad
+	gb.Joypad = ^(uint8(b&0xF)<<4 | uint8(b>>4)&0xF)
+
+	if gb.Joypad != 0xFF {
 		gb.SetIrq(IRQ_JPD)
 	}
 }
@@ -215,14 +215,25 @@ func (gb *GameBoy) SaveRam() {
 		return
 	}
 
-	if !gb.MemoryBus.ramSaved {
+	if !gb.MemoryBus.ramSaved || gb.Cartridge.Dirty {
 		cartridge.WriteRam(gb.Cartridge.SavPath, gb.Cartridge.RamData)
 		gb.Cartridge.Mbc.Save()
 		gb.MemoryBus.ramSaved = true
+		gb.Cartridge.Dirty = false
 	}
 }
 
+// SAVE_FRAMES is how often deterministic runs save, about the second of the
+// save loop
+const SAVE_FRAMES = 60
+
+// InitSaveLoop writes the save every second. Deterministic runs save between
+// frames instead, so saves never race the emulation.
 func (gb *GameBoy) InitSaveLoop() {
+	if config.Conf.General.Deterministic {
+		return
+	}
+
 	saveTicker := time.Tick(time.Second)
 
 	go func() {
@@ -392,7 +403,7 @@ func (gb *GameBoy) ReadIO(addr uint16) uint8 {
 		return gb.MemoryBus.Serial.ReadSb()
 
 	case 0xFF02:
-		return gb.MemoryBus.Serial.ReadSc()
+		return gb.MemoryBus.Serial.ReadSc(gb)
 
 	case 0xFF03:
 		return 0xFF
@@ -488,7 +499,7 @@ func (gb *GameBoy) WriteIO(addr uint16, v uint8) {
 		gb.MemoryBus.Serial.WriteSb(v)
 
 	case 0xFF02:
-		gb.MemoryBus.Serial.WriteSc(v)
+		gb.MemoryBus.Serial.WriteSc(gb, v)
 
 	case 0xFF04: // DIV
 
@@ -0,0 +1,31 @@
+package gb
+
+import (
+	"time"
+
+	"github.com/aabalke/guac/emu/gb/cartridge"
+	"github.com/aabalke/guac/emu/movie"
+	"github.com/aabalke/guac/input"
+)
+
+// FRAME_TIME is the emulated time of a frame, a seeded clock moves by it
+const FRAME_TIME = time.Second * CYCLES_PER_FRAME / 4194304
+
+// Input is what is held for the next frame, the mbc7 tilt included
+func (gb *GameBoy) Input() movie.Frame {
+	f := movie.Frame{Buttons: uint16(gb.buttons)}
+
+	if m, ok := gb.Cartridge.Mbc.(*cartridge.Mbc7); ok {
+		f.Tilt = m.Tilt
+	}
+
+	return f
+}
+
+func (gb *GameBoy) SetInput(f movie.Frame) {
+	gb.SetButtons(input.Buttons(f.Buttons))
+
+	if m, ok := gb.Cartridge.Mbc.(*cartridge.Mbc7); ok {
+		m.Tilt = f.Tilt
+	}
+}
@@ -0,0 +1,384 @@
+// printer emulates the Game Boy Printer on the other end of the link cable.
+//
+// The game clocks every byte of a packet and the printer shifts its reply
+// back at the same time, so the reply to a byte is decided by the bytes
+// before it. A packet is
+//
+//	0x88 0x33 cmd compression len_lo len_hi data... sum_lo sum_hi 0x00 0x00
+//
+// and the printer replies 0x81 during the first trailing zero and its status
+// during the second. Printed pages are written to Dir as png.
+package printer
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	MAGIC_0 = 0x88
+	MAGIC_1 = 0x33
+
+	// reply during the first trailing zero of a packet
+	ALIVE = 0x81
+)
+
+const (
+	CMD_INIT   = 0x01
+	CMD_PRINT  = 0x02
+	CMD_DATA   = 0x04
+	CMD_STATUS = 0x0F
+)
+
+const (
+	STATUS_CHECKSUM    = 1 << 0
+	STATUS_BUSY        = 1 << 1
+	STATUS_FULL        = 1 << 2
+	STATUS_UNPROCESSED = 1 << 3
+	STATUS_PACKET      = 1 << 4
+	STATUS_JAM         = 1 << 5
+	STATUS_OTHER       = 1 << 6
+	STATUS_BATTERY     = 1 << 7
+)
+
+const (
+	WIDTH       = 160
+	TILES_WIDTH = WIDTH / 8
+	TILE_SIZE   = 16
+
+	// a data packet is two rows of tiles, the buffer holds nine of them
+	DATA_SIZE   = TILES_WIDTH * TILE_SIZE * 2
+	BUFFER_SIZE = DATA_SIZE * 9
+
+	// status packets answered busy after printing
+	BUSY_STATUS = 4
+
+	DEFAULT_PALETTE  = 0xE4
+	DEFAULT_EXPOSURE = 0x40
+)
+
+// parse states, in packet order
+const (
+	STATE_MAGIC_0 = iota
+	STATE_MAGIC_1
+	STATE_CMD
+	STATE_COMPRESSION
+	STATE_LEN_LO
+	STATE_LEN_HI
+	STATE_DATA
+	STATE_SUM_LO
+	STATE_SUM_HI
+	STATE_ALIVE
+	STATE_STATUS
+)
+
+// shades of the 4 printer colors, white to black
+var SHADES = [4]int{0xFF, 0xAA, 0x55, 0x00}
+
+type Printer struct {
+	Dir  string
+	Name string // file name prefix
+
+	state      int
+	cmd        uint8
+	compressed bool
+	length     uint16
+	sum        uint16
+	check      uint16
+	data       []byte
+
+	out, reply uint8
+
+	Status uint8
+	busy   int
+
+	buffer []byte // tile data since init
+	page   []byte // gray pixels printed since the last margin
+	pages  int
+}
+
+func New(dir, name string) *Printer {
+	return &Printer{
+		Dir:  dir,
+		Name: name,
+	}
+}
+
+// Transfer takes a byte clocked in by the game
+func (p *Printer) Transfer(v uint32) {
+	p.reply = p.out
+	p.out = 0
+	p.receive(uint8(v))
+}
+
+// Reply returns the byte shifted out during the last Transfer, the printer
+// always has it ready
+func (p *Printer) Reply() (uint32, bool) {
+	return uint32(p.reply), true
+}
+
+// the printer never drives the clock
+func (p *Printer) Ready(bool) {}
+
+func (p *Printer) Clocked(uint32) (uint32, bool) {
+	return 0, false
+}
+
+// Close writes a page which was never ended by a margin
+func (p *Printer) Close() error {
+	return p.flush()
+}
+
+func (p *Printer) receive(v uint8) {
+	switch p.state {
+	case STATE_MAGIC_0:
+		if v == MAGIC_0 {
+			p.state = STATE_MAGIC_1
+		}
+		return
+
+	case STATE_MAGIC_1:
+		if v != MAGIC_1 {
+			p.state = STATE_MAGIC_0
+			return
+		}
+
+		p.sum = 0
+		p.data = p.data[:0]
+
+	case STATE_CMD:
+		p.cmd = v
+		p.sum += uint16(v)
+
+	case STATE_COMPRESSION:
+		p.compressed = v&1 != 0
+		p.sum += uint16(v)
+
+	case STATE_LEN_LO:
+		p.length = uint16(v)
+		p.sum += uint16(v)
+
+	case STATE_LEN_HI:
+		p.length |= uint16(v) << 8
+		p.sum += uint16(v)
+
+		if p.length == 0 {
+			p.state = STATE_SUM_LO
+			return
+		}
+
+	case STATE_DATA:
+		p.data = append(p.data, v)
+		p.sum += uint16(v)
+
+		if len(p.data) < int(p.length) {
+			return
+		}
+
+	case STATE_SUM_LO:
+		p.check = uint16(v)
+
+	case STATE_SUM_HI:
+		p.check |= uint16(v) << 8
+		p.out = ALIVE
+
+	case STATE_ALIVE:
+		p.command()
+		p.out = p.Status
+
+	case STATE_STATUS:
+		p.state = STATE_MAGIC_0
+		return
+	}
+
+	p.state++
+}
+
+func (p *Printer) command() {
+	if p.check != p.sum {
+		p.Status |= STATUS_CHECKSUM
+		return
+	}
+
+	p.Status &^= STATUS_CHECKSUM | STATUS_PACKET
+
+	switch p.cmd {
+	case CMD_INIT:
+		p.buffer = p.buffer[:0]
+		p.Status = 0
+		p.busy = 0
+
+	case CMD_DATA:
+		// an empty data packet ends the image
+		if len(p.data) == 0 {
+			return
+		}
+
+		data := p.data
+		if p.compressed {
+			data = decompress(data)
+		}
+
+		p.buffer = append(p.buffer, data[:min(len(data), BUFFER_SIZE-len(p.buffer))]...)
+		p.Status |= STATUS_UNPROCESSED
+
+		if len(p.buffer) >= BUFFER_SIZE {
+			p.Status |= STATUS_FULL
+		}
+
+	case CMD_PRINT:
+		if len(p.data) < 4 {
+			p.Status |= STATUS_PACKET
+			return
+		}
+
+		p.print(p.data[0], p.data[1], p.data[2], p.data[3])
+
+		p.buffer = p.buffer[:0]
+		p.Status &^= STATUS_UNPROCESSED | STATUS_FULL
+		p.Status |= STATUS_BUSY
+		p.busy = BUSY_STATUS
+
+	case CMD_STATUS:
+		if p.busy > 0 {
+			p.busy--
+			if p.busy == 0 {
+				p.Status &^= STATUS_BUSY
+			}
+		}
+
+	default:
+		p.Status |= STATUS_PACKET
+	}
+}
+
+// decompress expands run length data. A control byte with the top bit set
+// repeats the next byte (n & 0x7F) + 2 times, otherwise n + 1 bytes follow.
+func decompress(src []byte) []byte {
+	var dst []byte
+
+	for i := 0; i < len(src); {
+		n := src[i]
+		i++
+
+		if n&0x80 != 0 {
+			if i >= len(src) {
+				break
+			}
+
+			for range int(n&0x7F) + 2 {
+				dst = append(dst, src[i])
+			}
+
+			i++
+			continue
+		}
+
+		end := min(len(src), i+int(n)+1)
+		dst = append(dst, src[i:end]...)
+		i = end
+	}
+
+	return dst
+}
+
+// print renders the buffer onto the page. The high nibble of margins feeds
+// paper before printing and the low nibble after, a margin ends the page.
+func (p *Printer) print(sheets, margins, palette, exposure uint8) {
+	if margins>>4 != 0 {
+		if err := p.flush(); err != nil {
+			log.Printf("Printer: %v\n", err)
+		}
+	}
+
+	if palette == 0 {
+		palette = DEFAULT_PALETTE
+	}
+
+	// 0x40 prints as is, lower is lighter and higher darker
+	darken := (int(exposure&0x7F) - DEFAULT_EXPOSURE) * 0x40 / DEFAULT_EXPOSURE
+
+	rows := len(p.buffer) / (TILES_WIDTH * TILE_SIZE)
+
+	for range sheets {
+		for y := range rows * 8 {
+			for x := range WIDTH {
+				tile := p.buffer[((y/8)*TILES_WIDTH+x/8)*TILE_SIZE:]
+				lo := tile[(y%8)*2] >> (7 - x%8) & 1
+				hi := tile[(y%8)*2+1] >> (7 - x%8) & 1
+
+				shade := SHADES[palette>>((hi<<1|lo)*2)&3]
+				p.page = append(p.page, uint8(min(0xFF, max(0, shade-darken))))
+			}
+		}
+	}
+
+	if margins&0xF != 0 {
+		if err := p.flush(); err != nil {
+			log.Printf("Printer: %v\n", err)
+		}
+	}
+}
+
+// Image returns the page printed so far
+func (p *Printer) Image() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, WIDTH, len(p.page)/WIDTH))
+	copy(img.Pix, p.page)
+	return img
+}
+
+// flush writes the page as a png and starts a new one
+func (p *Printer) flush() error {
+	if len(p.page) == 0 {
+		return nil
+	}
+
+	img := p.Image()
+	p.page = p.page[:0]
+	p.pages++
+
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s_%d.png", fileName(p.Name), time.Now().Format("20060102_150405"), p.pages)
+
+	f, err := os.Create(filepath.Join(p.Dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		return err
+	}
+
+	log.Printf("Printer: printed %s\n", name)
+
+	return nil
+}
+
+// fileName keeps the letters and digits of a rom title
+func fileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == ' ', r == '-', r == '_':
+			return '_'
+		}
+		return -1
+	}, title)
+
+	if name == "" {
+		return "print"
+	}
+
+	return name
+}
@@ -0,0 +1,123 @@
+package printer
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// packet clocks a packet through the printer and returns the replies to the
+// trailing zeros
+func packet(p *Printer, cmd uint8, compressed bool, data []byte) (alive, status uint8) {
+	var comp uint8
+	if compressed {
+		comp = 1
+	}
+
+	b := []byte{MAGIC_0, MAGIC_1, cmd, comp, uint8(len(data)), uint8(len(data) >> 8)}
+	b = append(b, data...)
+
+	var sum uint16
+	for _, v := range b[2:] {
+		sum += uint16(v)
+	}
+
+	b = append(b, uint8(sum), uint8(sum>>8))
+
+	for _, v := range b {
+		p.Transfer(uint32(v))
+	}
+
+	p.Transfer(0)
+	v, _ := p.Reply()
+	alive = uint8(v)
+	p.Transfer(0)
+	v, _ = p.Reply()
+	status = uint8(v)
+
+	return alive, status
+}
+
+func TestDecompress(t *testing.T) {
+	got := decompress([]byte{0x81, 0xAA, 0x01, 0x01, 0x02})
+	want := []byte{0xAA, 0xAA, 0xAA, 0x01, 0x02}
+
+	if string(got) != string(want) {
+		t.Fatalf("decompressed %X, expected %X", got, want)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	dir := t.TempDir()
+	p := New(dir, "POKEMON YELLOW")
+
+	if alive, status := packet(p, CMD_INIT, false, nil); alive != ALIVE || status != 0 {
+		t.Fatalf("init replied %02X %02X", alive, status)
+	}
+
+	// two rows of tiles, every pixel color 3, compressed into runs of 0x80
+	var data []byte
+	for range DATA_SIZE / 0x80 {
+		data = append(data, 0xFE, 0xFF)
+	}
+
+	if _, status := packet(p, CMD_DATA, true, data); status != STATUS_UNPROCESSED {
+		t.Fatalf("data status %02X", status)
+	}
+
+	packet(p, CMD_DATA, false, nil)
+
+	if _, status := packet(p, CMD_PRINT, false, []byte{1, 0x03, DEFAULT_PALETTE, DEFAULT_EXPOSURE}); status&STATUS_BUSY == 0 {
+		t.Fatalf("print status %02X", status)
+	}
+
+	for range BUSY_STATUS {
+		packet(p, CMD_STATUS, false, nil)
+	}
+
+	if p.Status != 0 {
+		t.Fatalf("status %02X after printing", p.Status)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "POKEMON_YELLOW_*.png"))
+	if len(files) != 1 {
+		t.Fatalf("printed %v", files)
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b.Dx() != WIDTH || b.Dy() != 16 {
+		t.Fatalf("printed %v", b)
+	}
+
+	if r, _, _, _ := img.At(10, 10).RGBA(); r != 0 {
+		t.Fatalf("color 3 printed as %X", r)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	p := New(t.TempDir(), "")
+
+	for _, v := range []byte{MAGIC_0, MAGIC_1, CMD_INIT, 0, 0, 0, 0xFF, 0xFF, 0} {
+		p.Transfer(uint32(v))
+	}
+
+	if v, _ := p.Reply(); v != ALIVE {
+		t.Fatalf("alive reply %02X", v)
+	}
+
+	p.Transfer(0)
+	if v, _ := p.Reply(); v&STATUS_CHECKSUM == 0 {
+		t.Fatalf("bad checksum status %02X", v)
+	}
+}
@@ -11,6 +11,8 @@ const (
 	EVENT_SND_SAMPLE_GEN
 	EVENT_SND_WAVE_CLOCK
 	EVENT_SND_FRAME_SEQ
+	EVENT_SERIAL
+	EVENT_SERIAL_POLL
 )
 
 type Scheduler struct {
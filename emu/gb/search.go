@@ -0,0 +1,41 @@
+package gb
+
+import (
+	"fmt"
+
+	"github.com/aabalke/guac/emu/search"
+)
+
+// RamRegions are wram, hram and cartridge ram, one region a bank as banks
+// share their addresses
+func (gb *GameBoy) RamRegions() []search.Region {
+	m := &gb.MemoryBus
+
+	regions := []search.Region{{Name: "wram0", Base: 0xC000, Data: m.WRAM[0][:]}}
+
+	banks := 1
+	if gb.Color {
+		banks = 7
+	}
+
+	for i := 1; i <= banks; i++ {
+		regions = append(regions, search.Region{
+			Name: fmt.Sprintf("wram%d", i),
+			Base: 0xD000,
+			Data: m.WRAM[i][:],
+		})
+	}
+
+	regions = append(regions, search.Region{Name: "hram", Base: 0xFF80, Data: m.HRAM[:]})
+
+	ram := gb.Cartridge.RamData
+	for i := 0; i < len(ram); i += 0x2000 {
+		regions = append(regions, search.Region{
+			Name: fmt.Sprintf("sram%d", i/0x2000),
+			Base: 0xA000,
+			Data: ram[i:min(i+0x2000, len(ram))],
+		})
+	}
+
+	return regions
+}
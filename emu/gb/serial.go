@@ -1,24 +1,93 @@
 package gb
 
+import (
+	"log"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/gb/printer"
+	"github.com/aabalke/guac/emu/link"
+)
+
+const (
+	// cycles per bit of the internal clock, 8192hz and 262144hz on cgb
+	SERIAL_CYCLES_SLOW = CPU_SPEED / 8192
+	SERIAL_CYCLES_FAST = CPU_SPEED / 262144
+
+	// how often an external clock transfer is checked for
+	SERIAL_POLL_CYCLES = 512
+)
+
+// Device is plugged into the link port, a link.Port to another console or
+// an accessory like the printer. Data is the low byte. Reply reports false
+// while the reply has not arrived, the transfer is held until it does.
+type Device interface {
+	Transfer(v uint32)
+	Reply() (uint32, bool)
+	Ready(ready bool)
+	Clocked(out uint32) (uint32, bool)
+	Close() error
+}
+
+// Serial is the link port. The internal clock finishes a transfer with an
+// EVENT_SERIAL 8 bits later. With the external clock the peer clocks the
+// transfer, it is taken from the Port by EVENT_SERIAL_POLL while enabled.
+// Without a Port transfers read 0xFF like an unplugged cable.
 type Serial struct {
-	out      uint8
-	in       uint8
+	Sb       uint8
 	IsMaster bool
 	Enabled  bool
+	Fast     bool
+
+	Port Device
 }
 
-func (s *Serial) WriteSc(v uint8) {
+func (s *Serial) WriteSc(gb *GameBoy, v uint8) {
 	s.IsMaster = v&1 != 0
 	s.Enabled = v&0x80 != 0
+	s.Fast = gb.Color && v&2 != 0
+
+	gb.Scheduler.cancel(EVENT_SERIAL)
+	gb.Scheduler.cancel(EVENT_SERIAL_POLL)
+
+	if s.Port != nil {
+		s.Port.Ready(s.Enabled && !s.IsMaster)
+	}
+
+	if !s.Enabled {
+		return
+	}
+
+	if !s.IsMaster {
+		if s.Port != nil {
+			gb.Scheduler.schedule(EVENT_SERIAL_POLL, SERIAL_POLL_CYCLES)
+		}
+		return
+	}
+
+	cycles := int64(SERIAL_CYCLES_SLOW)
+	if s.Fast {
+		cycles = SERIAL_CYCLES_FAST
+	}
+
+	if s.Port != nil {
+		s.Port.Transfer(uint32(s.Sb))
+	}
+
+	// the serial clock runs at double speed with the cpu
+	gb.Scheduler.schedule(EVENT_SERIAL, (cycles*8)>>gb.DoubleSpeedFlag)
 }
 
-func (s *Serial) ReadSc() uint8 {
-	v := uint8(0x7E)
+func (s *Serial) ReadSc(gb *GameBoy) uint8 {
+	v := uint8(0x7C)
 
 	if s.IsMaster {
 		v |= 1
 	}
 
+	if s.Fast || !gb.Color {
+		v |= 2
+	}
+
 	if s.Enabled {
 		v |= 0x80
 	}
@@ -27,9 +96,63 @@ func (s *Serial) ReadSc() uint8 {
 }
 
 func (s *Serial) WriteSb(v uint8) {
-	s.out = v
+	s.Sb = v
 }
 
 func (s *Serial) ReadSb() uint8 {
-	return s.in
+	return s.Sb
+}
+
+// newSerialDevice plugs in the printer or a link cable from the config
+func newSerialDevice(title string) Device {
+	if config.Conf.Gb.Printer.Enabled {
+		return printer.New(config.Conf.Gb.Printer.Directory, title)
+	}
+
+	port, err := link.Open(config.Conf.Link.Listen, config.Conf.Link.Connect)
+	if err != nil {
+		log.Printf("Link: %v\n", err)
+	}
+
+	if port == nil {
+		return nil
+	}
+
+	return port
+}
+
+// finish completes an internal clock transfer, one still waiting on the
+// peer's reply is checked again after SERIAL_POLL_CYCLES
+func (s *Serial) finish(gb *GameBoy) {
+	v := uint32(0xFF)
+	if s.Port != nil {
+		var ok bool
+		if v, ok = s.Port.Reply(); !ok {
+			gb.Scheduler.schedule(EVENT_SERIAL, SERIAL_POLL_CYCLES)
+			return
+		}
+	}
+
+	s.Sb = uint8(v)
+
+	s.Enabled = false
+	gb.SetIrq(IRQ_SER)
+}
+
+// poll completes an external clock transfer once the peer clocked one
+func (s *Serial) poll(gb *GameBoy) {
+	if !s.Enabled || s.IsMaster {
+		return
+	}
+
+	v, ok := s.Port.Clocked(uint32(s.Sb))
+	if !ok {
+		gb.Scheduler.schedule(EVENT_SERIAL_POLL, SERIAL_POLL_CYCLES)
+		return
+	}
+
+	s.Sb = uint8(v)
+	s.Enabled = false
+	s.Port.Ready(false)
+	gb.SetIrq(IRQ_SER)
 }
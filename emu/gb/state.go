@@ -0,0 +1,101 @@
+package gb
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/state"
+)
+
+func (gb *GameBoy) stateHeader() state.Header {
+	return state.Header{
+		Console:  state.GB,
+		Checksum: gb.Cartridge.RomChecksum(),
+		Title:    gb.Cartridge.Title,
+		Frame:    gb.Frame,
+	}
+}
+
+// SaveState writes the full console state, the rom is not included
+func (gb *GameBoy) SaveState(w io.Writer) error {
+	if err := state.Save(w, gb.stateHeader(), gb.saveChunks); err != nil {
+		return fmt.Errorf("gb save state: %w", err)
+	}
+
+	return nil
+}
+
+// LoadState restores a state written by SaveState. States from other roms
+// are refused. The console should not be used if an error is returned after
+// the header was accepted, it may be partially loaded.
+func (gb *GameBoy) LoadState(r io.Reader) error {
+	if _, err := state.Load(r, gb.stateHeader(), gb.loadChunks); err != nil {
+		return fmt.Errorf("gb load state: %w", err)
+	}
+
+	return nil
+}
+
+func (gb *GameBoy) saveChunks(e *state.Encoder) {
+	e.Chunk("gb")
+	e.Encode(gb, gb.Cpu, gb.Scheduler)
+	e.Chunk("cart")
+	gb.Cartridge.SaveState(e)
+	e.Chunk("apu")
+	e.Encode(gb.Apu)
+}
+
+func (gb *GameBoy) loadChunks(d *state.Decoder) {
+	d.Chunk("gb")
+	d.Decode(gb, gb.Cpu, gb.Scheduler)
+	d.Chunk("cart")
+	gb.Cartridge.LoadState(d)
+	d.Chunk("apu")
+	d.Decode(gb.Apu)
+
+	// the sound ring moved, a capture goes on from here
+	gb.Capture.SkipSound(gb.Apu.WritePointer)
+
+	// opcode pointer may point into a different bank
+	gb.Cpu.PcPtr = nil
+	gb.Cpu.PcOff = 0
+	gb.Cpu.isBranching = false
+
+	// palettes are user config, not console state
+	gb.UpdateFromConfig()
+}
+
+func newRewind() *state.Rewind {
+	c := &config.Conf.General
+
+	if c.Headless || c.RewindBufferSize <= 0 {
+		return nil
+	}
+
+	return state.NewRewind(c.RewindBufferSize<<20, c.RewindInterval)
+}
+
+func (gb *GameBoy) captureRewind() {
+	if gb.Rewind == nil {
+		return
+	}
+
+	if err := gb.Rewind.Capture(gb.saveChunks); err != nil {
+		log.Printf("Rewind Disabled: %v\n", err)
+		gb.Rewind = nil
+	}
+}
+
+// rewindStep runs in place of a frame while rewinding, each call goes back
+// one snapshot
+func (gb *GameBoy) rewindStep() {
+	if _, err := gb.Rewind.Step(gb.loadChunks); err != nil {
+		log.Printf("Rewind Disabled: %v\n", err)
+		gb.Rewind = nil
+		return
+	}
+
+	gb.Image.WritePixels(gb.Pixels)
+}
@@ -334,3 +334,9 @@ func (a *Apu) PowerOff() {
 	a.SoundCntH = 0
 	a.SoundCntX = 0
 }
+
+// SampleRate is the rate samples are made at, a sample every sampCycles is
+// not quite sndFrequency
+func (a *Apu) SampleRate() int {
+	return a.cpuFreqHz / a.sampCycles
+}
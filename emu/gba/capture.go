@@ -0,0 +1,45 @@
+package gba
+
+import (
+	"log"
+
+	"github.com/aabalke/guac/emu/capture"
+)
+
+// FRAME_RATE is the emulated frame rate, audio and video stay in step at it
+var FRAME_RATE = capture.Rate{Num: 16777216, Den: CYCLES_FRAME}
+
+// StartCapture records the picture and sound of every frame to path, see
+// capture.New
+func (gba *GBA) StartCapture(path string) error {
+	r, err := capture.New(path, SCREEN_WIDTH, SCREEN_HEIGHT, FRAME_RATE, gba.Apu.SampleRate())
+	if err != nil {
+		return err
+	}
+
+	r.SkipSound(gba.Apu.WritePointer)
+	gba.Capture = r
+
+	return nil
+}
+
+// captureFrame adds the frame just drawn and its sound
+func (gba *GBA) captureFrame() {
+	if gba.Capture == nil {
+		return
+	}
+
+	err := gba.Capture.Record(gba.Screenshot(), gba.Apu.SoundBuffer, gba.Apu.WritePointer)
+	if err != nil {
+		log.Printf("Capture Failed: %v\n", err)
+		gba.StopCapture()
+	}
+}
+
+// StopCapture ends recording and returns the frames recorded
+func (gba *GBA) StopCapture() (int, error) {
+	n, err := gba.Capture.Stop()
+	gba.Capture = nil
+
+	return n, err
+}
@@ -4,11 +4,15 @@ import (
 	"bufio"
 	"log"
 	"os"
+	"sync"
+
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
 )
 
 type Cartridge struct {
-	RomPath      string
-	SavPath      string
+	RomPath      string `state:"-"`
+	SavPath      string `state:"-"`
 	Header       *Header
 	RomLength    uint32
 	Id           int
@@ -19,10 +23,14 @@ type Cartridge struct {
 	Device       uint32
 	FlashStage   uint32
 
-	Rom    [0x200_0000]uint8
+	Rom    [0x200_0000]uint8 `state:"-"`
 	SRAM   [0x1_0000]uint8
 	Flash  [0x2_0000]uint8 // multiple banks
 	Eeprom [0x2000]uint8
+
+	Gpio Gpio
+
+	checksum func() uint32
 }
 
 const (
@@ -40,16 +48,24 @@ const (
 	TYPE_MACRONIX128 = 5
 )
 
-func NewCartridge(rom, sav string) *Cartridge {
+// NewCartridge takes the rom as loaded, saves are named after the patch when
+// it is patched
+func NewCartridge(img *rom.Image) *Cartridge {
 	c := Cartridge{
-		RomPath: rom,
-		SavPath: sav,
+		RomPath: img.Path,
+		SavPath: img.Name() + ".save",
 	}
 
-	c.load()
+	c.load(img.Data)
 
 	c.Header = NewHeader(&c)
 
+	c.Gpio.Plug(GPIO_GAMES[c.Header.GameCode[:3]])
+
+	c.checksum = sync.OnceValue(func() uint32 {
+		return state.Checksum(c.Rom[:c.RomLength])
+	})
+
 	switch c.Id {
 	case NONE:
 		log.Printf("Cartridge Type NONE\n")
@@ -135,12 +151,7 @@ func (c *Cartridge) getDeviceManufacturer() int {
 	panic("UNKNOWN FLASH ROM DEVICE AND MANUFACTUER")
 }
 
-func (c *Cartridge) load() {
-	buf, err := os.ReadFile(c.RomPath)
-	if err != nil {
-		panic(err)
-	}
-
+func (c *Cartridge) load(buf []uint8) {
 	c.RomLength = uint32(len(buf))
 
 	for i := range len(buf) {
@@ -183,6 +194,18 @@ func (c *Cartridge) load() {
 	}
 }
 
+// Backup is the memory of the cart's save type, as written to the save
+func (c *Cartridge) Backup() []uint8 {
+	switch c.Id {
+	case SRAM:
+		return c.SRAM[:]
+	case EEPROM:
+		return c.Eeprom[:]
+	default:
+		return c.Flash[:]
+	}
+}
+
 func (c *Cartridge) Save() {
 	log.Printf("Saving Game Path: %s\n", c.SavPath)
 
@@ -194,17 +217,7 @@ func (c *Cartridge) Save() {
 
 	writer := bufio.NewWriter(f)
 
-	var bytes []uint8
-	switch c.Id {
-	case SRAM:
-		bytes = c.SRAM[:]
-	case EEPROM:
-		bytes = c.Eeprom[:]
-	default:
-		bytes = c.Flash[:]
-	}
-
-	_, err = writer.Write(bytes)
+	_, err = writer.Write(c.Backup())
 	if err != nil {
 		panic(err)
 	}
@@ -214,9 +227,6 @@ func (c *Cartridge) Read(addr uint32) uint8 {
 	switch c.Id {
 	case SRAM:
 		return c.SRAM[addr]
-	case EEPROM:
-		log.Printf("Attempted GPIO Read with EEPROM. Not supported.\n")
-		return 0
 	case FLASH, FLASH128:
 		return c.ReadFlash(addr)
 	default:
@@ -228,8 +238,6 @@ func (c *Cartridge) Write(addr uint32, v uint8) {
 	switch c.Id {
 	case SRAM:
 		c.SRAM[addr] = v
-	case EEPROM:
-		log.Printf("Attempted GPIO Write with EEPROM. Not supported.\n")
 	case FLASH, FLASH128:
 		c.WriteFlash(addr, v)
 	}
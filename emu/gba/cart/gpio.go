@@ -0,0 +1,159 @@
+package cart
+
+import "log"
+
+// gpio port, relative to the start of rom
+const (
+	GPIO_DATA    = 0xC4
+	GPIO_DIR     = 0xC6
+	GPIO_CONTROL = 0xC8
+	GPIO_END     = 0xCA
+)
+
+// devices wired to the gpio port
+const (
+	GPIO_RTC = 1 << iota
+	GPIO_SOLAR
+	GPIO_GYRO
+	GPIO_RUMBLE
+)
+
+// GPIO_GAMES are the carts with gpio devices by the first 3 letters of the
+// game code, the last is the region
+var GPIO_GAMES = map[string]int{
+	"AXV": GPIO_RTC,                // pokemon ruby
+	"AXP": GPIO_RTC,                // pokemon sapphire
+	"BPE": GPIO_RTC,                // pokemon emerald
+	"BKA": GPIO_RTC,                // sennen kazoku
+	"BR4": GPIO_RTC,                // rockman exe 4.5
+	"U3I": GPIO_RTC | GPIO_SOLAR,   // boktai
+	"U32": GPIO_RTC | GPIO_SOLAR,   // boktai 2
+	"U33": GPIO_RTC | GPIO_SOLAR,   // boktai 3
+	"RZW": GPIO_GYRO | GPIO_RUMBLE, // warioware twisted
+	"V49": GPIO_RUMBLE,             // drill dozer
+}
+
+// GpioDevice is wired to the 4 pins of the port. Pins is called after every
+// data write with the pin levels and returns them with the pins the device
+// drives set.
+type GpioDevice interface {
+	Pins(pins uint8) uint8
+}
+
+// Gpio is the 4 bit port some carts have in rom at 0x80000C4. Dir sets the
+// pins the gba drives, the others are read from the devices. Reads return
+// rom unless the port is made Readable.
+type Gpio struct {
+	Data     uint8
+	Dir      uint8
+	Readable bool
+
+	Devices int
+	Rtc     Rtc
+	Solar   Solar
+	Gyro    Gyro
+	Rumble  Rumble
+
+	devices []GpioDevice `state:"-"`
+}
+
+// Plug wires up the devices in the GPIO_* mask
+func (g *Gpio) Plug(devices int) {
+	g.Devices = devices
+	g.devices = nil
+
+	if devices&GPIO_RTC != 0 {
+		g.Rtc.Reset()
+		g.devices = append(g.devices, &g.Rtc)
+	}
+
+	if devices&GPIO_SOLAR != 0 {
+		g.devices = append(g.devices, &g.Solar)
+	}
+
+	if devices&GPIO_GYRO != 0 {
+		g.devices = append(g.devices, &g.Gyro)
+	}
+
+	if devices&GPIO_RUMBLE != 0 {
+		g.devices = append(g.devices, &g.Rumble)
+	}
+
+	if devices != 0 {
+		log.Printf("Cartridge GPIO %s\n", gpioNames(devices))
+	}
+}
+
+// InRange reports if a rom offset is in the port
+func (g *Gpio) InRange(offset uint32) bool {
+	return g.Devices != 0 && offset >= GPIO_DATA && offset < GPIO_END
+}
+
+func (g *Gpio) Read(offset uint32) uint8 {
+	switch offset {
+	case GPIO_DATA:
+		return g.Data & 0xF
+	case GPIO_DIR:
+		return g.Dir & 0xF
+	case GPIO_CONTROL:
+		if g.Readable {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (g *Gpio) Write(offset uint32, v uint8) {
+	switch offset {
+	case GPIO_DATA:
+		g.Data = g.Data&^g.Dir | v&g.Dir&0xF
+		g.update()
+	case GPIO_DIR:
+		g.Dir = v & 0xF
+	case GPIO_CONTROL:
+		g.Readable = v&1 != 0
+	}
+}
+
+// update lets the devices see the pins and drive their outputs
+func (g *Gpio) update() {
+	pins := g.Data
+	for _, d := range g.devices {
+		pins = d.Pins(pins)
+	}
+
+	g.Data = g.Data&g.Dir | pins&^g.Dir&0xF
+}
+
+func gpioNames(devices int) string {
+	s := ""
+	for i, name := range []string{"RTC", "SOLAR", "GYRO", "RUMBLE"} {
+		if devices&(1<<i) == 0 {
+			continue
+		}
+
+		if s != "" {
+			s += " "
+		}
+
+		s += name
+	}
+
+	return s
+}
+
+func (c *Cartridge) ReadGpio(addr uint32) uint8 {
+	return c.Gpio.Read(addr & 0x1FF_FFFF)
+}
+
+func (c *Cartridge) WriteGpio(addr uint32, v uint8) {
+	if offset := addr & 0x1FF_FFFF; c.Gpio.InRange(offset) {
+		c.Gpio.Write(offset, v)
+	}
+}
+
+// GpioReadable reports if reads of addr go to the gpio port instead of rom
+func (c *Cartridge) GpioReadable(addr uint32) bool {
+	return c.Gpio.Readable && c.Gpio.InRange(addr&0x1FF_FFFF)
+}
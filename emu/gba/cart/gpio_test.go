@@ -0,0 +1,182 @@
+package cart
+
+import (
+	"testing"
+	"time"
+)
+
+var rtcTime = time.Date(2024, time.March, 9, 17, 45, 30, 0, time.UTC)
+
+func newRtcGpio() *Gpio {
+	g := &Gpio{}
+	g.Plug(GPIO_RTC)
+	g.Rtc.Now = func() time.Time { return rtcTime }
+	g.Write(GPIO_CONTROL, 1)
+	return g
+}
+
+// the siirtc driver in pokemon sends commands msb first and data lsb first
+
+func rtcStart(g *Gpio) {
+	g.Write(GPIO_DIR, 7)
+	g.Write(GPIO_DATA, RTC_SCK)
+	g.Write(GPIO_DATA, RTC_SCK|RTC_CS)
+}
+
+func rtcStop(g *Gpio) {
+	g.Write(GPIO_DATA, RTC_SCK)
+	g.Write(GPIO_DATA, RTC_SCK)
+}
+
+func rtcWrite(g *Gpio, v uint8, msbFirst bool) {
+	for i := range 8 {
+		bit := v >> i & 1
+		if msbFirst {
+			bit = v >> (7 - i) & 1
+		}
+
+		g.Write(GPIO_DATA, bit<<1|RTC_CS)
+		g.Write(GPIO_DATA, bit<<1|RTC_SCK|RTC_CS)
+	}
+}
+
+func rtcRead(g *Gpio) uint8 {
+	g.Write(GPIO_DIR, 5)
+
+	var v uint8
+	for range 8 {
+		g.Write(GPIO_DATA, RTC_CS)
+		g.Write(GPIO_DATA, RTC_SCK|RTC_CS)
+		v = v>>1 | (g.Read(GPIO_DATA)&RTC_SIO)>>1<<7
+	}
+
+	return v
+}
+
+func TestRtcDateTime(t *testing.T) {
+	g := newRtcGpio()
+
+	rtcStart(g)
+	rtcWrite(g, 0x65, true) // date time, read
+
+	var got [7]uint8
+	for i := range got {
+		got[i] = rtcRead(g)
+	}
+	rtcStop(g)
+
+	want := [7]uint8{0x24, 0x03, 0x09, 0x06, 0x17, 0x45, 0x30}
+	if got != want {
+		t.Fatalf("date time %02X, expected %02X", got, want)
+	}
+}
+
+func TestRtcStatus(t *testing.T) {
+	g := newRtcGpio()
+
+	rtcStart(g)
+	rtcWrite(g, 0x63, true) // status, read
+	if got := rtcRead(g); got != RTC_CONTROL_24H {
+		t.Fatalf("status %02X, expected 24 hour", got)
+	}
+	rtcStop(g)
+
+	// 12 hour clock, pm flag
+	rtcStart(g)
+	rtcWrite(g, 0x62, true)
+	rtcWrite(g, 0, false)
+	rtcStop(g)
+
+	rtcStart(g)
+	rtcWrite(g, 0x67, true) // time, read
+	if got := rtcRead(g); got != 0x85 {
+		t.Fatalf("12 hour %02X, expected 85", got)
+	}
+	rtcStop(g)
+}
+
+func TestRtcSet(t *testing.T) {
+	g := newRtcGpio()
+
+	rtcStart(g)
+	rtcWrite(g, 0x64, true) // date time, write
+	for _, v := range []uint8{0x00, 0x01, 0x01, 0x06, 0x00, 0x00, 0x00} {
+		rtcWrite(g, v, false)
+	}
+	rtcStop(g)
+
+	rtcStart(g)
+	rtcWrite(g, 0x65, true)
+	year, month, day := rtcRead(g), rtcRead(g), rtcRead(g)
+	rtcStop(g)
+
+	if year != 0 || month != 1 || day != 1 {
+		t.Fatalf("set clock reads %02X-%02X-%02X", year, month, day)
+	}
+}
+
+func TestGpioReadable(t *testing.T) {
+	c := &Cartridge{}
+	c.Gpio.Plug(GPIO_RTC)
+
+	if c.GpioReadable(0x80000C4) {
+		t.Fatal("gpio readable before control")
+	}
+
+	c.WriteGpio(0x80000C8, 1)
+
+	if !c.GpioReadable(0x80000C4) || c.GpioReadable(0x80000CA) || c.GpioReadable(0x80000C2) {
+		t.Fatal("gpio range")
+	}
+}
+
+func TestSolar(t *testing.T) {
+	counts := func(level int) int {
+		g := &Gpio{}
+		g.Plug(GPIO_SOLAR)
+		g.Solar.Level = level
+		g.Write(GPIO_DIR, 7)
+		g.Write(GPIO_CONTROL, 1)
+
+		g.Write(GPIO_DATA, 2)
+		g.Write(GPIO_DATA, 0)
+
+		n := 0
+		for ; g.Read(GPIO_DATA)&8 == 0; n++ {
+			g.Write(GPIO_DATA, 1)
+			g.Write(GPIO_DATA, 0)
+		}
+
+		return n
+	}
+
+	if dark, sun := counts(0), counts(SOLAR_MAX_LEVEL); sun >= dark {
+		t.Fatalf("sunlight counts %d, dark %d", sun, dark)
+	}
+}
+
+func TestGyro(t *testing.T) {
+	g := &Gpio{}
+	g.Plug(GPIO_GYRO | GPIO_RUMBLE)
+	g.Gyro.Rate = 0x100
+	g.Write(GPIO_DIR, 0b1011)
+	g.Write(GPIO_CONTROL, 1)
+
+	g.Write(GPIO_DATA, 1|8)
+	g.Write(GPIO_DATA, 0|8)
+
+	if !g.Rumble.On {
+		t.Fatal("rumble off")
+	}
+
+	var v uint16
+	for range 16 {
+		g.Write(GPIO_DATA, 2)
+		g.Write(GPIO_DATA, 0)
+		v = v<<1 | uint16(g.Read(GPIO_DATA)>>2&1)
+	}
+
+	if v != GYRO_CENTER+0x100 {
+		t.Fatalf("gyro sample %X", v)
+	}
+}
@@ -0,0 +1,226 @@
+package cart
+
+import "time"
+
+// rtc pins
+const (
+	RTC_SCK = 1 << 0
+	RTC_SIO = 1 << 1
+	RTC_CS  = 1 << 2
+)
+
+const (
+	RTC_RESET    = 0
+	RTC_DATETIME = 2
+	RTC_IRQ      = 3
+	RTC_CONTROL  = 4
+	RTC_TIME     = 6
+
+	RTC_CONTROL_24H      = 1 << 6
+	RTC_CONTROL_WRITABLE = 0x6A
+)
+
+// parameter bytes of each command
+var RTC_LEN = [8]int{0, 0, 7, 0, 1, 0, 3, 0}
+
+// Rtc is the Seiko S-3511 real time clock. Bytes are shifted lsb first on
+// the rising edge of sck while cs is high, the first is the command:
+// 0110 in the low nibble, the register and the read flag in bit 7.
+//
+// The time is Now plus Offset, games setting the clock change the Offset.
+type Rtc struct {
+	Control uint8
+	Offset  time.Duration
+
+	Active  bool // a command was received
+	Cmd     uint8
+	Reading bool
+	Regs    [7]uint8 // year, month, day, weekday, hour, minute, second
+	Idx     int
+	Len     int
+
+	Bits   uint8
+	BitCnt int
+	Out    uint8
+
+	WasSck bool
+	WasCs  bool
+
+	Now func() time.Time `state:"-"`
+}
+
+func (r *Rtc) Reset() {
+	r.Control = RTC_CONTROL_24H
+	r.Active = false
+	r.Bits, r.BitCnt = 0, 0
+}
+
+func (r *Rtc) Pins(pins uint8) uint8 {
+	sck := pins&RTC_SCK != 0
+	cs := pins&RTC_CS != 0
+
+	if !cs {
+		r.WasCs = false
+		r.WasSck = sck
+		return pins
+	}
+
+	if start := !r.WasCs; start {
+		r.Active = false
+		r.Reading = false
+		r.Bits, r.BitCnt = 0, 0
+	}
+
+	rising := sck && !r.WasSck
+	r.WasCs, r.WasSck = true, sck
+
+	if rising {
+		r.clock(pins&RTC_SIO != 0)
+	}
+
+	// the last bit is held until cs goes low
+	if r.Reading {
+		pins = pins&^RTC_SIO | r.Out<<1
+	}
+
+	return pins
+}
+
+func (r *Rtc) clock(sio bool) {
+	if r.Active && r.Reading {
+		r.Out = r.Regs[r.Idx] >> r.BitCnt & 1
+		r.BitCnt++
+
+		if r.BitCnt == 8 {
+			r.BitCnt = 0
+			r.Idx++
+			r.Active = r.Idx < r.Len
+		}
+
+		return
+	}
+
+	if sio {
+		r.Bits |= 1 << r.BitCnt
+	}
+
+	r.BitCnt++
+
+	if r.BitCnt == 8 {
+		r.receive(r.Bits)
+		r.Bits, r.BitCnt = 0, 0
+	}
+}
+
+func (r *Rtc) receive(v uint8) {
+	if !r.Active {
+		r.command(v)
+		return
+	}
+
+	r.Regs[r.Idx] = v
+	r.Idx++
+
+	if r.Idx < r.Len {
+		return
+	}
+
+	r.Active = false
+
+	switch r.Cmd {
+	case RTC_CONTROL:
+		r.Control = v & RTC_CONTROL_WRITABLE
+	case RTC_DATETIME:
+		r.set(r.Regs[:])
+	case RTC_TIME:
+		now := r.time()
+		r.set(append([]uint8{
+			bcd(now.Year() - 2000), bcd(int(now.Month())), bcd(now.Day()), bcd(int(now.Weekday())),
+		}, r.Regs[:3]...))
+	}
+}
+
+func (r *Rtc) command(v uint8) {
+	if invalidCmd := v&0xF != 0b0110; invalidCmd {
+		return
+	}
+
+	r.Cmd = v >> 4 & 7
+	r.Reading = v&0x80 != 0
+	r.Len = RTC_LEN[r.Cmd]
+	r.Idx = 0
+	r.Active = r.Len > 0
+
+	switch r.Cmd {
+	case RTC_RESET:
+		r.Control = 0
+		r.Offset = 0
+	case RTC_CONTROL:
+		r.Regs[0] = r.Control
+	case RTC_DATETIME:
+		r.latch()
+	case RTC_TIME:
+		r.latch()
+		copy(r.Regs[:], r.Regs[4:])
+	}
+}
+
+func (r *Rtc) time() time.Time {
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+
+	return now().Add(r.Offset)
+}
+
+// latch copies the time into the registers
+func (r *Rtc) latch() {
+	now := r.time()
+
+	hour := bcd(now.Hour())
+	if r.Control&RTC_CONTROL_24H == 0 {
+		hour = bcd(now.Hour() % 12)
+		if now.Hour() >= 12 {
+			hour |= 0x80
+		}
+	}
+
+	r.Regs = [7]uint8{
+		bcd(now.Year() - 2000),
+		bcd(int(now.Month())),
+		bcd(now.Day()),
+		bcd(int(now.Weekday())),
+		hour,
+		bcd(now.Minute()),
+		bcd(now.Second()),
+	}
+}
+
+// set moves the clock to the time in the registers
+func (r *Rtc) set(regs []uint8) {
+	hour := unbcd(regs[4] & 0x3F)
+	if r.Control&RTC_CONTROL_24H == 0 && regs[4]&0x80 != 0 {
+		hour += 12
+	}
+
+	now := r.time()
+	t := time.Date(
+		2000+unbcd(regs[0]), time.Month(unbcd(regs[1])), unbcd(regs[2]),
+		hour, unbcd(regs[5]), unbcd(regs[6]), 0, now.Location(),
+	)
+
+	r.Offset += t.Sub(now)
+}
+
+func bcd(v int) uint8 {
+	if v < 0 || v > 99 {
+		return 0xFF
+	}
+
+	return uint8((v/10)<<4 | v%10)
+}
+
+func unbcd(v uint8) int {
+	return int(v>>4)*10 + int(v&0xF)
+}
@@ -0,0 +1,89 @@
+package cart
+
+const (
+	SOLAR_MAX_LEVEL = 10
+
+	// gyro sample at rest, Rate is added to it
+	GYRO_CENTER   = 0x6C0
+	GYRO_MAX_RATE = 0x400
+)
+
+// sunlight added to the base sample per level
+var SOLAR_LEVELS = [SOLAR_MAX_LEVEL]uint16{5, 11, 18, 27, 42, 62, 84, 109, 139, 183}
+
+// Solar is the boktai light sensor, selected with pin 2 low. Pin 1 takes a
+// sample and resets the counter, pin 0 counts up, and pin 3 is set once the
+// counter reaches the sample. More light is a lower sample. Level is 0 - 10.
+type Solar struct {
+	Level   int
+	Counter uint16
+	Sample  uint16
+	WasClk  bool
+}
+
+func (s *Solar) Pins(pins uint8) uint8 {
+	if pins&4 != 0 {
+		return pins
+	}
+
+	if pins&2 != 0 {
+		s.Counter = 0
+		s.Sample = s.sample()
+	}
+
+	clk := pins&1 != 0
+	if clk && !s.WasClk {
+		s.Counter++
+	}
+	s.WasClk = clk
+
+	pins &^= 8
+	if s.Counter >= s.Sample {
+		pins |= 8
+	}
+
+	return pins
+}
+
+func (s *Solar) sample() uint16 {
+	v := uint16(0x16)
+	if level := min(s.Level, SOLAR_MAX_LEVEL); level > 0 {
+		v += SOLAR_LEVELS[level-1]
+	}
+
+	return 0xFF - v
+}
+
+// Gyro is the warioware twisted rotation sensor. Pin 0 takes a sample of
+// Rate, which is shifted out msb first on pin 2 on falling edges of pin 1.
+// Rate is -0x400 - 0x400, positive turning clockwise.
+type Gyro struct {
+	Rate   int
+	Sample uint16
+	WasClk bool
+}
+
+func (g *Gyro) Pins(pins uint8) uint8 {
+	if pins&1 != 0 {
+		g.Sample = uint16(GYRO_CENTER + max(-GYRO_MAX_RATE, min(g.Rate, GYRO_MAX_RATE)))
+	}
+
+	clk := pins&2 != 0
+	if falling := g.WasClk && !clk; falling {
+		pins = pins&^4 | uint8(g.Sample>>15)<<2
+		g.Sample <<= 1
+	}
+	g.WasClk = clk
+
+	return pins
+}
+
+// Rumble is the motor on pin 3
+type Rumble struct {
+	On bool
+}
+
+func (r *Rumble) Pins(pins uint8) uint8 {
+	r.On = pins&8 != 0
+	return pins
+}
@@ -0,0 +1,26 @@
+package cart
+
+import "github.com/aabalke/guac/emu/state"
+
+// RomChecksum identifies the rom in save states, it is computed on first use
+func (c *Cartridge) RomChecksum() uint32 {
+	return c.checksum()
+}
+
+func (c *Cartridge) SaveState(e *state.Encoder) {
+	e.Encode(c)
+	e.Encode(
+		&eepromReadBitsCount, &eepromReadBits,
+		&eepromWriteBitsCount, &eepromWriteBits,
+		&EepromWidth, &EepromAddr, &EepromState,
+	)
+}
+
+func (c *Cartridge) LoadState(d *state.Decoder) {
+	d.Decode(c)
+	d.Decode(
+		&eepromReadBitsCount, &eepromReadBits,
+		&eepromWriteBitsCount, &eepromWriteBits,
+		&EepromWidth, &EepromAddr, &EepromState,
+	)
+}
@@ -0,0 +1,18 @@
+package gba
+
+import (
+	"github.com/aabalke/guac/emu/cheat"
+	"github.com/aabalke/guac/emu/rom"
+)
+
+func (gba *GBA) newCheats() *cheat.Engine {
+	c := gba.Cartridge
+	return cheat.NewEngine(rom.GBA, cheat.ArmBus{Mem: gba.Mem}, c.Rom[:c.RomLength])
+}
+
+// SetCheats replaces the cheats in use. The rom checksum is taken first so
+// rom patches do not change which save states fit the rom.
+func (gba *GBA) SetCheats(cheats []cheat.Cheat) error {
+	gba.Cartridge.RomChecksum()
+	return gba.Cheats.Set(cheats)
+}
@@ -1,11 +1,23 @@
 package gba
 
 import (
+	"image"
+	"log"
+
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/capture"
+	"github.com/aabalke/guac/emu/cheat"
+	"github.com/aabalke/guac/emu/clock"
 	"github.com/aabalke/guac/emu/cpu"
 	"github.com/aabalke/guac/emu/cpu/arm7"
+	"github.com/aabalke/guac/emu/debugger"
 	"github.com/aabalke/guac/emu/gba/apu"
 	"github.com/aabalke/guac/emu/gba/cart"
+	"github.com/aabalke/guac/emu/link"
+	"github.com/aabalke/guac/emu/movie"
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
+	"github.com/aabalke/guac/input"
 	"github.com/aabalke/guac/utils"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/oto"
@@ -28,7 +40,8 @@ const (
 var CURR_INST = uint64(0)
 
 type GBA struct {
-	Debugger  Debugger
+	Debugger  *debugger.Debugger
+	Frontends []debugger.Frontend
 	Cartridge *cart.Cartridge
 	Cpu       *arm7.Cpu
 	Mem       *Memory
@@ -36,12 +49,22 @@ type GBA struct {
 	Timers    [4]Timer
 	Dma       [4]DMA
 	Irq       cpu.Irq
+	Sio       Sio
 	Apu       *apu.Apu
 
-	Paused, Muted, Save, Drawn bool
-	OpenBusOpcode              uint32
-	AccCycles                  uint32
-	Keypad                     Keypad
+	Paused, Muted, Save bool `state:"-"`
+	Rewinding           bool `state:"-"`
+	Rewind              *state.Rewind
+	Cheats              *cheat.Engine
+	movie.Deck          `state:"-"`
+	Capture             *capture.Recorder
+	PatchErr            error // the rom's patch failed, it runs unpatched
+	Drawn               bool
+	midFrame            bool `state:"-"` // the debugger stopped the last frame
+	OpenBusOpcode       uint32
+	AccCycles           uint32
+	Keypad              Keypad
+	buttons             input.Buttons `state:"-"` // held, for movies
 
 	SoundCycles     uint32
 	SoundCyclesMask uint32
@@ -50,19 +73,42 @@ type GBA struct {
 
 	Pixels      []byte
 	Image       *ebiten.Image
-	DrawOptions ebiten.DrawImageOptions
+	DrawOptions ebiten.DrawImageOptions `state:"-"`
 
 	Frame uint64
 }
 
 func (gba *GBA) Update(stdFps bool) {
-	gba.AccCycles = 0
+	for _, f := range gba.Frontends {
+		f.Poll()
+	}
+
+	if gba.Debugger.Stopped() {
+		return
+	}
+
+	if !gba.midFrame {
+		gba.AccCycles = 0
+	}
 
 	if gba.Paused {
 		return
 	}
 
-	gba.Drawn = false
+	// rewinding would put the run out of step with a movie
+	if gba.Rewinding && gba.Rewind != nil && gba.Movie == nil {
+		gba.rewindStep()
+		return
+	}
+
+	if !gba.midFrame {
+		gba.Drawn = false
+		clock.Advance(FRAME_TIME)
+		gba.StepMovie()
+		gba.Cheats.Apply()
+	}
+
+	gba.midFrame = false
 
 	for !gba.Drawn {
 
@@ -70,6 +116,11 @@ func (gba *GBA) Update(stdFps bool) {
 
 		if !gba.Cpu.Halted {
 
+			if gba.Debugger.Active() && gba.Debugger.Check(gba.Cpu.Reg.R[PC]) {
+				gba.midFrame = true
+				return
+			}
+
 			thumb := gba.Cpu.Reg.CPSR.T
 
 			insts, ok := gba.Cpu.Execute()
@@ -107,7 +158,14 @@ func (gba *GBA) Update(stdFps bool) {
 
 	gba.Apu.Play(gba.Muted, stdFps)
 	gba.Frame++
+	if config.Conf.General.Deterministic && gba.Frame%SAVE_FRAMES == 0 {
+		gba.Mem.FlushSave()
+	}
+
+	gba.captureFrame()
+
 	gba.Image.WritePixels(gba.Pixels)
+	gba.captureRewind()
 }
 
 func (gba *GBA) Tick(cycles uint32) {
@@ -120,6 +178,7 @@ func (gba *GBA) Tick(cycles uint32) {
 
 	gba.VideoUpdate(uint32(cycles))
 	gba.UpdateTimers(uint32(cycles))
+	gba.Sio.Tick(gba, cycles)
 }
 
 func NewGBA(path string, ctx *oto.Context) *GBA {
@@ -136,17 +195,26 @@ func NewGBA(path string, ctx *oto.Context) *GBA {
 		Apu:             apu.NewApu(ctx, CPU_FREQ_HZ, SND_FREQUENCY, SND_SAMPLES),
 		SoundCyclesMask: max(0x80, uint32(config.Conf.Gba.SoundClockUpdateCycles)),
 		PPU:             &PPU{},
+		Rewind:          newRewind(),
 	}
 
 	gba.PPU.gba = &gba
 
-	gba.Debugger = Debugger{Gba: &gba, Version: 1}
-
 	gba.Irq = cpu.Irq{}
+
+	port, err := link.Open(config.Conf.Link.Listen, config.Conf.Link.Connect)
+	if err != nil {
+		log.Printf("Link: %v\n", err)
+	}
+	gba.Sio.Port = port
+
 	gba.Mem = NewMemory(&gba)
 	//gba.Cpu = arm7.NewCpu(config.Conf.Jit.Enabled, &gba.Mem, &gba.Irq)
 	gba.Cpu = arm7.NewCpu(false, gba.Mem, &gba.Irq)
 
+	gba.Debugger = debugger.New("arm7", gba.Cpu, gba.Mem, false)
+	gba.Frontends = debugger.Attach(config.Conf.General.Debugger, config.Conf.General.GdbPort, gba.Debugger)
+
 	gba.Timers[0].Gba = &gba
 	gba.Timers[1].Gba = &gba
 	gba.Timers[2].Gba = &gba
@@ -221,14 +289,42 @@ func (gba *GBA) TogglePause() bool {
 	return gba.Paused
 }
 
+// Screenshot returns a copy of the current frame
+func (gba *GBA) Screenshot() *image.RGBA {
+	return utils.NewScreenshot(SCREEN_WIDTH, SCREEN_HEIGHT, gba.Pixels)
+}
+
 func (gba *GBA) Close() {
 	gba.Muted = true
 	gba.Paused = true
 	gba.Apu.Close()
+	gba.StopCapture()
+
+	if config.Conf.General.Deterministic {
+		gba.Mem.FlushSave()
+	}
+
+	if gba.Sio.Port != nil {
+		gba.Sio.Port.Close()
+	}
+
+	for _, f := range gba.Frontends {
+		f.Close()
+	}
 }
 
 func (gba *GBA) LoadGame(path string) {
-	gba.Cartridge = cart.NewCartridge(path, path+".save")
+	game, err := rom.Load(path, config.Conf.General.Patches)
+	if err != nil {
+		panic(err)
+	}
+
+	gba.Cartridge = cart.NewCartridge(game)
+	gba.PatchErr = game.PatchErr
+	gba.Cheats = gba.newCheats()
+	gba.Deck = movie.NewDeck(gba, state.GBA, gba.Cartridge.RomChecksum)
+	gba.Cartridge.Gpio.Rtc.Now = rtcNow
+	gba.Cartridge.Gpio.Solar.Level = config.Conf.Gba.Solar.Level
 }
 
 // RidgeX/ygba BSD3
@@ -0,0 +1,77 @@
+package gba
+
+import (
+	"testing"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/config/file"
+	"github.com/aabalke/guac/emu/testroms"
+	"github.com/aabalke/guac/input"
+)
+
+var GBA_ROMS = []string{".gba"}
+
+func newTestGBA(t *testing.T, rom string) *GBA {
+	file.Decode()
+	config.Conf.General.Headless = true
+	config.Conf.General.RewindBufferSize = 0
+
+	gba := NewGBA(rom, nil)
+	t.Cleanup(gba.Close)
+
+	return gba
+}
+
+func TestJsmolka(t *testing.T) {
+	// https://github.com/jsmolka/gba-tests
+	testroms.Run(t, "jsmolka gba-tests", "gba-tests", GBA_ROMS, func(t *testing.T, rom string) testroms.Result {
+		gba := newTestGBA(t, rom)
+
+		idle := testroms.Idle{}
+
+		for range 60 * 10 {
+			gba.Update(false)
+
+			if idle.Sample(gba.Cpu.Reg.R[PC], 10) {
+				return testroms.Jsmolka(gba.Cpu.Reg.R[12])
+			}
+		}
+
+		return testroms.Result{Detail: "timed out"}
+	})
+}
+
+func TestMgbaSuite(t *testing.T) {
+	// https://github.com/mgba-emu/suite
+	testroms.Run(t, "mGBA Test Suite", "mgba-suite", GBA_ROMS, runScreenTest)
+}
+
+func TestArmwrestler(t *testing.T) {
+	// https://github.com/destoer/armwrestler-gba-fixed
+	testroms.Run(t, "ARMWrestler", "armwrestler-gba", GBA_ROMS, runScreenTest)
+}
+
+// runScreenTest plays the sidecar inputs of rom and compares the final screen
+// against its reference
+func runScreenTest(t *testing.T, rom string) testroms.Result {
+	s, err := testroms.LoadSidecar(rom, 60*10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gba := newTestGBA(t, rom)
+
+	for frame := 1; frame <= s.Frames; frame++ {
+		var b input.Buttons
+		if held := s.Buttons(frame); held != "" {
+			if b, err = input.ParseButtons(held); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		gba.SetButtons(b)
+		gba.Update(false)
+	}
+
+	return testroms.Reference(t, rom, gba.Screenshot())
+}
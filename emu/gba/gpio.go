@@ -0,0 +1,86 @@
+package gba
+
+import (
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/clock"
+	"github.com/aabalke/guac/emu/gba/cart"
+	"github.com/aabalke/guac/input"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// stick travel ignored by the gyro
+const GYRO_DEADZONE = 0.1
+
+// rtcNow is the host time the cart rtc runs on
+func rtcNow() time.Time {
+	return clock.Now().Add(time.Hour * time.Duration(config.Conf.Gba.Rtc.AdditionalHours))
+}
+
+// gpioInput feeds the host into the cart sensors and the rumble back out to
+// the gamepads. The left stick turns the gyro.
+func (gba *GBA) gpioInput(justKeys []ebiten.Key, justButtons []ebiten.StandardGamepadButton) {
+	gpio := &gba.Cartridge.Gpio
+
+	if gpio.Devices == 0 {
+		return
+	}
+
+	if gpio.Devices&cart.GPIO_SOLAR != 0 {
+		var (
+			keyCfg    = &config.Conf.Gba.KeyboardConfig
+			buttonCfg = &config.Conf.Gba.ControllerConfig
+			level     = &config.Conf.Gba.Solar.Level
+			prev      = *level
+		)
+
+		for _, key := range justKeys {
+			switch {
+			case slices.Contains(keyCfg.SolarUp, key):
+				*level++
+			case slices.Contains(keyCfg.SolarDown, key):
+				*level--
+			}
+		}
+
+		for _, button := range justButtons {
+			switch {
+			case slices.Contains(buttonCfg.SolarUp, button):
+				*level++
+			case slices.Contains(buttonCfg.SolarDown, button):
+				*level--
+			}
+		}
+
+		*level = max(0, min(*level, cart.SOLAR_MAX_LEVEL))
+		if *level != prev {
+			log.Printf("Solar Level %d\n", *level)
+		}
+
+		gpio.Solar.Level = *level
+	}
+
+	ids := ebiten.AppendGamepadIDs(nil)
+
+	if gpio.Devices&cart.GPIO_GYRO != 0 {
+		gpio.Gyro.Rate = 0
+
+		for _, id := range ids {
+			v := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+			if math.Abs(v) < GYRO_DEADZONE {
+				continue
+			}
+
+			gpio.Gyro.Rate = int(v * cart.GYRO_MAX_RATE)
+			break
+		}
+	}
+
+	if gpio.Devices&cart.GPIO_RUMBLE != 0 && gpio.Rumble.On {
+		input.Rumble()
+	}
+}
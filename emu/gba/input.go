@@ -1,69 +1,24 @@
 package gba
 
 import (
-	"slices"
-
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/input"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-func (gba *GBA) InputHandler(keys []ebiten.Key, buttons []ebiten.StandardGamepadButton) {
-	keyConfig := config.Conf.Gba.KeyboardConfig
-	buttonConfig := config.Conf.Gba.ControllerConfig
-
-	k := &gba.Keypad.KEYINPUT
-
-	*k = 0b11_1111_1111
+func (gba *GBA) InputHandler(justKeys, keys []ebiten.Key, justButtons, buttons []ebiten.StandardGamepadButton) {
+	gba.SetButtons(input.Held(keys, buttons,
+		&config.Conf.Gba.KeyboardConfig,
+		&config.Conf.Gba.ControllerConfig,
+	))
 
-	for _, key := range keys {
-		switch {
-		case slices.Contains(keyConfig.A, key):
-			*k &^= 0b1
-		case slices.Contains(keyConfig.B, key):
-			*k &^= 0b10
-		case slices.Contains(keyConfig.Select, key):
-			*k &^= 0b100
-		case slices.Contains(keyConfig.Start, key):
-			*k &^= 0b1000
-		case slices.Contains(keyConfig.Right, key):
-			*k &^= 0b10000
-		case slices.Contains(keyConfig.Left, key):
-			*k &^= 0b100000
-		case slices.Contains(keyConfig.Up, key):
-			*k &^= 0b1000000
-		case slices.Contains(keyConfig.Down, key):
-			*k &^= 0b10000000
-		case slices.Contains(keyConfig.R, key):
-			*k &^= 0b100000000
-		case slices.Contains(keyConfig.L, key):
-			*k &^= 0b1000000000
-		}
-	}
+	gba.gpioInput(justKeys, justButtons)
+}
 
-	for _, button := range buttons {
-		switch {
-		case slices.Contains(buttonConfig.A, button):
-			*k &^= 0b1
-		case slices.Contains(buttonConfig.B, button):
-			*k &^= 0b10
-		case slices.Contains(buttonConfig.Select, button):
-			*k &^= 0b100
-		case slices.Contains(buttonConfig.Start, button):
-			*k &^= 0b1000
-		case slices.Contains(buttonConfig.Right, button):
-			*k &^= 0b10000
-		case slices.Contains(buttonConfig.Left, button):
-			*k &^= 0b100000
-		case slices.Contains(buttonConfig.Up, button):
-			*k &^= 0b1000000
-		case slices.Contains(buttonConfig.Down, button):
-			*k &^= 0b10000000
-		case slices.Contains(buttonConfig.R, button):
-			*k &^= 0b100000000
-		case slices.Contains(buttonConfig.L, button):
-			*k &^= 0b1000000000
-		}
-	}
+// SetButtons sets the buttons held for the next frame
+func (gba *GBA) SetButtons(b input.Buttons) {
+	gba.buttons = b
+	gba.Keypad.KEYINPUT = 0b11_1111_1111 &^ uint16(b)
 
 	if gba.Keypad.keyIRQ() {
 		gba.Irq.SetIRQ(12)
@@ -7,11 +7,12 @@ import (
 	"unsafe"
 
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/debugger"
 )
 
 type Memory struct {
 	GBA   *GBA
-	BIOS  [0x4000]uint8
+	BIOS  [0x4000]uint8 `state:"-"`
 	WRAM1 [0x40000]uint8
 	WRAM2 [0x8000]uint8
 
@@ -43,24 +44,38 @@ func NewMemory(gba *GBA) *Memory {
 	return m
 }
 
+// SAVE_FRAMES is how often deterministic runs save, about the second of the
+// save loop
+const SAVE_FRAMES = 60
+
+// InitSaveLoop writes the save every second. Deterministic runs call
+// FlushSave between frames instead, so saves never race the emulation.
 func (m *Memory) InitSaveLoop() {
+	if config.Conf.General.Deterministic {
+		return
+	}
+
 	saveTicker := time.Tick(time.Second)
 
 	go func() {
 		for range saveTicker {
-
-			if config.Conf.General.DisableSaves {
-				continue
-			}
-
-			if m.GBA.Save {
-				m.GBA.Cartridge.Save()
-				m.GBA.Save = false
-			}
+			m.FlushSave()
 		}
 	}()
 }
 
+// FlushSave writes the save if it changed
+func (m *Memory) FlushSave() {
+	if config.Conf.General.DisableSaves {
+		return
+	}
+
+	if m.GBA.Save {
+		m.GBA.Cartridge.Save()
+		m.GBA.Save = false
+	}
+}
+
 func (m *Memory) initWriteRegions() {
 	for i := range len(m.writeRegions) {
 		m.writeRegions[i] = func(m *Memory, addr uint32, v uint8, byteWrite bool) {
@@ -138,6 +153,12 @@ func (m *Memory) initWriteRegions() {
 		m.GBA.PPU.UpdateOAM(rel)
 	}
 
+	for i := 0x8; i < 0xE; i++ {
+		m.writeRegions[i] = func(m *Memory, addr uint32, v uint8, byteWrite bool) {
+			m.GBA.Cartridge.WriteGpio(addr, v)
+		}
+	}
+
 	m.writeRegions[0xE] = func(m *Memory, addr uint32, v uint8, byteWrite bool) {
 		m.GBA.Save = true
 
@@ -215,6 +236,10 @@ func (m *Memory) initReadRegions() {
 
 	for i := 0x8; i < 0xE; i++ {
 		m.readRegions[i] = func(m *Memory, addr uint32) uint8 {
+			if m.GBA.Cartridge.GpioReadable(addr) {
+				return m.GBA.Cartridge.ReadGpio(addr)
+			}
+
 			return m.GBA.Cartridge.Rom[addr&0x1FFFFFF]
 		}
 	}
@@ -253,6 +278,11 @@ func (m *Memory) ReadPtr(addr uint32, _ bool) (unsafe.Pointer, bool) {
 		), true
 
 	case 0x8, 0x9, 0xA, 0xB, 0xC, 0xD:
+		// gpio reads are not rom
+		if m.GBA.Cartridge.GpioReadable(addr) {
+			return nil, false
+		}
+
 		return unsafe.Add(
 			unsafe.Pointer(&m.GBA.Cartridge.Rom), addr&0x1FF_FFFF,
 		), true
@@ -336,6 +366,9 @@ func (m *Memory) ReadIO(addr uint32) uint8 {
 		return m.ReadOpenBus(addr)
 	case addr >= 0x60 && addr < 0xB0:
 		return m.ReadSoundIO(addr)
+	case addr >= 0x120 && addr < 0x12C,
+		addr == 0x134 || addr == 0x135:
+		return m.GBA.Sio.Read(addr)
 	}
 
 	switch addr {
@@ -476,6 +509,10 @@ func (m *Memory) ReadIO(addr uint32) uint8 {
 }
 
 func (m *Memory) Read8(addr uint32, _ bool) uint32 {
+	if d := m.GBA.Debugger; d.Watching() {
+		d.Watch(addr, 1, debugger.READ)
+	}
+
 	if badRom := addr >= 0x800_0000 && addr < 0xE00_0000; badRom {
 		if addr&0x1FF_FFFF >= m.GBA.Cartridge.RomLength {
 			return m.ReadBadRom(addr, 1)
@@ -488,6 +525,10 @@ func (m *Memory) Read8(addr uint32, _ bool) uint32 {
 // Accessing SRAM Area by 16bit/32bit
 // Reading retrieves 8bit value from specified address, multiplied by 0101h (LDRH) or by 01010101h (LDR). Writing changes the 8bit value at the specified address only, being set to LSB of (source_data ROR (address*8)).
 func (m *Memory) Read16(addr uint32, _ bool) uint32 {
+	if d := m.GBA.Debugger; d.Watching() {
+		d.Watch(addr, 2, debugger.READ)
+	}
+
 	switch {
 	case addr >= 0xE00_0000:
 
@@ -521,6 +562,10 @@ func (m *Memory) Read16(addr uint32, _ bool) uint32 {
 }
 
 func (m *Memory) Read32(addr uint32, _ bool) uint32 {
+	if d := m.GBA.Debugger; d.Watching() {
+		d.Watch(addr, 4, debugger.READ)
+	}
+
 	switch {
 	case addr >= 0xE00_0000:
 
@@ -585,6 +630,11 @@ func (m *Memory) WriteIO(addr uint32, v uint8) {
 		return
 	}
 
+	if sio := addr >= 0x120 && addr < 0x12C || addr == 0x134 || addr == 0x135; sio {
+		m.GBA.Sio.Write(addr, v)
+		return
+	}
+
 	switch addr {
 	case 0x004:
 		m.Dispstat.Write(v, false)
@@ -820,10 +870,18 @@ func (m *Memory) WriteIO(addr uint32, v uint8) {
 }
 
 func (m *Memory) Write8(addr uint32, v uint8, _ bool) {
+	if d := m.GBA.Debugger; d.Watching() {
+		d.Watch(addr, 1, debugger.WRITE)
+	}
+
 	m.Write(addr, v, true)
 }
 
 func (m *Memory) Write16(addr uint32, v uint16, _ bool) {
+	if d := m.GBA.Debugger; d.Watching() {
+		d.Watch(addr, 2, debugger.WRITE)
+	}
+
 	switch {
 	case addr >= 0xE00_0000:
 		if addr&1 == 1 {
@@ -845,6 +903,10 @@ func (m *Memory) Write16(addr uint32, v uint16, _ bool) {
 }
 
 func (m *Memory) Write32(addr uint32, v uint32, _ bool) {
+	if d := m.GBA.Debugger; d.Watching() {
+		d.Watch(addr, 4, debugger.WRITE)
+	}
+
 	if sram := addr >= 0xE00_0000; sram {
 		is := (addr << 3) & 0x1F
 		v = bits.RotateLeft32(v, -int(is))
@@ -0,0 +1,30 @@
+package gba
+
+import (
+	"time"
+
+	"github.com/aabalke/guac/emu/movie"
+	"github.com/aabalke/guac/input"
+)
+
+// FRAME_TIME is the emulated time of a frame, a seeded clock moves by it
+const FRAME_TIME = time.Second * 280896 / 16777216
+
+// Input is what is held for the next frame, the gyro and sunlight included
+func (gba *GBA) Input() movie.Frame {
+	gpio := &gba.Cartridge.Gpio
+
+	return movie.Frame{
+		Buttons: uint16(gba.buttons),
+		Gyro:    int16(gpio.Gyro.Rate),
+		Solar:   uint8(gpio.Solar.Level),
+	}
+}
+
+func (gba *GBA) SetInput(f movie.Frame) {
+	gba.SetButtons(input.Buttons(f.Buttons))
+
+	gpio := &gba.Cartridge.Gpio
+	gpio.Gyro.Rate = int(f.Gyro)
+	gpio.Solar.Level = int(f.Solar)
+}
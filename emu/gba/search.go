@@ -0,0 +1,10 @@
+package gba
+
+import "github.com/aabalke/guac/emu/search"
+
+func (gba *GBA) RamRegions() []search.Region {
+	return []search.Region{
+		{Name: "ewram", Base: 0x200_0000, Data: gba.Mem.WRAM1[:]},
+		{Name: "iwram", Base: 0x300_0000, Data: gba.Mem.WRAM2[:]},
+	}
+}
@@ -0,0 +1,404 @@
+package gba
+
+import (
+	"github.com/aabalke/guac/emu/cpu"
+	"github.com/aabalke/guac/emu/link"
+)
+
+const (
+	SIO_NORMAL8 = iota
+	SIO_NORMAL32
+	SIO_MULTI
+	SIO_UART
+	SIO_GPIO // rcnt takes over, also joy bus
+)
+
+const (
+	SIOCNT_INTERNAL = 1 << 0
+	SIOCNT_2MHZ     = 1 << 1
+	SIOCNT_SI       = 1 << 2
+	SIOCNT_SD       = 1 << 3
+	SIOCNT_START    = 1 << 7
+	SIOCNT_IRQ      = 1 << 14
+
+	// multiplayer
+	SIOCNT_ERROR = 1 << 6
+
+	// uart
+	SIOCNT_SEND_FULL     = 1 << 4
+	SIOCNT_RECV_EMPTY    = 1 << 5
+	SIOCNT_FIFO          = 1 << 8
+	SIOCNT_SEND_ENABLE   = 1 << 10
+	SIOCNT_RECV_ENABLE   = 1 << 11
+	SIOCNT_UART_READONLY = SIOCNT_SEND_FULL | SIOCNT_RECV_EMPTY | SIOCNT_ERROR
+)
+
+const (
+	SIO_CPU_FREQ = 16777216
+
+	// normal mode cycles per bit at 256khz and 2mhz
+	SIO_CYCLES_SLOW = SIO_CPU_FREQ / 262144
+	SIO_CYCLES_FAST = SIO_CPU_FREQ / 2097152
+
+	// multiplayer sends a start bit, 16 data bits and a stop bit per
+	// console, uart a start bit, 8 data bits and a stop bit
+	SIO_MULTI_BITS = 18
+	SIO_UART_BITS  = 10
+
+	SIO_UART_FIFO = 4
+
+	// how often transfers clocked by the peer are checked for
+	SIO_POLL_CYCLES = 1024
+)
+
+var SIO_BAUD = [4]uint32{9600, 38400, 57600, 115200}
+
+// Sio is the serial port. Transfers clocked by this console finish after
+// Cycles, ones clocked by the peer are polled from the Port. The console
+// listening for links is the multiplayer parent.
+//
+// Data is SIOMULTI0 - 3, SIODATA32 is Data[0] and Data[1]. Send is
+// SIOMLT_SEND, SIODATA8 is its low byte.
+type Sio struct {
+	Cnt  uint16
+	Rcnt uint16
+	Data [4]uint16
+	Send uint16
+
+	Cycles     uint32 // until the running transfer finishes, 0 when idle
+	PollCycles uint32
+	pending    bool // start written, begins once both bytes of cnt are
+
+	Recv    [SIO_UART_FIFO]uint8
+	RecvLen int
+
+	Port *link.Port
+}
+
+func (s *Sio) mode() int {
+	if s.Rcnt&0x8000 != 0 {
+		return SIO_GPIO
+	}
+
+	return int(s.Cnt>>12) & 3
+}
+
+func (s *Sio) baudCycles() uint32 {
+	return SIO_CPU_FREQ / SIO_BAUD[s.Cnt&3]
+}
+
+func (s *Sio) Read(addr uint32) uint8 {
+	switch addr {
+	case 0x128:
+		return uint8(s.readCnt())
+	case 0x129:
+		return uint8(s.readCnt() >> 8)
+	case 0x12A:
+		if s.mode() == SIO_UART {
+			return s.pop()
+		}
+		return uint8(s.Send)
+	case 0x12B:
+		return uint8(s.Send >> 8)
+	case 0x134:
+		return uint8(s.Rcnt)
+	case 0x135:
+		return uint8(s.Rcnt >> 8)
+	}
+
+	// 0x120 - 0x127
+	i := (addr - 0x120) >> 1
+	return uint8(s.Data[i] >> ((addr & 1) << 3))
+}
+
+func (s *Sio) Write(addr uint32, v uint8) {
+	switch addr {
+	case 0x128:
+		s.writeCnt(v, false)
+	case 0x129:
+		s.writeCnt(v, true)
+	case 0x12A:
+		s.Send = s.Send&0xFF00 | uint16(v)
+
+		if s.mode() == SIO_UART && s.Cnt&SIOCNT_SEND_ENABLE != 0 && s.Cycles == 0 {
+			s.Port.Send(v)
+			s.Cnt |= SIOCNT_SEND_FULL
+			s.Cycles = s.baudCycles() * SIO_UART_BITS
+		}
+
+		s.Port.SetMulti(s.Send)
+	case 0x12B:
+		s.Send = s.Send&0xFF | uint16(v)<<8
+		s.Port.SetMulti(s.Send)
+	case 0x134:
+		s.Rcnt = s.Rcnt&0xFF00 | uint16(v)
+	case 0x135:
+		s.Rcnt = s.Rcnt&0xFF | uint16(v)<<8
+		s.ready()
+	default:
+		// 0x120 - 0x127
+		i := (addr - 0x120) >> 1
+		shift := (addr & 1) << 3
+		s.Data[i] = s.Data[i]&^(0xFF<<shift) | uint16(v)<<shift
+	}
+}
+
+func (s *Sio) readCnt() uint16 {
+	cnt := s.Cnt
+
+	switch s.mode() {
+	case SIO_MULTI:
+		cnt &^= SIOCNT_SI | SIOCNT_SD
+
+		if !s.Port.Host() {
+			cnt |= SIOCNT_SI
+		}
+
+		if s.Port.Connected() {
+			cnt |= SIOCNT_SD
+		}
+
+	case SIO_UART:
+		cnt &^= SIOCNT_RECV_EMPTY
+		if s.RecvLen == 0 {
+			cnt |= SIOCNT_RECV_EMPTY
+		}
+	}
+
+	return cnt
+}
+
+func (s *Sio) writeCnt(v uint8, hi bool) {
+	if hi {
+		s.Cnt = s.Cnt&0xFF | uint16(v)<<8
+		s.ready()
+		return
+	}
+
+	old := s.Cnt
+	s.Cnt = s.Cnt&0xFF00 | uint16(v)
+
+	switch s.mode() {
+	case SIO_MULTI:
+		// id and terminals are read only
+		s.Cnt = s.Cnt&^0x3C | old&0x3C
+
+		// a transfer can not be stopped, only the parent starts one
+		if old&SIOCNT_START != 0 {
+			s.Cnt |= SIOCNT_START
+		}
+
+	case SIO_UART:
+		s.Cnt = s.Cnt&^SIOCNT_UART_READONLY | old&SIOCNT_UART_READONLY
+	}
+
+	if v&SIOCNT_START != 0 && old&SIOCNT_START == 0 {
+		s.pending = true
+	}
+
+	s.ready()
+}
+
+// ready tells the peers if this console takes their clock
+func (s *Sio) ready() {
+	if s.Port == nil {
+		return
+	}
+
+	switch s.mode() {
+	case SIO_NORMAL8, SIO_NORMAL32:
+		s.Port.Ready(s.Cnt&SIOCNT_INTERNAL == 0 && s.Cnt&SIOCNT_START != 0)
+	case SIO_MULTI:
+		s.Port.Ready(!s.Port.Host())
+		s.Port.SetMulti(s.Send)
+	default:
+		s.Port.Ready(false)
+	}
+}
+
+func (s *Sio) Tick(gba *GBA, cycles uint32) {
+	if s.pending {
+		s.pending = false
+		s.start()
+	}
+
+	if s.Port != nil {
+		s.PollCycles += cycles
+		if s.PollCycles >= SIO_POLL_CYCLES {
+			s.PollCycles = 0
+			s.poll(gba)
+		}
+	}
+
+	if s.Cycles == 0 {
+		return
+	}
+
+	if s.Cycles > cycles {
+		s.Cycles -= cycles
+		return
+	}
+
+	s.Cycles = 0
+	if !s.finish(gba) {
+		s.Cycles = SIO_POLL_CYCLES
+	}
+}
+
+// start begins a transfer this console clocks
+func (s *Sio) start() {
+	if s.Cycles != 0 {
+		return
+	}
+
+	switch s.mode() {
+	case SIO_NORMAL8, SIO_NORMAL32:
+		if s.Cnt&SIOCNT_INTERNAL == 0 {
+			return
+		}
+
+		bitCycles := uint32(SIO_CYCLES_SLOW)
+		if s.Cnt&SIOCNT_2MHZ != 0 {
+			bitCycles = SIO_CYCLES_FAST
+		}
+
+		if s.mode() == SIO_NORMAL8 {
+			s.Port.Transfer(uint32(s.Send & 0xFF))
+			s.Cycles = bitCycles * 8
+			return
+		}
+
+		s.Port.Transfer(uint32(s.Data[0]) | uint32(s.Data[1])<<16)
+		s.Cycles = bitCycles * 32
+
+	case SIO_MULTI:
+		if !s.Port.Host() {
+			s.Cnt &^= SIOCNT_START
+			return
+		}
+
+		s.Port.MultiTransfer(s.Send)
+		s.Cycles = s.baudCycles() * SIO_MULTI_BITS * uint32(s.Port.Players())
+	}
+}
+
+// finish completes a transfer this console clocks. It reports false while
+// the peers have not replied, the transfer is held and finished later.
+func (s *Sio) finish(gba *GBA) bool {
+	switch s.mode() {
+	case SIO_NORMAL8, SIO_NORMAL32:
+		v, ok := s.Port.Reply()
+		if !ok {
+			return false
+		}
+
+		if s.mode() == SIO_NORMAL8 {
+			s.Send = s.Send&0xFF00 | uint16(v&0xFF)
+		} else {
+			s.Data[0], s.Data[1] = uint16(v), uint16(v>>16)
+		}
+	case SIO_MULTI:
+		data, ok := s.Port.MultiReply()
+		if !ok {
+			return false
+		}
+
+		s.Data = data
+		s.Data[0] = s.Send
+		s.Cnt &^= 0x30 | SIOCNT_ERROR
+	case SIO_UART:
+		s.Cnt &^= SIOCNT_SEND_FULL
+	}
+
+	s.Cnt &^= SIOCNT_START
+	s.irq(gba)
+
+	return true
+}
+
+// poll takes transfers clocked by the peer
+func (s *Sio) poll(gba *GBA) {
+	switch s.mode() {
+	case SIO_NORMAL8, SIO_NORMAL32:
+		if s.Cnt&SIOCNT_INTERNAL != 0 || s.Cnt&SIOCNT_START == 0 {
+			return
+		}
+
+		out := uint32(s.Data[0]) | uint32(s.Data[1])<<16
+		if s.mode() == SIO_NORMAL8 {
+			out = uint32(s.Send & 0xFF)
+		}
+
+		v, ok := s.Port.Clocked(out)
+		if !ok {
+			return
+		}
+
+		if s.mode() == SIO_NORMAL8 {
+			s.Send = s.Send&0xFF00 | uint16(v&0xFF)
+		} else {
+			s.Data[0], s.Data[1] = uint16(v), uint16(v>>16)
+		}
+
+		s.Cnt &^= SIOCNT_START
+		s.Port.Ready(false)
+		s.irq(gba)
+
+	case SIO_MULTI:
+		data, ok := s.Port.MultiClocked()
+		if !ok {
+			return
+		}
+
+		s.Data = data
+		s.Cnt = s.Cnt&^(0x30|SIOCNT_START|SIOCNT_ERROR) | uint16(s.Port.ID()&3)<<4
+		s.irq(gba)
+
+	case SIO_UART:
+		if s.Cnt&SIOCNT_RECV_ENABLE == 0 {
+			return
+		}
+
+		size := 1
+		if s.Cnt&SIOCNT_FIFO != 0 {
+			size = SIO_UART_FIFO
+		}
+
+		received := false
+		for s.RecvLen < size {
+			v, ok := s.Port.Receive()
+			if !ok {
+				break
+			}
+
+			s.Recv[s.RecvLen] = v
+			s.RecvLen++
+			received = true
+		}
+
+		if received {
+			s.irq(gba)
+		}
+	}
+}
+
+// pop reads the uart receive fifo
+func (s *Sio) pop() uint8 {
+	if s.RecvLen == 0 {
+		return uint8(s.Send)
+	}
+
+	v := s.Recv[0]
+	copy(s.Recv[:], s.Recv[1:s.RecvLen])
+	s.RecvLen--
+
+	s.Send = s.Send&0xFF00 | uint16(v)
+
+	return v
+}
+
+func (s *Sio) irq(gba *GBA) {
+	if s.Cnt&SIOCNT_IRQ != 0 {
+		gba.Irq.SetIRQ(cpu.IRQ_SIO)
+	}
+}
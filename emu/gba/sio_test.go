@@ -0,0 +1,214 @@
+package gba
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aabalke/guac/emu/cpu"
+	"github.com/aabalke/guac/emu/link"
+)
+
+// sioNode is a console with only a serial port, enough for Tick
+type sioNode struct {
+	gba GBA
+	sio Sio
+}
+
+func linked(t *testing.T) (*sioNode, *sioNode) {
+	host, guest := link.Loopback()
+	t.Cleanup(func() {
+		host.Close()
+		guest.Close()
+	})
+
+	a, b := &sioNode{sio: Sio{Port: host}}, &sioNode{sio: Sio{Port: guest}}
+
+	for !host.Connected() || !guest.Connected() {
+		time.Sleep(time.Millisecond)
+	}
+
+	return a, b
+}
+
+func writeCnt(s *Sio, cnt uint16) {
+	s.Write(0x129, uint8(cnt>>8))
+	s.Write(0x128, uint8(cnt))
+}
+
+func readCnt(s *Sio) uint16 {
+	return uint16(s.Read(0x128)) | uint16(s.Read(0x129))<<8
+}
+
+// tick runs the nodes until done, the peers take their messages between
+// ticks
+func tick(t *testing.T, done func() bool, nodes ...*sioNode) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("transfer did not finish")
+		}
+
+		for _, n := range nodes {
+			n.sio.Tick(&n.gba, 0x100)
+		}
+
+		time.Sleep(time.Microsecond)
+	}
+}
+
+func irqRaised(n *sioNode) bool {
+	return n.gba.Irq.IF&(1<<cpu.IRQ_SIO) != 0
+}
+
+func TestSioUnplugged(t *testing.T) {
+	n := &sioNode{}
+	n.sio.Send = 0x12
+
+	writeCnt(&n.sio, SIOCNT_IRQ|SIOCNT_START|SIOCNT_INTERNAL)
+
+	// the transfer begins on the next tick and runs 8 bits at 256khz
+	n.sio.Tick(&n.gba, 1)
+	if n.sio.Cycles != SIO_CYCLES_SLOW*8-1 {
+		t.Fatalf("transfer takes %d more cycles", n.sio.Cycles)
+	}
+
+	n.sio.Tick(&n.gba, SIO_CYCLES_SLOW*8-2)
+	if readCnt(&n.sio)&SIOCNT_START == 0 || irqRaised(n) {
+		t.Fatal("transfer finished early")
+	}
+
+	n.sio.Tick(&n.gba, 1)
+
+	// nothing on the other end reads back all ones
+	if cnt := readCnt(&n.sio); cnt&SIOCNT_START != 0 || n.sio.Read(0x12A) != 0xFF || !irqRaised(n) {
+		t.Fatalf("cnt %04X, data %02X, if %04X", cnt, n.sio.Read(0x12A), n.gba.Irq.IF)
+	}
+}
+
+func TestSioNormal(t *testing.T) {
+	a, b := linked(t)
+
+	a.sio.Write(0x12A, 0x12)
+	b.sio.Write(0x12A, 0x34)
+
+	// the child waits on the external clock with start set
+	writeCnt(&b.sio, SIOCNT_IRQ|SIOCNT_START)
+	writeCnt(&a.sio, SIOCNT_IRQ|SIOCNT_START|SIOCNT_INTERNAL|SIOCNT_2MHZ)
+
+	tick(t, func() bool {
+		return readCnt(&a.sio)&SIOCNT_START == 0 && readCnt(&b.sio)&SIOCNT_START == 0
+	}, a, b)
+
+	if a.sio.Read(0x12A) != 0x34 || b.sio.Read(0x12A) != 0x12 {
+		t.Fatalf("parent got %02X, child got %02X", a.sio.Read(0x12A), b.sio.Read(0x12A))
+	}
+
+	if !irqRaised(a) || !irqRaised(b) {
+		t.Fatalf("irq parent %t, child %t", irqRaised(a), irqRaised(b))
+	}
+}
+
+func TestSioMulti(t *testing.T) {
+	a, b := linked(t)
+
+	const multi = SIO_MULTI << 12
+
+	writeCnt(&a.sio, multi|SIOCNT_IRQ)
+	writeCnt(&b.sio, multi|SIOCNT_IRQ)
+
+	// si is low on the parent only, sd is high once a cable is in
+	if cnt := readCnt(&a.sio); cnt&(SIOCNT_SI|SIOCNT_SD) != SIOCNT_SD {
+		t.Fatalf("parent cnt %04X", cnt)
+	}
+
+	if cnt := readCnt(&b.sio); cnt&(SIOCNT_SI|SIOCNT_SD) != SIOCNT_SI|SIOCNT_SD {
+		t.Fatalf("child cnt %04X", cnt)
+	}
+
+	a.sio.Write(0x12A, 0x11)
+	a.sio.Write(0x12B, 0x11)
+	b.sio.Write(0x12A, 0x22)
+	b.sio.Write(0x12B, 0x22)
+
+	// only the parent starts a transfer
+	writeCnt(&b.sio, multi|SIOCNT_IRQ|SIOCNT_START)
+	b.sio.Tick(&b.gba, 1)
+	if readCnt(&b.sio)&SIOCNT_START != 0 {
+		t.Fatal("child started a transfer")
+	}
+
+	writeCnt(&a.sio, multi|SIOCNT_IRQ|SIOCNT_START)
+
+	// a transfer can not be stopped
+	a.sio.Write(0x128, 0)
+	if readCnt(&a.sio)&SIOCNT_START == 0 {
+		t.Fatal("start cleared during a transfer")
+	}
+
+	tick(t, func() bool {
+		return readCnt(&a.sio)&SIOCNT_START == 0 && irqRaised(b)
+	}, a, b)
+
+	want := [4]uint16{0x1111, 0x2222, link.MULTI_DISCONNECTED, link.MULTI_DISCONNECTED}
+	if a.sio.Data != want || b.sio.Data != want {
+		t.Fatalf("parent got % X, child got % X", a.sio.Data, b.sio.Data)
+	}
+
+	// the child reads its id from cnt
+	if id := readCnt(&b.sio) >> 4 & 3; id != 1 {
+		t.Fatalf("child id %d", id)
+	}
+
+	// and can not write it
+	b.sio.Write(0x128, 0)
+	if id := readCnt(&b.sio) >> 4 & 3; id != 1 {
+		t.Fatalf("child id written to %d", id)
+	}
+}
+
+func TestSioUart(t *testing.T) {
+	a, b := linked(t)
+
+	const uart = SIO_UART << 12
+
+	writeCnt(&a.sio, uart|SIOCNT_SEND_ENABLE)
+	writeCnt(&b.sio, uart|SIOCNT_IRQ|SIOCNT_RECV_ENABLE|SIOCNT_FIFO)
+
+	if cnt := readCnt(&b.sio); cnt&SIOCNT_RECV_EMPTY == 0 {
+		t.Fatalf("child cnt %04X with nothing received", cnt)
+	}
+
+	// the flags are read only
+	writeCnt(&b.sio, uart|SIOCNT_IRQ|SIOCNT_RECV_ENABLE|SIOCNT_FIFO|SIOCNT_SEND_FULL)
+	if readCnt(&b.sio)&SIOCNT_SEND_FULL != 0 {
+		t.Fatal("send full written")
+	}
+
+	// the send flag holds until the byte is out
+	a.sio.Write(0x12A, 0x5A)
+	if readCnt(&a.sio)&SIOCNT_SEND_FULL == 0 {
+		t.Fatal("send not full after a write")
+	}
+
+	tick(t, func() bool {
+		return readCnt(&a.sio)&SIOCNT_SEND_FULL == 0
+	}, a)
+
+	a.sio.Write(0x12A, 0xA5)
+
+	tick(t, func() bool { return b.sio.RecvLen == 2 }, a, b)
+
+	if !irqRaised(b) || readCnt(&b.sio)&SIOCNT_RECV_EMPTY != 0 {
+		t.Fatalf("child cnt %04X, irq %t", readCnt(&b.sio), irqRaised(b))
+	}
+
+	if v0, v1 := b.sio.Read(0x12A), b.sio.Read(0x12A); v0 != 0x5A || v1 != 0xA5 {
+		t.Fatalf("child received %02X %02X", v0, v1)
+	}
+
+	if readCnt(&b.sio)&SIOCNT_RECV_EMPTY == 0 {
+		t.Fatal("fifo not empty once read")
+	}
+}
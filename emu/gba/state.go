@@ -0,0 +1,97 @@
+package gba
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/state"
+)
+
+func (gba *GBA) stateHeader() state.Header {
+	return state.Header{
+		Console:  state.GBA,
+		Checksum: gba.Cartridge.RomChecksum(),
+		Title:    gba.Cartridge.Header.Title,
+		Frame:    gba.Frame,
+	}
+}
+
+// SaveState writes the full console state, rom and bios are not included
+func (gba *GBA) SaveState(w io.Writer) error {
+	if err := state.Save(w, gba.stateHeader(), gba.saveChunks); err != nil {
+		return fmt.Errorf("gba save state: %w", err)
+	}
+
+	return nil
+}
+
+// LoadState restores a state written by SaveState. States from other roms
+// are refused. The console should not be used if an error is returned after
+// the header was accepted, it may be partially loaded.
+func (gba *GBA) LoadState(r io.Reader) error {
+	if _, err := state.Load(r, gba.stateHeader(), gba.loadChunks); err != nil {
+		return fmt.Errorf("gba load state: %w", err)
+	}
+
+	return nil
+}
+
+func (gba *GBA) saveChunks(e *state.Encoder) {
+	e.Chunk("cpu")
+	gba.Cpu.SaveState(e)
+	e.Chunk("gba")
+	e.Encode(gba, gba.Mem, gba.PPU)
+	e.Chunk("cart")
+	gba.Cartridge.SaveState(e)
+	e.Chunk("apu")
+	e.Encode(gba.Apu)
+}
+
+func (gba *GBA) loadChunks(d *state.Decoder) {
+	d.Chunk("cpu")
+	gba.Cpu.LoadState(d)
+	d.Chunk("gba")
+	d.Decode(gba, gba.Mem, gba.PPU)
+	d.Chunk("cart")
+	gba.Cartridge.LoadState(d)
+	d.Chunk("apu")
+	d.Decode(gba.Apu)
+
+	// the sound ring moved, a capture goes on from here
+	gba.Capture.SkipSound(gba.Apu.WritePointer)
+}
+
+func newRewind() *state.Rewind {
+	c := &config.Conf.General
+
+	if c.Headless || c.RewindBufferSize <= 0 {
+		return nil
+	}
+
+	return state.NewRewind(c.RewindBufferSize<<20, c.RewindInterval)
+}
+
+func (gba *GBA) captureRewind() {
+	if gba.Rewind == nil {
+		return
+	}
+
+	if err := gba.Rewind.Capture(gba.saveChunks); err != nil {
+		log.Printf("Rewind Disabled: %v\n", err)
+		gba.Rewind = nil
+	}
+}
+
+// rewindStep runs in place of a frame while rewinding, each call goes back
+// one snapshot
+func (gba *GBA) rewindStep() {
+	if _, err := gba.Rewind.Step(gba.loadChunks); err != nil {
+		log.Printf("Rewind Disabled: %v\n", err)
+		gba.Rewind = nil
+		return
+	}
+
+	gba.Image.WritePixels(gba.Pixels)
+}
@@ -0,0 +1,546 @@
+// link carries serial transfers between consoles over a socket.
+//
+// A Port is one end of the cable. The end driving the clock calls Transfer
+// and then polls Reply once the transfer is due to finish, the transfer is
+// held by the core until the reply arrives. The other end has its data
+// Ready and takes clocked transfers from its core with Clocked, which sends
+// the reply back. A Port without a peer, or a peer that is not ready,
+// shifts in all ones like an unplugged cable.
+//
+// The listening Port is the host and accepts up to 3 peers, numbered from 1
+// in the order they connect. The host is the parent of gba multiplayer
+// transfers, which collect a half word from every console, see MultiTransfer.
+//
+// The cable also carries the infrared led of carts like the huc1, see SetLed.
+//
+// Addresses are tcp "host:port", or "unix:path" for a unix socket.
+package link
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// longest a transfer waits for the peer, a slow peer reads as unplugged.
+	// Reply and MultiReply never block on it, they are polled until then
+	TIMEOUT = 100 * time.Millisecond
+
+	DISCONNECTED       = 0xFFFF_FFFF
+	MULTI_DISCONNECTED = 0xFFFF
+
+	MAX_PLAYERS = 4
+)
+
+type Port struct {
+	mu    sync.Mutex
+	peers [MAX_PLAYERS]*peer // the host's peers by id, a peer's host at 0
+	ln    net.Listener
+	id    atomic.Int32
+
+	ready    atomic.Bool
+	multiOut atomic.Uint32
+	led      atomic.Bool
+	light    atomic.Bool // a peer's led is on
+
+	replies chan message
+	clocked chan message
+	results chan message
+	uart    chan uint8
+
+	seq      uint8
+	deadline time.Time // the running transfer reads as unplugged after it
+
+	multiData    [MAX_PLAYERS]uint16 // half words of the running multiplayer transfer
+	multiWaiting int                 // peers that have not sent theirs
+}
+
+func NewPort() *Port {
+	p := &Port{
+		replies: make(chan message, 16),
+		clocked: make(chan message, 16),
+		results: make(chan message, 16),
+		uart:    make(chan uint8, 64),
+	}
+
+	p.multiOut.Store(MULTI_DISCONNECTED)
+
+	return p
+}
+
+// Open returns a port listening on listen or connected to connect, nil
+// without either
+func Open(listen, connect string) (*Port, error) {
+	var err error
+
+	p := NewPort()
+
+	switch {
+	case listen != "":
+		err = p.Listen(listen)
+	case connect != "":
+		err = p.Dial(connect)
+	default:
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Loopback returns both ends of an in process cable, the first is the host
+func Loopback() (*Port, *Port) {
+	a, b := net.Pipe()
+
+	host, guest := NewPort(), NewPort()
+	host.accept(a)
+	guest.attach(b)
+
+	return host, guest
+}
+
+func split(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return "unix", path
+	}
+
+	return "tcp", addr
+}
+
+func Dial(addr string) (net.Conn, error) {
+	return net.Dial(split(addr))
+}
+
+func Listen(addr string) (net.Listener, error) {
+	network, address := split(addr)
+
+	// a socket left behind by a previous run
+	if fi, err := os.Stat(address); network == "unix" && err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(address)
+	}
+
+	return net.Listen(network, address)
+}
+
+func (p *Port) Dial(addr string) error {
+	conn, err := Dial(addr)
+	if err != nil {
+		return err
+	}
+
+	p.attach(conn)
+	log.Printf("Link: connected to %s\n", addr)
+
+	return nil
+}
+
+// Listen accepts peers on addr in the background
+func (p *Port) Listen(addr string) error {
+	ln, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.ln = ln
+	p.mu.Unlock()
+
+	log.Printf("Link: listening on %s\n", ln.Addr())
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			p.accept(conn)
+		}
+	}()
+
+	return nil
+}
+
+// accept adds a peer to the host with the lowest free id
+func (p *Port) accept(conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id := 1; id < MAX_PLAYERS; id++ {
+		if p.peers[id] != nil {
+			continue
+		}
+
+		p.peers[id] = newPeer(conn, id, p.handle, p.disconnect)
+		p.peers[id].send(message{kind: MSG_HELLO, data: uint64(id)})
+		return
+	}
+
+	log.Printf("Link: refused %s, cable is full\n", conn.RemoteAddr())
+	conn.Close()
+}
+
+// attach connects a peer to its host
+func (p *Port) attach(conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.peers[0] != nil {
+		p.peers[0].close()
+	}
+
+	p.peers[0] = newPeer(conn, 0, p.handle, p.disconnect)
+}
+
+func (p *Port) disconnect(pr *peer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.peers[pr.id] == pr {
+		p.peers[pr.id] = nil
+		p.light.Store(false)
+	}
+}
+
+// ID is 0 for the host and unconnected ports, 1 - 3 for peers
+func (p *Port) ID() int {
+	if p == nil {
+		return 0
+	}
+
+	return int(p.id.Load())
+}
+
+// Host reports if the port is the parent of multiplayer transfers
+func (p *Port) Host() bool {
+	if p == nil {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.peers[0] == nil
+}
+
+// Players counts the consoles on the cable including this one. A peer only
+// knows its own id, the host does not tell it about the others.
+func (p *Port) Players() int {
+	if p == nil {
+		return 1
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.peers[0] != nil {
+		return max(2, p.ID()+1)
+	}
+
+	n := 1
+	for _, pr := range p.peers[1:] {
+		if pr != nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (p *Port) Connected() bool {
+	return p.Players() > 1
+}
+
+func (p *Port) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	if p.ln != nil {
+		err = p.ln.Close()
+	}
+
+	for i, pr := range p.peers {
+		if pr != nil {
+			err = errors.Join(err, pr.close())
+			p.peers[i] = nil
+		}
+	}
+
+	return err
+}
+
+// send writes to every peer
+func (p *Port) send(msg message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, pr := range p.peers {
+		if pr != nil {
+			pr.send(msg)
+		}
+	}
+}
+
+// handle runs on the reader goroutine of pr
+func (p *Port) handle(pr *peer, msg message) {
+	switch msg.kind {
+	case MSG_HELLO:
+		p.id.Store(int32(msg.data))
+
+	case MSG_TRANSFER:
+		// nothing shifts without the receiving end ready, same as a
+		// real cable the clock end reads all ones
+		if !p.ready.Load() {
+			pr.send(message{kind: MSG_REPLY, seq: msg.seq, data: DISCONNECTED})
+			return
+		}
+
+		msg.from = pr
+
+		// a core that stopped taking transfers, paused or closed, reads
+		// as unplugged rather than holding up the peer's other messages
+		select {
+		case p.clocked <- msg:
+		default:
+			pr.send(message{kind: MSG_REPLY, seq: msg.seq, data: DISCONNECTED})
+		}
+
+	case MSG_REPLY, MSG_MULTI_REPLY:
+		msg.from = pr
+
+		select {
+		case p.replies <- msg:
+		default:
+		}
+
+	case MSG_MULTI_START:
+		// children send their half word without the cpu
+		out := uint64(MULTI_DISCONNECTED)
+		if p.ready.Load() {
+			out = uint64(p.multiOut.Load())
+		}
+
+		pr.send(message{kind: MSG_MULTI_REPLY, seq: msg.seq, data: out})
+
+	case MSG_MULTI_RESULT:
+		if !p.ready.Load() {
+			return
+		}
+
+		select {
+		case p.results <- msg:
+		default:
+		}
+
+	case MSG_IR:
+		p.light.Store(msg.data != 0)
+
+	case MSG_UART:
+		select {
+		case p.uart <- uint8(msg.data):
+		default:
+			// overrun, the receiver lost the byte
+		}
+	}
+}
+
+// Transfer starts clocking v out to the peer
+func (p *Port) Transfer(v uint32) {
+	if p == nil {
+		return
+	}
+
+	p.seq++
+	p.deadline = time.Now().Add(TIMEOUT)
+	p.send(message{kind: MSG_TRANSFER, seq: p.seq, data: uint64(v)})
+}
+
+// Reply returns the peer's data for the last Transfer. It reports false
+// while the reply has not arrived yet, the core polls it again later.
+func (p *Port) Reply() (uint32, bool) {
+	if p == nil || !p.Connected() {
+		return DISCONNECTED, true
+	}
+
+	for {
+		select {
+		case msg := <-p.replies:
+			// replies to transfers which already timed out
+			if msg.kind != MSG_REPLY || msg.seq != p.seq {
+				continue
+			}
+
+			return uint32(msg.data), true
+
+		default:
+			if time.Now().After(p.deadline) {
+				return DISCONNECTED, true
+			}
+
+			return 0, false
+		}
+	}
+}
+
+// Ready sets if the port is waiting for the peer's clock
+func (p *Port) Ready(ready bool) {
+	if p == nil {
+		return
+	}
+
+	p.ready.Store(ready)
+}
+
+// Clocked returns data the peer clocked in and replies with out
+func (p *Port) Clocked(out uint32) (uint32, bool) {
+	if p == nil {
+		return 0, false
+	}
+
+	select {
+	case msg := <-p.clocked:
+		msg.from.send(message{kind: MSG_REPLY, seq: msg.seq, data: uint64(out)})
+		return uint32(msg.data), true
+	default:
+		return 0, false
+	}
+}
+
+// MultiTransfer starts a multiplayer transfer of the parent's half word,
+// only the host is the parent
+func (p *Port) MultiTransfer(v uint16) {
+	if p == nil {
+		return
+	}
+
+	p.seq++
+	p.deadline = time.Now().Add(TIMEOUT)
+	p.multiData = [MAX_PLAYERS]uint16{v, MULTI_DISCONNECTED, MULTI_DISCONNECTED, MULTI_DISCONNECTED}
+	p.multiWaiting = p.Players() - 1
+	p.send(message{kind: MSG_MULTI_START, seq: p.seq, data: uint64(v)})
+}
+
+// MultiReply collects the half words of every peer of the last
+// MultiTransfer and sends them all to the peers. It reports false while a
+// peer has not sent its half word yet, the core polls it again later.
+// Missing consoles read MULTI_DISCONNECTED.
+func (p *Port) MultiReply() ([MAX_PLAYERS]uint16, bool) {
+	if p == nil {
+		return [MAX_PLAYERS]uint16{MULTI_DISCONNECTED, MULTI_DISCONNECTED, MULTI_DISCONNECTED, MULTI_DISCONNECTED}, true
+	}
+
+	for p.multiWaiting > 0 {
+		select {
+		case msg := <-p.replies:
+			if msg.kind != MSG_MULTI_REPLY || msg.seq != p.seq {
+				continue
+			}
+
+			p.multiData[msg.from.id] = uint16(msg.data)
+			p.multiWaiting--
+			continue
+
+		default:
+		}
+
+		if !time.Now().After(p.deadline) {
+			return p.multiData, false
+		}
+
+		p.multiWaiting = 0
+	}
+
+	var result uint64
+	for i, v := range p.multiData {
+		result |= uint64(v) << (i * 16)
+	}
+
+	p.send(message{kind: MSG_MULTI_RESULT, seq: p.seq, data: result})
+
+	return p.multiData, true
+}
+
+// SetMulti sets the half word a peer sends in the next multiplayer transfer
+func (p *Port) SetMulti(v uint16) {
+	if p == nil {
+		return
+	}
+
+	p.multiOut.Store(uint32(v))
+}
+
+// MultiClocked returns the half words of a multiplayer transfer the parent
+// ran
+func (p *Port) MultiClocked() ([MAX_PLAYERS]uint16, bool) {
+	var data [MAX_PLAYERS]uint16
+
+	if p == nil {
+		return data, false
+	}
+
+	select {
+	case msg := <-p.results:
+		for i := range data {
+			data[i] = uint16(msg.data >> (i * 16))
+		}
+		return data, true
+	default:
+		return data, false
+	}
+}
+
+// Send writes a uart byte to the peers
+func (p *Port) Send(v uint8) {
+	if p == nil {
+		return
+	}
+
+	p.send(message{kind: MSG_UART, data: uint64(v)})
+}
+
+// Receive returns a uart byte a peer sent
+func (p *Port) Receive() (uint8, bool) {
+	if p == nil {
+		return 0, false
+	}
+
+	select {
+	case v := <-p.uart:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// SetLed turns the infrared led on or off, peers see it with Light
+func (p *Port) SetLed(on bool) {
+	if p == nil || p.led.Swap(on) == on {
+		return
+	}
+
+	var v uint64
+	if on {
+		v = 1
+	}
+
+	p.send(message{kind: MSG_IR, data: v})
+}
+
+// Light reports if a peer's infrared led is on
+func (p *Port) Light() bool {
+	if p == nil {
+		return false
+	}
+
+	return p.light.Load()
+}
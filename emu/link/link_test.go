@@ -0,0 +1,239 @@
+package link
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// clock runs a transfer from a to b, polling b like a core would
+func clock(t *testing.T, a, b *Port, out, reply uint32) (got, in uint32) {
+	t.Helper()
+
+	a.Transfer(out)
+
+	for {
+		if v, ok := b.Clocked(reply); ok {
+			in = v
+		}
+
+		if got, ok := a.Reply(); ok {
+			return got, in
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer b.Close()
+
+	if got, _ := clock(t, a, b, 0x12, 0x34); got != DISCONNECTED {
+		t.Fatalf("peer not ready replied %X", got)
+	}
+
+	b.Ready(true)
+
+	got, in := clock(t, a, b, 0x12, 0x34)
+	if got != 0x34 || in != 0x12 {
+		t.Fatalf("transfer got %X, peer got %X", got, in)
+	}
+}
+
+func TestUnplugged(t *testing.T) {
+	var p *Port
+
+	p.Transfer(0x12)
+	if got, ok := p.Reply(); !ok || got != DISCONNECTED {
+		t.Fatalf("nil port replied %X", got)
+	}
+
+	if _, ok := p.Clocked(0); ok {
+		t.Fatal("nil port clocked")
+	}
+}
+
+func TestSockets(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:0", "unix:" + filepath.Join(t.TempDir(), "link.sock")} {
+		a := NewPort()
+		if err := a.Listen(addr); err != nil {
+			t.Skip(err)
+		}
+
+		if addr == "127.0.0.1:0" {
+			addr = a.ln.Addr().String()
+		}
+
+		b, err := Open("", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for !a.Connected() {
+			time.Sleep(time.Millisecond)
+		}
+
+		a.Ready(true)
+
+		got, in := clock(t, b, a, 0xAB, 0xCD)
+		if got != 0xCD || in != 0xAB {
+			t.Fatalf("%s: transfer got %X, peer got %X", addr, got, in)
+		}
+
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestMulti(t *testing.T) {
+	host := NewPort()
+	if err := host.Listen("127.0.0.1:0"); err != nil {
+		t.Skip(err)
+	}
+	defer host.Close()
+
+	var peers []*Port
+	for i := range 2 {
+		p, err := Open("", host.ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+
+		for p.ID() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		p.Ready(true)
+		p.SetMulti(uint16(0x1111 * (i + 1)))
+		peers = append(peers, p)
+	}
+
+	if host.Players() != 3 || !host.Host() || peers[0].Host() {
+		t.Fatalf("host has %d players", host.Players())
+	}
+
+	host.MultiTransfer(0xAAAA)
+
+	want := [MAX_PLAYERS]uint16{0xAAAA, 0x1111, 0x2222, MULTI_DISCONNECTED}
+
+	var got [MAX_PLAYERS]uint16
+	for ok := false; !ok; got, ok = host.MultiReply() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if got != want {
+		t.Fatalf("parent got %X, expected %X", got, want)
+	}
+
+	for _, p := range peers {
+		var got [MAX_PLAYERS]uint16
+		for ok := false; !ok; got, ok = p.MultiClocked() {
+			time.Sleep(time.Millisecond)
+		}
+
+		if got != want {
+			t.Fatalf("child %d got %X, expected %X", p.ID(), got, want)
+		}
+	}
+}
+
+func TestSlowPeer(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer b.Close()
+
+	b.Ready(true)
+
+	for a.Players() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the peer never clocks the transfer in, polling does not wait on it
+	a.Transfer(0x12)
+
+	start := time.Now()
+	if _, ok := a.Reply(); ok {
+		t.Fatal("reply before the peer clocked")
+	}
+
+	if d := time.Since(start); d >= TIMEOUT {
+		t.Fatalf("reply blocked for %v", d)
+	}
+
+	for {
+		if got, ok := a.Reply(); ok {
+			if got != DISCONNECTED || time.Since(start) < TIMEOUT/2 {
+				t.Fatalf("slow peer replied %X after %v", got, time.Since(start))
+			}
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInfrared(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer b.Close()
+
+	for _, on := range []bool{true, false} {
+		a.SetLed(on)
+
+		for b.Light() != on {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if a.Light() {
+		t.Fatal("led seen by its own port")
+	}
+}
+
+func TestBacklog(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer b.Close()
+
+	b.Ready(true)
+
+	// b's core is paused and never clocks the transfers in, the ones it has
+	// no room for read as unplugged and the reader goes on
+	for range 32 {
+		a.Transfer(0x12)
+	}
+
+	b.SetLed(true)
+	for !a.Light() {
+		time.Sleep(time.Millisecond)
+	}
+
+	a.SetLed(true)
+	for !b.Light() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUart(t *testing.T) {
+	a, b := Loopback()
+	defer a.Close()
+	defer b.Close()
+
+	for _, v := range []uint8{'h', 'i'} {
+		a.Send(v)
+	}
+
+	var got []byte
+	for len(got) < 2 {
+		if v, ok := b.Receive(); ok {
+			got = append(got, v)
+		}
+	}
+
+	if string(got) != "hi" {
+		t.Fatalf("received %q", got)
+	}
+}
@@ -0,0 +1,114 @@
+package link
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	MSG_HELLO = iota + 1
+	MSG_TRANSFER
+	MSG_REPLY
+	MSG_MULTI_START
+	MSG_MULTI_REPLY
+	MSG_MULTI_RESULT
+	MSG_UART
+	MSG_IR
+)
+
+// messages are kind, seq and data little endian
+const MSG_LEN = 10
+
+type message struct {
+	kind uint8
+	seq  uint8
+	data uint64
+
+	from *peer // set by the receiver
+}
+
+// peer is a connection to another console. Messages are written on their own
+// goroutine, so neither the core nor the reader block on a peer which is not
+// reading.
+type peer struct {
+	conn net.Conn
+	id   int
+
+	mu     sync.Mutex
+	out    chan message
+	closed bool
+}
+
+func newPeer(conn net.Conn, id int, handle func(*peer, message), disconnect func(*peer)) *peer {
+	pr := &peer{
+		conn: conn,
+		id:   id,
+		out:  make(chan message, 64),
+	}
+
+	go pr.write()
+
+	go func() {
+		pr.read(handle)
+		pr.close()
+		disconnect(pr)
+	}()
+
+	return pr
+}
+
+func (pr *peer) read(handle func(*peer, message)) {
+	var b [MSG_LEN]byte
+
+	for {
+		if _, err := io.ReadFull(pr.conn, b[:]); err != nil {
+			return
+		}
+
+		handle(pr, message{kind: b[0], seq: b[1], data: binary.LittleEndian.Uint64(b[2:])})
+	}
+}
+
+func (pr *peer) write() {
+	var b [MSG_LEN]byte
+
+	for msg := range pr.out {
+		b[0], b[1] = msg.kind, msg.seq
+		binary.LittleEndian.PutUint64(b[2:], msg.data)
+
+		if _, err := pr.conn.Write(b[:]); err != nil {
+			pr.conn.Close()
+		}
+	}
+}
+
+func (pr *peer) send(msg message) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if pr.closed {
+		return
+	}
+
+	select {
+	case pr.out <- msg:
+	default:
+		// peer stopped reading
+	}
+}
+
+func (pr *peer) close() error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if pr.closed {
+		return nil
+	}
+
+	pr.closed = true
+	close(pr.out)
+
+	return pr.conn.Close()
+}
@@ -0,0 +1,72 @@
+package movie
+
+import "github.com/aabalke/guac/emu/state"
+
+// Console is a core movies are recorded on. Input is what is held for the
+// next frame, SetInput replaces it with a recorded frame.
+type Console interface {
+	Stater
+	Input() Frame
+	SetInput(f Frame)
+}
+
+// Deck records and plays the movies of a core, which embeds it and calls
+// StepMovie before every frame
+type Deck struct {
+	Movie *Session // nil without a movie
+
+	c        Console
+	console  state.Console
+	checksum func() uint32
+}
+
+func NewDeck(c Console, console state.Console, checksum func() uint32) Deck {
+	return Deck{c: c, console: console, checksum: checksum}
+}
+
+// RecordMovie records from here, a movie from power on is recorded on a
+// console just created
+func (d *Deck) RecordMovie() error {
+	m, err := New(d.c, d.console, d.checksum())
+	if err != nil {
+		return err
+	}
+
+	d.Movie = Record(m)
+	return nil
+}
+
+// PlayMovie replays m, see Movie.Start for power on movies
+func (d *Deck) PlayMovie(m *Movie) error {
+	if err := m.Start(d.c, d.console, d.checksum()); err != nil {
+		return err
+	}
+
+	d.Movie = Play(m)
+	return nil
+}
+
+// StepMovie records or replays the input of the frame about to run
+func (d *Deck) StepMovie() {
+	if d.Movie == nil {
+		return
+	}
+
+	f := d.c.Input()
+	if d.Movie.Step(&f) && !d.Movie.Recording {
+		d.c.SetInput(f)
+	}
+}
+
+// StopMovie ends recording or playback and returns the movie, nil if there
+// was none
+func (d *Deck) StopMovie() *Movie {
+	if d.Movie == nil {
+		return nil
+	}
+
+	m := d.Movie.Movie
+	d.Movie = nil
+
+	return m
+}
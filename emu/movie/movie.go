@@ -0,0 +1,337 @@
+// movie records the input of every frame so a run can be replayed exactly.
+//
+// A Movie starts from the save state it carries with the guest clocks seeded
+// at RtcSeed. Replaying its frames into the same rom gives the same run,
+// which makes a bug report a regression test. Movies recorded from power on
+// carry a state too, taken before the first frame, so the battery save and
+// rtc the run started with travel with it instead of coming from the
+// replaying machine's .sav and .rtc.
+//
+// The file is a header followed by the gzipped state and frames, all little
+// endian.
+//
+//	"GUACMOVIE" version u16 console u8 rom crc32 u32 rtc seed i64
+//	gzip: state length u32, state, frame count u32, frames
+//	frame: buttons u16, touch u8, x u16, y u16, tilt x f64, tilt y f64,
+//	       gyro i16, solar u8
+package movie
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/aabalke/guac/emu/clock"
+	"github.com/aabalke/guac/emu/state"
+)
+
+// Version is bumped whenever the layout of a movie or of a frame changes
+const Version = 2
+
+const (
+	magic      = "GUACMOVIE"
+	FRAME_SIZE = 26
+)
+
+var (
+	ErrFormat  = errors.New("movie: not a movie")
+	ErrVersion = errors.New("movie: unsupported version")
+	ErrConsole = errors.New("movie: wrong console")
+	ErrRom     = errors.New("movie: recorded with a different rom")
+)
+
+// Frame is the input held during a frame. Buttons are input.Buttons, the
+// lid is one of them, touch is the nds pen. The rest are the sensors of
+// carts, the mbc7 accelerometer and the gba gyro and solar sensor.
+type Frame struct {
+	Buttons uint16
+	Touch   bool
+	X, Y    uint16
+
+	Tilt  [2]float64
+	Gyro  int16
+	Solar uint8
+}
+
+type Movie struct {
+	Console  state.Console
+	Checksum uint32 // crc32 of the rom
+	RtcSeed  int64  // unix seconds the guest clocks start at
+	State    []byte // save state the movie starts from, nil is power on from disk
+	Frames   []Frame
+}
+
+// Stater is a console with save states
+type Stater interface {
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+}
+
+// New starts a movie of c from a state of it. The clock is seeded where it
+// reads now and the state is loaded back, so the run goes on exactly as a
+// replay starts. A movie from power on is started on a console just created.
+func New(c Stater, console state.Console, checksum uint32) (*Movie, error) {
+	m := &Movie{Console: console, Checksum: checksum}
+
+	clock.Seed(clock.Now())
+
+	var b bytes.Buffer
+	if err := c.SaveState(&b); err != nil {
+		return nil, err
+	}
+
+	m.State = b.Bytes()
+
+	if err := c.LoadState(bytes.NewReader(m.State)); err != nil {
+		return nil, err
+	}
+
+	m.RtcSeed = clock.Now().Unix()
+
+	return m, nil
+}
+
+// Start readies c to play the movie, its state is loaded. A movie without
+// one plays from power on, the console has to be created just now on a
+// clock seeded with Seed.
+func (m *Movie) Start(c Stater, console state.Console, checksum uint32) error {
+	if err := m.Check(console, checksum); err != nil {
+		return err
+	}
+
+	if m.State == nil {
+		return nil
+	}
+
+	clock.Seed(m.Seed())
+
+	return c.LoadState(bytes.NewReader(m.State))
+}
+
+// Seed is the time the guest clocks start at
+func (m *Movie) Seed() time.Time {
+	return time.Unix(m.RtcSeed, 0)
+}
+
+// Check reports if the movie was recorded on console with the rom
+func (m *Movie) Check(console state.Console, checksum uint32) error {
+	switch {
+	case m.Console != console:
+		return fmt.Errorf("%w %s, expected %s", ErrConsole, m.Console, console)
+	case m.Checksum != checksum:
+		return fmt.Errorf("%w (%08X), loaded (%08X)", ErrRom, m.Checksum, checksum)
+	}
+
+	return nil
+}
+
+func (m *Movie) Write(w io.Writer) error {
+	b := []byte(magic)
+	b = binary.LittleEndian.AppendUint16(b, Version)
+	b = append(b, uint8(m.Console))
+	b = binary.LittleEndian.AppendUint32(b, m.Checksum)
+	b = binary.LittleEndian.AppendUint64(b, uint64(m.RtcSeed))
+
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+
+	b = binary.LittleEndian.AppendUint32(b[:0], uint32(len(m.State)))
+	b = append(b, m.State...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(m.Frames)))
+
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+
+	var buf [FRAME_SIZE]byte
+
+	for _, f := range m.Frames {
+		binary.LittleEndian.PutUint16(buf[0:], f.Buttons)
+		buf[2] = 0
+		if f.Touch {
+			buf[2] = 1
+		}
+		binary.LittleEndian.PutUint16(buf[3:], f.X)
+		binary.LittleEndian.PutUint16(buf[5:], f.Y)
+		binary.LittleEndian.PutUint64(buf[7:], math.Float64bits(f.Tilt[0]))
+		binary.LittleEndian.PutUint64(buf[15:], math.Float64bits(f.Tilt[1]))
+		binary.LittleEndian.PutUint16(buf[23:], uint16(f.Gyro))
+		buf[25] = f.Solar
+
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func Read(r io.Reader) (*Movie, error) {
+	var buf [len(magic) + 2 + 1 + 4 + 8]byte
+
+	if _, err := io.ReadFull(r, buf[:]); err != nil || string(buf[:len(magic)]) != magic {
+		return nil, ErrFormat
+	}
+
+	b := buf[len(magic):]
+
+	if v := binary.LittleEndian.Uint16(b[0:]); v != Version {
+		return nil, fmt.Errorf("%w %d, expected %d", ErrVersion, v, Version)
+	}
+
+	m := &Movie{
+		Console:  state.Console(b[2]),
+		Checksum: binary.LittleEndian.Uint32(b[3:]),
+		RtcSeed:  int64(binary.LittleEndian.Uint64(b[7:])),
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("movie: %w", err)
+	}
+
+	defer zr.Close()
+
+	br := bufio.NewReader(zr)
+
+	n, err := readLen(br)
+	if err != nil {
+		return nil, err
+	}
+
+	// the length is not trusted, a short file ends the read before it is
+	// all allocated
+	if n != 0 {
+		if m.State, err = io.ReadAll(io.LimitReader(br, int64(n))); err != nil {
+			return nil, fmt.Errorf("movie: state %w", err)
+		}
+
+		if len(m.State) != n {
+			return nil, fmt.Errorf("movie: state %w", io.ErrUnexpectedEOF)
+		}
+	}
+
+	if n, err = readLen(br); err != nil {
+		return nil, err
+	}
+
+	m.Frames = make([]Frame, 0, min(n, 1<<20))
+
+	var fb [FRAME_SIZE]byte
+
+	for range n {
+		if _, err := io.ReadFull(br, fb[:]); err != nil {
+			return nil, fmt.Errorf("movie: frame %d %w", len(m.Frames), err)
+		}
+
+		m.Frames = append(m.Frames, Frame{
+			Buttons: binary.LittleEndian.Uint16(fb[0:]),
+			Touch:   fb[2] != 0,
+			X:       binary.LittleEndian.Uint16(fb[3:]),
+			Y:       binary.LittleEndian.Uint16(fb[5:]),
+			Tilt: [2]float64{
+				math.Float64frombits(binary.LittleEndian.Uint64(fb[7:])),
+				math.Float64frombits(binary.LittleEndian.Uint64(fb[15:])),
+			},
+			Gyro:  int16(binary.LittleEndian.Uint16(fb[23:])),
+			Solar: fb[25],
+		})
+	}
+
+	return m, nil
+}
+
+func readLen(r io.Reader) (int, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, fmt.Errorf("movie: %w", err)
+	}
+
+	return int(binary.LittleEndian.Uint32(b[:])), nil
+}
+
+func Load(path string) (*Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return Read(bufio.NewReader(f))
+}
+
+func (m *Movie) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Session records into or plays back a movie, a frame at a time
+type Session struct {
+	Movie     *Movie
+	Recording bool
+
+	frame int
+}
+
+func Record(m *Movie) *Session {
+	return &Session{Movie: m, Recording: true}
+}
+
+func Play(m *Movie) *Session {
+	return &Session{Movie: m}
+}
+
+// Step is called once before every frame with the input held. Recording
+// keeps it, playback replaces it with the recorded input. It reports false
+// once playback is past the last frame, f is left alone then.
+func (s *Session) Step(f *Frame) bool {
+	if s.Recording {
+		s.Movie.Frames = append(s.Movie.Frames, *f)
+		s.frame++
+		return true
+	}
+
+	if s.frame >= len(s.Movie.Frames) {
+		return false
+	}
+
+	*f = s.Movie.Frames[s.frame]
+	s.frame++
+
+	return true
+}
+
+// Frame is the frames stepped so far
+func (s *Session) Frame() int {
+	return s.frame
+}
+
+// Done reports if playback is past the last frame
+func (s *Session) Done() bool {
+	return !s.Recording && s.frame >= len(s.Movie.Frames)
+}
@@ -0,0 +1,204 @@
+package movie
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aabalke/guac/emu/clock"
+	"github.com/aabalke/guac/emu/state"
+)
+
+func TestRoundTrip(t *testing.T) {
+	m := &Movie{
+		Console:  state.NDS,
+		Checksum: 0xDEADBEEF,
+		RtcSeed:  1_100_000_000,
+		State:    []byte("state"),
+		Frames: []Frame{
+			{Buttons: 0x0009},
+			{Buttons: 0x4000, Touch: true, X: 128, Y: 96},
+			{Touch: true, X: 0xFFFF, Y: 191},
+			{Tilt: [2]float64{-0.25, 1.0 / 3}, Gyro: -0x400, Solar: 10},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "run.gmv")
+
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, m) {
+		t.Fatalf("loaded %+v, saved %+v", got, m)
+	}
+
+	if err := got.Check(state.NDS, 0xDEADBEEF); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := got.Check(state.NDS, 1); !errors.Is(err, ErrRom) {
+		t.Fatalf("other rom %v", err)
+	}
+
+	if err := got.Check(state.GBA, 0xDEADBEEF); !errors.Is(err, ErrConsole) {
+		t.Fatalf("other console %v", err)
+	}
+}
+
+func TestPowerOn(t *testing.T) {
+	var b bytes.Buffer
+
+	if err := (&Movie{Console: state.GB}).Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.State != nil || len(m.Frames) != 0 {
+		t.Fatalf("empty movie read as %+v", m)
+	}
+
+	if _, err := Read(bytes.NewReader([]byte("GUACSTATE......."))); !errors.Is(err, ErrFormat) {
+		t.Fatalf("save state read as a movie %v", err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	var b bytes.Buffer
+
+	b.WriteString(magic)
+	b.Write(binary.LittleEndian.AppendUint16(nil, Version))
+	b.Write(make([]byte, 1+4+8))
+
+	// a 4gb state that is not there
+	zw := gzip.NewWriter(&b)
+	zw.Write(binary.LittleEndian.AppendUint32(nil, 0xFFFF_FFFF))
+	zw.Write([]byte("short"))
+	zw.Close()
+
+	if _, err := Read(&b); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated state read with %v", err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	m := &Movie{}
+	rec := Record(m)
+
+	for i := range 3 {
+		f := Frame{Buttons: uint16(i)}
+		if !rec.Step(&f) {
+			t.Fatal("recording stopped")
+		}
+	}
+
+	play := Play(m)
+
+	for i := range 3 {
+		f := Frame{Buttons: 0xFF}
+		if !play.Step(&f) || f.Buttons != uint16(i) {
+			t.Fatalf("frame %d played %+v", i, f)
+		}
+	}
+
+	f := Frame{Buttons: 0xFF}
+	if play.Step(&f) || f.Buttons != 0xFF || !play.Done() {
+		t.Fatalf("played past the end %+v", f)
+	}
+}
+
+// battery is a console whose only state is its battery save
+type battery struct {
+	sav []byte
+}
+
+func (b *battery) SaveState(w io.Writer) error {
+	_, err := w.Write(b.sav)
+	return err
+}
+
+func (b *battery) LoadState(r io.Reader) error {
+	var err error
+	b.sav, err = io.ReadAll(r)
+	return err
+}
+
+func TestPowerOnCarriesSave(t *testing.T) {
+	defer clock.Unseed()
+
+	rec := &battery{sav: []byte("tester's save")}
+
+	m, err := New(rec, state.GB, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the replaying machine has its own save beside the rom
+	play := &battery{sav: []byte("other save")}
+
+	if err := m.Start(play, state.GB, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(play.sav) != "tester's save" {
+		t.Fatalf("replay started from %q", play.sav)
+	}
+}
+
+// pad is a console holding one frame of input
+type pad struct {
+	battery
+	held Frame
+}
+
+func (p *pad) Input() Frame     { return p.held }
+func (p *pad) SetInput(f Frame) { p.held = f }
+
+func TestDeck(t *testing.T) {
+	defer clock.Unseed()
+
+	rec := &pad{}
+	d := NewDeck(rec, state.GBA, func() uint32 { return 1 })
+
+	if err := d.RecordMovie(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, level := range []uint8{3, 7} {
+		rec.held = Frame{Buttons: 1, Gyro: -0x100, Solar: level}
+		d.StepMovie()
+	}
+
+	m := d.StopMovie()
+	if d.Movie != nil || len(m.Frames) != 2 {
+		t.Fatalf("recorded %d frames", len(m.Frames))
+	}
+
+	play := &pad{}
+	d = NewDeck(play, state.GBA, func() uint32 { return 1 })
+
+	if err := d.PlayMovie(m); err != nil {
+		t.Fatal(err)
+	}
+
+	d.StepMovie()
+	d.StepMovie()
+
+	if want := (Frame{Buttons: 1, Gyro: -0x100, Solar: 7}); play.held != want {
+		t.Fatalf("replayed %+v, expected %+v", play.held, want)
+	}
+}
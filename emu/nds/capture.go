@@ -0,0 +1,116 @@
+package nds
+
+import (
+	"image"
+	"log"
+
+	"github.com/aabalke/guac/emu/capture"
+	"github.com/aabalke/guac/utils"
+)
+
+// FRAME_RATE is the emulated frame rate, audio and video stay in step at it
+var FRAME_RATE = capture.Rate{Num: CPU_FREQ_HZ, Den: CYCLES_FRAME}
+
+// StartCapture records the picture and sound of every frame to path, see
+// capture.New. The screens are laid out as they are when it starts.
+func (nds *Nds) StartCapture(path string) error {
+	b := nds.captureImage().Bounds()
+
+	r, err := capture.New(path, b.Dx(), b.Dy(), FRAME_RATE, nds.mem.Snd.SampleRate())
+	if err != nil {
+		return err
+	}
+
+	r.SkipSound(nds.mem.Snd.WritePointer)
+	nds.Capture = r
+
+	return nil
+}
+
+// captureImage lays the screens out at native resolution as Screen shows
+// them, rotated as a whole. Hybrid scales the screens, it is kept vertical.
+func (nds *Nds) captureImage() *image.RGBA {
+	t, b := nds.GetScreens()
+
+	var img *image.RGBA
+
+	switch {
+	case *nds.Screen.Sizing == SIZING_ONLY_TOP:
+		img = utils.NewScreenshot(SCREEN_WIDTH, SCREEN_HEIGHT, *t)
+
+	case *nds.Screen.Sizing == SIZING_ONLY_BOTTOM:
+		img = utils.NewScreenshot(SCREEN_WIDTH, SCREEN_HEIGHT, *b)
+
+	case *nds.Screen.Layout == LAYOUT_HORZONTAL:
+		img = image.NewRGBA(image.Rect(0, 0, SCREEN_WIDTH*2, SCREEN_HEIGHT))
+
+		for y := range SCREEN_HEIGHT {
+			row := img.Pix[y*img.Stride:]
+			copy(row, (*t)[y*SCREEN_WIDTH*4:(y+1)*SCREEN_WIDTH*4])
+			copy(row[SCREEN_WIDTH*4:], (*b)[y*SCREEN_WIDTH*4:(y+1)*SCREEN_WIDTH*4])
+		}
+
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xFF
+		}
+
+	default:
+		img = nds.Screenshot()
+	}
+
+	return rotate(img, *nds.Screen.Rotation)
+}
+
+// rotate turns img clockwise, as Screen draws rotations
+func rotate(img *image.RGBA, rot int) *image.RGBA {
+	if rot == ROT_0 {
+		return img
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	out := image.NewRGBA(image.Rect(0, 0, h, w))
+	if rot == ROT_180 {
+		out = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+
+			switch rot {
+			case ROT_90:
+				dx, dy = h-1-y, x
+			case ROT_180:
+				dx, dy = w-1-x, h-1-y
+			case ROT_270:
+				dx, dy = y, w-1-x
+			}
+
+			copy(out.Pix[out.PixOffset(dx, dy):][:4], img.Pix[img.PixOffset(x, y):])
+		}
+	}
+
+	return out
+}
+
+// captureFrame adds the frame just drawn and its sound
+func (nds *Nds) captureFrame() {
+	if nds.Capture == nil {
+		return
+	}
+
+	err := nds.Capture.Record(nds.captureImage(), nds.mem.Snd.SoundBuffer, nds.mem.Snd.WritePointer)
+	if err != nil {
+		log.Printf("Capture Failed: %v\n", err)
+		nds.StopCapture()
+	}
+}
+
+// StopCapture ends recording and returns the frames recorded
+func (nds *Nds) StopCapture() (int, error) {
+	n, err := nds.Capture.Stop()
+	nds.Capture = nil
+
+	return n, err
+}
@@ -4,26 +4,30 @@ import (
 	"encoding/binary"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/aabalke/guac/config"
 	"github.com/aabalke/guac/emu/cpu"
 	"github.com/aabalke/guac/emu/nds/mem/dma"
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
 	"github.com/aabalke/guac/utils"
 )
 
 type Cartridge struct {
-	Rom     []uint8
-	RomPath string
-	RomLen  int
+	Rom     []uint8 `state:"-"`
+	RomPath string  `state:"-"`
+	RomLen  int     `state:"-"`
 
 	Sav     []uint8
-	SavPath string
+	SavPath string `state:"-"`
 	SavLen  int
 
 	//io
-	Header  Header
+	Header  Header `state:"-"`
 	ExMem   ExMem
+	Slot2   Slot2
 	AuxSpi  AuxSpi
 	RomCtrl RomCtrl
 
@@ -38,34 +42,38 @@ type Cartridge struct {
 	Status         uint8
 	Buffer         []uint8
 	ChipId         [4]uint8
+
+	checksum func() uint32
 }
 
-func NewCartridge(romPath, savPath string, bios *[]uint8, irq7, irq9 *cpu.Irq, dma7, dma9 *[4]dma.DMA) *Cartridge {
+// NewCartridge takes the rom as loaded, saves are named after the patch when
+// it is patched
+func NewCartridge(img *rom.Image, bios *[]uint8, irq7, irq9 *cpu.Irq, dma7, dma9 *[4]dma.DMA) *Cartridge {
 
 	c := &Cartridge{
-		RomPath: romPath,
-		SavPath: savPath,
+		RomPath: img.Path,
+		SavPath: img.Name() + ".save",
+		Rom:     img.Data,
+		RomLen:  len(img.Data),
 		irq7:    irq7,
 		irq9:    irq9,
 		dma7:    dma7,
 		dma9:    dma9,
 	}
 
-	var ok bool
-	c.Rom, c.RomLen, ok = utils.ReadFile(romPath)
-	if !ok {
-		panic("could not read rom path")
-	}
-
 	c.Header = NewHeader(c)
 
+	c.checksum = sync.OnceValue(func() uint32 {
+		return state.Checksum(c.Rom[:c.RomLen])
+	})
+
 	if !c.Header.Decrypted {
 		NewKey1(bios, &c.Rom).DecryptCard()
 	}
 
 	code := binary.LittleEndian.Uint32(c.Header.GameCode)
 	c.Backup = NewBackup(c)
-	c.readSave(savPath, code)
+	c.readSave(c.SavPath, code)
 	c.Backup.setCartType()
 
 	// matches no cash nitrofs test
@@ -81,6 +89,8 @@ func NewCartridge(romPath, savPath string, bios *[]uint8, irq7, irq9 *cpu.Irq, d
 	c.Status = GAMECARD_STAT_KY2
 	c.ChipId = createChipId(c.Backup)
 
+	c.Slot2 = NewSlot2(config.Conf.Nds.Slot2)
+
 	c.InitSaveLoop()
 
 	return c
@@ -272,22 +282,35 @@ func (c *Cartridge) RomTransfer(initial bool, arm9 bool) {
 	}
 }
 
+// InitSaveLoop writes saves every second. Deterministic runs call FlushSave
+// between frames instead, so saves never race the emulation.
 func (c *Cartridge) InitSaveLoop() {
 
+	if config.Conf.General.Deterministic {
+		return
+	}
+
 	saveTicker := time.Tick(time.Second)
 
 	go func() {
 		for range saveTicker {
-
-			if config.Conf.General.DisableSaves {
-				continue
-			}
-
-			if c.SaveFlag {
-				log.Printf("Saving Game Path: %s\n", c.SavPath)
-				utils.WriteFile(c.SavPath, c.Sav[:])
-				c.SaveFlag = false
-			}
+			c.FlushSave()
 		}
 	}()
 }
+
+// FlushSave writes the save and the slot 2 save if they changed
+func (c *Cartridge) FlushSave() {
+
+	if config.Conf.General.DisableSaves {
+		return
+	}
+
+	if c.SaveFlag {
+		log.Printf("Saving Game Path: %s\n", c.SavPath)
+		utils.WriteFile(c.SavPath, c.Sav[:])
+		c.SaveFlag = false
+	}
+
+	c.saveSlot2()
+}
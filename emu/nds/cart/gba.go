@@ -1,18 +1,139 @@
 package cart
 
+import (
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/aabalke/guac/config"
+	gba "github.com/aabalke/guac/emu/gba/cart"
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
+	"github.com/aabalke/guac/utils"
+)
+
+// GbaSlot is a gba cart in slot 2, the ds reads its rom and backup. Saves
+// are shared with the gba emulator.
+type GbaSlot struct {
+	Cart *gba.Cartridge
+	Save bool
+
+	// the save loop copies the backup while the cpu writes it
+	mu sync.Mutex `state:"-"`
+}
+
+func NewGbaSlot(romPath string) (*GbaSlot, error) {
+	log.Printf("Slot 2 GBA Cartridge: %s\n", romPath)
+
+	img, err := rom.Load(romPath, config.Conf.General.Patches)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GbaSlot{
+		Cart: gba.NewCartridge(img),
+	}, nil
+}
+
+func (s *GbaSlot) Read(addr uint32) uint8 {
+	c := s.Cart
+
+	if sram := addr >= 0xA00_0000; sram {
+		return c.Read(addr & 0xFFFF)
+	}
+
+	if c.GpioReadable(addr) {
+		return c.ReadGpio(addr)
+	}
+
+	if addr&0x1FF_FFFF >= c.RomLength {
+		return openBus(addr)
+	}
+
+	return c.Rom[addr&0x1FF_FFFF]
+}
+
+func (s *GbaSlot) Write(addr uint32, v uint8) {
+	c := s.Cart
+
+	if sram := addr >= 0xA00_0000; sram {
+		s.mu.Lock()
+		c.Write(addr&0xFFFF, v)
+		s.Save = true
+		s.mu.Unlock()
+		return
+	}
+
+	c.WriteGpio(addr, v)
+}
+
+func (s *GbaSlot) SaveState(e *state.Encoder) {
+	e.Encode(s)
+	s.Cart.SaveState(e)
+}
+
+func (s *GbaSlot) LoadState(d *state.Decoder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d.Decode(s)
+	s.Cart.LoadState(d)
+}
+
+// flush writes the backup if it changed, a copy is taken so the cpu is only
+// held while copying
+func (s *GbaSlot) flush() {
+	s.mu.Lock()
+
+	if !s.Save {
+		s.mu.Unlock()
+		return
+	}
+
+	buf := slices.Clone(s.Cart.Backup())
+	s.Save = false
+	s.mu.Unlock()
+
+	log.Printf("Saving Game Path: %s\n", s.Cart.SavPath)
+	if !utils.WriteFile(s.Cart.SavPath, buf) {
+		log.Printf("Saving Game Failed: %s\n", s.Cart.SavPath)
+	}
+}
+
+// gbaAccess reports if the cpu owns the gba slot in EXMEMCNT
+func (c *Cartridge) gbaAccess(arm9 bool) bool {
+	return arm9 != c.ExMem.IsGBAAccessArm7
+}
+
 func (c *Cartridge) ReadGbaSlot(addr uint32, arm9 bool) uint8 {
 
-	if arm9 && c.ExMem.IsGBAAccessArm7 {
+	if !c.gbaAccess(arm9) {
 		return 0
 	}
 
-	if !arm9 && !c.ExMem.IsGBAAccessArm7 {
-		return 0
+	if c.Slot2 != nil {
+		return c.Slot2.Read(addr)
 	}
 
 	if sram := addr >= 0xA00_0000; sram {
 		return 0xFF
 	}
 
-	return uint8(((addr >> 1) & 0xFFFF) >> ((addr & 1) << 3))
+	return openBus(addr)
+}
+
+func (c *Cartridge) WriteGbaSlot(addr uint32, v uint8, arm9 bool) {
+
+	if !c.gbaAccess(arm9) || c.Slot2 == nil {
+		return
+	}
+
+	c.Slot2.Write(addr, v)
+}
+
+// saveSlot2 writes the backup of a gba cart in slot 2
+func (c *Cartridge) saveSlot2() {
+	if s, ok := c.Slot2.(*GbaSlot); ok {
+		s.flush()
+	}
 }
@@ -0,0 +1,129 @@
+package cart
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gba "github.com/aabalke/guac/emu/gba/cart"
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
+)
+
+func TestEmptySlot(t *testing.T) {
+	c := &Cartridge{}
+
+	// the bus reads back the halfword address
+	if lo, hi := c.ReadGbaSlot(0x800_2468, true), c.ReadGbaSlot(0x800_2469, true); lo != 0x34 || hi != 0x12 {
+		t.Errorf("rom read %02X%02X, want open bus", hi, lo)
+	}
+
+	if v := c.ReadGbaSlot(0xA00_0000, true); v != 0xFF {
+		t.Errorf("sram read %02X", v)
+	}
+}
+
+func TestExMemAccess(t *testing.T) {
+	c := &Cartridge{Slot2: &GuitarGrip{}}
+
+	// the arm9 owns the slot after power on
+	if v := c.ReadGbaSlot(0x800_0001, true); v != 0xF9 {
+		t.Errorf("arm9 read %02X", v)
+	}
+
+	if v := c.ReadGbaSlot(0x800_0001, false); v != 0 {
+		t.Errorf("arm7 read %02X without access", v)
+	}
+
+	c.WriteExMem(0x80, 0)
+
+	if v := c.ReadGbaSlot(0x800_0001, false); v != 0xF9 {
+		t.Errorf("arm7 read %02X", v)
+	}
+
+	if v := c.ReadGbaSlot(0x800_0001, true); v != 0 {
+		t.Errorf("arm9 read %02X without access", v)
+	}
+
+	// writes from the cpu without access are dropped
+	r := &RumblePak{}
+	c.Slot2 = r
+
+	c.WriteGbaSlot(0x800_0000, 2, true)
+	if r.Moved {
+		t.Error("arm9 moved the rumble pak without access")
+	}
+
+	c.WriteGbaSlot(0x800_0000, 2, false)
+	if !r.Moved {
+		t.Error("arm7 did not move the rumble pak")
+	}
+}
+
+func TestGbaSlot(t *testing.T) {
+	dir := t.TempDir()
+
+	data := make([]uint8, 0x200)
+	copy(data[0xC0:], "SRAM_V113")
+	data[0x100] = 0xAB
+
+	s := &GbaSlot{Cart: gba.NewCartridge(&rom.Image{Path: filepath.Join(dir, "game.gba"), Data: data})}
+
+	if v := s.Read(0x800_0100); v != 0xAB {
+		t.Errorf("rom read %02X", v)
+	}
+
+	if v := s.Read(0x800_0200); v != openBus(0x800_0200) {
+		t.Errorf("read past the rom %02X, want open bus", v)
+	}
+
+	s.Write(0xA00_0010, 0x5A)
+	if v := s.Read(0xA00_0010); v != 0x5A || !s.Save {
+		t.Errorf("sram read %02X, save %t", v, s.Save)
+	}
+
+	s.flush()
+
+	buf, err := os.ReadFile(s.Cart.SavPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Save || buf[0x10] != 0x5A {
+		t.Errorf("flushed save %t, wrote %02X", s.Save, buf[0x10])
+	}
+}
+
+func TestSlot2State(t *testing.T) {
+	c := &Cartridge{Slot2: &ExpansionPak{Ram: make([]uint8, EXPANSION_RAM_SIZE), Unlocked: true}}
+	c.Slot2.Write(0x900_0010, 0x77)
+
+	var b bytes.Buffer
+	e := state.NewEncoder(&b)
+	c.SaveSlot2State(e)
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := b.Bytes()
+
+	loaded := &Cartridge{Slot2: &ExpansionPak{Ram: make([]uint8, EXPANSION_RAM_SIZE)}}
+	d := state.NewDecoder(bytes.NewReader(saved))
+	loaded.LoadSlot2State(d)
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := loaded.Slot2.(*ExpansionPak); !e.Unlocked || e.Ram[0x10] != 0x77 {
+		t.Errorf("loaded unlocked %t, ram %02X", e.Unlocked, e.Ram[0x10])
+	}
+
+	// a state is only loaded with the device it was saved with
+	other := &Cartridge{Slot2: &RumblePak{}}
+	d = state.NewDecoder(bytes.NewReader(saved))
+	other.LoadSlot2State(d)
+	if d.Err() == nil {
+		t.Error("expansion pak state loaded into a rumble pak")
+	}
+}
@@ -0,0 +1,190 @@
+package cart
+
+import (
+	"log"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/state"
+)
+
+// Slot2 is a device in the gba slot, 0x8000000 - 0xAFFFFFF. Addresses are
+// passed as is, 0xA000000 and up is the 8 bit sram bus.
+type Slot2 interface {
+	Read(addr uint32) uint8
+	Write(addr uint32, v uint8)
+	SaveState(e *state.Encoder)
+	LoadState(d *state.Decoder)
+}
+
+// NewSlot2 plugs in the device chosen in the config, nil is an empty slot
+func NewSlot2(cfg config.NdsSlot2) Slot2 {
+	switch cfg.Device {
+	case config.SLOT2_GBA:
+		if cfg.GbaRomPath == "" {
+			log.Printf("Slot 2 GBA Cartridge has no rom path\n")
+			return nil
+		}
+
+		s, err := NewGbaSlot(cfg.GbaRomPath)
+		if err != nil {
+			log.Printf("Slot 2 GBA Cartridge: %v\n", err)
+			return nil
+		}
+
+		return s
+	case config.SLOT2_RUMBLE:
+		log.Printf("Slot 2 Rumble Pak\n")
+		return &RumblePak{}
+	case config.SLOT2_EXPANSION:
+		log.Printf("Slot 2 Memory Expansion Pak\n")
+		return &ExpansionPak{Ram: make([]uint8, EXPANSION_RAM_SIZE)}
+	case config.SLOT2_GUITAR:
+		log.Printf("Slot 2 Guitar Grip\n")
+		return &GuitarGrip{}
+	default:
+		return nil
+	}
+}
+
+// openBus is the empty slot, the 16 bit bus reads back the address
+func openBus(addr uint32) uint8 {
+	return uint8(((addr >> 1) & 0xFFFF) >> ((addr & 1) << 3))
+}
+
+// RumblePak moves its motor every time the value written to the rom area
+// changes. Games detect it by ad1 being pulled low on rom reads.
+type RumblePak struct {
+	Value uint8
+	Moved bool // cleared by the frontend once it rumbles
+}
+
+func (r *RumblePak) Read(addr uint32) uint8 {
+	if sram := addr >= 0xA00_0000; sram {
+		return 0xFF
+	}
+
+	v := openBus(addr)
+	if addr&1 == 0 {
+		v &^= 0b10
+	}
+
+	return v
+}
+
+func (r *RumblePak) SaveState(e *state.Encoder) { e.Encode(r) }
+func (r *RumblePak) LoadState(d *state.Decoder) { d.Decode(r) }
+
+func (r *RumblePak) Write(addr uint32, v uint8) {
+	if sram := addr >= 0xA00_0000; sram || addr&1 != 0 {
+		return
+	}
+
+	if v != r.Value {
+		r.Value = v
+		r.Moved = true
+	}
+}
+
+const (
+	EXPANSION_RAM_SIZE  = 0x80_0000
+	EXPANSION_RAM_START = 0x100_0000
+	EXPANSION_RAM_END   = EXPANSION_RAM_START + EXPANSION_RAM_SIZE
+	EXPANSION_LOCK      = 0x24_0000
+)
+
+// halfwords of the expansion pak id, at 0x80000B0 in the gba header
+var expansionId = map[uint32]uint16{
+	0xB0:    0xFFFF,
+	0xB2:    0x0000,
+	0xB4:    0x2400,
+	0xB6:    0x2424,
+	0xB8:    0xFFFF,
+	0xBA:    0xFFFF,
+	0xBC:    0xFFFF,
+	0xBE:    0x7FFF,
+	0x1FFFC: 0xFFFF,
+	0x1FFFE: 0x7FFF,
+}
+
+// ExpansionPak is the 8mb memory expansion pak. Its ram is at 0x9000000,
+// writable once unlocked by writing 1 to 0x8240000.
+type ExpansionPak struct {
+	Ram      []uint8
+	Unlocked bool
+}
+
+func (e *ExpansionPak) Read(addr uint32) uint8 {
+	if sram := addr >= 0xA00_0000; sram {
+		return 0xFF
+	}
+
+	offset := addr & 0x1FF_FFFF
+	shift := (offset & 1) << 3
+
+	switch {
+	case offset >= EXPANSION_RAM_START && offset < EXPANSION_RAM_END:
+		return e.Ram[offset-EXPANSION_RAM_START]
+	case offset&^1 == EXPANSION_LOCK:
+		if e.Unlocked && offset&1 == 0 {
+			return 1
+		}
+		return 0
+	case offset&^1 == EXPANSION_LOCK+2:
+		return 0
+	}
+
+	if v, ok := expansionId[offset&^1]; ok {
+		return uint8(v >> shift)
+	}
+
+	return 0xFF
+}
+
+func (e *ExpansionPak) SaveState(enc *state.Encoder) { enc.Encode(e) }
+func (e *ExpansionPak) LoadState(dec *state.Decoder) { dec.Decode(e) }
+
+func (e *ExpansionPak) Write(addr uint32, v uint8) {
+	if sram := addr >= 0xA00_0000; sram {
+		return
+	}
+
+	offset := addr & 0x1FF_FFFF
+
+	switch {
+	case offset == EXPANSION_LOCK:
+		e.Unlocked = v&1 != 0
+	case offset >= EXPANSION_RAM_START && offset < EXPANSION_RAM_END && e.Unlocked:
+		e.Ram[offset-EXPANSION_RAM_START] = v
+	}
+}
+
+// guitar grip buttons, active low on the sram bus
+const (
+	GRIP_BLUE   = 1 << 3
+	GRIP_YELLOW = 1 << 4
+	GRIP_RED    = 1 << 5
+	GRIP_GREEN  = 1 << 6
+)
+
+// GuitarGrip is the guitar hero on tour grip, identified by F9FF on rom
+// reads. Buttons are set by the frontend.
+type GuitarGrip struct {
+	Buttons uint8
+}
+
+func (g *GuitarGrip) Read(addr uint32) uint8 {
+	if sram := addr >= 0xA00_0000; sram {
+		return ^g.Buttons
+	}
+
+	if addr&1 == 0 {
+		return 0xFF
+	}
+
+	return 0xF9
+}
+
+func (g *GuitarGrip) Write(addr uint32, v uint8) {}
+
+func (g *GuitarGrip) SaveState(e *state.Encoder) { e.Encode(g) }
+func (g *GuitarGrip) LoadState(d *state.Decoder) { d.Decode(g) }
@@ -0,0 +1,96 @@
+package cart
+
+import "testing"
+
+// read16 reads a halfword from slot 2 as the 16 bit bus does
+func read16(s Slot2, addr uint32) uint16 {
+	return uint16(s.Read(addr)) | uint16(s.Read(addr+1))<<8
+}
+
+func TestRumblePak(t *testing.T) {
+	r := &RumblePak{}
+
+	// ad1 is pulled low on even addresses
+	if v := read16(r, 0x800_00FE); v != 0x007D {
+		t.Errorf("id read %04X", v)
+	}
+
+	if v := r.Read(0xA00_0000); v != 0xFF {
+		t.Errorf("sram read %02X", v)
+	}
+
+	r.Write(0x800_0000, 0)
+	if r.Moved {
+		t.Error("moved without a change")
+	}
+
+	r.Write(0x800_0001, 2)
+	r.Write(0xA00_0000, 2)
+	if r.Moved {
+		t.Error("moved by an odd or sram write")
+	}
+
+	r.Write(0x800_0000, 2)
+	if !r.Moved || r.Value != 2 {
+		t.Errorf("write moved %t, value %d", r.Moved, r.Value)
+	}
+}
+
+func TestExpansionPak(t *testing.T) {
+	e := &ExpansionPak{Ram: make([]uint8, EXPANSION_RAM_SIZE)}
+
+	for addr, want := range map[uint32]uint16{
+		0x800_00B0: 0xFFFF,
+		0x800_00B2: 0x0000,
+		0x800_00B4: 0x2400,
+		0x800_00B6: 0x2424,
+		0x800_00BE: 0x7FFF,
+		0x801_FFFE: 0x7FFF,
+		0x800_00C0: 0xFFFF,
+	} {
+		if v := read16(e, addr); v != want {
+			t.Errorf("id %08X read %04X, want %04X", addr, v, want)
+		}
+	}
+
+	const ram = 0x900_0000
+
+	e.Write(ram, 0x12)
+	if v := e.Read(ram); v != 0 {
+		t.Errorf("locked ram written, read %02X", v)
+	}
+
+	e.Write(0x800_0000+EXPANSION_LOCK, 1)
+	if v := read16(e, 0x800_0000+EXPANSION_LOCK); v != 1 {
+		t.Errorf("lock read %04X after unlock", v)
+	}
+
+	e.Write(ram, 0x12)
+	e.Write(ram+EXPANSION_RAM_SIZE-1, 0x34)
+	if v := e.Read(ram); v != 0x12 {
+		t.Errorf("ram read %02X", v)
+	}
+
+	if v := e.Read(ram + EXPANSION_RAM_SIZE - 1); v != 0x34 {
+		t.Errorf("ram end read %02X", v)
+	}
+
+	e.Write(0x800_0000+EXPANSION_LOCK, 0)
+	e.Write(ram, 0x56)
+	if v := e.Read(ram); v != 0x12 {
+		t.Errorf("relocked ram written, read %02X", v)
+	}
+}
+
+func TestGuitarGrip(t *testing.T) {
+	g := &GuitarGrip{}
+
+	if v := read16(g, 0x800_0000); v != 0xF9FF {
+		t.Errorf("id read %04X", v)
+	}
+
+	g.Buttons = GRIP_GREEN | GRIP_BLUE
+	if v := g.Read(0xA00_0000); v != ^uint8(GRIP_GREEN|GRIP_BLUE) {
+		t.Errorf("buttons read %02X", v)
+	}
+}
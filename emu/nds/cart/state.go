@@ -0,0 +1,49 @@
+package cart
+
+import "github.com/aabalke/guac/emu/state"
+
+// RomChecksum identifies the rom in save states, it is computed on first use
+func (c *Cartridge) RomChecksum() uint32 {
+	return c.checksum()
+}
+
+func (c *Cartridge) SaveState(e *state.Encoder) {
+	e.Encode(c, c.Backup)
+}
+
+func (c *Cartridge) LoadState(d *state.Decoder) {
+	d.Decode(c, c.Backup)
+}
+
+// slot2Chunk names the slot 2 chunk after the device, a state is only loaded
+// with the device it was saved with
+func (c *Cartridge) slot2Chunk() string {
+	switch c.Slot2.(type) {
+	case *GbaSlot:
+		return "slot2 gba"
+	case *RumblePak:
+		return "slot2 rumble"
+	case *ExpansionPak:
+		return "slot2 expansion"
+	case *GuitarGrip:
+		return "slot2 guitar"
+	default:
+		return "slot2 none"
+	}
+}
+
+// SaveSlot2State writes the device in slot 2, gba cart backups and expansion
+// pak ram included
+func (c *Cartridge) SaveSlot2State(e *state.Encoder) {
+	e.Chunk(c.slot2Chunk())
+	if c.Slot2 != nil {
+		c.Slot2.SaveState(e)
+	}
+}
+
+func (c *Cartridge) LoadSlot2State(d *state.Decoder) {
+	d.Chunk(c.slot2Chunk())
+	if c.Slot2 != nil {
+		c.Slot2.LoadState(d)
+	}
+}
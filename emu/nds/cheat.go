@@ -0,0 +1,16 @@
+package nds
+
+import (
+	"github.com/aabalke/guac/emu/cheat"
+	"github.com/aabalke/guac/emu/rom"
+)
+
+// action replay codes run on the arm9's view of memory
+func (nds *Nds) newCheats() *cheat.Engine {
+	return cheat.NewEngine(rom.NDS, cheat.ArmBus{Mem: &nds.mem, Arm9: true}, nil)
+}
+
+// SetCheats replaces the cheats in use
+func (nds *Nds) SetCheats(cheats []cheat.Cheat) error {
+	return nds.Cheats.Set(cheats)
+}
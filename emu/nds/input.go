@@ -12,47 +12,16 @@ import (
 var _ = fmt.Sprint
 
 func (nds *Nds) InputHandler(justKeys, keys []ebiten.Key, buttons []ebiten.StandardGamepadButton, mouse *input.Mouse, frame uint64) {
-	var (
-		keyCfg    = config.Conf.Nds.KeyboardConfig
-		buttonCfg = config.Conf.Nds.ControllerConfig
-		k         = &nds.mem.Keypad.KEYINPUT
-		k2        = &nds.mem.Keypad.KEYINPUT2
-	)
+	keyCfg := config.Conf.Nds.KeyboardConfig
 
-	*k = 0x3FF
-	*k2 |= 0b0100_1011
-	*k2 &^= 0b1000_0000
+	nds.SetButtons(input.Held(keys, buttons,
+		&config.Conf.Nds.KeyboardConfig,
+		&config.Conf.Nds.ControllerConfig,
+	))
 
-	mouseInput(nds, mouse, k2)
+	mouseInput(nds, mouse, &nds.mem.Keypad.KEYINPUT2)
 
-	for _, key := range keys {
-		switch {
-		case slices.Contains(keyCfg.A, key):
-			*k &^= 1 << 0
-		case slices.Contains(keyCfg.B, key):
-			*k &^= 1 << 1
-		case slices.Contains(keyCfg.Select, key):
-			*k &^= 1 << 2
-		case slices.Contains(keyCfg.Start, key):
-			*k &^= 1 << 3
-		case slices.Contains(keyCfg.Right, key):
-			*k &^= 1 << 4
-		case slices.Contains(keyCfg.Left, key):
-			*k &^= 1 << 5
-		case slices.Contains(keyCfg.Up, key):
-			*k &^= 1 << 6
-		case slices.Contains(keyCfg.Down, key):
-			*k &^= 1 << 7
-		case slices.Contains(keyCfg.R, key):
-			*k &^= 1 << 8
-		case slices.Contains(keyCfg.L, key):
-			*k &^= 1 << 9
-		case slices.Contains(keyCfg.X, key):
-			*k2 &^= 1 << 0
-		case slices.Contains(keyCfg.Y, key):
-			*k2 &^= 1 << 1
-		}
-	}
+	nds.slot2Input(keys, buttons)
 
 	for _, key := range justKeys {
 		switch {
@@ -66,36 +35,36 @@ func (nds *Nds) InputHandler(justKeys, keys []ebiten.Key, buttons []ebiten.Stand
 			nds.ppu.Rasterizer.Export.Export()
 		}
 	}
+}
 
-	for _, button := range buttons {
-		switch {
-		case slices.Contains(buttonCfg.A, button):
-			*k &^= 1 << 0
-		case slices.Contains(buttonCfg.B, button):
-			*k &^= 1 << 1
-		case slices.Contains(buttonCfg.Select, button):
-			*k &^= 1 << 2
-		case slices.Contains(buttonCfg.Start, button):
-			*k &^= 1 << 3
-		case slices.Contains(buttonCfg.Right, button):
-			*k &^= 1 << 4
-		case slices.Contains(buttonCfg.Left, button):
-			*k &^= 1 << 5
-		case slices.Contains(buttonCfg.Up, button):
-			*k &^= 1 << 6
-		case slices.Contains(buttonCfg.Down, button):
-			*k &^= 1 << 7
-		case slices.Contains(buttonCfg.R, button):
-			*k &^= 1 << 8
-		case slices.Contains(buttonCfg.L, button):
-			*k &^= 1 << 9
-		case slices.Contains(buttonCfg.X, button):
-			*k2 &^= 1 << 0
-		case slices.Contains(buttonCfg.Y, button):
-			*k2 &^= 1 << 1
-		}
+// SetButtons sets the buttons held for the next frame and releases the pen,
+// touch has to be set afterwards. The lid is closed while Lid is held.
+func (nds *Nds) SetButtons(b input.Buttons) {
+	var (
+		k  = &nds.mem.Keypad.KEYINPUT
+		k2 = &nds.mem.Keypad.KEYINPUT2
+	)
+
+	nds.buttons = b
+
+	*k = 0x3FF &^ uint16(b&0x3FF)
+
+	closed := *k2&0b1000_0000 != 0
+
+	// x, y, debug and pen released, hinge open
+	*k2 |= 0b0100_1011
+	*k2 &^= 0b1000_0000
+	*k2 &^= uint16(b>>10) & 0b11
+
+	if b&input.Lid != 0 {
+		*k2 |= 0b1000_0000
+	} else if closed {
+		// screens unfolding wakes the arm7
+		nds.arm7.Irq.SetIRQ(22)
 	}
 
+	nds.mem.Spi.Tsc.Mic.SetInput(b&input.Mic != 0, b&input.Blow != 0)
+
 	if nds.mem.Keypad.KeyIRQ() {
 		nds.arm9.Irq.SetIRQ(12)
 		nds.arm7.Irq.SetIRQ(12)
@@ -3,11 +3,13 @@ package mem
 import (
 	"encoding/binary"
 	"fmt"
+	"log"
 	"unsafe"
 
 	"github.com/aabalke/guac/config"
 	"github.com/aabalke/guac/emu/bios"
 	"github.com/aabalke/guac/emu/cpu"
+	"github.com/aabalke/guac/emu/debugger"
 	"github.com/aabalke/guac/emu/nds/cart"
 	"github.com/aabalke/guac/emu/nds/mem/dma"
 	"github.com/aabalke/guac/emu/nds/mem/spi"
@@ -26,8 +28,8 @@ type Mem struct {
 	Arm7Bios *[]uint8
 	Arm9Bios *[]uint8
 
-	// this size is temp
-	IO [0x100_0000]uint8
+	// this size is temp, only the register pages are in states
+	IO [0x100_0000]uint8 `state:"-"`
 
 	halted7, halted9 *bool
 	irq7, irq9       *cpu.Irq
@@ -55,6 +57,8 @@ type Mem struct {
 	Timers      [8]Timer
 
 	Jit7, Jit9 Jit
+
+	Debug7, Debug9 *debugger.Debugger
 }
 
 type BiosProt uint16
@@ -107,8 +111,17 @@ func NewMemory(
 	m.PowCnt.WriteCNT1(1, 0x82, Ppu)
 
 	m.Spi.Init()
+	m.Spi.Tsc.Mic.Load()
+
+	m.Wifi = wifi.NewWifi(irq7)
 
-	m.Wifi = wifi.NewWifi()
+	if addr := config.Conf.Nds.Wifi.Air; addr != "" {
+		if air, err := wifi.OpenAir(addr); err != nil {
+			log.Printf("Wifi: could not open air %s: %v\n", addr, err)
+		} else {
+			m.Wifi.Connect(air)
+		}
+	}
 
 	return m
 }
@@ -193,10 +206,24 @@ func (mem *Mem) Read(addr uint32, arm9 bool) uint8 {
 	}
 }
 
+// watch passes cpu accesses to the debugger of the cpu while it is watching
+func (mem *Mem) watch(addr, size uint32, access debugger.Access, arm9 bool) {
+	d := mem.Debug7
+	if arm9 {
+		d = mem.Debug9
+	}
+
+	if d.Watching() {
+		d.Watch(addr, size, access)
+	}
+}
+
 func (mem *Mem) Read8(addr uint32, arm9 bool) uint32 {
+	mem.watch(addr, 1, debugger.READ, arm9)
 	return uint32(mem.Read(addr, arm9))
 }
 func (mem *Mem) Read16(addr uint32, arm9 bool) uint32 {
+	mem.watch(addr, 2, debugger.READ, arm9)
 
 	if !arm9 && addr >= 0x480_0000 && addr < 0x490_0000 {
 		return uint32(mem.Wifi.Read16(addr))
@@ -207,6 +234,11 @@ func (mem *Mem) Read16(addr uint32, arm9 bool) uint32 {
 	return uint32(mem.Read(addr, arm9)) | (uint32(mem.Read(addr+1, arm9)) << 8)
 }
 func (mem *Mem) Read32(addr uint32, arm9 bool) uint32 {
+	mem.watch(addr, 4, debugger.READ, arm9)
+
+	if !arm9 && addr >= 0x480_0000 && addr < 0x490_0000 {
+		return uint32(mem.Wifi.Read16(addr)) | uint32(mem.Wifi.Read16(addr+2))<<16
+	}
 
 	switch addr {
 	case 0x410_0000:
@@ -338,6 +370,8 @@ func (mem *Mem) Write(addr uint32, v uint8, arm9 bool) {
 		case 0x7:
 			mem.Oam[addr&0x7FF] = v
 			mem.Ppu.UpdateOAM(addr, v, &mem.Oam)
+		case 0x8, 0x9, 0xA:
+			mem.Cartridge.WriteGbaSlot(addr, v, arm9)
 		}
 
 		return
@@ -353,13 +387,17 @@ func (mem *Mem) Write(addr uint32, v uint8, arm9 bool) {
 		mem.WriteArm7IO(addr-0x400_0000, v)
 	case 0x6:
 		mem.Ppu.Vram.Write7(addr, v)
+	case 0x8, 0x9, 0xA:
+		mem.Cartridge.WriteGbaSlot(addr, v, arm9)
 	}
 }
 
 func (mem *Mem) Write8(addr uint32, v uint8, arm9 bool) {
+	mem.watch(addr, 1, debugger.WRITE, arm9)
 	mem.Write(addr, v, arm9)
 }
 func (mem *Mem) Write16(addr uint32, v uint16, arm9 bool) {
+	mem.watch(addr, 2, debugger.WRITE, arm9)
 
 	if !arm9 && addr >= 0x480_0000 && addr < 0x490_0000 {
 		mem.Wifi.Write16(addr, v)
@@ -375,6 +413,13 @@ func (mem *Mem) Write16(addr uint32, v uint16, arm9 bool) {
 	mem.Write(addr+1, uint8(v>>8), arm9)
 }
 func (mem *Mem) Write32(addr uint32, v uint32, arm9 bool) {
+	mem.watch(addr, 4, debugger.WRITE, arm9)
+
+	if !arm9 && addr >= 0x480_0000 && addr < 0x490_0000 {
+		mem.Wifi.Write16(addr, uint16(v))
+		mem.Wifi.Write16(addr+2, uint16(v>>16))
+		return
+	}
 
 	if arm9 {
 
@@ -1220,7 +1265,8 @@ func (mem *Mem) WriteArm7IO(addr uint32, v uint8) {
 		return
 
 	case 0x204:
-		mem.Cartridge.WriteExMem(v, 0)
+		// the arm7 cannot take the gba slot from the arm9
+		mem.Cartridge.WriteExMem(v&0x7F|mem.Cartridge.ReadExMem(0)&0x80, 0)
 
 	case 0x208:
 		mem.irq7.WriteIME(v)
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/clock"
 )
 
 // interrupts not setup
@@ -209,7 +210,7 @@ func (r *Rtc) WriteData(v uint8) {
 
 	case CMD_DT, CMD_TIME:
 
-		now := time.Now().Add(time.Hour * time.Duration(config.Conf.Nds.Rtc.AdditionalHours))
+		now := clock.Now().Add(time.Hour * time.Duration(config.Conf.Nds.Rtc.AdditionalHours))
 
 		var hour uint8
 		if hr24 := r.RegStatus1&2 != 0; hr24 {
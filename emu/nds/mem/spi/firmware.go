@@ -4,6 +4,8 @@ import (
 	_ "embed"
 	"fmt"
 	"log"
+	"net"
+	"os"
 
 	"github.com/aabalke/guac/config"
 	"github.com/aabalke/guac/utils"
@@ -40,10 +42,37 @@ func (f *Firmware) Load() {
 		f.Data, _, _ = utils.ReadFile(path)
 	}
 
+	if mac, ok := wifiMac(); ok {
+		FirmwareSetMac(&f.Data, mac)
+	}
+
 	// user settings come from config and should override firmware file
 	FirmwareSetUserSettings(&f.Data)
 }
 
+// wifiMac is the mac set in config, or one made from the process id when the
+// wifi is on the air, instances sharing a firmware would share its mac
+func wifiMac() (net.HardwareAddr, bool) {
+	w := config.Conf.Nds.Wifi
+
+	if w.Mac != "" {
+		mac, err := net.ParseMAC(w.Mac)
+		if err != nil || len(mac) != 6 {
+			log.Printf("Wifi: invalid mac %q\n", w.Mac)
+			return nil, false
+		}
+
+		return mac, true
+	}
+
+	if w.Air == "" {
+		return nil, false
+	}
+
+	pid := os.Getpid()
+	return net.HardwareAddr{0x00, 0x09, 0xBF, uint8(pid >> 16), uint8(pid >> 8), uint8(pid)}, true
+}
+
 func (f *Firmware) Transfer(data []uint8) (reply []uint8, stat uint8) {
 
 	switch inst := data[0]; inst {
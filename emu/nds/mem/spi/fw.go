@@ -4,9 +4,9 @@ package spi
 
 import (
 	"encoding/binary"
-	"time"
 
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/clock"
 )
 
 var bbinit = [0x69]byte{
@@ -144,6 +144,16 @@ func FirmwareSetHeader(data *[]byte) {
 	binary.LittleEndian.PutUint16((*data)[0x2A:], crc)
 }
 
+// FirmwareSetMac replaces the wifi mac address, the wifi settings crc covers
+// it
+func FirmwareSetMac(data *[]byte, mac []byte) {
+	copy((*data)[0x36:0x3C], mac)
+
+	l := binary.LittleEndian.Uint16((*data)[0x2C:])
+	crc := Crc16((*data)[0x2C:0x2C+l], 0)
+	binary.LittleEndian.PutUint16((*data)[0x2A:], crc)
+}
+
 func FirmwareSetAccessPoints(d *[]byte) {
 	offset := int(binary.LittleEndian.Uint16((*d)[0x20:])) * 8
 	firmwareSetAccessPoint(d, offset-0x400)
@@ -196,7 +206,7 @@ func firwareSetUseSetting(d *[]byte, base, idx uint32) {
 
 	(*d)[base+0x64] = 0b0111_0001
 	(*d)[base+0x65] = 0b1110_1100
-	(*d)[base+0x66] = byte(time.Now().Year())
+	(*d)[base+0x66] = byte(clock.Now().Year())
 
 	(*d)[base+0x70] = byte(idx)
 
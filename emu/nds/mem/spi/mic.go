@@ -0,0 +1,108 @@
+package spi
+
+import (
+	"log"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/wav"
+)
+
+const (
+	MIC_CPU_FREQ_HZ = 33513982
+
+	// blowing is noise close to the full range of the mic
+	MIC_BLOW_LEVEL = 0x6000
+)
+
+// Mic is the microphone on the touchscreen controller aux channel. The wav
+// plays on a loop or once each time the mic key is pressed, blowing is noise
+// while the blow key is held. Samples advance with emulated time.
+type Mic struct {
+	wav  *wav.Wav
+	loop bool
+
+	Pos     int
+	Acc     uint64
+	Playing bool
+	Blowing bool
+	Pressed bool
+	Noise   uint32
+	Sample  int16
+}
+
+func (m *Mic) Load() {
+	c := config.Conf.Nds.Mic
+
+	m.Noise = 1
+	m.loop = c.Loop
+
+	if c.WavPath == "" {
+		return
+	}
+
+	w, err := wav.ReadFile(c.WavPath)
+	if err != nil || len(w.Samples) == 0 || w.Rate == 0 {
+		log.Printf("Mic: could not load %s: %v\n", c.WavPath, err)
+		return
+	}
+
+	log.Printf("Mic: %s, %d samples at %dhz\n", c.WavPath, len(w.Samples), w.Rate)
+
+	m.wav = w
+	m.Playing = m.loop
+}
+
+// SetInput takes the mic and blow keys held this frame, a press of the mic
+// key starts the wav over
+func (m *Mic) SetInput(mic, blow bool) {
+	if mic && !m.Pressed && m.wav != nil {
+		m.Playing = true
+		m.Pos, m.Acc = 0, 0
+	}
+
+	m.Pressed = mic
+	m.Blowing = blow
+}
+
+// Update advances the mic by arm7 cycles
+func (m *Mic) Update(cycles uint32) {
+	if !m.Playing && !m.Blowing {
+		m.Sample = 0
+		return
+	}
+
+	if m.Blowing {
+		// xorshift, the game only looks at the loudness
+		m.Noise ^= m.Noise << 13
+		m.Noise ^= m.Noise >> 17
+		m.Noise ^= m.Noise << 5
+		m.Sample = int16(int32(m.Noise%(MIC_BLOW_LEVEL*2)) - MIC_BLOW_LEVEL)
+	}
+
+	if !m.Playing || m.wav == nil {
+		return
+	}
+
+	m.Acc += uint64(cycles) * uint64(m.wav.Rate)
+	m.Pos += int(m.Acc / MIC_CPU_FREQ_HZ)
+	m.Acc %= MIC_CPU_FREQ_HZ
+
+	if m.Pos >= len(m.wav.Samples) {
+		if !m.loop {
+			m.Playing = false
+			m.Pos = 0
+			return
+		}
+
+		m.Pos %= len(m.wav.Samples)
+	}
+
+	if !m.Blowing {
+		m.Sample = m.wav.Samples[m.Pos]
+	}
+}
+
+// Adc is the 12 bit conversion of the current sample
+func (m *Mic) Adc() uint16 {
+	return uint16(int32(m.Sample)>>4+0x800) & 0xFFF
+}
@@ -0,0 +1,134 @@
+package spi
+
+import (
+	"testing"
+
+	"github.com/aabalke/guac/emu/wav"
+)
+
+// newMic has a wav of rising samples at 32768hz, a sample every ~1023 arm7
+// cycles
+func newMic(loop bool) *Mic {
+	w := &wav.Wav{Rate: 32768, Samples: make([]int16, 4)}
+	for i := range w.Samples {
+		w.Samples[i] = int16(i+1) << 8
+	}
+
+	return &Mic{wav: w, loop: loop, Noise: 1}
+}
+
+func TestMicTiming(t *testing.T) {
+	m := newMic(false)
+
+	m.Update(0x100)
+	if m.Sample != 0 || m.Adc() != 0x800 {
+		t.Fatalf("silent mic sample %d, adc %03X", m.Sample, m.Adc())
+	}
+
+	m.SetInput(true, false)
+
+	// samples only move on with emulated time
+	for _, step := range []struct {
+		cycles uint32
+		pos    int
+	}{
+		{1000, 0},
+		{22, 0},
+		{1, 1},
+		{1023, 2},
+		{1023, 3},
+	} {
+		m.Update(step.cycles)
+
+		if m.Pos != step.pos || m.Sample != m.wav.Samples[step.pos] {
+			t.Fatalf("after %d cycles pos %d sample %d, want %d", step.cycles, m.Pos, m.Sample, step.pos)
+		}
+	}
+
+	if adc := m.Adc(); adc != 0x840 {
+		t.Fatalf("adc %03X", adc)
+	}
+
+	// holding the key does not start over
+	m.SetInput(true, false)
+	if m.Pos != 3 {
+		t.Fatalf("held key moved the wav to %d", m.Pos)
+	}
+
+	// a single play stops at the end, the mic goes quiet after
+	m.Update(1023)
+	if m.Playing || m.Pos != 0 {
+		t.Fatalf("playing %t at %d past the end", m.Playing, m.Pos)
+	}
+
+	m.Update(0x100)
+	if m.Sample != 0 {
+		t.Fatalf("stopped mic sample %d", m.Sample)
+	}
+
+	// a new press plays it again
+	m.SetInput(false, false)
+	m.SetInput(true, false)
+	m.Update(0x100)
+	if !m.Playing || m.Sample != m.wav.Samples[0] {
+		t.Fatalf("replay playing %t, sample %d", m.Playing, m.Sample)
+	}
+}
+
+func TestMicLoop(t *testing.T) {
+	m := newMic(true)
+	m.Playing = true
+
+	// 5 samples in, past the end of 4
+	m.Update(5 * 1023)
+
+	if !m.Playing || m.Pos != 1 || m.Sample != m.wav.Samples[1] {
+		t.Fatalf("looped playing %t to %d, sample %d", m.Playing, m.Pos, m.Sample)
+	}
+}
+
+func TestMicBlow(t *testing.T) {
+	m := newMic(false)
+	m.SetInput(false, true)
+
+	seen := map[int16]bool{}
+
+	for range 64 {
+		m.Update(0x100)
+
+		if m.Sample < -MIC_BLOW_LEVEL || m.Sample >= MIC_BLOW_LEVEL {
+			t.Fatalf("blow sample %d out of range", m.Sample)
+		}
+
+		seen[m.Sample] = true
+	}
+
+	if len(seen) < 32 {
+		t.Fatalf("blowing made %d distinct samples", len(seen))
+	}
+
+	m.SetInput(false, false)
+	m.Update(0x100)
+	if m.Sample != 0 {
+		t.Fatalf("sample %d after blowing", m.Sample)
+	}
+}
+
+func TestMicAdc(t *testing.T) {
+	for _, tt := range []struct {
+		sample int16
+		adc    uint16
+	}{
+		{0, 0x800},
+		{0x10, 0x801},
+		{-0x10, 0x7FF},
+		{0x7FFF, 0xFFF},
+		{-0x8000, 0x000},
+	} {
+		m := &Mic{Sample: tt.sample}
+
+		if adc := m.Adc(); adc != tt.adc {
+			t.Errorf("sample %d adc %03X, want %03X", tt.sample, adc, tt.adc)
+		}
+	}
+}
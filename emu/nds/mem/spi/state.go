@@ -0,0 +1,29 @@
+package spi
+
+import "github.com/aabalke/guac/emu/state"
+
+// SaveState covers what the owning struct encode skips, the spi value itself
+// is encoded as part of mem
+func (s *Spi) SaveState(e *state.Encoder) {
+	active := s.TransferDevice != nil
+	device := uint8(0)
+	if active {
+		device = *s.TransferDevice
+	}
+
+	e.Encode(s.Pmd, &active, &device)
+}
+
+func (s *Spi) LoadState(d *state.Decoder) {
+	var (
+		active bool
+		device uint8
+	)
+
+	d.Decode(s.Pmd, &active, &device)
+
+	s.TransferDevice = nil
+	if active {
+		s.TransferDevice = &device
+	}
+}
@@ -34,6 +34,8 @@ type Tsc struct {
 	IrqEnabled bool
 
 	TouchActive bool
+
+	Mic Mic
 }
 
 func (t *Tsc) Transfer(data []uint8) (reply []uint8, stat uint8) {
@@ -84,7 +86,7 @@ func (t *Tsc) Transfer(data []uint8) (reply []uint8, stat uint8) {
 
 	case CH_AUX:
 
-		out = 0x0
+		out = t.Mic.Adc()
 
 	default:
 		//out = 0
@@ -0,0 +1,24 @@
+package mem
+
+import "github.com/aabalke/guac/emu/state"
+
+// IO_STATE_SIZE is the part of IO kept in states, the arm9 and arm7 register
+// pages. Registers past it are decoded into their own fields, the other 16mb
+// would only make every snapshot larger.
+const IO_STATE_SIZE = 0x2000
+
+func (mem *Mem) SaveState(e *state.Encoder) {
+	e.Encode(mem, &lockWrites, &irqEmptyFlag, &irqNotEmptyFlag)
+	e.Encode((*[IO_STATE_SIZE]uint8)(mem.IO[:]))
+	mem.Spi.SaveState(e)
+	mem.Wifi.SaveState(e)
+	e.Encode(mem.Snd)
+}
+
+func (mem *Mem) LoadState(d *state.Decoder) {
+	d.Decode(mem, &lockWrites, &irqEmptyFlag, &irqNotEmptyFlag)
+	d.Decode((*[IO_STATE_SIZE]uint8)(mem.IO[:]))
+	mem.Spi.LoadState(d)
+	mem.Wifi.LoadState(d)
+	d.Decode(mem.Snd)
+}
@@ -0,0 +1,194 @@
+package wifi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Air is the medium frames travel on between guac instances on one machine.
+// Every node sends its frames to every other node, there is no host, the
+// ds software sorts out who talks to who like it would over the air.
+//
+// Addresses are "udp:host:port", where a node binds the first free port of
+// AIR_NODES ports from port, or "unix:dir", where a node binds a datagram
+// socket in dir.
+
+const (
+	AIR_NODES = 8
+	AIR_MAGIC = 0x4649_5747 // GWIF
+	AIR_HDR   = 8
+	AIR_MTU   = AIR_HDR + 0x1000
+
+	// how often a unix node looks for nodes that joined or left
+	AIR_REFRESH = time.Second
+)
+
+// nodes numbers the unix nodes of this process
+var nodes atomic.Int32
+
+type packet struct {
+	rate    uint16
+	frame   []uint8
+	replied bool // the mp reply was sent by the listener
+}
+
+type Air struct {
+	conn net.PacketConn
+	path string // unix socket to remove on close
+
+	mu        sync.Mutex
+	peers     []net.Addr
+	refreshed time.Time
+	list      func() []net.Addr
+}
+
+func OpenAir(addr string) (*Air, error) {
+	if dir, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return openUnix(dir)
+	}
+
+	addr, _ = strings.CutPrefix(addr, "udp:")
+	return openUdp(addr)
+}
+
+func openUdp(addr string) (*Air, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("air port %q: %w", port, err)
+	}
+
+	for i := range AIR_NODES {
+		conn, err := net.ListenPacket("udp", net.JoinHostPort(host, strconv.Itoa(base+i)))
+		if err != nil {
+			continue
+		}
+
+		a := &Air{conn: conn}
+
+		for j := range AIR_NODES {
+			if j == i {
+				continue
+			}
+
+			peer, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(base+j)))
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+
+			a.peers = append(a.peers, peer)
+		}
+
+		log.Printf("Wifi: on air at %s\n", conn.LocalAddr())
+		return a, nil
+	}
+
+	return nil, fmt.Errorf("air %s: all %d nodes are taken", addr, AIR_NODES)
+}
+
+func openUnix(dir string) (*Air, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%d-%d.sock", os.Getpid(), nodes.Add(1))
+	path := filepath.Join(dir, name)
+	os.Remove(path)
+
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Air{conn: conn, path: path}
+
+	a.list = func() []net.Addr {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.sock"))
+
+		var peers []net.Addr
+		for _, p := range paths {
+			if p != path {
+				peers = append(peers, &net.UnixAddr{Name: p, Net: "unixgram"})
+			}
+		}
+
+		return peers
+	}
+
+	log.Printf("Wifi: on air at %s\n", path)
+	return a, nil
+}
+
+func (a *Air) nodes() []net.Addr {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.list != nil && time.Since(a.refreshed) > AIR_REFRESH {
+		a.peers = a.list()
+		a.refreshed = time.Now()
+	}
+
+	return a.peers
+}
+
+// Send puts a frame on the air, nodes that are not listening miss it
+func (a *Air) Send(rate uint16, frame []uint8) {
+	buf := make([]uint8, AIR_HDR+len(frame))
+	binary.LittleEndian.PutUint32(buf, AIR_MAGIC)
+	binary.LittleEndian.PutUint16(buf[4:], rate)
+	binary.LittleEndian.PutUint16(buf[6:], uint16(len(frame)))
+	copy(buf[AIR_HDR:], frame)
+
+	for _, peer := range a.nodes() {
+		a.conn.WriteTo(buf, peer)
+	}
+}
+
+// Recv blocks until a frame is heard, false once the air is closed
+func (a *Air) Recv() (packet, bool) {
+	buf := make([]uint8, AIR_MTU)
+
+	for {
+		n, _, err := a.conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return packet{}, false
+		}
+
+		if err != nil || n < AIR_HDR || binary.LittleEndian.Uint32(buf) != AIR_MAGIC {
+			continue
+		}
+
+		l := int(binary.LittleEndian.Uint16(buf[6:]))
+		if AIR_HDR+l > n {
+			continue
+		}
+
+		return packet{
+			rate:  binary.LittleEndian.Uint16(buf[4:]),
+			frame: append([]uint8(nil), buf[AIR_HDR:AIR_HDR+l]...),
+		}, true
+	}
+}
+
+func (a *Air) Close() {
+	a.conn.Close()
+
+	if a.path != "" {
+		os.Remove(a.path)
+	}
+}
@@ -0,0 +1,664 @@
+package wifi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/aabalke/guac/emu/cpu"
+)
+
+const (
+	W_ID            = 0x000
+	W_IF            = 0x010
+	W_IE            = 0x012
+	W_MACADDR       = 0x018
+	W_BSSID         = 0x020
+	W_AID_LOW       = 0x028
+	W_RXCNT         = 0x030
+	W_RXBUF_WRCSR   = 0x054
+	W_RXBUF_WR_ADDR = 0x056
+	W_RXBUF_READCSR = 0x05A
+	W_RXBUF_COUNT   = 0x05C
+	W_RXBUF_GAP     = 0x062
+	W_RXBUF_GAPDISP = 0x064
+	W_TXBUF_COUNT   = 0x06C
+	W_TXBUF_BEACON  = 0x080
+	W_BEACONINT     = 0x08C
+	W_TXBUF_CMD     = 0x090
+	W_TXBUF_REPLY1  = 0x094
+	W_TXBUF_REPLY2  = 0x098
+	W_TXBUF_LOC1    = 0x0A0
+	W_TXBUF_LOC2    = 0x0A4
+	W_TXBUF_LOC3    = 0x0A8
+	W_TXREQ_RESET   = 0x0AC
+	W_TXREQ_SET     = 0x0AE
+	W_TXBUF_RESET   = 0x0B4
+	W_TXBUSY        = 0x0B6
+	W_TXSTAT        = 0x0B8
+	W_US_COUNTCNT   = 0x0E8
+	W_US_COMPARECNT = 0x0EA
+	W_US_COMPARE0   = 0x0F0
+	W_US_COUNT0     = 0x0F8
+	W_PRE_BEACON    = 0x110
+	W_BEACONCOUNT1  = 0x11C
+	W_BEACONCOUNT2  = 0x134
+	W_TX_SEQNO      = 0x210
+	W_IF_SET        = 0x21C
+)
+
+const (
+	IRQ_RX_DONE     = 0
+	IRQ_TX_DONE     = 1
+	IRQ_RX_INC      = 2
+	IRQ_TX_ERR      = 3
+	IRQ_RX_OVF      = 4
+	IRQ_TX_OVF      = 5
+	IRQ_RX_START    = 6
+	IRQ_TX_START    = 7
+	IRQ_TXBUF_CNT   = 8
+	IRQ_RXBUF_CNT   = 9
+	IRQ_RF_WAKEUP   = 11
+	IRQ_MP_END      = 12
+	IRQ_POST_BEACON = 13
+	IRQ_BEACON      = 14
+	IRQ_PRE_BEACON  = 15
+)
+
+// tx slots, the bit of the loc and cmd slots in W_TXREQ
+const (
+	SLOT_LOC1 = iota
+	SLOT_CMD
+	SLOT_LOC2
+	SLOT_LOC3
+	SLOT_BEACON
+)
+
+const (
+	// arm7 cycles per microsecond, in thousandths
+	CYCLES_PER_US = 33_514
+
+	RATE_1MBIT = 0x0A
+	RATE_2MBIT = 0x14
+
+	RX_HDR = 12
+	TX_HDR = 12
+	FCS    = 4
+
+	// longest the host waits on its clients to reply to a mp command, the
+	// clients reply from their listener as soon as the command is heard.
+	// The emulation keeps running while it waits
+	MP_REPLY_TIMEOUT = 5 * time.Millisecond
+
+	// frame control of the multiplay frames, without the retry and power bits
+	FC_MP_CMD         = 0x0228
+	FC_MP_ACK         = 0x0218
+	FC_MP_REPLY       = 0x0118
+	FC_MP_EMPTY_REPLY = 0x0158
+	FC_MP_MASK        = 0x03FC
+)
+
+var (
+	slotRegs  = [...]uint32{W_TXBUF_LOC1, W_TXBUF_CMD, W_TXBUF_LOC2, W_TXBUF_LOC3, W_TXBUF_BEACON}
+	slotOrder = [...]int{SLOT_BEACON, SLOT_CMD, SLOT_LOC3, SLOT_LOC2, SLOT_LOC1}
+
+	mpReplyAddr = []uint8{0x03, 0x09, 0xBF, 0x00, 0x00, 0x10}
+	mpAckAddr   = []uint8{0x03, 0x09, 0xBF, 0x00, 0x00, 0x03}
+)
+
+// Connect puts the wifi on the air, frames are sent and heard from then on
+func (wf *Wifi) Connect(air *Air) {
+	wf.air = air
+	go wf.listen()
+}
+
+func (wf *Wifi) Close() {
+	if wf.air != nil {
+		wf.air.Close()
+	}
+}
+
+// Update runs the mac for arm7 cycles, the us counter, beacon timing and the
+// frame being sent advance in microsecond steps
+func (wf *Wifi) Update(cycles uint32) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	if len(wf.rx) != 0 {
+		wf.drain()
+	}
+
+	idle := wf.io[W_US_COUNTCNT>>1]&1 == 0 && !wf.TxActive && !wf.BeaconReq && wf.WTxReqRead == 0
+	if idle {
+		return
+	}
+
+	wf.UsCycles += cycles * 1000
+	for wf.UsCycles >= CYCLES_PER_US {
+		wf.UsCycles -= CYCLES_PER_US
+		wf.tick()
+	}
+}
+
+func (wf *Wifi) tick() {
+	if wf.io[W_US_COUNTCNT>>1]&1 != 0 {
+		wf.UsCount++
+
+		us := wf.UsCount & 0x3FF
+
+		if wf.io[W_US_COMPARECNT>>1]&1 != 0 {
+			before := uint32(wf.io[W_BEACONCOUNT1>>1])<<10 | uint32(0x3FF-us)
+			if before == uint32(wf.io[W_PRE_BEACON>>1]) {
+				wf.setIrq(IRQ_PRE_BEACON)
+			}
+		}
+
+		if us == 0 {
+			wf.msTick()
+		}
+	}
+
+	if wf.TxActive {
+		if wf.MpReplying {
+			wf.mpCollect()
+			return
+		}
+
+		if wf.TxTime--; wf.TxTime == 0 {
+			wf.finishTx()
+		}
+		return
+	}
+
+	if wf.BeaconReq || wf.WTxReqRead != 0 {
+		wf.startTx()
+	}
+}
+
+func (wf *Wifi) msTick() {
+	if wf.io[W_US_COMPARECNT>>1]&1 != 0 && wf.UsCount&^0x3FF == wf.UsCompare&^0x3FF {
+		wf.beaconSlot()
+	}
+
+	if cnt := &wf.io[W_BEACONCOUNT1>>1]; *cnt != 0 {
+		if *cnt--; *cnt == 0 {
+			wf.beaconSlot()
+		}
+	}
+
+	if cnt := &wf.io[W_BEACONCOUNT2>>1]; *cnt != 0 {
+		if *cnt--; *cnt == 0 {
+			wf.setIrq(IRQ_POST_BEACON)
+		}
+	}
+}
+
+// beaconSlot starts the next beacon interval, the beacon is sent if enabled
+func (wf *Wifi) beaconSlot() {
+	wf.io[W_BEACONCOUNT1>>1] = wf.io[W_BEACONINT>>1]
+	wf.setIrq(IRQ_BEACON)
+
+	if wf.io[W_TXBUF_BEACON>>1]&0x8000 != 0 {
+		wf.BeaconReq = true
+	}
+}
+
+func (wf *Wifi) setIrq(bit int) {
+	pending := wf.If&wf.Ie != 0
+	wf.If |= 1 << bit
+
+	if !pending && wf.If&wf.Ie != 0 && wf.irq != nil {
+		wf.irq.SetIRQ(cpu.IRQ_WIFI)
+	}
+}
+
+func (wf *Wifi) writeIe(v uint16) {
+	pending := wf.If&wf.Ie != 0
+	wf.Ie = v
+
+	if !pending && wf.If&wf.Ie != 0 && wf.irq != nil {
+		wf.irq.SetIRQ(cpu.IRQ_WIFI)
+	}
+}
+
+func (wf *Wifi) requested(slot int) bool {
+	if slot == SLOT_BEACON {
+		return wf.BeaconReq
+	}
+
+	return wf.WTxReqRead&(1<<slot) != 0
+}
+
+// startTx sends the requested slot with the highest priority, the frame is
+// put on the air right away and the slot finishes after its air time
+func (wf *Wifi) startTx() {
+	for _, slot := range slotOrder {
+		reg := wf.io[slotRegs[slot]>>1]
+
+		if !wf.requested(slot) {
+			continue
+		}
+
+		if reg&0x8000 == 0 {
+			if slot == SLOT_BEACON {
+				wf.BeaconReq = false
+			}
+			continue
+		}
+
+		addr := uint32(reg&0xFFF) << 1
+		rate := wf.ram16(addr + 8)
+		length := wf.ram16(addr + 10)
+
+		frame := addr + TX_HDR
+
+		wf.stampSeq(frame)
+
+		if slot == SLOT_BEACON {
+			for i := range uint32(4) {
+				wf.setRam16(frame+24+i*2, uint16(wf.UsCount>>(i*16)))
+			}
+		}
+
+		f := wf.read(frame, max(int(length)-FCS, 0))
+
+		if slot == SLOT_CMD {
+			wf.MpClients = 0
+			if len(f) >= 28 {
+				wf.MpClients = binary.LittleEndian.Uint16(f[26:])
+			}
+
+			// replies to an earlier command are stale
+			for len(wf.replies) != 0 {
+				<-wf.replies
+			}
+		}
+
+		wf.TxSlot = slot
+		wf.TxAddr = addr
+		wf.TxTime = txTime(rate, length)
+		wf.TxActive = true
+		wf.io[W_TXBUSY>>1] |= 1 << slot
+
+		wf.setIrq(IRQ_TX_START)
+		wf.send(rate, f)
+		return
+	}
+}
+
+func (wf *Wifi) finishTx() {
+	slot := wf.TxSlot
+
+	if slot == SLOT_CMD && wf.mpAwait() {
+		return
+	}
+
+	wf.TxActive = false
+	wf.io[W_TXBUSY>>1] &^= 1 << slot
+
+	// tx header status, sent
+	wf.setRam16(wf.TxAddr, 0x0001)
+
+	switch slot {
+	case SLOT_BEACON:
+		wf.BeaconReq = false
+		wf.io[W_TXSTAT>>1] = 0x0301
+
+	case SLOT_CMD:
+		wf.io[W_TXBUF_CMD>>1] &^= 0x8000
+		wf.mpAck()
+		wf.io[W_TXSTAT>>1] = 0x0B01
+		wf.setIrq(IRQ_MP_END)
+
+	case SLOT_LOC1:
+		wf.io[W_TXBUF_LOC1>>1] &^= 0x8000
+		wf.io[W_TXSTAT>>1] = 0x0001
+
+	default:
+		wf.io[slotRegs[slot]>>1] &^= 0x8000
+		wf.io[W_TXSTAT>>1] = 0x0001 | uint16(slot-1)<<12
+	}
+
+	wf.setIrq(IRQ_TX_DONE)
+}
+
+// mpAwait starts waiting on the replies to the command that was sent, the
+// command slot stays busy until mpCollect has them. It reports false when
+// there is nobody to wait on.
+func (wf *Wifi) mpAwait() bool {
+	if wf.MpReplying {
+		return false
+	}
+
+	wf.MpMissing = wf.MpClients &^ 1
+
+	if wf.air == nil || wf.MpMissing == 0 {
+		return false
+	}
+
+	wf.MpReplying = true
+	wf.mpDeadline = time.Now().Add(MP_REPLY_TIMEOUT)
+
+	return true
+}
+
+// mpCollect takes the replies heard so far without waiting on more, the
+// command finishes once every client replied or the timeout passed
+func (wf *Wifi) mpCollect() {
+	for wf.MpMissing != 0 {
+		select {
+		case p := <-wf.replies:
+			wf.receive(p)
+			wf.MpMissing &^= 1 << replyAid(p.frame)
+			continue
+		default:
+		}
+
+		break
+	}
+
+	if wf.MpMissing != 0 && time.Now().Before(wf.mpDeadline) {
+		return
+	}
+
+	wf.finishTx()
+	wf.MpReplying = false
+}
+
+// mpAck acks the replies to the command, the ack holds the clients that did
+// not reply
+func (wf *Wifi) mpAck() {
+	missing := wf.MpMissing
+
+	mac, bssid := wf.addr(W_MACADDR), wf.addr(W_BSSID)
+
+	ack := make([]uint8, 28)
+	binary.LittleEndian.PutUint16(ack, FC_MP_ACK)
+	copy(ack[4:], mpAckAddr)
+	copy(ack[10:], mac[:])
+	copy(ack[16:], bssid[:])
+	binary.LittleEndian.PutUint16(ack[22:], wf.nextSeq()<<4)
+	binary.LittleEndian.PutUint16(ack[24:], 0x0033)
+	binary.LittleEndian.PutUint16(ack[26:], missing)
+
+	wf.send(RATE_2MBIT, ack)
+}
+
+func (wf *Wifi) send(rate uint16, frame []uint8) {
+	if wf.air != nil {
+		wf.air.Send(rate, frame)
+	}
+}
+
+// listen takes frames off the air. Mp commands are replied to here, the
+// host is waiting on the reply while this console may be between frames
+func (wf *Wifi) listen() {
+	for {
+		p, ok := wf.air.Recv()
+		if !ok {
+			return
+		}
+
+		switch mpKind(p.frame) {
+		case FC_MP_REPLY, FC_MP_EMPTY_REPLY:
+			wf.mu.Lock()
+			bssid := wf.addr(W_BSSID)
+			wf.mu.Unlock()
+
+			// replies to the host of another bss
+			if !bytes.Equal(p.frame[16:22], bssid[:]) {
+				continue
+			}
+
+			select {
+			case wf.replies <- p:
+			default:
+			}
+			continue
+
+		case FC_MP_CMD:
+			wf.mu.Lock()
+			p.replied = wf.mpReply(p.frame)
+			wf.mu.Unlock()
+		}
+
+		select {
+		case wf.rx <- p:
+		default:
+		}
+	}
+}
+
+func mpKind(f []uint8) uint16 {
+	if len(f) < 24 {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint16(f) & FC_MP_MASK
+}
+
+// replyAid is the aid of the client that sent an mp reply, it is carried in
+// the duration field as in a ps-poll
+func replyAid(f []uint8) uint16 {
+	return binary.LittleEndian.Uint16(f[2:]) & 0xF
+}
+
+// mpReply answers a command from the host of this console's bss, reply 2 is
+// sent when it is enabled, otherwise an empty reply
+func (wf *Wifi) mpReply(cmd []uint8) bool {
+	if wf.io[W_RXCNT>>1]&0x8000 == 0 || len(cmd) < 28 {
+		return false
+	}
+
+	bssid := wf.addr(W_BSSID)
+	if !bytes.Equal(cmd[16:22], bssid[:]) {
+		return false
+	}
+
+	aid := wf.io[W_AID_LOW>>1] & 0xF
+	if binary.LittleEndian.Uint16(cmd[26:])&(1<<aid) == 0 {
+		return false
+	}
+
+	if reg := &wf.io[W_TXBUF_REPLY2>>1]; *reg&0x8000 != 0 {
+		addr := uint32(*reg&0xFFF) << 1
+		rate := wf.ram16(addr + 8)
+		length := wf.ram16(addr + 10)
+
+		*reg &^= 0x8000
+		wf.stampSeq(addr + TX_HDR)
+		wf.setRam16(addr, 0x0001)
+
+		reply := wf.read(addr+TX_HDR, max(int(length)-FCS, 0))
+		if len(reply) < 24 {
+			return true
+		}
+
+		binary.LittleEndian.PutUint16(reply[2:], 0xC000|aid)
+		wf.send(rate, reply)
+		return true
+	}
+
+	mac := wf.addr(W_MACADDR)
+
+	reply := make([]uint8, 24)
+	binary.LittleEndian.PutUint16(reply, FC_MP_EMPTY_REPLY)
+	binary.LittleEndian.PutUint16(reply[2:], 0xC000|aid)
+	copy(reply[4:], mpReplyAddr)
+	copy(reply[10:], mac[:])
+	copy(reply[16:], bssid[:])
+	binary.LittleEndian.PutUint16(reply[22:], wf.nextSeq()<<4)
+
+	wf.send(RATE_2MBIT, reply)
+	return true
+}
+
+func (wf *Wifi) drain() {
+	for len(wf.rx) != 0 {
+		wf.receive(<-wf.rx)
+	}
+}
+
+// receive writes a heard frame behind a rx header into the rx buffer
+func (wf *Wifi) receive(p packet) {
+	f := p.frame
+
+	if len(f) < 24 || wf.io[W_RXCNT>>1]&0x8000 == 0 || !wf.accepts(f) {
+		return
+	}
+
+	size := (RX_HDR + len(f) + 3) &^ 3
+	if !wf.rxFits(size) {
+		wf.setIrq(IRQ_RX_OVF)
+		return
+	}
+
+	bssid := wf.addr(W_BSSID)
+
+	flags := rxFlags(binary.LittleEndian.Uint16(f))
+	if bytes.Equal(f[16:22], bssid[:]) {
+		flags |= 0x8000
+	}
+
+	hdr := make([]uint8, RX_HDR, size)
+	binary.LittleEndian.PutUint16(hdr[0:], flags)
+	binary.LittleEndian.PutUint16(hdr[2:], 0x0040)
+	binary.LittleEndian.PutUint16(hdr[6:], p.rate)
+	binary.LittleEndian.PutUint16(hdr[8:], uint16(len(f)))
+	binary.LittleEndian.PutUint16(hdr[10:], 0x4080) // rssi
+
+	buf := append(append(hdr, f...), make([]uint8, size-RX_HDR-len(f))...)
+
+	wf.setIrq(IRQ_RX_START)
+
+	csr := &wf.io[W_RXBUF_WRCSR>>1]
+	for i := 0; i < len(buf); i += 2 {
+		wf.ram[*csr&0xFFF] = uint16(buf[i]) | uint16(buf[i+1])<<8
+		*csr = wf.rxNext(*csr)
+	}
+
+	wf.setIrq(IRQ_RX_DONE)
+
+	if p.replied {
+		wf.io[W_TXSTAT>>1] = 0x0401
+		wf.setIrq(IRQ_TX_START)
+		wf.setIrq(IRQ_TX_DONE)
+	}
+}
+
+// rxNext is the halfword after csr in the rx buffer
+func (wf *Wifi) rxNext(csr uint16) uint16 {
+	csr = (csr + 1) & 0xFFF
+
+	if csr<<1 >= wf.WRxBufEnd&0x1FFE {
+		csr = (wf.WRxBufBegin & 0x1FFE) >> 1
+	}
+
+	return csr
+}
+
+// rxFits reports if size bytes fit in the rx buffer ahead of the read
+// cursor
+func (wf *Wifi) rxFits(size int) bool {
+	begin := int(wf.WRxBufBegin & 0x1FFE)
+	end := int(wf.WRxBufEnd & 0x1FFE)
+
+	if end <= begin {
+		return false
+	}
+
+	wr := int(wf.io[W_RXBUF_WRCSR>>1]&0xFFF) << 1
+	rd := int(wf.io[W_RXBUF_READCSR>>1]&0xFFF) << 1
+
+	if wr < begin || wr >= end || rd < begin || rd >= end {
+		return size < end-begin
+	}
+
+	free := (rd - wr + end - begin) % (end - begin)
+	if free == 0 {
+		free = end - begin
+	}
+
+	return size < free
+}
+
+func (wf *Wifi) accepts(f []uint8) bool {
+	if group := f[4]&1 != 0; group {
+		return true
+	}
+
+	mac := wf.addr(W_MACADDR)
+	return bytes.Equal(f[4:10], mac[:])
+}
+
+func rxFlags(fc uint16) uint16 {
+	switch fc & FC_MP_MASK {
+	case FC_MP_CMD:
+		return 0xC
+	case FC_MP_ACK:
+		return 0xD
+	case FC_MP_REPLY:
+		return 0xE
+	case FC_MP_EMPTY_REPLY:
+		return 0xF
+	}
+
+	switch fc & 0x0C {
+	case 0x00:
+		if beacon := fc&0xF0 == 0x80; beacon {
+			return 0x1
+		}
+		return 0x0
+	case 0x04:
+		return 0x5
+	default:
+		return 0x8
+	}
+}
+
+// txTime is the air time of a frame in microseconds, length includes the fcs
+func txTime(rate, length uint16) uint32 {
+	if rate == RATE_2MBIT {
+		return 96 + uint32(length)*4
+	}
+
+	return 192 + uint32(length)*8
+}
+
+func (wf *Wifi) addr(reg uint32) (a [6]uint8) {
+	for i := range 3 {
+		binary.LittleEndian.PutUint16(a[i*2:], wf.io[reg>>1+uint32(i)])
+	}
+
+	return a
+}
+
+func (wf *Wifi) nextSeq() uint16 {
+	seq := &wf.io[W_TX_SEQNO>>1]
+	v := *seq & 0xFFF
+	*seq = (v + 1) & 0xFFF
+	return v
+}
+
+func (wf *Wifi) stampSeq(frame uint32) {
+	wf.setRam16(frame+22, wf.nextSeq()<<4)
+}
+
+func (wf *Wifi) ram16(addr uint32) uint16 {
+	return wf.ram[(addr&0x1FFF)>>1]
+}
+
+func (wf *Wifi) setRam16(addr uint32, v uint16) {
+	wf.ram[(addr&0x1FFF)>>1] = v
+}
+
+// read copies n bytes of wifi ram from a halfword aligned addr
+func (wf *Wifi) read(addr uint32, n int) []uint8 {
+	b := make([]uint8, n+1)
+
+	for i := 0; i < n; i += 2 {
+		binary.LittleEndian.PutUint16(b[i:], wf.ram16(addr+uint32(i)))
+	}
+
+	return b[:n]
+}
@@ -1,7 +1,10 @@
 package wifi
 
 import (
-	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aabalke/guac/emu/cpu"
 )
 
 type Wifi struct {
@@ -29,7 +32,33 @@ type Wifi struct {
 	WRfPins    uint16
 	WRfStatus  uint16
 
-	rand *rand.Rand
+	If, Ie uint16
+
+	UsCount   uint64
+	UsCompare uint64
+	UsCycles  uint32
+
+	BeaconReq bool
+	TxActive  bool
+	TxSlot    int
+	TxAddr    uint32
+	TxTime    uint32 // microseconds until the frame is sent
+	MpClients uint16 // clients polled by the running mp command
+
+	// after the command is sent the host waits on the replies, the wait
+	// runs on as the slot being sent and ends in the ack
+	MpReplying bool
+	MpMissing  uint16    // clients that have not replied yet
+	mpDeadline time.Time `state:"-"`
+
+	random uint16 // W_RANDOM, kept in states so loaded runs read the same values
+	irq    *cpu.Irq
+
+	// the listener replies to mp commands while the cpu runs
+	mu      sync.Mutex `state:"-"`
+	air     *Air
+	rx      chan packet
+	replies chan packet
 
 	ram [0x2000 >> 1]uint16
 	//WifiRam hwio.Mem `hwio:"bank=1,offset=0,size=0x2000,rw8=off,rw16,rw32"`
@@ -37,18 +66,100 @@ type Wifi struct {
 	io [0x8000 >> 1]uint16
 }
 
-func NewWifi() *Wifi {
-	wf := &Wifi{}
-	wf.rand = rand.New(rand.NewSource(0))
+func NewWifi(irq *cpu.Irq) *Wifi {
+	wf := &Wifi{
+		irq:     irq,
+		rx:      make(chan packet, 64),
+		replies: make(chan packet, 16),
+	}
+	wf.random = 1
 	wf.bbInit()
 	return wf
 }
 
 func (wf *Wifi) Write16(addr uint32, v uint16) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
 
 	addr &= 0x7FFF
 
+	if ram := addr >= 0x4000 && addr < 0x6000; ram {
+		wf.setRam16(addr, v)
+		return
+	}
+
 	switch addr {
+	case W_ID:
+		return
+
+	case W_IF:
+		wf.If &^= v
+		return
+
+	case W_IE:
+		wf.writeIe(v)
+		return
+
+	case W_IF_SET:
+		for i := range 16 {
+			if v&(1<<i) != 0 {
+				wf.setIrq(i)
+			}
+		}
+		return
+
+	case W_RXCNT:
+		if v&1 != 0 {
+			wf.io[W_RXBUF_WRCSR>>1] = wf.io[W_RXBUF_WR_ADDR>>1] & 0xFFF
+		}
+
+		if v&0x80 != 0 {
+			wf.io[W_TXBUF_REPLY2>>1] = wf.io[W_TXBUF_REPLY1>>1]
+			wf.io[W_TXBUF_REPLY1>>1] = 0
+		}
+
+		v &= 0xFF0E
+
+	case W_TXREQ_RESET:
+		wf.WTxReqRead &^= v & 0xF
+		return
+
+	case W_TXREQ_SET:
+		wf.WTxReqRead |= v & 0xF
+		return
+
+	case W_TXBUF_RESET:
+		for i, reg := range []uint32{W_TXBUF_LOC1, W_TXBUF_CMD, W_TXBUF_LOC2, W_TXBUF_LOC3} {
+			if v&(1<<i) != 0 {
+				wf.io[reg>>1] &^= 0x8000
+			}
+		}
+		if v&0x40 != 0 {
+			wf.io[W_TXBUF_REPLY2>>1] &^= 0x8000
+		}
+		if v&0x80 != 0 {
+			wf.io[W_TXBUF_REPLY1>>1] &^= 0x8000
+		}
+		return
+
+	case W_TXBUSY, W_TXSTAT:
+		return
+
+	case W_US_COMPARE0:
+		if v&1 != 0 {
+			wf.setIrq(IRQ_BEACON)
+		}
+		v &= 0xFC00
+		fallthrough
+
+	case W_US_COMPARE0 + 2, W_US_COMPARE0 + 4, W_US_COMPARE0 + 6:
+		shift := (addr - W_US_COMPARE0) * 8
+		wf.UsCompare = wf.UsCompare&^(0xFFFF<<shift) | uint64(v)<<shift
+
+	case W_US_COUNT0, W_US_COUNT0 + 2, W_US_COUNT0 + 4, W_US_COUNT0 + 6:
+		shift := (addr - W_US_COUNT0) * 8
+		wf.UsCount = wf.UsCount&^(0xFFFF<<shift) | uint64(v)<<shift
+
 	case 0x50:
 		wf.WRxBufBegin = v
 
@@ -103,10 +214,31 @@ func (wf *Wifi) Write16(addr uint32, v uint16) {
 }
 
 func (wf *Wifi) Read16(addr uint32) uint16 {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
 
 	addr &= 0x7FFF
 
+	if ram := addr >= 0x4000 && addr < 0x6000; ram {
+		return wf.ram16(addr)
+	}
+
 	switch addr {
+	case W_ID:
+		return 0x1440
+
+	case W_IF:
+		return wf.If
+
+	case W_IE:
+		return wf.Ie
+
+	case W_US_COMPARE0, W_US_COMPARE0 + 2, W_US_COMPARE0 + 4, W_US_COMPARE0 + 6:
+		return uint16(wf.UsCompare >> ((addr - W_US_COMPARE0) * 8))
+
+	case W_US_COUNT0, W_US_COUNT0 + 2, W_US_COUNT0 + 4, W_US_COUNT0 + 6:
+		return uint16(wf.UsCount >> ((addr - W_US_COUNT0) * 8))
+
 	case 0x44:
 		return wf.ReadRANDOM()
 
@@ -201,8 +333,11 @@ func (wf *Wifi) WriteBASEBANDCNT(val uint16) {
 	}
 }
 
+// ReadRANDOM steps the 11 bit generator on each read. Hardware steps it all
+// the time, which would make the values depend on when a game reads them.
 func (wf *Wifi) ReadRANDOM() uint16 {
-	return uint16(wf.rand.Uint32()) & 0x3FF
+	wf.random = (wf.random & 1) ^ (((wf.random & 0x3FF) << 1) | (wf.random >> 10))
+	return wf.random & 0x3FF
 }
 
 func (wf *Wifi) WriteWTXBUFWRDATA(val uint16) {
@@ -216,6 +351,12 @@ func (wf *Wifi) WriteWTXBUFWRDATA(val uint16) {
 	}
 	off &= 0x1FFF
 	wf.WTxBufWrAddr = off
+
+	if cnt := &wf.io[W_TXBUF_COUNT>>1]; *cnt != 0 {
+		if *cnt--; *cnt == 0 {
+			wf.setIrq(IRQ_TXBUF_CNT)
+		}
+	}
 }
 
 func (wf *Wifi) ReadWRXBUFRDDATA() uint16 {
@@ -223,10 +364,20 @@ func (wf *Wifi) ReadWRXBUFRDDATA() uint16 {
 	val := wf.ram[off>>1]
 	//val := binary.LittleEndian.Uint16(wf.WifiRam[off : off+2])
 	off += 2
+	if off == wf.io[W_RXBUF_GAP>>1]&0x1FFE {
+		off += wf.io[W_RXBUF_GAPDISP>>1] * 2
+	}
 	if off == wf.WRxBufEnd&0x1FFF {
 		off = wf.WRxBufBegin
 	}
 	off &= 0x1FFF
 	wf.WRxBufRdAddr = off
+
+	if cnt := &wf.io[W_RXBUF_COUNT>>1]; *cnt != 0 {
+		if *cnt--; *cnt == 0 {
+			wf.setIrq(IRQ_RXBUF_CNT)
+		}
+	}
+
 	return val
 }
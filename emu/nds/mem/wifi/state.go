@@ -0,0 +1,18 @@
+package wifi
+
+import "github.com/aabalke/guac/emu/state"
+
+// SaveState holds the listener off while the wifi is encoded
+func (wf *Wifi) SaveState(e *state.Encoder) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	e.Encode(wf)
+}
+
+func (wf *Wifi) LoadState(d *state.Decoder) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	d.Decode(wf)
+}
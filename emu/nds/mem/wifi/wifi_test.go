@@ -0,0 +1,284 @@
+package wifi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/aabalke/guac/emu/cpu"
+)
+
+const (
+	RX_BEGIN = 0x4C28
+	RX_END   = 0x5F60
+)
+
+// node is a powered wifi listening on the air in dir
+func node(t *testing.T, dir string, mac, bssid [6]uint8, aid uint16) *Wifi {
+	t.Helper()
+
+	air, err := OpenAir("unix:" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wf := NewWifi(&cpu.Irq{})
+	wf.Connect(air)
+	t.Cleanup(wf.Close)
+
+	for i := range uint32(3) {
+		wf.Write16(W_MACADDR+i*2, binary.LittleEndian.Uint16(mac[i*2:]))
+		wf.Write16(W_BSSID+i*2, binary.LittleEndian.Uint16(bssid[i*2:]))
+	}
+
+	wf.Write16(W_AID_LOW, aid)
+	wf.Write16(0x50, RX_BEGIN)
+	wf.Write16(0x52, RX_END)
+	wf.Write16(W_RXBUF_WR_ADDR, (RX_BEGIN&0x1FFF)>>1)
+	wf.Write16(W_RXCNT, 0x8001)
+	wf.Write16(W_US_COUNTCNT, 1)
+	wf.Write16(W_IE, 0xFFFF)
+
+	return wf
+}
+
+// queue writes a frame to wifi ram behind a tx header
+func queue(wf *Wifi, addr uint32, frame []uint8) {
+	buf := make([]uint8, TX_HDR+len(frame)+1)
+	binary.LittleEndian.PutUint16(buf[8:], RATE_2MBIT)
+	binary.LittleEndian.PutUint16(buf[10:], uint16(len(frame)+FCS))
+	copy(buf[TX_HDR:], frame)
+
+	for i := 0; i < len(buf)-1; i += 2 {
+		wf.Write16(0x4000+addr+uint32(i), binary.LittleEndian.Uint16(buf[i:]))
+	}
+}
+
+// run updates the wifi until an irq is raised. It sleeps between updates as
+// the emulation does between frames, the listeners get to run then
+func run(t *testing.T, wf *Wifi, irq int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for wf.Read16(W_IF)&(1<<irq) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("irq %d was not raised, if %04X", irq, wf.Read16(W_IF))
+		}
+
+		wf.Update(0x100)
+		time.Sleep(time.Microsecond)
+	}
+}
+
+// received is the frame at the start of the rx buffer and its rx flags
+func received(wf *Wifi) (uint16, []uint8) {
+	flags := wf.Read16(RX_BEGIN)
+	n := int(wf.Read16(RX_BEGIN + 8))
+
+	return flags, wf.read(RX_BEGIN+RX_HDR, n)
+}
+
+func frame(fc uint16, dst, src, bssid [6]uint8, body ...uint8) []uint8 {
+	f := make([]uint8, 24, 24+len(body))
+	binary.LittleEndian.PutUint16(f, fc)
+	copy(f[4:], dst[:])
+	copy(f[10:], src[:])
+	copy(f[16:], bssid[:])
+	return append(f, body...)
+}
+
+var (
+	hostMac   = [6]uint8{0x00, 0x09, 0xBF, 0x00, 0x00, 0x01}
+	clientMac = [6]uint8{0x00, 0x09, 0xBF, 0x00, 0x00, 0x02}
+	broadcast = [6]uint8{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+func TestTxRx(t *testing.T) {
+	dir := t.TempDir()
+	host := node(t, dir, hostMac, hostMac, 0)
+	client := node(t, dir, clientMac, hostMac, 1)
+
+	sent := frame(0x0008, broadcast, hostMac, hostMac, 0x12, 0x34, 0x56, 0x78)
+	queue(host, 0x000, sent)
+
+	host.Write16(W_TXBUF_LOC1, 0x8000)
+	host.Write16(W_TXREQ_SET, 1)
+
+	run(t, host, IRQ_TX_DONE)
+
+	if host.Read16(W_TXBUF_LOC1)&0x8000 != 0 {
+		t.Fatal("loc1 still enabled after sending")
+	}
+
+	run(t, client, IRQ_RX_DONE)
+
+	flags, got := received(client)
+	if flags != 0x8008 {
+		t.Fatalf("rx flags %04X", flags)
+	}
+
+	// the sequence number is stamped on send
+	sent[22] = 0
+	if !bytes.Equal(got, sent) {
+		t.Fatalf("received % X, sent % X", got, sent)
+	}
+
+	if wr, want := client.Read16(W_RXBUF_WRCSR), uint16(RX_BEGIN&0x1FFF+(RX_HDR+len(sent)+3)&^3)>>1; wr != want {
+		t.Fatalf("rx write cursor %X, want %X", wr, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	dir := t.TempDir()
+	host := node(t, dir, hostMac, hostMac, 0)
+	client := node(t, dir, clientMac, hostMac, 1)
+
+	other := [6]uint8{0x00, 0x09, 0xBF, 0x00, 0x00, 0x03}
+	queue(host, 0x000, frame(0x0008, other, hostMac, hostMac))
+
+	host.Write16(W_TXBUF_LOC1, 0x8000)
+	host.Write16(W_TXREQ_SET, 1)
+	run(t, host, IRQ_TX_DONE)
+
+	time.Sleep(10 * time.Millisecond)
+	client.Update(0x100)
+
+	if client.Read16(W_IF)&(1<<IRQ_RX_DONE) != 0 {
+		t.Fatal("received a frame for another mac")
+	}
+}
+
+func TestMultiplay(t *testing.T) {
+	dir := t.TempDir()
+	host := node(t, dir, hostMac, hostMac, 0)
+	client := node(t, dir, clientMac, hostMac, 1)
+
+	mp := [6]uint8{0x03, 0x09, 0xBF, 0x00, 0x00, 0x00}
+
+	// the command polls client 1
+	cmd := frame(FC_MP_CMD, mp, hostMac, hostMac, 0x00, 0x01, 0x02, 0x00)
+	queue(host, 0x000, cmd)
+
+	host.Write16(W_TXBUF_CMD, 0x8000)
+	host.Write16(W_TXREQ_SET, 1<<SLOT_CMD)
+
+	run(t, host, IRQ_MP_END)
+
+	if flags, reply := received(host); flags != 0x800F || !bytes.Equal(reply[10:16], clientMac[:]) {
+		t.Fatalf("host received reply flags %04X from % X", flags, reply[10:16])
+	}
+
+	run(t, client, IRQ_TX_DONE)
+
+	if stat := client.Read16(W_TXSTAT); stat != 0x0401 {
+		t.Fatalf("client tx stat %04X", stat)
+	}
+
+	if flags, _ := received(client); flags != 0x800C {
+		t.Fatalf("client received cmd flags %04X", flags)
+	}
+
+	// the ack follows the command in the rx buffer, no client is missing
+	ack := uint32(RX_BEGIN + (RX_HDR+len(cmd)+3)&^3)
+
+	deadline := time.Now().Add(time.Second)
+	for client.Read16(ack) == 0 && time.Now().Before(deadline) {
+		client.Update(0x100)
+		time.Sleep(time.Microsecond)
+	}
+
+	if flags := client.Read16(ack); flags != 0x800D {
+		t.Fatalf("client received ack flags %04X", flags)
+	}
+
+	if missing := client.Read16(ack + RX_HDR + 26); missing != 0 {
+		t.Fatalf("ack reports missing clients %04X", missing)
+	}
+}
+
+func TestMultiplayMissing(t *testing.T) {
+	host := node(t, t.TempDir(), hostMac, hostMac, 0)
+
+	mp := [6]uint8{0x03, 0x09, 0xBF, 0x00, 0x00, 0x00}
+	queue(host, 0x000, frame(FC_MP_CMD, mp, hostMac, hostMac, 0x00, 0x01, 0x02, 0x00))
+
+	host.Write16(W_TXBUF_CMD, 0x8000)
+	host.Write16(W_TXREQ_SET, 1<<SLOT_CMD)
+
+	// nobody replies, updates go on while the host waits out the timeout
+	updates := 0
+	deadline := time.Now().Add(time.Second)
+	for host.Read16(W_IF)&(1<<IRQ_MP_END) == 0 && time.Now().Before(deadline) {
+		host.Update(0x100)
+		updates++
+	}
+
+	if host.MpReplying || host.MpMissing != 2 {
+		t.Fatalf("ack missing clients %04X", host.MpMissing)
+	}
+
+	if updates < 2 || host.Read16(W_TXSTAT) != 0x0B01 {
+		t.Fatalf("mp end after %d updates, tx stat %04X", updates, host.Read16(W_TXSTAT))
+	}
+}
+
+func TestMultiplayAid(t *testing.T) {
+	dir := t.TempDir()
+	host := node(t, dir, hostMac, hostMac, 0)
+	node(t, dir, clientMac, hostMac, 2)
+
+	air, err := OpenAir("unix:" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(air.Close)
+
+	mp := [6]uint8{0x03, 0x09, 0xBF, 0x00, 0x00, 0x00}
+
+	// the command polls clients 1 and 2, only client 2 is in the bss
+	queue(host, 0x000, frame(FC_MP_CMD, mp, hostMac, hostMac, 0x00, 0x01, 0x06, 0x00))
+
+	host.Write16(W_TXBUF_CMD, 0x8000)
+	host.Write16(W_TXREQ_SET, 1<<SLOT_CMD)
+
+	// a client 1 replying to the host of another bss
+	other := [6]uint8{0x00, 0x09, 0xBF, 0x00, 0x00, 0x03}
+	stray := frame(FC_MP_EMPTY_REPLY, mp, other, other)
+	binary.LittleEndian.PutUint16(stray[2:], 0xC001)
+	air.Send(RATE_2MBIT, stray)
+
+	run(t, host, IRQ_MP_END)
+
+	if host.MpMissing != 1<<1 {
+		t.Fatalf("ack missing clients %04X", host.MpMissing)
+	}
+}
+
+func TestBeacon(t *testing.T) {
+	wf := NewWifi(&cpu.Irq{})
+	wf.Write16(W_IE, 0xFFFF)
+	wf.Write16(W_US_COUNTCNT, 1)
+	wf.Write16(W_BEACONINT, 2)
+	wf.Write16(W_BEACONCOUNT1, 2)
+
+	queue(wf, 0x000, frame(0x0080, broadcast, hostMac, hostMac, make([]uint8, 12)...))
+	wf.Write16(W_TXBUF_BEACON, 0x8000)
+
+	run(t, wf, IRQ_BEACON)
+
+	// updates run a few microseconds at a time
+	if us := wf.UsCount; us < 2<<10 || us > 2<<10+8 {
+		t.Fatalf("beacon at %d us", us)
+	}
+
+	run(t, wf, IRQ_TX_DONE)
+
+	if stamp := uint64(wf.Read16(0x4000+TX_HDR+24)) | uint64(wf.Read16(0x4000+TX_HDR+26))<<16; stamp != 2<<10 {
+		t.Fatalf("beacon timestamp %d", stamp)
+	}
+
+	if cnt := wf.Read16(W_BEACONCOUNT1); cnt != 2 {
+		t.Fatalf("beacon count reloaded to %d", cnt)
+	}
+}
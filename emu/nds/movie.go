@@ -0,0 +1,35 @@
+package nds
+
+import (
+	"time"
+
+	"github.com/aabalke/guac/emu/movie"
+	"github.com/aabalke/guac/input"
+)
+
+// FRAME_TIME is the emulated time of a frame, a seeded clock moves by it
+const FRAME_TIME = time.Second * 560190 / 33513982
+
+// Input is what is held for the next frame, the pen included
+func (nds *Nds) Input() movie.Frame {
+	tsc := &nds.mem.Spi.Tsc
+
+	return movie.Frame{
+		Buttons: uint16(nds.buttons),
+		Touch:   tsc.TouchActive,
+		X:       tsc.TouchX,
+		Y:       tsc.TouchY,
+	}
+}
+
+func (nds *Nds) SetInput(f movie.Frame) {
+	nds.SetButtons(input.Buttons(f.Buttons))
+
+	tsc := &nds.mem.Spi.Tsc
+	tsc.TouchActive = f.Touch
+	tsc.TouchX, tsc.TouchY = f.X, f.Y
+
+	if f.Touch {
+		nds.mem.Keypad.KEYINPUT2 &^= 0b100_0000
+	}
+}
@@ -2,20 +2,30 @@ package nds
 
 import (
 	"fmt"
+	"image"
 	"os"
 	"sync"
 
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/capture"
+	"github.com/aabalke/guac/emu/cheat"
+	"github.com/aabalke/guac/emu/clock"
 	"github.com/aabalke/guac/emu/cpu"
 	"github.com/aabalke/guac/emu/cpu/arm7"
 	"github.com/aabalke/guac/emu/cpu/arm9"
 	"github.com/aabalke/guac/emu/cpu/arm9/cp15"
+	"github.com/aabalke/guac/emu/debugger"
+	"github.com/aabalke/guac/emu/movie"
 	"github.com/aabalke/guac/emu/nds/cart"
 	"github.com/aabalke/guac/emu/nds/debug"
 	"github.com/aabalke/guac/emu/nds/mem"
 	"github.com/aabalke/guac/emu/nds/mem/dma"
 	"github.com/aabalke/guac/emu/nds/ppu"
 	"github.com/aabalke/guac/emu/nds/snd"
+	"github.com/aabalke/guac/emu/rom"
+	"github.com/aabalke/guac/emu/state"
+	"github.com/aabalke/guac/input"
+	"github.com/aabalke/guac/utils"
 	"github.com/hajimehoshi/oto"
 )
 
@@ -44,6 +54,10 @@ const (
 
 	// zelda spirit track needs single threaded for 3d screen switching
 	SINGLE_THREAD = !true // debugging
+
+	// SAVE_FRAMES is how often deterministic runs save, about the second of
+	// the save loop
+	SAVE_FRAMES = 60
 )
 
 var RASTERIZE_WG = sync.WaitGroup{}
@@ -61,6 +75,19 @@ type Nds struct {
 
 	Muted, Paused, Drawn bool
 
+	buttons input.Buttons `state:"-"` // held, for movies
+
+	Rewind     *state.Rewind
+	Rewinding  bool
+	Cheats     *cheat.Engine
+	movie.Deck `state:"-"`
+	Capture    *capture.Recorder
+	PatchErr   error // the rom's patch failed, it runs unpatched
+
+	Debug7, Debug9 *debugger.Debugger
+	Frontends      []debugger.Frontend
+	midFrame       bool // the debugger stopped the last frame
+
 	AccCycles   uint32
 	TimerCycles uint8
 	GeoCycles   uint8
@@ -70,7 +97,7 @@ type Nds struct {
 
 func NewNds(path string, audioCtx *oto.Context) *Nds {
 
-	nds := Nds{}
+	nds := Nds{Rewind: newRewind()}
 
 	nds.Screen = NewScreen()
 
@@ -114,8 +141,15 @@ func NewNds(path string, audioCtx *oto.Context) *Nds {
 		nds.dma7[i].Init(i, &nds.mem, &irq7, false)
 	}
 
+	game, err := rom.Load(path, config.Conf.General.Patches)
+	if err != nil {
+		panic(err)
+	}
+
+	nds.PatchErr = game.PatchErr
+
 	nds.Cartridge = cart.NewCartridge(
-		path, path+".save",
+		game,
 		nds.mem.Arm7Bios,
 		&irq7, &irq9,
 		&nds.dma7, &nds.dma9,
@@ -123,8 +157,19 @@ func NewNds(path string, audioCtx *oto.Context) *Nds {
 
 	nds.mem.Cartridge = nds.Cartridge
 
+	nds.Debug7 = debugger.New("arm7", nds.arm7, &nds.mem, false)
+	nds.Debug9 = debugger.New("arm9", nds.arm9, &nds.mem, true)
+	nds.mem.Debug7 = nds.Debug7
+	nds.mem.Debug9 = nds.Debug9
+
+	// arm9 is thread 1 in gdb
+	nds.Frontends = debugger.Attach(config.Conf.General.Debugger, config.Conf.General.GdbPort, nds.Debug9, nds.Debug7)
+
 	nds.DirectBoot()
 
+	nds.Cheats = nds.newCheats()
+	nds.Deck = movie.NewDeck(&nds, state.NDS, nds.Cartridge.RomChecksum)
+
 	if config.Conf.General.Logger {
 		debug.Init("./log.csv")
 	}
@@ -133,10 +178,22 @@ func NewNds(path string, audioCtx *oto.Context) *Nds {
 }
 
 func (nds *Nds) Update(stdFps bool) {
-	if nds.Paused {
+	for _, f := range nds.Frontends {
+		f.Poll()
+	}
+
+	if nds.Paused || nds.Stopped() {
 		return
 	}
 
+	// rewinding would put the run out of step with a movie
+	if nds.Rewinding && nds.Rewind != nil && nds.Movie == nil {
+		nds.rewindStep()
+		return
+	}
+
+	defer nds.captureRewind()
+
 	if !nds.ppu.EngineA.Dispcnt.Is3D {
 		nds.UpdateFrame(stdFps)
 		t, b := nds.GetScreens()
@@ -145,6 +202,17 @@ func (nds *Nds) Update(stdFps bool) {
 		return
 	}
 
+	// deterministic runs render the last frame's polygons before the next
+	// frame runs, the threads below race the cpus over the 3d buffers
+	if config.Conf.General.Deterministic {
+		nds.ppu.Rasterizer.Render.UpdateRender()
+		nds.UpdateFrame(stdFps)
+		t, b := nds.GetScreens()
+		nds.Screen.Top.WritePixels(*t)
+		nds.Screen.Bottom.WritePixels(*b)
+		return
+	}
+
 	if SINGLE_THREAD {
 		nds.UpdateFrame(stdFps)
 		nds.ppu.Rasterizer.Render.UpdateRender()
@@ -174,8 +242,26 @@ func (nds *Nds) Update(stdFps bool) {
 }
 
 func (nds *Nds) UpdateFrame(stdFps bool) {
-	for nds.Drawn = false; !nds.Drawn; {
-		if config.Conf.Nds.Jit.Enabled {
+
+	// breakpoints are checked every instruction, compiled blocks would run
+	// past them
+	debugging := nds.Debug7.Active() || nds.Debug9.Active()
+	nds.arm7.Interpreter = debugging
+	nds.arm9.Interpreter = debugging
+
+	jit := config.Conf.Nds.Jit.Enabled && !debugging
+
+	if !nds.midFrame {
+		nds.Drawn = false
+		clock.Advance(FRAME_TIME)
+		nds.StepMovie()
+		nds.Cheats.Apply()
+	}
+
+	nds.midFrame = false
+
+	for !nds.Drawn {
+		if jit {
 
 			for c := uint32(0); c < config.Conf.Nds.Jit.BatchInstA9; {
 				c += nds.StepArm9()
@@ -208,6 +294,11 @@ func (nds *Nds) UpdateFrame(stdFps bool) {
 			nds.VideoUpdate(1)
 			nds.StepOther()
 		}
+
+		if debugging && nds.Stopped() {
+			nds.midFrame = true
+			return
+		}
 	}
 
 	if config.Conf.Nds.Jit.Enabled {
@@ -217,6 +308,12 @@ func (nds *Nds) UpdateFrame(stdFps bool) {
 
 	nds.mem.Snd.Play(nds.Muted, stdFps)
 	nds.Frame++
+
+	if config.Conf.General.Deterministic && nds.Frame%SAVE_FRAMES == 0 {
+		nds.Cartridge.FlushSave()
+	}
+
+	nds.captureFrame()
 }
 
 func (nds *Nds) StepOther() {
@@ -224,6 +321,11 @@ func (nds *Nds) StepOther() {
 		nds.UpdateTimers(TIMER_CYCLE_MASK + 1)
 	}
 
+	if nds.TimerCycles == 0 {
+		nds.mem.Wifi.Update(0x100)
+		nds.mem.Spi.Tsc.Mic.Update(0x100)
+	}
+
 	nds.TimerCycles++
 }
 
@@ -236,6 +338,10 @@ func (nds *Nds) StepArm9() uint32 {
 
 	r := &nds.arm9.Reg.R
 
+	if nds.Debug9.Active() && nds.Debug9.Check(r[15]) {
+		return 0xFFFF_FFFF
+	}
+
 	cycles, ok := nds.arm9.Execute()
 	if !ok {
 		fmt.Printf("ARM9 Decode Error: PC %08X\n", r[15])
@@ -264,6 +370,10 @@ func (nds *Nds) StepArm7() uint32 {
 
 	r7 := &nds.arm7.Reg.R
 
+	if nds.Debug7.Active() && nds.Debug7.Check(r7[15]) {
+		return 0xFFFF_FFFF
+	}
+
 	cycles, ok := nds.arm7.Execute()
 	if !ok {
 		fmt.Printf("ARM7 Decode Error: PC %08X\n", r7[15])
@@ -273,6 +383,11 @@ func (nds *Nds) StepArm7() uint32 {
 	return uint32(cycles)
 }
 
+// Stopped reports if a debugger stopped either cpu
+func (nds *Nds) Stopped() bool {
+	return nds.Debug7.Stopped() || nds.Debug9.Stopped()
+}
+
 func (nds *Nds) ToggleMute() bool {
 	nds.Muted = !nds.Muted
 	return nds.Muted
@@ -294,18 +409,35 @@ func (nds *Nds) GetScreens() (t, b *[]byte) {
 	return pb, pa
 }
 
+// Screenshot returns a copy of the current frame, the top screen above the
+// bottom screen
+func (nds *Nds) Screenshot() *image.RGBA {
+	t, b := nds.GetScreens()
+	return utils.NewScreenshot(SCREEN_WIDTH, SCREEN_HEIGHT*2, *t, *b)
+}
+
 func (nds *Nds) Close() {
 	RASTERIZE_WG.Wait()
 
 	nds.Muted = true
 	nds.Paused = true
 
+	if config.Conf.General.Deterministic {
+		nds.Cartridge.FlushSave()
+	}
+
+	nds.StopCapture()
 	nds.mem.Snd.Close()
+	nds.mem.Wifi.Close()
 	if debug.L != nil {
 		debug.L.Close()
 	}
 	nds.arm7.Jit.Close()
 	nds.arm9.Jit.Close()
+
+	for _, f := range nds.Frontends {
+		f.Close()
+	}
 }
 
 func (nds *Nds) DirectBoot() {
@@ -0,0 +1,52 @@
+package nds
+
+import (
+	"testing"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/config/file"
+	"github.com/aabalke/guac/emu/testroms"
+	"github.com/aabalke/guac/input"
+)
+
+var NDS_ROMS = []string{".nds"}
+
+func newTestNds(t *testing.T, rom string) *Nds {
+	file.Decode()
+	config.Conf.General.Headless = true
+	config.Conf.General.RewindBufferSize = 0
+
+	nds := NewNds(rom, nil)
+	t.Cleanup(nds.Close)
+
+	return nds
+}
+
+func TestArmwrestler(t *testing.T) {
+	testroms.Run(t, "ARMWrestler DS", "armwrestler-nds", NDS_ROMS, runScreenTest)
+}
+
+// runScreenTest plays the sidecar inputs of rom and compares both screens
+// against its reference
+func runScreenTest(t *testing.T, rom string) testroms.Result {
+	s, err := testroms.LoadSidecar(rom, 60*10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nds := newTestNds(t, rom)
+
+	for frame := 1; frame <= s.Frames; frame++ {
+		var b input.Buttons
+		if held := s.Buttons(frame); held != "" {
+			if b, err = input.ParseButtons(held); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		nds.SetButtons(b)
+		nds.Update(false)
+	}
+
+	return testroms.Reference(t, rom, nds.Screenshot())
+}
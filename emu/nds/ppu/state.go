@@ -0,0 +1,98 @@
+package ppu
+
+import (
+	"unsafe"
+
+	"github.com/aabalke/guac/emu/state"
+)
+
+func (p *PPU) SaveState(e *state.Encoder) {
+	e.Encode(p, p.EngineA.Blend, p.EngineB.Blend)
+
+	slots := p.Vram.slotOffsets()
+	e.Encode(&slots)
+
+	p.Rasterizer.SaveState(e)
+}
+
+func (p *PPU) LoadState(d *state.Decoder) {
+	d.Decode(p, p.EngineA.Blend, p.EngineB.Blend)
+
+	var slots vramSlots
+	d.Decode(&slots)
+	p.Vram.setSlotOffsets(&slots)
+
+	p.Vram.TextureCache.Reset()
+	p.Capture.ActiveData.ReadBlock = p.Capture.ReadBlock
+
+	p.EngineA.MasterBright.RebuildLUT()
+	p.EngineB.MasterBright.RebuildLUT()
+
+	p.Rasterizer.LoadState(d)
+}
+
+// mapped slots are stored as offsets into vram, -1 when unmapped
+type vramSlots struct {
+	Texture [4]int64
+	TexPal  [6]int64
+	ExtBgA  [4]int64
+	ExtBgB  [4]int64
+	ExtObjA int64
+	ExtObjB int64
+}
+
+func (vm *VRAM) offset(ptr unsafe.Pointer) int64 {
+	if ptr == nil {
+		return -1
+	}
+
+	return int64(uintptr(ptr) - uintptr(unsafe.Pointer(&vm.a)))
+}
+
+func (vm *VRAM) pointer(ofs int64) unsafe.Pointer {
+	if ofs < 0 || ofs >= int64(unsafe.Sizeof(*vm)) {
+		return nil
+	}
+
+	return unsafe.Add(unsafe.Pointer(&vm.a), ofs)
+}
+
+func (vm *VRAM) slotOffsets() vramSlots {
+	s := vramSlots{
+		ExtObjA: vm.offset(unsafe.Pointer(vm.engineA.ExtObj)),
+		ExtObjB: vm.offset(unsafe.Pointer(vm.engineB.ExtObj)),
+	}
+
+	for i := range vm.TextureSlots {
+		s.Texture[i] = vm.offset(unsafe.Pointer(vm.TextureSlots[i]))
+	}
+
+	for i := range vm.TexPalSlots {
+		s.TexPal[i] = vm.offset(unsafe.Pointer(vm.TexPalSlots[i]))
+	}
+
+	for i := range vm.engineA.ExtBgSlots {
+		s.ExtBgA[i] = vm.offset(unsafe.Pointer(vm.engineA.ExtBgSlots[i]))
+		s.ExtBgB[i] = vm.offset(unsafe.Pointer(vm.engineB.ExtBgSlots[i]))
+	}
+
+	return s
+}
+
+func (vm *VRAM) setSlotOffsets(s *vramSlots) {
+	vm.engineA.ExtObj = (*[0x4000]uint8)(vm.pointer(s.ExtObjA))
+	vm.engineB.ExtObj = (*[0x4000]uint8)(vm.pointer(s.ExtObjB))
+
+	for i := range vm.TextureSlots {
+		vm.TextureSlots[i] = (*[0x2_0000]uint8)(vm.pointer(s.Texture[i]))
+	}
+
+	for i := range vm.TexPalSlots {
+		vm.TexPalSlots[i] = (*[0x4000]uint8)(vm.pointer(s.TexPal[i]))
+	}
+
+	for i := range vm.engineA.ExtBgSlots {
+		vm.engineA.ExtBgSlots[i] = (*[0x2000]uint8)(vm.pointer(s.ExtBgA[i]))
+		vm.engineB.ExtBgSlots[i] = (*[0x2000]uint8)(vm.pointer(s.ExtBgB[i]))
+	}
+}
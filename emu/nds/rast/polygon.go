@@ -173,9 +173,11 @@ func (p *Polygon) GetVertex(g *GeoEngine, x, y, z float32) gl.Vertex {
 }
 
 func (p *Polygon) GetTexture(g *GeoEngine) *gl.Texture {
-
 	// texture has to be copy
-	t := g.Texture
+	return p.newTexture(g, g.Texture)
+}
+
+func (p *Polygon) newTexture(g *GeoEngine, t Texture) *gl.Texture {
 
 	if t.Format == TEX_FMT_NONE {
 		return &gl.Texture{
@@ -0,0 +1,41 @@
+package rast
+
+import "github.com/aabalke/guac/emu/state"
+
+func (r *Rasterizer) SaveState(e *state.Encoder) {
+	g := r.GeoEngine
+	s := g.MtxStacks
+
+	e.Encode(r, g, s, g.Vertex, &r.Render.Pixels)
+
+	// coordinate and directional stacks share a pointer
+	e.Encode(s.Stacks[0].Pointer, s.Stacks[1].Pointer, s.Stacks[3].Pointer)
+}
+
+// LoadState has to run after vram is restored, textures are rebuilt from it
+func (r *Rasterizer) LoadState(d *state.Decoder) {
+	g := r.GeoEngine
+	s := g.MtxStacks
+
+	d.Decode(r, g, s, g.Vertex, &r.Render.Pixels)
+	d.Decode(s.Stacks[0].Pointer, s.Stacks[1].Pointer, s.Stacks[3].Pointer)
+
+	g.TextureCache.Reset()
+
+	// vertex textures are not saved, build them again from polygon params
+	for _, buf := range []*Buffer{&r.Buffers.A, &r.Buffers.B} {
+		for i := range buf.Polys {
+			p := &buf.Polys[i]
+			p.rebuildTextures(g, p.Texture)
+		}
+	}
+
+	g.PrepPoly.rebuildTextures(g, g.Texture)
+	g.ActivePoly.rebuildTextures(g, g.Texture)
+}
+
+func (p *Polygon) rebuildTextures(g *GeoEngine, t Texture) {
+	for i := range p.Vertices {
+		p.Vertices[i].NdsTexture = p.newTexture(g, t)
+	}
+}
@@ -0,0 +1,13 @@
+package nds
+
+import "github.com/aabalke/guac/emu/search"
+
+// RamRegions are main ram, shared wram and arm7 wram. Shared wram is given
+// whole, which cpu sees which half depends on WRAMCNT.
+func (nds *Nds) RamRegions() []search.Region {
+	return []search.Region{
+		{Name: "main", Base: 0x200_0000, Data: nds.mem.MainRam[:]},
+		{Name: "wram", Base: 0x300_0000, Data: nds.mem.WRAM.Wram[:]},
+		{Name: "wram7", Base: 0x380_0000, Data: nds.mem.WRAM.WRAM7[:]},
+	}
+}
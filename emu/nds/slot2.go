@@ -0,0 +1,56 @@
+package nds
+
+import (
+	"slices"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/nds/cart"
+	"github.com/aabalke/guac/input"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// slot2Input sets the guitar grip buttons and shakes the gamepads for the
+// rumble pak
+func (nds *Nds) slot2Input(keys []ebiten.Key, buttons []ebiten.StandardGamepadButton) {
+	switch s := nds.Cartridge.Slot2.(type) {
+	case *cart.GuitarGrip:
+		var (
+			keyCfg    = &config.Conf.Nds.KeyboardConfig
+			buttonCfg = &config.Conf.Nds.ControllerConfig
+		)
+
+		frets := []struct {
+			bit     uint8
+			keys    []ebiten.Key
+			buttons []ebiten.StandardGamepadButton
+		}{
+			{cart.GRIP_GREEN, keyCfg.GripGreen, buttonCfg.GripGreen},
+			{cart.GRIP_RED, keyCfg.GripRed, buttonCfg.GripRed},
+			{cart.GRIP_YELLOW, keyCfg.GripYellow, buttonCfg.GripYellow},
+			{cart.GRIP_BLUE, keyCfg.GripBlue, buttonCfg.GripBlue},
+		}
+
+		s.Buttons = 0
+
+		for _, f := range frets {
+			held := slices.ContainsFunc(keys, func(k ebiten.Key) bool {
+				return slices.Contains(f.keys, k)
+			}) || slices.ContainsFunc(buttons, func(b ebiten.StandardGamepadButton) bool {
+				return slices.Contains(f.buttons, b)
+			})
+
+			if held {
+				s.Buttons |= f.bit
+			}
+		}
+
+	case *cart.RumblePak:
+		if !s.Moved {
+			return
+		}
+
+		s.Moved = false
+
+		input.Rumble()
+	}
+}
@@ -60,7 +60,7 @@ type Snd struct {
 
 	steamCh chan []uint8
 
-	muted bool
+	muted bool `state:"-"`
 }
 
 func NewSnd(ctx *oto.Context, freq, rate, cnt int) *Snd {
@@ -253,3 +253,9 @@ func clip(v int32) int16 {
 	}
 	return int16(v)
 }
+
+// SampleRate is the rate samples are made at, a sample every sampCycles is
+// not quite sndFrequency
+func (s *Snd) SampleRate() int {
+	return s.cpuFreqHz / s.sampCycles
+}
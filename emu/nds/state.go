@@ -0,0 +1,123 @@
+package nds
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/state"
+)
+
+func (nds *Nds) stateHeader() state.Header {
+	return state.Header{
+		Console:  state.NDS,
+		Checksum: nds.Cartridge.RomChecksum(),
+		Title:    nds.Cartridge.Header.Title,
+		Frame:    nds.Frame,
+	}
+}
+
+// SaveState writes the full console state, rom and bios are not included
+func (nds *Nds) SaveState(w io.Writer) error {
+	RASTERIZE_WG.Wait()
+
+	if err := state.Save(w, nds.stateHeader(), nds.saveChunks); err != nil {
+		return fmt.Errorf("nds save state: %w", err)
+	}
+
+	return nil
+}
+
+// LoadState restores a state written by SaveState. States from other roms
+// are refused. The console should not be used if an error is returned after
+// the header was accepted, it may be partially loaded.
+func (nds *Nds) LoadState(r io.Reader) error {
+	RASTERIZE_WG.Wait()
+
+	if _, err := state.Load(r, nds.stateHeader(), nds.loadChunks); err != nil {
+		return fmt.Errorf("nds load state: %w", err)
+	}
+
+	return nil
+}
+
+func (nds *Nds) saveChunks(e *state.Encoder) {
+	e.Chunk("arm7")
+	nds.arm7.SaveState(e)
+	e.Chunk("arm9")
+	nds.arm9.SaveState(e)
+	e.Chunk("mem")
+	nds.mem.SaveState(e)
+	e.Chunk("ppu")
+	nds.ppu.SaveState(e)
+	e.Chunk("cart")
+	nds.Cartridge.SaveState(e)
+	nds.Cartridge.SaveSlot2State(e)
+	e.Chunk("nds")
+	e.Encode(
+		&nds.dma7, &nds.dma9,
+		&nds.AccCycles, &nds.TimerCycles, &nds.GeoCycles,
+		&nds.Frame,
+	)
+}
+
+func (nds *Nds) loadChunks(d *state.Decoder) {
+	d.Chunk("arm7")
+	nds.arm7.LoadState(d)
+	d.Chunk("arm9")
+	nds.arm9.LoadState(d)
+	d.Chunk("mem")
+	nds.mem.LoadState(d)
+	d.Chunk("ppu")
+	nds.ppu.LoadState(d)
+	d.Chunk("cart")
+	nds.Cartridge.LoadState(d)
+	nds.Cartridge.LoadSlot2State(d)
+	d.Chunk("nds")
+	d.Decode(
+		&nds.dma7, &nds.dma9,
+		&nds.AccCycles, &nds.TimerCycles, &nds.GeoCycles,
+		&nds.Frame,
+	)
+
+	// the sound ring moved, a capture goes on from here
+	nds.Capture.SkipSound(nds.mem.Snd.WritePointer)
+}
+
+func newRewind() *state.Rewind {
+	c := &config.Conf.General
+
+	if c.Headless || c.RewindBufferSize <= 0 {
+		return nil
+	}
+
+	return state.NewRewind(c.RewindBufferSize<<20, c.RewindInterval)
+}
+
+func (nds *Nds) captureRewind() {
+	if nds.Rewind == nil || nds.midFrame {
+		return
+	}
+
+	if err := nds.Rewind.Capture(nds.saveChunks); err != nil {
+		log.Printf("Rewind Disabled: %v\n", err)
+		nds.Rewind = nil
+	}
+}
+
+// rewindStep runs in place of a frame while rewinding, each call goes back
+// one snapshot
+func (nds *Nds) rewindStep() {
+	RASTERIZE_WG.Wait()
+
+	if _, err := nds.Rewind.Step(nds.loadChunks); err != nil {
+		log.Printf("Rewind Disabled: %v\n", err)
+		nds.Rewind = nil
+		return
+	}
+
+	t, b := nds.GetScreens()
+	nds.Screen.Top.WritePixels(*t)
+	nds.Screen.Bottom.WritePixels(*b)
+}
@@ -0,0 +1,104 @@
+package patch
+
+const (
+	BPS_MAGIC = "BPS1"
+
+	BPS_SOURCE_READ = 0
+	BPS_TARGET_READ = 1
+	BPS_SOURCE_COPY = 2
+	BPS_TARGET_COPY = 3
+)
+
+// bps builds the rom from runs copied out of the source, the patch or what
+// was already written. The crc32 of the source, the target and the patch
+// itself are checked.
+func applyBps(src, patch []uint8) ([]uint8, error) {
+	if len(patch) < len(BPS_MAGIC)+FOOTER {
+		return nil, ErrCorrupt
+	}
+
+	if err := checkFooter(src, patch); err != nil {
+		return nil, err
+	}
+
+	r := reader{buf: patch[:len(patch)-FOOTER], pos: len(BPS_MAGIC)}
+
+	srcSize := r.number()
+	dstSize := r.number()
+	r.bytes(r.number()) // metadata
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if srcSize != len(src) {
+		return nil, ErrChecksum
+	}
+
+	var (
+		out    = make([]uint8, dstSize)
+		pos    int
+		srcRel int
+		dstRel int
+	)
+
+	// signed offsets keep the sign in bit 0
+	offset := func() int {
+		d := r.number()
+		if d&1 != 0 {
+			return -(d >> 1)
+		}
+		return d >> 1
+	}
+
+	for r.pos < len(r.buf) && r.err == nil {
+		data := r.number()
+		n := data>>2 + 1
+
+		if pos+n > len(out) {
+			return nil, ErrCorrupt
+		}
+
+		switch data & 3 {
+		case BPS_SOURCE_READ:
+			if pos+n > len(src) {
+				return nil, ErrCorrupt
+			}
+
+			copy(out[pos:], src[pos:pos+n])
+
+		case BPS_TARGET_READ:
+			copy(out[pos:], r.bytes(n))
+
+		case BPS_SOURCE_COPY:
+			srcRel += offset()
+			if srcRel < 0 || srcRel+n > len(src) {
+				return nil, ErrCorrupt
+			}
+
+			copy(out[pos:], src[srcRel:srcRel+n])
+			srcRel += n
+
+		case BPS_TARGET_COPY:
+			dstRel += offset()
+			if dstRel < 0 || dstRel >= pos {
+				return nil, ErrCorrupt
+			}
+
+			// runs may overlap what they are writing
+			for i := range n {
+				out[pos+i] = out[dstRel+i]
+			}
+
+			dstRel += n
+		}
+
+		pos += n
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return out, checkTarget(out, patch)
+}
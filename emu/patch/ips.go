@@ -0,0 +1,62 @@
+package patch
+
+const (
+	IPS_MAGIC = "PATCH"
+	IPS_EOF   = 0x454F46 // "EOF"
+)
+
+// ips records write bytes at 24 bit offsets, growing the rom when needed.
+// The format has no checksums. A 24 bit size may follow the end marker to
+// truncate the rom.
+func applyIps(src, patch []uint8) ([]uint8, error) {
+	out := append([]uint8(nil), src...)
+
+	r := reader{buf: patch, pos: len(IPS_MAGIC)}
+
+	for {
+		b := r.bytes(3)
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		off := int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		if off == IPS_EOF {
+			break
+		}
+
+		b = r.bytes(2)
+		size := int(b[0])<<8 | int(b[1])
+
+		var data []uint8
+		if rle := size == 0; rle {
+			b = r.bytes(2)
+			size = int(b[0])<<8 | int(b[1])
+
+			v := r.byte()
+			data = make([]uint8, size)
+			for i := range data {
+				data[i] = v
+			}
+		} else {
+			data = r.bytes(size)
+		}
+
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		if end := off + len(data); end > len(out) {
+			out = append(out, make([]uint8, end-len(out))...)
+		}
+
+		copy(out[off:], data)
+	}
+
+	if b := r.bytes(3); r.err == nil {
+		if size := int(b[0])<<16 | int(b[1])<<8 | int(b[2]); size < len(out) {
+			out = out[:size]
+		}
+	}
+
+	return out, nil
+}
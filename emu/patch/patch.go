@@ -0,0 +1,146 @@
+// patch applies ips, ups and bps patches to roms in memory. A patch is used
+// when it is listed for the rom or sits beside it with the same name, so
+// game.gba (or game.zip) takes game.ips, game.ups or game.bps.
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrFormat   = errors.New("not an ips, ups or bps patch")
+	ErrChecksum = errors.New("checksum mismatch")
+	ErrCorrupt  = errors.New("patch is corrupt")
+)
+
+// extensions searched for beside the rom, in order
+var Extensions = []string{".ips", ".ups", ".bps"}
+
+// Apply returns the patched rom, src is left untouched
+func Apply(src, patch []uint8) ([]uint8, error) {
+	switch {
+	case bytes.HasPrefix(patch, []uint8(IPS_MAGIC)):
+		return applyIps(src, patch)
+	case bytes.HasPrefix(patch, []uint8(UPS_MAGIC)):
+		return applyUps(src, patch)
+	case bytes.HasPrefix(patch, []uint8(BPS_MAGIC)):
+		return applyBps(src, patch)
+	default:
+		return nil, ErrFormat
+	}
+}
+
+// ApplyFile applies the patch at path
+func ApplyFile(src []uint8, path string) ([]uint8, error) {
+	p, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := Apply(src, p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	return out, nil
+}
+
+// Find is the patch for the rom at path, the one listed by rom file name
+// first and then one beside it. It is empty when there is none.
+func Find(path string, listed map[string]string) string {
+	if p, ok := listed[filepath.Base(path)]; ok && isFile(p) {
+		return p
+	}
+
+	if p, ok := listed[path]; ok && isFile(p) {
+		return p
+	}
+
+	stem := Stem(path)
+	for _, ext := range Extensions {
+		if p := stem + ext; isFile(p) {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// Stem is path without the rom and archive extensions
+func Stem(path string) string {
+	for range 2 {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".zip", ".gz", ".gb", ".gbc", ".gba", ".nds":
+			path = strings.TrimSuffix(path, filepath.Ext(path))
+		}
+	}
+
+	return path
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// reader walks a patch, reads past the end fail instead of panicking
+type reader struct {
+	buf []uint8
+	pos int
+	err error
+}
+
+func (r *reader) byte() uint8 {
+	if r.pos >= len(r.buf) {
+		r.err = ErrCorrupt
+		return 0
+	}
+
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *reader) bytes(n int) []uint8 {
+	if n < 0 || r.pos+n > len(r.buf) {
+		r.err = ErrCorrupt
+		r.pos = len(r.buf)
+		return nil
+	}
+
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// number is the variable length integer used by ups and bps
+func (r *reader) number() int {
+	var (
+		n     uint64
+		shift uint64 = 1
+	)
+
+	for r.err == nil {
+		x := r.byte()
+		n += uint64(x&0x7F) * shift
+
+		if x&0x80 != 0 {
+			break
+		}
+
+		shift <<= 7
+		n += shift
+
+		// larger than any rom
+		if shift > 1<<42 {
+			r.err = ErrCorrupt
+		}
+	}
+
+	return int(n)
+}
@@ -0,0 +1,180 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func number(n int) []uint8 {
+	var out []uint8
+
+	for {
+		x := uint8(n & 0x7F)
+		n >>= 7
+
+		if n == 0 {
+			return append(out, x|0x80)
+		}
+
+		out = append(out, x)
+		n--
+	}
+}
+
+// footer appends the crcs of src, dst and the patch
+func footer(p, src, dst []uint8) []uint8 {
+	p = binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(src))
+	p = binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(dst))
+	return binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(p))
+}
+
+var src = []uint8("the quick brown fox jumps over the lazy dog")
+
+func TestIps(t *testing.T) {
+	p := []uint8(IPS_MAGIC)
+	p = append(p, 0x00, 0x00, 0x04, 0x00, 0x05)
+	p = append(p, "QUICK"...)
+	p = append(p, 0x00, 0x00, 0x2B, 0x00, 0x00, 0x00, 0x03, '!') // rle past the end
+	p = append(p, "EOF"...)
+
+	got, err := Apply(src, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "the QUICK brown fox jumps over the lazy dog!!!"; string(got) != want {
+		t.Fatalf("got %q", got)
+	}
+
+	// truncated to 9 bytes
+	p = append(p, 0x00, 0x00, 0x09)
+	if got, _ = Apply(src, p); string(got) != "the QUICK" {
+		t.Fatalf("truncated to %q", got)
+	}
+
+	if _, err := Apply(src, p[:12]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("short patch: %v", err)
+	}
+}
+
+func TestUps(t *testing.T) {
+	dst := []uint8("the quick brown cat jumps over the lazy dog, twice")
+
+	p := []uint8(UPS_MAGIC)
+	p = append(p, number(len(src))...)
+	p = append(p, number(len(dst))...)
+
+	// skip to the difference, xor until a zero
+	p = append(p, number(16)...)
+	p = append(p, 'f'^'c', 'o'^'a', 'x'^'t', 0)
+
+	p = append(p, number(len(src)-20)...)
+	for _, c := range dst[len(src):] {
+		p = append(p, c)
+	}
+	p = append(p, 0)
+
+	p = footer(p, src, dst)
+
+	got, err := Apply(src, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, dst) {
+		t.Fatalf("got %q", got)
+	}
+
+	other := []uint8("a different rom entirely, the same length ok")
+	if _, err := Apply(other[:len(src)], p); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("wrong source: %v", err)
+	}
+
+	p[len(p)-20] ^= 1
+	if _, err := Apply(src, p); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("damaged patch: %v", err)
+	}
+}
+
+func TestBps(t *testing.T) {
+	dst := []uint8("the lazy dog jumps over the quick brown fox fox fox")
+
+	action := func(cmd, n int) []uint8 { return number((n-1)<<2 | cmd) }
+	signed := func(d int) []uint8 {
+		if d < 0 {
+			return number(-d<<1 | 1)
+		}
+		return number(d << 1)
+	}
+
+	p := []uint8(BPS_MAGIC)
+	p = append(p, number(len(src))...)
+	p = append(p, number(len(dst))...)
+	p = append(p, number(4)...)
+	p = append(p, "meta"...)
+
+	p = append(p, action(BPS_SOURCE_READ, 4)...) // "the "
+	p = append(p, action(BPS_SOURCE_COPY, 8)...) // "lazy dog"
+	p = append(p, signed(35)...)
+	p = append(p, action(BPS_SOURCE_COPY, 11)...) // " jumps over"
+	p = append(p, signed(19-43)...)
+	p = append(p, action(BPS_TARGET_READ, 5)...)
+	p = append(p, " the "...)
+	p = append(p, action(BPS_SOURCE_COPY, 15)...) // "quick brown fox"
+	p = append(p, signed(4-30)...)
+	p = append(p, action(BPS_TARGET_COPY, 8)...) // " fox fox", overlapping
+	p = append(p, signed(39)...)
+
+	p = footer(p, src, dst)
+
+	got, err := Apply(src, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, dst) {
+		t.Fatalf("got %q", got)
+	}
+
+	if _, err := Apply(dst[:len(src)], p); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("wrong source: %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []uint8(IPS_MAGIC+"EOF"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rom := filepath.Join(dir, "game.gba.zip")
+
+	if p := Find(rom, nil); p != "" {
+		t.Fatalf("found %s without a patch", p)
+	}
+
+	bps := write("game.bps")
+	if p := Find(rom, nil); p != bps {
+		t.Fatalf("found %q beside the rom", p)
+	}
+
+	ips := write("game.ips")
+	if p := Find(rom, nil); p != ips {
+		t.Fatalf("found %q, ips comes first", p)
+	}
+
+	hack := write("hack.ups")
+	if p := Find(rom, map[string]string{"game.gba.zip": hack}); p != hack {
+		t.Fatalf("found %q, the listed patch comes first", p)
+	}
+}
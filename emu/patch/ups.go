@@ -0,0 +1,89 @@
+package patch
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+const (
+	UPS_MAGIC = "UPS1"
+
+	// crc32 of the source, target and patch
+	FOOTER = 12
+)
+
+// ups xors runs of bytes into the rom. The crc32 of the source, the target
+// and the patch itself are checked.
+func applyUps(src, patch []uint8) ([]uint8, error) {
+	if len(patch) < len(UPS_MAGIC)+FOOTER {
+		return nil, ErrCorrupt
+	}
+
+	if err := checkFooter(src, patch); err != nil {
+		return nil, err
+	}
+
+	r := reader{buf: patch[:len(patch)-FOOTER], pos: len(UPS_MAGIC)}
+
+	srcSize := r.number()
+	dstSize := r.number()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if srcSize != len(src) {
+		return nil, ErrChecksum
+	}
+
+	out := make([]uint8, dstSize)
+	copy(out, src)
+
+	for pos := 0; r.pos < len(r.buf) && r.err == nil; {
+		pos += r.number()
+
+		// a run ends on a zero, which also moves past a byte
+		for r.err == nil {
+			x := r.byte()
+
+			if pos < len(out) {
+				out[pos] ^= x
+			}
+
+			pos++
+
+			if x == 0 {
+				break
+			}
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return out, checkTarget(out, patch)
+}
+
+// checkFooter checks the source and patch crcs shared by ups and bps
+func checkFooter(src, patch []uint8) error {
+	footer := patch[len(patch)-FOOTER:]
+
+	if crc32.ChecksumIEEE(patch[:len(patch)-4]) != binary.LittleEndian.Uint32(footer[8:]) {
+		return ErrCorrupt
+	}
+
+	if crc32.ChecksumIEEE(src) != binary.LittleEndian.Uint32(footer) {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
+func checkTarget(out, patch []uint8) error {
+	if crc32.ChecksumIEEE(out) != binary.LittleEndian.Uint32(patch[len(patch)-8:]) {
+		return ErrChecksum
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+// rom reads roms as they are stored, plain or in a .zip or .gz archive, and
+// tells which console a rom is for. The console comes from the extension of
+// the rom, inside the archive if there is one, and from the nintendo logo in
+// the header when the extension does not say. Load also soft patches the rom.
+package rom
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aabalke/guac/emu/patch"
+)
+
+type Console int
+
+const (
+	UNKNOWN Console = iota
+	GB
+	GBA
+	NDS
+)
+
+// enough of the header to hold every logo
+const HEADER_SIZE = 0x200
+
+var (
+	gbLogo = []uint8{0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B, 0x03, 0x73, 0x00, 0x83}
+
+	// gba and nds carts share the logo, the nds header has it at c0h
+	armLogo = []uint8{0x24, 0xFF, 0xAE, 0x51, 0x69, 0x9A, 0xA2, 0x21, 0x3D, 0x84, 0x82, 0x0A}
+)
+
+var ErrNoRom = errors.New("no rom in archive")
+
+// ReadFile returns the rom at path, unpacked if it is archived
+func ReadFile(path string) ([]uint8, error) {
+	switch archive(path) {
+	case ".zip":
+		z, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+
+		defer z.Close()
+
+		f, err := pick(z.File)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		defer r.Close()
+
+		return io.ReadAll(r)
+
+	case ".gz":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+
+		return io.ReadAll(r)
+	}
+
+	return os.ReadFile(path)
+}
+
+// Identify tells the console of the rom at path
+func Identify(path string) Console {
+	var (
+		name   = path
+		header []uint8
+	)
+
+	switch archive(path) {
+	case ".zip":
+		z, err := zip.OpenReader(path)
+		if err != nil {
+			return UNKNOWN
+		}
+
+		defer z.Close()
+
+		f, err := pick(z.File)
+		if err != nil {
+			return UNKNOWN
+		}
+
+		name = f.Name
+		header = readHeader(f.Open)
+
+	case ".gz":
+		name = strings.TrimSuffix(path, filepath.Ext(path))
+
+		header = readHeader(func() (io.ReadCloser, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+
+			r, err := gzip.NewReader(f)
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+
+			if r.Name != "" {
+				name = r.Name
+			}
+
+			return struct {
+				io.Reader
+				io.Closer
+			}{r, f}, nil
+		})
+
+	default:
+		header = readHeader(func() (io.ReadCloser, error) { return os.Open(path) })
+	}
+
+	if c := ByExtension(name); c != UNKNOWN {
+		return c
+	}
+
+	return Detect(header)
+}
+
+// ByExtension tells the console from the file extension alone
+func ByExtension(name string) Console {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gb", ".gbc":
+		return GB
+	case ".gba":
+		return GBA
+	case ".nds":
+		return NDS
+	default:
+		return UNKNOWN
+	}
+}
+
+// Detect tells the console from the nintendo logo in the header
+func Detect(header []uint8) Console {
+	has := func(off int, logo []uint8) bool {
+		return len(header) >= off+len(logo) && bytes.Equal(header[off:off+len(logo)], logo)
+	}
+
+	switch {
+	case has(0xC0, armLogo):
+		return NDS
+	case has(0x04, armLogo):
+		return GBA
+	case has(0x104, gbLogo):
+		return GB
+	default:
+		return UNKNOWN
+	}
+}
+
+// IsArchive reports if path is opened as an archive
+func IsArchive(path string) bool {
+	return archive(path) != ""
+}
+
+func archive(path string) string {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".zip", ".gz":
+		return ext
+	default:
+		return ""
+	}
+}
+
+// pick is the rom in a zip, the first file with a rom extension or else the
+// first with a logo
+func pick(files []*zip.File) (*zip.File, error) {
+	for _, f := range files {
+		if ByExtension(f.Name) != UNKNOWN {
+			return f, nil
+		}
+	}
+
+	for _, f := range files {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		if Detect(readHeader(f.Open)) != UNKNOWN {
+			return f, nil
+		}
+	}
+
+	return nil, ErrNoRom
+}
+
+func readHeader(open func() (io.ReadCloser, error)) []uint8 {
+	r, err := open()
+	if err != nil {
+		return nil
+	}
+
+	defer r.Close()
+
+	buf := make([]uint8, HEADER_SIZE)
+	n, _ := io.ReadFull(r, buf)
+
+	return buf[:n]
+}
+
+// Image is a rom ready for a cartridge, soft patched when a patch was found
+type Image struct {
+	Path     string // rom as opened
+	Patch    string // patch applied, empty when unpatched
+	PatchErr error  // why the patch found was not applied
+	Data     []uint8
+}
+
+// Load reads the rom at path and applies its patch, listed maps rom file
+// names to patches kept elsewhere. A patch that fails is kept in PatchErr
+// and the rom is loaded unpatched, a bad patch beside a rom does not stop it
+// running. The frontend tells the player.
+func Load(path string, listed map[string]string) (*Image, error) {
+	buf, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	img := &Image{Path: path, Data: buf}
+
+	if img.Patch = patch.Find(path, listed); img.Patch == "" {
+		return img, nil
+	}
+
+	data, err := patch.ApplyFile(buf, img.Patch)
+	if err != nil {
+		log.Printf("Patch Failed, Loading Unpatched: %s: %v\n", filepath.Base(img.Patch), err)
+		img.PatchErr = fmt.Errorf("%s: %w", filepath.Base(img.Patch), err)
+		img.Patch = ""
+		return img, nil
+	}
+
+	img.Data = data
+
+	log.Printf("Patched %s with %s\n", filepath.Base(path), filepath.Base(img.Patch))
+
+	return img, nil
+}
+
+// Name identifies the game for saves, a patched rom goes by its patch so a
+// hack does not share the save of the original
+func (i *Image) Name() string {
+	if i.Patch != "" {
+		return i.Patch
+	}
+
+	return i.Path
+}
@@ -0,0 +1,202 @@
+package rom
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// image is a rom of size with a logo at off
+func image(size, off int, logo []uint8) []uint8 {
+	buf := make([]uint8, size)
+	copy(buf[off:], logo)
+
+	for i := off + len(logo); i < size; i++ {
+		buf[i] = uint8(i)
+	}
+
+	return buf
+}
+
+func writeZip(t *testing.T, path string, files map[string][]uint8, order ...string) {
+	t.Helper()
+
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+
+	for _, name := range order {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write(files[name])
+	}
+
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeGzip(t *testing.T, path, name string, data []uint8) {
+	t.Helper()
+
+	var b bytes.Buffer
+	z := gzip.NewWriter(&b)
+	z.Name = name
+	z.Write(data)
+
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "game.zip")
+
+	gba := image(0x1000, 0x04, armLogo)
+
+	writeZip(t, path, map[string][]uint8{
+		"readme.txt": []uint8("not a rom"),
+		"game.gba":   gba,
+	}, "readme.txt", "game.gba")
+
+	buf, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf, gba) {
+		t.Fatal("zip read the wrong entry")
+	}
+
+	if c := Identify(path); c != GBA {
+		t.Fatalf("identified %d", c)
+	}
+}
+
+func TestZipMagic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "game.zip")
+
+	nds := image(0x1000, 0xC0, armLogo)
+
+	writeZip(t, path, map[string][]uint8{
+		"readme.txt": []uint8("not a rom"),
+		"game.bin":   nds,
+	}, "readme.txt", "game.bin")
+
+	buf, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf, nds) {
+		t.Fatal("zip read the wrong entry")
+	}
+
+	if c := Identify(path); c != NDS {
+		t.Fatalf("identified %d", c)
+	}
+}
+
+func TestZipEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.zip")
+
+	writeZip(t, path, map[string][]uint8{"readme.txt": []uint8("not a rom")}, "readme.txt")
+
+	if _, err := ReadFile(path); err == nil {
+		t.Fatal("read a rom from an archive without one")
+	}
+
+	if c := Identify(path); c != UNKNOWN {
+		t.Fatalf("identified %d", c)
+	}
+}
+
+func TestGzip(t *testing.T) {
+	dir := t.TempDir()
+
+	gb := image(0x8000, 0x104, gbLogo)
+
+	// the stored name has no extension, the logo decides
+	path := filepath.Join(dir, "game.gz")
+	writeGzip(t, path, "", gb)
+
+	buf, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf, gb) {
+		t.Fatal("gzip read differs")
+	}
+
+	if c := Identify(path); c != GB {
+		t.Fatalf("identified %d", c)
+	}
+
+	// the name without .gz is enough
+	path = filepath.Join(dir, "game.gba.gz")
+	writeGzip(t, path, "", gb)
+
+	if c := Identify(path); c != GBA {
+		t.Fatalf("identified %d by name", c)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, tt := range []struct {
+		header []uint8
+		want   Console
+	}{
+		{image(HEADER_SIZE, 0x104, gbLogo), GB},
+		{image(HEADER_SIZE, 0x04, armLogo), GBA},
+		{image(HEADER_SIZE, 0xC0, armLogo), NDS},
+		{make([]uint8, HEADER_SIZE), UNKNOWN},
+		{image(0x108, 0x104, gbLogo[:4]), UNKNOWN},
+	} {
+		if got := Detect(tt.header); got != tt.want {
+			t.Errorf("detected %d, want %d", got, tt.want)
+		}
+	}
+}
+
+func TestBadPatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "game.gba")
+	data := image(0x200, 0x04, armLogo)
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "game.ips"), []uint8("PATCH\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Patch != "" || !bytes.Equal(img.Data, data) || img.Name() != path {
+		t.Fatalf("bad patch loaded as %q", img.Patch)
+	}
+
+	if img.PatchErr == nil {
+		t.Fatal("bad patch not reported")
+	}
+}
@@ -0,0 +1,289 @@
+// search finds the addresses of values in guest ram, for making cheats.
+//
+// A Search starts with every address of its regions as a candidate and the
+// current values as its snapshot. Each Filter keeps the candidates whose
+// value passes and takes a new snapshot, so increased and decreased compare
+// against the previous filter.
+//
+//	s := search.New(gba.RamRegions(), search.Options{Width: 2})
+//	// play, lose a life
+//	s.Filter(search.DECREASED, 0)
+//	// play, lose another
+//	s.Filter(search.CHANGED_BY, -1)
+//	s.Results(10)
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Region is a block of guest ram at a cpu address. Data is the emulator's
+// own memory, it is read as the game runs.
+type Region struct {
+	Name string
+	Base uint32
+	Data []uint8
+}
+
+type Op int
+
+const (
+	EQUAL      Op = iota // the value is n
+	NOT_EQUAL            // the value is not n
+	INCREASED            // since the snapshot
+	DECREASED            // since the snapshot
+	CHANGED              // since the snapshot
+	UNCHANGED            // since the snapshot
+	CHANGED_BY           // the value is the snapshot plus n
+)
+
+var opNames = map[string]Op{
+	"eq":        EQUAL,
+	"ne":        NOT_EQUAL,
+	"inc":       INCREASED,
+	"dec":       DECREASED,
+	"changed":   CHANGED,
+	"unchanged": UNCHANGED,
+	"by":        CHANGED_BY,
+}
+
+var ErrOp = errors.New("unknown search op")
+
+// ParseOp takes the short names used by scripts, eq, ne, inc, dec, changed,
+// unchanged and by
+func ParseOp(s string) (Op, error) {
+	op, ok := opNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("%q: %w", s, ErrOp)
+	}
+
+	return op, nil
+}
+
+func (op Op) String() string {
+	for name, v := range opNames {
+		if v == op {
+			return name
+		}
+	}
+
+	return "op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// ParseValue takes decimal or 0x hex, either may be negative
+func ParseValue(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	var (
+		v   uint64
+		err error
+	)
+
+	if h, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		v, err = strconv.ParseUint(h, 16, 64)
+	} else {
+		v, err = strconv.ParseUint(s, 10, 64)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if neg {
+		return -int64(v), nil
+	}
+
+	return int64(v), nil
+}
+
+type Options struct {
+	Width  int  // bytes, 1, 2 or 4, 0 is 1
+	Signed bool // values are two's complement
+
+	// Unaligned searches every address instead of multiples of the width,
+	// 8 bit cpus keep wide values anywhere
+	Unaligned bool
+}
+
+// Result is a candidate, Prev is its value at the snapshot before the last
+type Result struct {
+	Region      string
+	Addr        uint32
+	Value, Prev int64
+}
+
+type Search struct {
+	Options
+
+	regions []Region
+	prev    [][]uint8  // snapshot of each region
+	old     [][]uint8  // the snapshot before prev, shown in results
+	hits    [][]uint64 // candidate bit per region offset
+	count   int
+}
+
+func New(regions []Region, opt Options) *Search {
+	switch opt.Width {
+	case 0:
+		opt.Width = 1
+	case 1, 2, 4:
+	default:
+		panic(fmt.Sprintf("search width %d", opt.Width))
+	}
+
+	s := &Search{Options: opt, regions: regions}
+	s.Reset()
+
+	return s
+}
+
+// Reset makes every address a candidate again and takes a snapshot
+func (s *Search) Reset() {
+	s.prev = make([][]uint8, len(s.regions))
+	s.old = make([][]uint8, len(s.regions))
+	s.hits = make([][]uint64, len(s.regions))
+	s.count = 0
+
+	step := s.step()
+
+	for i, r := range s.regions {
+		s.prev[i] = make([]uint8, len(r.Data))
+		s.old[i] = make([]uint8, len(r.Data))
+		s.hits[i] = make([]uint64, (len(r.Data)+63)/64)
+
+		for off := 0; off+s.Width <= len(r.Data); off += step {
+			s.hits[i][off/64] |= 1 << (off % 64)
+			s.count++
+		}
+	}
+
+	s.Snapshot()
+	s.Snapshot()
+}
+
+// Snapshot keeps the current values for the next filter without filtering
+func (s *Search) Snapshot() {
+	s.old, s.prev = s.prev, s.old
+
+	for i, r := range s.regions {
+		copy(s.prev[i], r.Data)
+	}
+}
+
+// Filter keeps the candidates which pass op and returns how many are left
+func (s *Search) Filter(op Op, n int64) int {
+	s.count = 0
+
+	for i, r := range s.regions {
+		for w, word := range s.hits[i] {
+			for word != 0 {
+				b := bits.TrailingZeros64(word)
+				word &^= 1 << b
+
+				off := w*64 + b
+
+				if !s.test(op, n, s.value(r.Data, off), s.value(s.prev[i], off)) {
+					s.hits[i][w] &^= 1 << b
+					continue
+				}
+
+				s.count++
+			}
+		}
+	}
+
+	s.Snapshot()
+
+	return s.count
+}
+
+func (s *Search) test(op Op, n, v, prev int64) bool {
+	switch op {
+	case EQUAL:
+		return v == s.wrap(n)
+	case NOT_EQUAL:
+		return v != s.wrap(n)
+	case INCREASED:
+		return v > prev
+	case DECREASED:
+		return v < prev
+	case CHANGED:
+		return v != prev
+	case UNCHANGED:
+		return v == prev
+	case CHANGED_BY:
+		return v == s.wrap(prev+n)
+	}
+
+	return false
+}
+
+// Count is the candidates left
+func (s *Search) Count() int {
+	return s.count
+}
+
+// Results are the first max candidates, all of them when max is 0
+func (s *Search) Results(max int) []Result {
+	out := []Result{}
+
+	for i, r := range s.regions {
+		for w, word := range s.hits[i] {
+			for word != 0 {
+				if max != 0 && len(out) == max {
+					return out
+				}
+
+				b := bits.TrailingZeros64(word)
+				word &^= 1 << b
+
+				off := w*64 + b
+
+				out = append(out, Result{
+					Region: r.Name,
+					Addr:   r.Base + uint32(off),
+					Value:  s.value(r.Data, off),
+					Prev:   s.value(s.old[i], off),
+				})
+			}
+		}
+	}
+
+	return out
+}
+
+func (s *Search) step() int {
+	if s.Unaligned {
+		return 1
+	}
+
+	return s.Width
+}
+
+// value is the little endian value at off
+func (s *Search) value(data []uint8, off int) int64 {
+	var v uint64
+	for i := range s.Width {
+		v |= uint64(data[off+i]) << (8 * i)
+	}
+
+	return s.wrap(int64(v))
+}
+
+// wrap cuts v to the width, sign extending signed values
+func (s *Search) wrap(v int64) int64 {
+	shift := 64 - 8*s.Width
+
+	if s.Signed {
+		return v << shift >> shift
+	}
+
+	return int64(uint64(v) << shift >> shift)
+}
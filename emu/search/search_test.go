@@ -0,0 +1,130 @@
+package search
+
+import "testing"
+
+func addrs(rs []Result) []uint32 {
+	out := []uint32{}
+	for _, r := range rs {
+		out = append(out, r.Addr)
+	}
+	return out
+}
+
+func same(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFilter(t *testing.T) {
+	ram := make([]uint8, 16)
+	ram[3], ram[9] = 5, 5
+
+	s := New([]Region{{"wram", 0xC000, ram}}, Options{})
+
+	if n := s.Filter(EQUAL, 5); n != 2 {
+		t.Fatalf("equal 5 left %d", n)
+	}
+
+	ram[3], ram[9] = 4, 6
+
+	if n := s.Filter(DECREASED, 0); n != 1 {
+		t.Fatalf("decreased left %d", n)
+	}
+
+	rs := s.Results(0)
+	if rs[0].Addr != 0xC003 || rs[0].Value != 4 || rs[0].Prev != 5 {
+		t.Fatalf("results %+v", rs)
+	}
+
+	ram[3] = 1
+
+	if n := s.Filter(CHANGED_BY, -3); n != 1 {
+		t.Fatalf("changed by -3 left %d", n)
+	}
+
+	if n := s.Filter(CHANGED, 0); n != 0 {
+		t.Fatalf("changed left %d", n)
+	}
+
+	s.Reset()
+
+	if n := s.Count(); n != 16 {
+		t.Fatalf("reset left %d", n)
+	}
+}
+
+func TestWidth(t *testing.T) {
+	ram := []uint8{0xFF, 0xFF, 0x34, 0x12, 0xFE, 0xFF, 0xFF, 0xFF}
+
+	s := New([]Region{{"ewram", 0x200_0000, ram}}, Options{Width: 2})
+
+	s.Filter(EQUAL, 0x1234)
+	if got := addrs(s.Results(0)); !same(got, []uint32{0x200_0002}) {
+		t.Fatalf("16 bit equal %X", got)
+	}
+
+	signed := New([]Region{{"ewram", 0x200_0000, ram}}, Options{Width: 2, Signed: true})
+
+	signed.Filter(EQUAL, -1)
+	if got := addrs(signed.Results(0)); !same(got, []uint32{0x200_0000, 0x200_0006}) {
+		t.Fatalf("signed equal -1 %X", got)
+	}
+
+	// -1 is 0xFFFF unsigned, out of range values are cut to the width
+	s = New([]Region{{"ewram", 0x200_0000, ram}}, Options{Width: 2})
+	s.Filter(EQUAL, -1)
+	if got := addrs(s.Results(0)); !same(got, []uint32{0x200_0000, 0x200_0006}) {
+		t.Fatalf("unsigned equal -1 %X", got)
+	}
+
+	wide := New([]Region{{"ewram", 0x200_0000, ram}}, Options{Width: 4, Signed: true})
+	wide.Filter(EQUAL, -2)
+	if got := addrs(wide.Results(0)); !same(got, []uint32{0x200_0004}) {
+		t.Fatalf("32 bit equal -2 %X", got)
+	}
+
+	// the counter wraps from -1 to 0
+	ram[6], ram[7] = 0, 0
+	signed.Filter(CHANGED_BY, 1)
+	if got := addrs(signed.Results(0)); !same(got, []uint32{0x200_0006}) {
+		t.Fatalf("wrapped changed by 1 %X", got)
+	}
+}
+
+func TestUnaligned(t *testing.T) {
+	ram := []uint8{0x00, 0x34, 0x12, 0x00}
+
+	s := New([]Region{{"wram", 0xC000, ram}}, Options{Width: 2, Unaligned: true})
+
+	if n := s.Count(); n != 3 {
+		t.Fatalf("unaligned candidates %d", n)
+	}
+
+	s.Filter(EQUAL, 0x1234)
+	if got := addrs(s.Results(0)); !same(got, []uint32{0xC001}) {
+		t.Fatalf("unaligned equal %X", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for s, want := range map[string]int64{"10": 10, "0x1F": 31, "-0x10": -16, "-3": -3} {
+		if v, err := ParseValue(s); err != nil || v != want {
+			t.Fatalf("%s parsed %d %v", s, v, err)
+		}
+	}
+
+	if _, err := ParseOp("bigger"); err == nil {
+		t.Fatal("unknown op parsed")
+	}
+
+	if op, _ := ParseOp("BY"); op != CHANGED_BY {
+		t.Fatalf("by parsed %v", op)
+	}
+}
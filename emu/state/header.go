@@ -0,0 +1,160 @@
+package state
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Version is bumped whenever the layout of any console state changes, older
+// states are refused instead of being loaded into the wrong fields
+const Version = 3
+
+const magic = "GUACSTATE"
+
+type Console uint8
+
+const (
+	GB Console = iota + 1
+	GBA
+	NDS
+)
+
+func (c Console) String() string {
+	switch c {
+	case GB:
+		return "gb"
+	case GBA:
+		return "gba"
+	case NDS:
+		return "nds"
+	default:
+		return fmt.Sprintf("unknown console %d", uint8(c))
+	}
+}
+
+var (
+	ErrFormat  = errors.New("state: not a save state")
+	ErrVersion = errors.New("state: unsupported version")
+	ErrConsole = errors.New("state: wrong console")
+	ErrRom     = errors.New("state: created with a different rom")
+)
+
+// Header is written uncompressed at the start of every state, so a state can
+// be identified without decoding it.
+type Header struct {
+	Version  uint16
+	Console  Console
+	Checksum uint32 // crc32 of the rom
+	Title    string
+	Frame    uint64
+}
+
+// Checksum is the rom identity stored in state headers
+func Checksum(rom []byte) uint32 {
+	return crc32.ChecksumIEEE(rom)
+}
+
+// Save writes h followed by the compressed chunks written in save.
+func Save(w io.Writer, h Header, save func(e *Encoder)) error {
+	h.Version = Version
+
+	if err := writeHeader(w, &h); err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+
+	e := NewEncoder(zw)
+	save(e)
+
+	if err := e.Flush(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// Load reads a state written by Save. The header has to match want's console
+// and checksum, otherwise nothing is decoded. If an error is returned after
+// load has been called the console is only partially restored.
+func Load(r io.Reader, want Header, load func(d *Decoder)) (Header, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return h, err
+	}
+
+	switch {
+	case h.Version != Version:
+		return h, fmt.Errorf("%w %d, expected %d", ErrVersion, h.Version, Version)
+	case h.Console != want.Console:
+		return h, fmt.Errorf("%w %s, expected %s", ErrConsole, h.Console, want.Console)
+	case h.Checksum != want.Checksum:
+		return h, fmt.Errorf("%w %q (%08X), loaded %q (%08X)", ErrRom,
+			h.Title, h.Checksum, want.Title, want.Checksum)
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return h, fmt.Errorf("state: %w", err)
+	}
+
+	defer zr.Close()
+
+	d := NewDecoder(zr)
+	load(d)
+
+	return h, d.Err()
+}
+
+// ReadHeader reads only the header of a state.
+func ReadHeader(r io.Reader) (Header, error) {
+	var (
+		h   Header
+		buf [len(magic) + 2 + 1 + 4 + 8 + 1]byte
+	)
+
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return h, ErrFormat
+	}
+
+	if string(buf[:len(magic)]) != magic {
+		return h, ErrFormat
+	}
+
+	b := buf[len(magic):]
+	h.Version = binary.LittleEndian.Uint16(b[0:])
+	h.Console = Console(b[2])
+	h.Checksum = binary.LittleEndian.Uint32(b[3:])
+	h.Frame = binary.LittleEndian.Uint64(b[7:])
+
+	title := make([]byte, b[15])
+	if _, err := io.ReadFull(r, title); err != nil {
+		return h, ErrFormat
+	}
+
+	h.Title = string(title)
+
+	return h, nil
+}
+
+func writeHeader(w io.Writer, h *Header) error {
+	title := h.Title
+	if len(title) > 0xFF {
+		title = title[:0xFF]
+	}
+
+	b := []byte(magic)
+	b = binary.LittleEndian.AppendUint16(b, h.Version)
+	b = append(b, uint8(h.Console))
+	b = binary.LittleEndian.AppendUint32(b, h.Checksum)
+	b = binary.LittleEndian.AppendUint64(b, h.Frame)
+	b = append(b, uint8(len(title)))
+	b = append(b, title...)
+
+	_, err := w.Write(b)
+	return err
+}
@@ -0,0 +1,254 @@
+package state
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Rewind is a rolling history of snapshots taken every Interval frames and
+// limited to roughly Budget bytes. Only the newest snapshot is kept whole,
+// each older one is stored as a compressed xor against the snapshot that
+// followed it, so memory which did not change between snapshots costs next
+// to nothing. When the budget is exceeded the oldest snapshots are dropped.
+//
+// Only the snapshot is written on the caller's goroutine, the delta of the
+// snapshot before it is compressed in the background and collected by the
+// next Capture, Step or Reset.
+type Rewind struct {
+	Budget   int
+	Interval int
+
+	frames int
+	cur    []byte
+	deltas [][]byte
+	size   int // bytes used by deltas
+
+	pending chan pendingDelta // delta being compressed, nil if none
+	older   []byte            // snapshot the pending delta is made from
+
+	spare []byte
+	xor   []byte
+	zw    *flate.Writer
+}
+
+type pendingDelta struct {
+	d   []byte
+	err error
+}
+
+func NewRewind(budget, interval int) *Rewind {
+	zw, _ := flate.NewWriter(nil, flate.BestSpeed)
+
+	return &Rewind{
+		Budget:   budget,
+		Interval: max(1, interval),
+		zw:       zw,
+	}
+}
+
+// Capture is called once per frame, every Interval frames save is used to
+// write a new snapshot.
+func (r *Rewind) Capture(save func(e *Encoder)) error {
+	if r.frames++; r.frames < r.Interval {
+		return nil
+	}
+
+	r.frames = 0
+
+	if err := r.wait(); err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(r.spare[:0])
+	e := NewEncoder(buf)
+	save(e)
+
+	if err := e.Flush(); err != nil {
+		return err
+	}
+
+	snap := buf.Bytes()
+	r.spare = nil
+
+	if older := r.cur; older != nil {
+		ch := make(chan pendingDelta, 1)
+		go func() {
+			d, err := r.delta(older, snap)
+			ch <- pendingDelta{d, err}
+		}()
+
+		r.pending = ch
+		r.older = older
+	}
+
+	r.cur = snap
+
+	return nil
+}
+
+// wait collects the delta being compressed, the snapshot it was made from
+// is reused for the next one
+func (r *Rewind) wait() error {
+	if r.pending == nil {
+		return nil
+	}
+
+	p := <-r.pending
+	r.pending = nil
+
+	if p.err != nil {
+		r.older = nil
+		return p.err
+	}
+
+	r.deltas = append(r.deltas, p.d)
+	r.size += len(p.d)
+	r.spare = r.older
+	r.older = nil
+
+	for len(r.deltas) != 0 && r.size+len(r.cur) > r.Budget {
+		r.size -= len(r.deltas[0])
+		r.deltas[0] = nil
+		r.deltas = r.deltas[1:]
+	}
+
+	return nil
+}
+
+// Step loads the newest snapshot with load and removes it, so the next Step
+// goes further back. Once only the oldest snapshot is left it is loaded
+// again on every Step and false is returned.
+func (r *Rewind) Step(load func(d *Decoder)) (bool, error) {
+	if err := r.wait(); err != nil {
+		r.Reset()
+		return false, err
+	}
+
+	if r.cur == nil {
+		return false, nil
+	}
+
+	d := NewDecoder(bytes.NewReader(r.cur))
+	load(d)
+
+	if err := d.Err(); err != nil {
+		r.Reset()
+		return false, err
+	}
+
+	// the next snapshot is taken a full interval after the loaded one
+	r.frames = 0
+
+	if len(r.deltas) == 0 {
+		return false, nil
+	}
+
+	last := r.deltas[len(r.deltas)-1]
+	r.deltas[len(r.deltas)-1] = nil
+	r.deltas = r.deltas[:len(r.deltas)-1]
+	r.size -= len(last)
+
+	prev, err := r.undo(r.cur, last)
+	if err != nil {
+		r.Reset()
+		return false, err
+	}
+
+	r.spare = r.cur
+	r.cur = prev
+
+	return true, nil
+}
+
+// Reset drops every snapshot, used when the console state is replaced
+func (r *Rewind) Reset() {
+	r.wait()
+
+	r.frames = 0
+	r.cur = nil
+	r.deltas = nil
+	r.size = 0
+}
+
+// Len is the number of snapshots held
+func (r *Rewind) Len() int {
+	if r.cur == nil {
+		return 0
+	}
+
+	if r.pending != nil {
+		return len(r.deltas) + 2
+	}
+
+	return len(r.deltas) + 1
+}
+
+// delta encodes older against newer as the length of older followed by the
+// deflated xor of both, the shorter one padded with zeros
+func (r *Rewind) delta(older, newer []byte) ([]byte, error) {
+	xor := r.xorBuf(max(len(older), len(newer)))
+
+	n := subtle.XORBytes(xor, older, newer)
+	if len(older) > n {
+		copy(xor[n:], older[n:])
+	} else {
+		copy(xor[n:], newer[n:])
+	}
+
+	out := bytes.Buffer{}
+	out.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(older))))
+
+	r.zw.Reset(&out)
+
+	if _, err := r.zw.Write(xor); err != nil {
+		return nil, fmt.Errorf("state: rewind %w", err)
+	}
+
+	if err := r.zw.Close(); err != nil {
+		return nil, fmt.Errorf("state: rewind %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// undo reverses delta, returning older
+func (r *Rewind) undo(newer, d []byte) ([]byte, error) {
+	if len(d) < 4 {
+		return nil, ErrFormat
+	}
+
+	olderLen := int(binary.LittleEndian.Uint32(d))
+	xor := r.xorBuf(max(olderLen, len(newer)))
+
+	zr := flate.NewReader(bytes.NewReader(d[4:]))
+	defer zr.Close()
+
+	if _, err := io.ReadFull(zr, xor); err != nil {
+		return nil, fmt.Errorf("state: rewind %w", err)
+	}
+
+	older := r.spare[:0]
+	if cap(older) < olderLen {
+		older = make([]byte, olderLen)
+	}
+
+	older = older[:olderLen]
+	r.spare = nil
+
+	n := subtle.XORBytes(older, xor[:olderLen], newer)
+	copy(older[n:], xor[n:])
+
+	return older, nil
+}
+
+func (r *Rewind) xorBuf(n int) []byte {
+	if cap(r.xor) < n {
+		r.xor = make([]byte, n)
+	}
+
+	return r.xor[:n]
+}
@@ -0,0 +1,457 @@
+// state serializes emulator structs for save states.
+//
+// Values are walked with reflection and written little endian. Pointers,
+// interfaces, funcs, chans and uintptrs are skipped, so a state is always
+// loaded into an already constructed console and its wiring is left alone.
+// Anything a pointer refers to has to be encoded explicitly by the owner.
+// Fields tagged `state:"-"` are skipped as well.
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+	"unsafe"
+)
+
+// written in place of a length for nil slices and maps
+const nilLen = math.MaxUint32
+
+type Encoder struct {
+	w   *bufio.Writer
+	err error
+	buf [8]byte
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriterSize(w, 1<<16)}
+}
+
+// Encode writes each value in order, every value has to be a non nil pointer.
+func (e *Encoder) Encode(vs ...any) {
+	for _, v := range vs {
+		if e.err != nil {
+			return
+		}
+
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			e.err = fmt.Errorf("state: cannot encode %T, expected non nil pointer", v)
+			return
+		}
+
+		e.value(rv.Elem())
+	}
+}
+
+// Chunk starts a named section. Decoding checks the name, so a layout
+// mismatch is reported at the section it happened in.
+func (e *Encoder) Chunk(name string) {
+	e.u32(uint32(len(name)))
+	e.write([]byte(name))
+}
+
+// Flush writes any buffered data and returns the first error encountered.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	e.err = e.w.Flush()
+	return e.err
+}
+
+func (e *Encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+
+	_, e.err = e.w.Write(b)
+}
+
+func (e *Encoder) u32(v uint32) {
+	binary.LittleEndian.PutUint32(e.buf[:], v)
+	e.write(e.buf[:4])
+}
+
+func (e *Encoder) u64(v uint64) {
+	binary.LittleEndian.PutUint64(e.buf[:], v)
+	e.write(e.buf[:8])
+}
+
+func (e *Encoder) value(v reflect.Value) {
+	t := v.Type()
+
+	if skipped(t) {
+		return
+	}
+
+	if n := plainSize(t); n >= 0 {
+		e.write(unsafe.Slice((*byte)(unsafe.Pointer(v.UnsafeAddr())), n))
+		return
+	}
+
+	// values read from unexported fields cannot be copied through reflect
+	if !v.CanInterface() {
+		v = reflect.NewAt(t, unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Int:
+		e.u64(uint64(v.Int()))
+
+	case reflect.Uint:
+		e.u64(v.Uint())
+
+	case reflect.String:
+		e.u32(uint32(v.Len()))
+		e.write([]byte(v.String()))
+
+	case reflect.Array:
+		for i := range v.Len() {
+			e.value(v.Index(i))
+		}
+
+	case reflect.Slice:
+		if v.IsNil() {
+			e.u32(nilLen)
+			return
+		}
+
+		e.u32(uint32(v.Len()))
+
+		if n := plainSize(t.Elem()); n >= 0 {
+			e.write(unsafe.Slice((*byte)(v.UnsafePointer()), n*v.Len()))
+			return
+		}
+
+		for i := range v.Len() {
+			e.value(v.Index(i))
+		}
+
+	case reflect.Struct:
+		for _, i := range fields(t) {
+			e.value(v.Field(i))
+		}
+
+	case reflect.Map:
+		e.mapValue(v)
+	}
+}
+
+// map entries are sorted by their encoded key so equal maps always produce
+// equal bytes, map iteration order is random
+func (e *Encoder) mapValue(v reflect.Value) {
+	if v.IsNil() {
+		e.u32(nilLen)
+		return
+	}
+
+	type entry struct {
+		key []byte
+		val reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+
+	iter := v.MapRange()
+	for iter.Next() {
+		var buf bytes.Buffer
+		ke := NewEncoder(&buf)
+
+		key := reflect.New(v.Type().Key()).Elem()
+		key.Set(iter.Key())
+		ke.value(key)
+
+		if err := ke.Flush(); err != nil {
+			e.err = err
+			return
+		}
+
+		val := reflect.New(v.Type().Elem()).Elem()
+		val.Set(iter.Value())
+
+		entries = append(entries, entry{buf.Bytes(), val})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+
+	e.u32(uint32(len(entries)))
+
+	for _, entry := range entries {
+		e.write(entry.key)
+		e.value(entry.val)
+	}
+}
+
+type Decoder struct {
+	r   *bufio.Reader
+	err error
+	buf [8]byte
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, 1<<16)}
+}
+
+// Decode reads into each value in order, every value has to be a non nil
+// pointer. Values must be decoded in the order they were encoded.
+func (d *Decoder) Decode(vs ...any) {
+	for _, v := range vs {
+		if d.err != nil {
+			return
+		}
+
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			d.err = fmt.Errorf("state: cannot decode %T, expected non nil pointer", v)
+			return
+		}
+
+		d.value(rv.Elem())
+	}
+}
+
+// Chunk reads the start of a section written by Encoder.Chunk.
+func (d *Decoder) Chunk(name string) {
+	n := d.u32()
+	if d.err != nil {
+		return
+	}
+
+	if n > 0xFF {
+		d.err = fmt.Errorf("state: expected chunk %q, found corrupt data", name)
+		return
+	}
+
+	b := make([]byte, n)
+	d.read(b)
+
+	if d.err == nil && string(b) != name {
+		d.err = fmt.Errorf("state: expected chunk %q, found %q", name, b)
+	}
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		d.err = fmt.Errorf("state: %w", err)
+	}
+}
+
+func (d *Decoder) u32() uint32 {
+	d.read(d.buf[:4])
+	return binary.LittleEndian.Uint32(d.buf[:])
+}
+
+func (d *Decoder) u64() uint64 {
+	d.read(d.buf[:8])
+	return binary.LittleEndian.Uint64(d.buf[:])
+}
+
+func (d *Decoder) value(v reflect.Value) {
+	if d.err != nil {
+		return
+	}
+
+	t := v.Type()
+
+	if skipped(t) {
+		return
+	}
+
+	if n := plainSize(t); n >= 0 {
+		d.read(unsafe.Slice((*byte)(unsafe.Pointer(v.UnsafeAddr())), n))
+		return
+	}
+
+	// unexported fields cannot be set through reflect
+	if !v.CanSet() {
+		v = reflect.NewAt(t, unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Int:
+		v.SetInt(int64(d.u64()))
+
+	case reflect.Uint:
+		v.SetUint(d.u64())
+
+	case reflect.String:
+		n := d.u32()
+		if d.err != nil {
+			return
+		}
+
+		b := make([]byte, n)
+		d.read(b)
+		v.SetString(string(b))
+
+	case reflect.Array:
+		for i := range v.Len() {
+			d.value(v.Index(i))
+		}
+
+	case reflect.Slice:
+		n := d.u32()
+
+		switch {
+		case d.err != nil:
+			return
+		case n == nilLen:
+			v.SetZero()
+			return
+		case v.IsNil() || v.Len() != int(n):
+			// only reuse backing arrays of matching length, framebuffers and
+			// saves keep their address while anything resized is replaced
+			v.Set(reflect.MakeSlice(t, int(n), int(n)))
+		}
+
+		if size := plainSize(t.Elem()); size >= 0 {
+			if n != 0 {
+				d.read(unsafe.Slice((*byte)(v.UnsafePointer()), size*int(n)))
+			}
+			return
+		}
+
+		for i := range int(n) {
+			d.value(v.Index(i))
+		}
+
+	case reflect.Struct:
+		for _, i := range fields(t) {
+			d.value(v.Field(i))
+		}
+
+	case reflect.Map:
+		n := d.u32()
+
+		switch {
+		case d.err != nil:
+			return
+		case n == nilLen:
+			v.SetZero()
+			return
+		}
+
+		m := reflect.MakeMapWithSize(t, int(n))
+
+		for range n {
+			key := reflect.New(t.Key()).Elem()
+			val := reflect.New(t.Elem()).Elem()
+
+			d.value(key)
+			d.value(val)
+
+			if d.err != nil {
+				return
+			}
+
+			m.SetMapIndex(key, val)
+		}
+
+		v.Set(m)
+	}
+}
+
+type typeInfo struct {
+	skipped bool
+	size    int
+	fields  []int
+}
+
+var infos sync.Map
+
+func info(t reflect.Type) *typeInfo {
+	if i, ok := infos.Load(t); ok {
+		return i.(*typeInfo)
+	}
+
+	i := &typeInfo{size: -1}
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Uintptr,
+		reflect.Interface, reflect.Func, reflect.Chan:
+		i.skipped = true
+
+	case reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		i.size = int(t.Size())
+
+	case reflect.Array:
+		elem := info(t.Elem())
+		i.skipped = elem.skipped
+		if elem.size >= 0 {
+			i.size = elem.size * t.Len()
+		}
+
+	case reflect.Slice:
+		i.skipped = info(t.Elem()).skipped
+
+	case reflect.Map:
+		i.skipped = info(t.Key()).skipped || info(t.Elem()).skipped
+
+	case reflect.Struct:
+		sum, plain := 0, true
+
+		for f := range t.NumField() {
+			field := t.Field(f)
+
+			if field.Name == "_" || field.Tag.Get("state") == "-" {
+				plain = false
+				continue
+			}
+
+			fi := info(field.Type)
+			if fi.skipped {
+				plain = false
+				continue
+			}
+
+			if fi.size < 0 {
+				plain = false
+			}
+
+			sum += fi.size
+			i.fields = append(i.fields, f)
+		}
+
+		// padding would be written as is, walk the fields instead
+		if plain && uintptr(sum) == t.Size() {
+			i.size = sum
+		}
+	}
+
+	infos.Store(t, i)
+	return i
+}
+
+func skipped(t reflect.Type) bool { return info(t).skipped }
+
+// plainSize is the byte size of types that can be copied straight from
+// memory, -1 otherwise
+func plainSize(t reflect.Type) int { return info(t).size }
+
+func fields(t reflect.Type) []int { return info(t).fields }
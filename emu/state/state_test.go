@@ -0,0 +1,226 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type inner struct {
+	A uint8
+	b uint32
+}
+
+type outer struct {
+	Plain  [4]uint16
+	In     inner
+	Ins    []inner
+	Bytes  []uint8
+	Nil    []uint8
+	Map    map[uint32]int
+	Str    string
+	N      int
+	Ptr    *int
+	Skip   uint32 `state:"-"`
+	hidden bool
+}
+
+func TestRoundTrip(t *testing.T) {
+	v := 7
+	src := outer{
+		Plain:  [4]uint16{1, 2, 3, 4},
+		In:     inner{5, 6},
+		Ins:    []inner{{7, 8}, {9, 10}},
+		Bytes:  []uint8{11, 12, 13},
+		Map:    map[uint32]int{1: -1, 2: -2, 3: -3},
+		Str:    "guac",
+		N:      -14,
+		Ptr:    &v,
+		Skip:   15,
+		hidden: true,
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.Encode(&src)
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := outer{Nil: []uint8{1}}
+	d := NewDecoder(bytes.NewReader(buf.Bytes()))
+	d.Decode(&dst)
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	switch {
+	case dst.Plain != src.Plain, dst.In != src.In, dst.Str != src.Str,
+		dst.N != src.N, dst.hidden != src.hidden:
+		t.Fatalf("fields differ: got %+v, want %+v", dst, src)
+	case len(dst.Ins) != 2 || dst.Ins[1] != src.Ins[1]:
+		t.Fatalf("slice differs: got %v, want %v", dst.Ins, src.Ins)
+	case !bytes.Equal(dst.Bytes, src.Bytes) || dst.Nil != nil:
+		t.Fatalf("bytes differ: got %v %v", dst.Bytes, dst.Nil)
+	case len(dst.Map) != 3 || dst.Map[2] != -2:
+		t.Fatalf("map differs: got %v", dst.Map)
+	case dst.Ptr != nil || dst.Skip != 0:
+		t.Fatalf("skipped fields were written: %+v", dst)
+	}
+}
+
+func TestMapOrder(t *testing.T) {
+	m := map[uint32]uint32{}
+	for i := range uint32(64) {
+		m[i] = i
+	}
+
+	var a, b bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&a, &b} {
+		e := NewEncoder(buf)
+		e.Encode(&m)
+		e.Flush()
+	}
+
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatal("map encoding is not deterministic")
+	}
+}
+
+func TestShortRead(t *testing.T) {
+	var v [8]uint32
+	d := NewDecoder(bytes.NewReader([]byte{1, 2, 3}))
+	d.Decode(&v)
+
+	if d.Err() == nil {
+		t.Fatal("expected error on truncated state")
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h := Header{Console: GBA, Checksum: Checksum([]byte("rom")), Title: "TITLE", Frame: 60}
+
+	var buf bytes.Buffer
+	err := Save(&buf, h, func(e *Encoder) {
+		v := uint32(0xDEADBEEF)
+		e.Chunk("test")
+		e.Encode(&v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Version != Version || got.Console != h.Console ||
+		got.Checksum != h.Checksum || got.Title != h.Title || got.Frame != h.Frame {
+		t.Fatalf("header differs: got %+v, want %+v", got, h)
+	}
+
+	var v uint32
+	_, err = Load(bytes.NewReader(buf.Bytes()), h, func(d *Decoder) {
+		d.Chunk("test")
+		d.Decode(&v)
+	})
+	if err != nil || v != 0xDEADBEEF {
+		t.Fatalf("load failed: %v %08X", err, v)
+	}
+
+	_, err = Load(bytes.NewReader(buf.Bytes()), h, func(d *Decoder) {
+		d.Chunk("other")
+	})
+	if err == nil {
+		t.Fatal("expected chunk mismatch")
+	}
+
+	wrong := h
+	wrong.Checksum = Checksum([]byte("other rom"))
+	_, err = Load(bytes.NewReader(buf.Bytes()), wrong, func(d *Decoder) {
+		t.Fatal("state from another rom was decoded")
+	})
+	if !errors.Is(err, ErrRom) {
+		t.Fatalf("expected ErrRom, got %v", err)
+	}
+
+	wrong = h
+	wrong.Console = NDS
+	if _, err = Load(bytes.NewReader(buf.Bytes()), wrong, nil); !errors.Is(err, ErrConsole) {
+		t.Fatalf("expected ErrConsole, got %v", err)
+	}
+
+	if _, err = ReadHeader(bytes.NewReader([]byte("not a state at all"))); !errors.Is(err, ErrFormat) {
+		t.Fatalf("expected ErrFormat, got %v", err)
+	}
+}
+
+func TestRewind(t *testing.T) {
+	type console struct {
+		Frame uint32
+		Ram   [0x100]uint8
+		Var   []uint8
+	}
+
+	var c console
+
+	save := func(e *Encoder) { e.Encode(&c) }
+	load := func(d *Decoder) { d.Decode(&c) }
+
+	r := NewRewind(1<<20, 2)
+
+	for frame := range uint32(10) {
+		c.Frame = frame
+		c.Ram[frame] = uint8(frame)
+		c.Var = make([]uint8, frame%3)
+
+		if err := r.Capture(save); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// snapshots are taken on the 2nd, 4th... capture
+	if r.Len() != 5 {
+		t.Fatalf("got %d snapshots, want 5", r.Len())
+	}
+
+	for want := 9; want >= 1; want -= 2 {
+		more, err := r.Step(load)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case c.Frame != uint32(want), c.Ram[want] != uint8(want),
+			len(c.Var) != want%3:
+			t.Fatalf("got frame %d, want %d", c.Frame, want)
+		case more != (want != 1):
+			t.Fatalf("frame %d: more = %v", want, more)
+		}
+	}
+
+	// oldest is kept
+	if more, _ := r.Step(load); more || c.Frame != 1 {
+		t.Fatalf("oldest snapshot not kept, frame %d", c.Frame)
+	}
+}
+
+func TestRewindBudget(t *testing.T) {
+	var ram [0x1000]uint8
+
+	save := func(e *Encoder) { e.Encode(&ram) }
+
+	r := NewRewind(len(ram)+0x100, 1)
+
+	for i := range 100 {
+		ram[i] = uint8(i)
+		if err := r.Capture(save); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if r.Len() == 100 || r.size+len(r.cur) > r.Budget {
+		t.Fatalf("budget not enforced, %d snapshots using %d bytes", r.Len(), r.size+len(r.cur))
+	}
+}
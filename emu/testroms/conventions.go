@@ -0,0 +1,230 @@
+package testroms
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+// MOONEYE_DONE is LD B, B, executed by mooneye tests once they are finished
+const MOONEYE_DONE = 0x40
+
+// Mooneye checks the registers at MOONEYE_DONE, a passing test leaves the
+// fibonacci numbers in b, c, d, e, h and l
+func Mooneye(b, c, d, e, h, l uint8) bool {
+	return b == 3 && c == 5 && d == 8 && e == 13 && h == 21 && l == 34
+}
+
+// Serial collects text blargg tests print through the link port
+type Serial struct {
+	text strings.Builder
+}
+
+func (s *Serial) Write(v uint8) {
+	s.text.WriteByte(v)
+}
+
+func (s *Serial) Text() string {
+	return s.text.String()
+}
+
+// Done reports if the printed text has a final result
+func (s *Serial) Done() (done, passed bool) {
+	text := s.text.String()
+
+	switch {
+	case strings.Contains(text, "Passed"):
+		return true, true
+	case strings.Contains(text, "Failed"):
+		return true, false
+	}
+
+	return false, false
+}
+
+// Blargg reads the result newer blargg tests write to cart ram. $A001-$A003
+// hold a signature, $A000 is 0x80 while running and the result code after.
+// The printed text starts at $A004.
+func Blargg(read func(addr uint16) uint8) (done, passed bool, text string) {
+	if read(0xA001) != 0xDE || read(0xA002) != 0xB0 || read(0xA003) != 0x61 {
+		return false, false, ""
+	}
+
+	var b strings.Builder
+	for addr := uint16(0xA004); addr < 0xC000; addr++ {
+		v := read(addr)
+		if v == 0 {
+			break
+		}
+
+		b.WriteByte(v)
+	}
+
+	switch status := read(0xA000); status {
+	case 0x80:
+		return false, false, b.String()
+	default:
+		return true, status == 0, b.String()
+	}
+}
+
+// Jsmolka checks a finished jsmolka gba-tests rom, which leaves the number of
+// the failed test in r12, or zero if all passed
+func Jsmolka(r12 uint32) Result {
+	if r12 != 0 {
+		return Result{Detail: fmt.Sprintf("failed test %d", r12)}
+	}
+
+	return Result{Passed: true}
+}
+
+// Idle detects a rom sitting in a branch to self loop, which is how most
+// suites finish. Sample is called once per frame with the program counter.
+type Idle struct {
+	pc     uint32
+	frames int
+}
+
+func (i *Idle) Sample(pc uint32, frames int) bool {
+	if pc != i.pc {
+		i.pc = pc
+		i.frames = 0
+		return false
+	}
+
+	i.frames++
+	return i.frames >= frames
+}
+
+// Reference compares a screenshot against the png beside the rom, used by
+// suites which only show results on screen, like armwrestler and the mGBA
+// suite. If the reference is missing or differs the screenshot is written as
+// <rom>.actual.png so it can be checked and copied beside the rom, see
+// writeActual.
+func Reference(t testing.TB, rom string, img *image.RGBA) Result {
+	t.Helper()
+
+	name := strings.TrimSuffix(rom, filepath.Ext(rom))
+
+	f, err := os.Open(name + ".png")
+	if err != nil {
+		return Result{Detail: "no reference image, " + writeActual(t, name, img)}
+	}
+
+	defer f.Close()
+
+	want, err := png.Decode(f)
+	if err != nil {
+		return Result{Detail: fmt.Sprintf("reference: %v", err)}
+	}
+
+	if want.Bounds() != img.Bounds() {
+		return Result{Detail: fmt.Sprintf("reference is %v, screen is %v", want.Bounds(), img.Bounds())}
+	}
+
+	diff := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r0, g0, b0, _ := want.At(x, y).RGBA()
+			r1, g1, b1, _ := img.At(x, y).RGBA()
+			if r0 != r1 || g0 != g1 || b0 != b1 {
+				diff++
+			}
+		}
+	}
+
+	if diff != 0 {
+		return Result{Detail: fmt.Sprintf("%d pixels differ, %s", diff, writeActual(t, name, img))}
+	}
+
+	return Result{Passed: true}
+}
+
+// Sidecar is an optional <rom>.toml beside a rom, for roms which have to be
+// driven through a menu before showing results. It uses the same layout as
+// headless scripts.
+//
+//	frames = 900
+//
+//	[[input]]
+//	frame   = 60
+//	buttons = "a"
+//	hold    = 2
+type Sidecar struct {
+	Frames int `toml:"frames"`
+	Inputs []struct {
+		Frame   int    `toml:"frame"`
+		Buttons string `toml:"buttons"`
+		Hold    int    `toml:"hold"`
+	} `toml:"input"`
+}
+
+// LoadSidecar reads the sidecar of rom, frames is used if there is none or it
+// does not set frames
+func LoadSidecar(rom string, frames int) (*Sidecar, error) {
+	s := &Sidecar{}
+
+	path := strings.TrimSuffix(rom, filepath.Ext(rom)) + ".toml"
+	if _, err := os.Stat(path); err == nil {
+		if _, err := toml.DecodeFile(path, s); err != nil {
+			return nil, fmt.Errorf("sidecar %s: %w", path, err)
+		}
+	}
+
+	if s.Frames == 0 {
+		s.Frames = frames
+	}
+
+	return s, nil
+}
+
+// Buttons returns the names of the buttons held in frame joined by "+",
+// frames are counted from 1
+func (s *Sidecar) Buttons(frame int) string {
+	var held []string
+	for _, in := range s.Inputs {
+		if frame >= in.Frame && frame < in.Frame+max(1, in.Hold) {
+			held = append(held, in.Buttons)
+		}
+	}
+
+	return strings.Join(held, "+")
+}
+
+// writeActual writes the screenshot of a failed reference to GUAC_TEST_RESULTS,
+// or a temp dir of the test, the suite directory is left alone. It returns
+// the detail for the result.
+func writeActual(t testing.TB, name string, img *image.RGBA) string {
+	dir := os.Getenv(ENV_RESULTS)
+	if dir == "" {
+		dir = t.TempDir()
+	}
+
+	actual := filepath.Join(dir, filepath.Base(name)+".actual.png")
+	if err := writePng(actual, img); err != nil {
+		return fmt.Sprintf("writing the screen: %v", err)
+	}
+
+	return "wrote " + actual
+}
+
+func writePng(path string, img *image.RGBA) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,153 @@
+// testroms is the shared harness for running third party test rom suites.
+//
+// Suites are not part of the repo. They are looked up in the directory named
+// by GUAC_TEST_ROMS, falling back to the testdata directory of the package
+// under test, and tests skip when a suite is missing. Each suite is a
+// directory under the root, e.g.
+//
+//	$GUAC_TEST_ROMS/mooneye-test-suite
+//	$GUAC_TEST_ROMS/blargg
+//	$GUAC_TEST_ROMS/gba-tests
+//
+// A results table is logged for every suite, and written as markdown to the
+// directory named by GUAC_TEST_RESULTS if it is set. Screens which fail their
+// reference image are written there too.
+package testroms
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	ENV_ROMS    = "GUAC_TEST_ROMS"
+	ENV_RESULTS = "GUAC_TEST_RESULTS"
+)
+
+type Result struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Dir returns the directory of suite, the test is skipped if it is missing
+func Dir(t testing.TB, suite string) string {
+	t.Helper()
+
+	root := os.Getenv(ENV_ROMS)
+	if root == "" {
+		root = "testdata"
+	}
+
+	dir := filepath.Join(root, suite)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Skipf("suite %s not found, set %s", dir, ENV_ROMS)
+	}
+
+	return dir
+}
+
+// Roms returns every file under dir with one of exts, sorted. The test is
+// skipped if there are none.
+func Roms(t testing.TB, dir string, exts ...string) []string {
+	t.Helper()
+
+	var roms []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && slices.Contains(exts, strings.ToLower(filepath.Ext(path))) {
+			roms = append(roms, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(roms) == 0 {
+		t.Skipf("no %s roms in %s", strings.Join(exts, ", "), dir)
+	}
+
+	slices.Sort(roms)
+
+	return roms
+}
+
+// Run runs every rom in the suite directory as a subtest. A rom fails its
+// subtest if run returns a failed result.
+func Run(t *testing.T, title, suite string, exts []string, run func(t *testing.T, rom string) Result) {
+	dir := Dir(t, suite)
+
+	var results []Result
+
+	for _, rom := range Roms(t, dir, exts...) {
+		name, _ := filepath.Rel(dir, rom)
+		name = filepath.ToSlash(strings.TrimSuffix(name, filepath.Ext(name)))
+
+		t.Run(name, func(t *testing.T) {
+			r := run(t, rom)
+			r.Name = name
+
+			results = append(results, r)
+
+			if !r.Passed {
+				t.Errorf("failed %s %s", name, r.Detail)
+			}
+		})
+	}
+
+	table := Table(title, results)
+	t.Log("\n" + table)
+
+	if out := os.Getenv(ENV_RESULTS); out != "" {
+		file := strings.ReplaceAll(strings.ToLower(suite), string(filepath.Separator), "_") + ".md"
+
+		if err := os.WriteFile(filepath.Join(out, file), []byte(table), 0o644); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// Table formats results as a markdown table
+func Table(title string, results []Result) string {
+	passed := 0
+	for _, r := range results {
+		if r.Passed {
+			passed++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "Results generated %s\n\n", time.Now().Format(time.RFC3339))
+
+	if len(results) != 0 {
+		fmt.Fprintf(&b, "Passing %d/%d %02d%%\n\n", passed, len(results), (passed*100)/len(results))
+	}
+
+	fmt.Fprintf(&b, "| rom | result | detail |\n")
+	fmt.Fprintf(&b, "| --- | --- | --- |\n")
+
+	for _, r := range results {
+		result := "❌"
+		if r.Passed {
+			result = "👍"
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", r.Name, result, strings.ReplaceAll(r.Detail, "\n", " "))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package testroms
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSerial(t *testing.T) {
+	var s Serial
+	for _, c := range []byte("cpu_instrs\n\n01:ok\n\nPassed all tests\n") {
+		s.Write(c)
+	}
+
+	if done, passed := s.Done(); !done || !passed {
+		t.Fatalf("done %v passed %v, text %q", done, passed, s.Text())
+	}
+}
+
+func TestBlargg(t *testing.T) {
+	ram := map[uint16]uint8{0xA000: 0x80, 0xA001: 0xDE, 0xA002: 0xB0, 0xA003: 0x61}
+	for i, c := range []byte("ok") {
+		ram[0xA004+uint16(i)] = c
+	}
+
+	read := func(addr uint16) uint8 { return ram[addr] }
+
+	if done, _, _ := Blargg(read); done {
+		t.Fatal("done while running")
+	}
+
+	ram[0xA000] = 0
+	if done, passed, text := Blargg(read); !done || !passed || text != "ok" {
+		t.Fatalf("done %v passed %v text %q", done, passed, text)
+	}
+}
+
+func TestReference(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	t.Setenv(ENV_RESULTS, out)
+
+	rom := filepath.Join(dir, "armwrestler.gba")
+	actual := filepath.Join(out, "armwrestler.actual.png")
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	if r := Reference(t, rom, img); r.Passed || !strings.Contains(r.Detail, actual) {
+		t.Fatalf("passed %v without a reference, %s", r.Passed, r.Detail)
+	}
+
+	// the written screenshot becomes the reference
+	if err := os.Rename(actual, filepath.Join(dir, "armwrestler.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := Reference(t, rom, img); !r.Passed {
+		t.Fatal(r.Detail)
+	}
+
+	img.Pix[0] = 0xFF
+	if r := Reference(t, rom, img); r.Passed || !strings.Contains(r.Detail, actual) {
+		t.Fatalf("passed %v with a different screen, %s", r.Passed, r.Detail)
+	}
+}
+
+func TestReferenceTemp(t *testing.T) {
+	t.Setenv(ENV_RESULTS, "")
+
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "suite.gba")
+
+	r := Reference(t, rom, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	actual, ok := strings.CutPrefix(r.Detail, "no reference image, wrote ")
+	if !ok {
+		t.Fatal(r.Detail)
+	}
+
+	if _, err := os.Stat(actual); err != nil {
+		t.Fatal(err)
+	}
+
+	// the suite is left as it was
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("wrote %s beside the rom", files[0].Name())
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "suite.gba")
+
+	os.WriteFile(filepath.Join(dir, "suite.toml"), []byte(`
+frames = 100
+
+[[input]]
+frame = 10
+buttons = "a"
+hold = 2
+
+[[input]]
+frame = 11
+buttons = "down"
+`), 0o644)
+
+	s, err := LoadSidecar(rom, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switch {
+	case s.Frames != 100:
+		t.Fatalf("frames %d", s.Frames)
+	case s.Buttons(9) != "", s.Buttons(10) != "a", s.Buttons(11) != "a+down", s.Buttons(12) != "":
+		t.Fatal("wrong buttons held")
+	}
+}
@@ -0,0 +1,129 @@
+// wav reads pcm wave files, 8 bit unsigned and 16 bit signed with any number
+// of channels. Channels are mixed down to mono. Writer writes 16 bit ones.
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const FORMAT_PCM = 1
+
+type Wav struct {
+	Rate    uint32
+	Samples []int16 // mono
+}
+
+func ReadFile(path string) (*Wav, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return Decode(f)
+}
+
+func Decode(r io.Reader) (*Wav, error) {
+	var riff [12]uint8
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, err
+	}
+
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return nil, errors.New("not a wave file")
+	}
+
+	var (
+		w        Wav
+		channels int
+		bits     int
+		fmtRead  bool
+	)
+
+	for {
+		var hdr [8]uint8
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, fmt.Errorf("no data chunk: %w", err)
+		}
+
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+
+		switch string(hdr[:4]) {
+		case "fmt ":
+			buf := make([]uint8, size)
+			if _, err := io.ReadFull(r, buf); err != nil || size < 16 {
+				return nil, fmt.Errorf("fmt chunk: %w", err)
+			}
+
+			if format := binary.LittleEndian.Uint16(buf); format != FORMAT_PCM {
+				return nil, fmt.Errorf("format %d is not pcm", format)
+			}
+
+			channels = int(binary.LittleEndian.Uint16(buf[2:]))
+			w.Rate = binary.LittleEndian.Uint32(buf[4:])
+			bits = int(binary.LittleEndian.Uint16(buf[14:]))
+
+			if channels == 0 || (bits != 8 && bits != 16) {
+				return nil, fmt.Errorf("%d channels of %d bit samples are not supported", channels, bits)
+			}
+
+			fmtRead = true
+
+		case "data":
+			if !fmtRead {
+				return nil, errors.New("data chunk before fmt chunk")
+			}
+
+			// a truncated data chunk keeps what was written
+			buf, err := io.ReadAll(io.LimitReader(r, size))
+			if err != nil {
+				return nil, fmt.Errorf("data chunk: %w", err)
+			}
+
+			w.Samples = mix(buf, channels, bits)
+			return &w, nil
+
+		default:
+			if _, err := io.CopyN(io.Discard, r, size); err != nil {
+				return nil, err
+			}
+		}
+
+		// chunks are word aligned
+		if size&1 != 0 {
+			io.CopyN(io.Discard, r, 1)
+		}
+	}
+}
+
+// mix averages the channels of each frame
+func mix(buf []uint8, channels, bits int) []int16 {
+	width := bits / 8
+	frame := width * channels
+
+	out := make([]int16, len(buf)/frame)
+
+	for i := range out {
+		sum := 0
+
+		for c := range channels {
+			off := i*frame + c*width
+
+			if bits == 8 {
+				sum += (int(buf[off]) - 0x80) << 8
+				continue
+			}
+
+			sum += int(int16(binary.LittleEndian.Uint16(buf[off:])))
+		}
+
+		out[i] = int16(sum / channels)
+	}
+
+	return out
+}
@@ -0,0 +1,98 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+func wave(channels, bits uint16, rate uint32, data []uint8, extra ...[]uint8) []uint8 {
+	var b bytes.Buffer
+
+	chunk := func(id string, body []uint8) {
+		b.WriteString(id)
+		binary.Write(&b, binary.LittleEndian, uint32(len(body)))
+		b.Write(body)
+		if len(body)&1 != 0 {
+			b.WriteByte(0)
+		}
+	}
+
+	f := make([]uint8, 16)
+	binary.LittleEndian.PutUint16(f, FORMAT_PCM)
+	binary.LittleEndian.PutUint16(f[2:], channels)
+	binary.LittleEndian.PutUint32(f[4:], rate)
+	binary.LittleEndian.PutUint16(f[14:], bits)
+
+	b.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	chunk("fmt ", f)
+	for _, e := range extra {
+		chunk("LIST", e)
+	}
+	chunk("data", data)
+
+	return b.Bytes()
+}
+
+func TestStereo16(t *testing.T) {
+	data := []uint8{}
+	for _, v := range []int16{100, 300, -1000, -2000} {
+		data = binary.LittleEndian.AppendUint16(data, uint16(v))
+	}
+
+	w, err := Decode(bytes.NewReader(wave(2, 16, 22050, data, []uint8{1, 2, 3})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Rate != 22050 || !slices.Equal(w.Samples, []int16{200, -1500}) {
+		t.Fatalf("rate %d, samples %v", w.Rate, w.Samples)
+	}
+}
+
+func TestMono8(t *testing.T) {
+	w, err := Decode(bytes.NewReader(wave(1, 8, 8000, []uint8{0x80, 0xFF, 0x00})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(w.Samples, []int16{0, 0x7F00, -0x8000}) {
+		t.Fatalf("samples %v", w.Samples)
+	}
+}
+
+func TestNotPcm(t *testing.T) {
+	b := wave(1, 16, 8000, nil)
+	b[20] = 3 // float
+
+	if _, err := Decode(bytes.NewReader(b)); err == nil {
+		t.Fatal("decoded a float wave")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	path := t.TempDir() + "/out.wav"
+
+	w, err := Create(path, 32768, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// written in pieces, as frames come
+	w.Write([]int16{100, 300})
+	w.Write([]int16{-1000, -2000})
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Rate != 32768 || !slices.Equal(got.Samples, []int16{200, -1500}) {
+		t.Fatalf("rate %d, samples %v", got.Rate, got.Samples)
+	}
+}
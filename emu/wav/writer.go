@@ -0,0 +1,107 @@
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+const HEADER_SIZE = 44
+
+// Writer writes 16 bit pcm as it comes. The sizes in the header are only
+// known at the end, Close fills them in.
+type Writer struct {
+	w        io.WriteSeeker
+	file     *os.File // closed with the writer when Create opened it
+	channels int
+	size     uint32 // data bytes
+}
+
+func Create(path string, rate uint32, channels int) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWriter(f, rate, channels)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	w.file = f
+
+	return w, nil
+}
+
+func NewWriter(w io.WriteSeeker, rate uint32, channels int) (*Writer, error) {
+	var h [HEADER_SIZE]uint8
+
+	copy(h[0:], "RIFF")
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], FORMAT_PCM)
+	binary.LittleEndian.PutUint16(h[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(h[24:], rate)
+	binary.LittleEndian.PutUint32(h[28:], rate*uint32(channels)*2)
+	binary.LittleEndian.PutUint16(h[32:], uint16(channels)*2)
+	binary.LittleEndian.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+
+	if _, err := w.Write(h[:]); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w, channels: channels}, nil
+}
+
+// Write takes samples interleaved by channel
+func (w *Writer) Write(samples []int16) error {
+	buf := make([]uint8, 0, len(samples)*2)
+	for _, s := range samples {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(s))
+	}
+
+	n, err := w.w.Write(buf)
+	w.size += uint32(n)
+
+	return err
+}
+
+// Close fills in the sizes, the file is closed too if Create opened it
+func (w *Writer) Close() error {
+	err := w.finish()
+
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func (w *Writer) finish() error {
+	var b [4]uint8
+
+	binary.LittleEndian.PutUint32(b[:], HEADER_SIZE-8+w.size)
+	if _, err := w.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := w.w.Write(b[:]); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(b[:], w.size)
+	if _, err := w.w.Seek(HEADER_SIZE-4, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := w.w.Write(b[:]); err != nil {
+		return err
+	}
+
+	_, err := w.w.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,245 @@
+// headless runs a rom without a window, driven by a Script. It is used for
+// regression runs of test roms, a run fails if a framebuffer hash does not
+// match the expected one.
+package headless
+
+import (
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/clock"
+	"github.com/aabalke/guac/emu/gb"
+	"github.com/aabalke/guac/emu/gba"
+	"github.com/aabalke/guac/emu/movie"
+	"github.com/aabalke/guac/emu/nds"
+	"github.com/aabalke/guac/emu/search"
+	"github.com/aabalke/guac/input"
+	"github.com/aabalke/guac/utils"
+)
+
+// candidates listed after a search, more are only counted
+const SEARCH_RESULTS = 16
+
+// exit codes
+const (
+	PASS     = 0
+	MISMATCH = 1
+	ERROR    = 2
+)
+
+type console interface {
+	Update(stdFps bool)
+	SetButtons(b input.Buttons)
+	Screenshot() *image.RGBA
+	RamRegions() []search.Region
+	RecordMovie() error
+	PlayMovie(m *movie.Movie) error
+	StopMovie() *movie.Movie
+	StartCapture(path string) error
+	StopCapture() (int, error)
+	Close()
+}
+
+func newConsole(path string) (console, error) {
+	switch romType := utils.GetRomType(path); romType {
+	case utils.GB:
+		return gb.NewGameBoy(path, nil), nil
+	case utils.GBA:
+		return gba.NewGBA(path, nil), nil
+	case utils.NDS:
+		return nds.NewNds(path, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported rom %s", path)
+	}
+}
+
+// Hash is the framebuffer hash compared against expects
+func Hash(img *image.RGBA) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(img.Pix))
+}
+
+// Run runs the script given in config and returns the exit code. Without a
+// frame count or events the rom runs forever.
+func Run() int {
+	s, err := LoadScript(&config.Conf.Headless, config.Conf.General.RomPath)
+	if err != nil {
+		log.Printf("Headless: %v\n", err)
+		return ERROR
+	}
+
+	var m *movie.Movie
+
+	if config.Conf.General.Deterministic {
+		clock.Seed(config.Conf.General.Epoch)
+	}
+
+	// guest clocks are seeded before power on, a run is then the same each
+	// time it is replayed
+	switch {
+	case s.Movie != "":
+		if m, err = movie.Load(s.Movie); err != nil {
+			log.Printf("Headless: %v\n", err)
+			return ERROR
+		}
+
+		clock.Seed(m.Seed())
+
+		if s.runAll {
+			s.Frames = max(s.Frames, len(m.Frames))
+		}
+
+	case s.Record != "":
+		clock.Seed(clock.Now())
+	}
+
+	c, err := newConsole(s.Rom)
+	if err != nil {
+		log.Printf("Headless: %v\n", err)
+		return ERROR
+	}
+
+	defer c.Close()
+
+	switch {
+	case m != nil:
+		err = c.PlayMovie(m)
+	case s.Record != "":
+		err = c.RecordMovie()
+	}
+
+	if err != nil {
+		log.Printf("Headless: %v\n", err)
+		return ERROR
+	}
+
+	if s.Capture != "" {
+		if err := c.StartCapture(s.Capture); err != nil {
+			log.Printf("Headless: %v\n", err)
+			return ERROR
+		}
+	}
+
+	if s.ScreenshotDir != "" {
+		if err := os.MkdirAll(s.ScreenshotDir, 0755); err != nil {
+			log.Printf("Headless: %v\n", err)
+			return ERROR
+		}
+	}
+
+	code := PASS
+
+	var ram *search.Search
+	if len(s.Searches) != 0 {
+		ram = search.New(c.RamRegions(), search.Options{
+			Width:     s.SearchWidth,
+			Signed:    s.SearchSigned,
+			Unaligned: utils.GetRomType(s.Rom) == utils.GB,
+		})
+	}
+
+	for frame := 1; s.Frames == 0 || frame <= s.Frames; frame++ {
+		c.SetButtons(s.buttons(frame))
+		c.Update(false)
+
+		var img *image.RGBA
+
+		for _, sc := range s.Screenshots {
+			if sc.Frame != frame {
+				continue
+			}
+
+			if img == nil {
+				img = c.Screenshot()
+			}
+
+			if err := writePng(filepath.Join(s.ScreenshotDir, sc.File), img); err != nil {
+				log.Printf("Headless: %v\n", err)
+				return ERROR
+			}
+		}
+
+		for _, e := range s.Expects {
+			if e.Frame != frame {
+				continue
+			}
+
+			if img == nil {
+				img = c.Screenshot()
+			}
+
+			switch hash := Hash(img); {
+			case e.Hash == "":
+				fmt.Printf("frame %d: hash %s\n", frame, hash)
+			case e.Hash == hash:
+				fmt.Printf("frame %d: hash %s ok\n", frame, hash)
+			default:
+				fmt.Printf("frame %d: hash %s, expected %s MISMATCH\n", frame, hash, e.Hash)
+				code = MISMATCH
+			}
+		}
+
+		for _, sr := range s.Searches {
+			if sr.Frame == frame {
+				runSearch(ram, frame, sr)
+			}
+		}
+	}
+
+	if s.Record != "" {
+		if err := c.StopMovie().Save(s.Record); err != nil {
+			log.Printf("Headless: %v\n", err)
+			return ERROR
+		}
+	}
+
+	if s.Capture != "" {
+		n, err := c.StopCapture()
+		if err != nil {
+			log.Printf("Headless: %v\n", err)
+			return ERROR
+		}
+
+		fmt.Printf("captured %d frames to %s\n", n, s.Capture)
+	}
+
+	return code
+}
+
+func runSearch(ram *search.Search, frame int, sr Search) {
+	if sr.reset {
+		ram.Reset()
+		fmt.Printf("frame %d: search new, %d left\n", frame, ram.Count())
+		return
+	}
+
+	n := ram.Filter(sr.op, sr.Value)
+	fmt.Printf("frame %d: search %s %d, %d left\n", frame, sr.op, sr.Value, n)
+
+	if n > SEARCH_RESULTS {
+		return
+	}
+
+	for _, r := range ram.Results(0) {
+		fmt.Printf("\t%s %08X = %d (was %d)\n", r.Region, r.Addr, r.Value, r.Prev)
+	}
+}
+
+func writePng(path string, img *image.RGBA) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
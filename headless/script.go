@@ -0,0 +1,323 @@
+package headless
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/search"
+	"github.com/aabalke/guac/input"
+)
+
+// Script is what a headless run does. Frames are counted from 1, inputs are
+// held during their frame, screenshots and hashes are taken once it is done.
+//
+//	rom    = "test.gba" # relative to the script
+//	frames = 600        # 0 runs until the last event
+//	screenshot_dir = "out"
+//
+// A movie replaces the inputs with the ones it recorded, frames default to
+// its length. Record saves the inputs of the run as a movie.
+//
+//	movie  = "bug.gmv"
+//	record = "run.gmv"
+//
+// Capture writes the picture and sound of every frame, to a y4m with a wav
+// beside it or to a directory of pngs and audio.wav.
+//
+//	capture = "run.y4m"
+//
+//	[[input]]
+//	frame   = 60
+//	buttons = "a+start"
+//	hold    = 2
+//
+//	[[screenshot]]
+//	frame = 300
+//	file  = "title.png"
+//
+//	[[expect]]
+//	frame = 600
+//	hash  = "9f0c2a41"
+//
+// Ram searches start before frame 1 and filter once their frame is done, op
+// "new" starts again. See emu/search for the ops.
+//
+//	search_width  = 2
+//	search_signed = false
+//
+//	[[search]]
+//	frame = 300
+//	op    = "dec"
+//
+//	[[search]]
+//	frame = 400
+//	op    = "eq"
+//	value = 2
+type Script struct {
+	Rom           string       `toml:"rom"`
+	Frames        int          `toml:"frames"`
+	ScreenshotDir string       `toml:"screenshot_dir"`
+	Inputs        []Input      `toml:"input"`
+	Screenshots   []Screenshot `toml:"screenshot"`
+	Expects       []Expect     `toml:"expect"`
+	SearchWidth   int          `toml:"search_width"`
+	SearchSigned  bool         `toml:"search_signed"`
+	Searches      []Search     `toml:"search"`
+	Movie         string       `toml:"movie"`
+	Record        string       `toml:"record"`
+	Capture       string       `toml:"capture"`
+
+	runAll bool // frames were not given, a movie runs to its end
+}
+
+type Input struct {
+	Frame   int    `toml:"frame"`
+	Buttons string `toml:"buttons"`
+	Hold    int    `toml:"hold"`
+
+	buttons input.Buttons
+}
+
+type Screenshot struct {
+	Frame int    `toml:"frame"`
+	File  string `toml:"file"`
+}
+
+type Expect struct {
+	Frame int    `toml:"frame"`
+	Hash  string `toml:"hash"`
+}
+
+type Search struct {
+	Frame int    `toml:"frame"`
+	Op    string `toml:"op"`
+	Value int64  `toml:"value"`
+
+	op    search.Op
+	reset bool
+}
+
+// LoadScript builds the script from config, a toml script is read first and
+// events given as flags are added to it
+func LoadScript(c *config.Headless, romPath string) (*Script, error) {
+	s := &Script{}
+
+	if c.ScriptPath != "" {
+		if _, err := toml.DecodeFile(c.ScriptPath, s); err != nil {
+			return nil, fmt.Errorf("script %s: %w", c.ScriptPath, err)
+		}
+
+		dir := filepath.Dir(c.ScriptPath)
+		if s.Rom != "" && !filepath.IsAbs(s.Rom) {
+			s.Rom = filepath.Join(dir, s.Rom)
+		}
+		if s.ScreenshotDir != "" && !filepath.IsAbs(s.ScreenshotDir) {
+			s.ScreenshotDir = filepath.Join(dir, s.ScreenshotDir)
+		}
+		if s.Movie != "" && !filepath.IsAbs(s.Movie) {
+			s.Movie = filepath.Join(dir, s.Movie)
+		}
+		if s.Record != "" && !filepath.IsAbs(s.Record) {
+			s.Record = filepath.Join(dir, s.Record)
+		}
+		if s.Capture != "" && !filepath.IsAbs(s.Capture) {
+			s.Capture = filepath.Join(dir, s.Capture)
+		}
+	}
+
+	if s.Rom == "" {
+		s.Rom = romPath
+	}
+	if c.Frames != 0 {
+		s.Frames = c.Frames
+	}
+	if c.ScreenshotDir != "" {
+		s.ScreenshotDir = c.ScreenshotDir
+	}
+	if c.SearchWidth != 0 {
+		s.SearchWidth = c.SearchWidth
+	}
+	if c.SearchSigned {
+		s.SearchSigned = true
+	}
+	if c.Movie != "" {
+		s.Movie = c.Movie
+	}
+	if c.Record != "" {
+		s.Record = c.Record
+	}
+	if c.Capture != "" {
+		s.Capture = c.Capture
+	}
+
+	for _, v := range c.Inputs {
+		f := strings.Split(v, ":")
+		if len(f) < 2 || len(f) > 3 {
+			return nil, fmt.Errorf("input %q, expected frame:buttons[:hold]", v)
+		}
+
+		in := Input{Buttons: f[1]}
+
+		var err error
+		if in.Frame, err = strconv.Atoi(f[0]); err != nil {
+			return nil, fmt.Errorf("input %q: %w", v, err)
+		}
+
+		if len(f) == 3 {
+			if in.Hold, err = strconv.Atoi(f[2]); err != nil {
+				return nil, fmt.Errorf("input %q: %w", v, err)
+			}
+		}
+
+		s.Inputs = append(s.Inputs, in)
+	}
+
+	for _, v := range c.Screenshots {
+		frame, file, _ := strings.Cut(v, ":")
+
+		n, err := strconv.Atoi(frame)
+		if err != nil {
+			return nil, fmt.Errorf("screenshot %q: %w", v, err)
+		}
+
+		s.Screenshots = append(s.Screenshots, Screenshot{Frame: n, File: file})
+	}
+
+	for _, v := range c.Expects {
+		frame, hash, ok := strings.Cut(v, ":")
+		if !ok {
+			return nil, fmt.Errorf("expect %q, expected frame:hash", v)
+		}
+
+		n, err := strconv.Atoi(frame)
+		if err != nil {
+			return nil, fmt.Errorf("expect %q: %w", v, err)
+		}
+
+		s.Expects = append(s.Expects, Expect{Frame: n, Hash: hash})
+	}
+
+	for _, v := range c.Searches {
+		f := strings.Split(v, ":")
+		if len(f) < 2 || len(f) > 3 {
+			return nil, fmt.Errorf("search %q, expected frame:op[:value]", v)
+		}
+
+		sr := Search{Op: f[1]}
+
+		var err error
+		if sr.Frame, err = strconv.Atoi(f[0]); err != nil {
+			return nil, fmt.Errorf("search %q: %w", v, err)
+		}
+
+		if len(f) == 3 {
+			if sr.Value, err = search.ParseValue(f[2]); err != nil {
+				return nil, fmt.Errorf("search %q: %w", v, err)
+			}
+		}
+
+		s.Searches = append(s.Searches, sr)
+	}
+
+	return s, s.validate()
+}
+
+func (s *Script) validate() error {
+	if s.Rom == "" {
+		return fmt.Errorf("no rom given")
+	}
+
+	if _, err := os.Stat(s.Rom); err != nil {
+		return err
+	}
+
+	last := 0
+
+	for i := range s.Inputs {
+		in := &s.Inputs[i]
+
+		b, err := input.ParseButtons(in.Buttons)
+		if err != nil {
+			return fmt.Errorf("input at frame %d: %w", in.Frame, err)
+		}
+
+		in.buttons = b
+		in.Hold = max(1, in.Hold)
+		last = max(last, in.Frame+in.Hold-1)
+	}
+
+	for i := range s.Screenshots {
+		sc := &s.Screenshots[i]
+		if sc.File == "" {
+			sc.File = fmt.Sprintf("frame_%06d.png", sc.Frame)
+		}
+
+		last = max(last, sc.Frame)
+	}
+
+	for i := range s.Expects {
+		e := &s.Expects[i]
+		e.Hash = strings.ToLower(strings.TrimPrefix(e.Hash, "0x"))
+		last = max(last, e.Frame)
+	}
+
+	switch s.SearchWidth {
+	case 0:
+		s.SearchWidth = 1
+	case 1, 2, 4:
+	default:
+		return fmt.Errorf("search width %d, expected 1, 2 or 4", s.SearchWidth)
+	}
+
+	for i := range s.Searches {
+		sr := &s.Searches[i]
+
+		if sr.reset = strings.EqualFold(sr.Op, "new"); !sr.reset {
+			op, err := search.ParseOp(sr.Op)
+			if err != nil {
+				return fmt.Errorf("search at frame %d: %w", sr.Frame, err)
+			}
+
+			sr.op = op
+		}
+
+		last = max(last, sr.Frame)
+	}
+
+	if s.Movie != "" && s.Record != "" {
+		return fmt.Errorf("a movie cannot be played and recorded at once")
+	}
+
+	if s.Frames == 0 {
+		s.Frames = last
+		s.runAll = true
+	}
+
+	if s.Record != "" && s.Frames == 0 {
+		return fmt.Errorf("recording a movie needs a frame count")
+	}
+
+	if s.Capture != "" && s.Frames == 0 {
+		return fmt.Errorf("capturing needs a frame count")
+	}
+
+	return nil
+}
+
+// buttons held during frame
+func (s *Script) buttons(frame int) input.Buttons {
+	var b input.Buttons
+	for _, in := range s.Inputs {
+		if frame >= in.Frame && frame < in.Frame+in.Hold {
+			b |= in.buttons
+		}
+	}
+
+	return b
+}
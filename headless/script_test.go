@@ -0,0 +1,223 @@
+package headless
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/search"
+	"github.com/aabalke/guac/input"
+)
+
+// rom is an empty file for scripts to point at, validate only checks it is
+// there
+func rom(t *testing.T, dir string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "test.gba")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestInputFlags(t *testing.T) {
+	path := rom(t, t.TempDir())
+
+	tests := []struct {
+		flag    string
+		want    Input
+		invalid bool
+	}{
+		{flag: "60:a", want: Input{Frame: 60, Buttons: "a", Hold: 1, buttons: input.A}},
+		{flag: "10:a+start:3", want: Input{Frame: 10, Buttons: "a+start", Hold: 3, buttons: input.A | input.Start}},
+		{flag: "5:Up + B", want: Input{Frame: 5, Buttons: "Up + B", Hold: 1, buttons: input.Up | input.B}},
+		{flag: "60", invalid: true},
+		{flag: "60:a:2:1", invalid: true},
+		{flag: "x:a", invalid: true},
+		{flag: "60:a:x", invalid: true},
+		{flag: "60:turbo", invalid: true},
+	}
+
+	for _, tt := range tests {
+		s, err := LoadScript(&config.Headless{Inputs: []string{tt.flag}}, path)
+
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%q: no error", tt.flag)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.flag, err)
+			continue
+		}
+
+		if got := s.Inputs[0]; got != tt.want {
+			t.Errorf("%q: %+v, want %+v", tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestSearchFlags(t *testing.T) {
+	path := rom(t, t.TempDir())
+
+	tests := []struct {
+		flag    string
+		want    Search
+		invalid bool
+	}{
+		{flag: "300:dec", want: Search{Frame: 300, Op: "dec", op: search.DECREASED}},
+		{flag: "400:eq:2", want: Search{Frame: 400, Op: "eq", Value: 2, op: search.EQUAL}},
+		{flag: "400:by:-0x10", want: Search{Frame: 400, Op: "by", Value: -16, op: search.CHANGED_BY}},
+		{flag: "500:new", want: Search{Frame: 500, Op: "new", reset: true}},
+		{flag: "300", invalid: true},
+		{flag: "300:eq:1:2", invalid: true},
+		{flag: "x:dec", invalid: true},
+		{flag: "300:eq:x", invalid: true},
+		{flag: "300:less", invalid: true},
+	}
+
+	for _, tt := range tests {
+		s, err := LoadScript(&config.Headless{Searches: []string{tt.flag}}, path)
+
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%q: no error", tt.flag)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %v", tt.flag, err)
+			continue
+		}
+
+		if got := s.Searches[0]; got != tt.want {
+			t.Errorf("%q: %+v, want %+v", tt.flag, got, tt.want)
+		}
+	}
+
+	_, err := LoadScript(&config.Headless{Searches: []string{"300:less"}}, path)
+	if !errors.Is(err, search.ErrOp) {
+		t.Errorf("unknown op error %v", err)
+	}
+}
+
+func TestScriptPaths(t *testing.T) {
+	dir := t.TempDir()
+	rom(t, dir)
+
+	abs := filepath.Join(t.TempDir(), "run.gmv")
+
+	script := filepath.Join(dir, "run.toml")
+	err := os.WriteFile(script, []byte(`
+rom = "test.gba"
+frames = 10
+screenshot_dir = "out"
+capture = "run.y4m"
+record = "`+filepath.ToSlash(abs)+`"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadScript(&config.Headless{ScriptPath: script}, "other.gba")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// paths are relative to the script, absolute ones are kept
+	for _, p := range []struct{ got, want string }{
+		{s.Rom, filepath.Join(dir, "test.gba")},
+		{s.ScreenshotDir, filepath.Join(dir, "out")},
+		{s.Capture, filepath.Join(dir, "run.y4m")},
+		{s.Record, abs},
+	} {
+		if p.got != p.want {
+			t.Errorf("path %s, want %s", p.got, p.want)
+		}
+	}
+
+	// flags are relative to where guac runs and replace the script's
+	s, err = LoadScript(&config.Headless{ScriptPath: script, ScreenshotDir: "shots"}, "other.gba")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.ScreenshotDir != "shots" {
+		t.Errorf("screenshot dir %s, want the flag", s.ScreenshotDir)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	path := rom(t, t.TempDir())
+
+	tests := []struct {
+		name    string
+		c       config.Headless
+		frames  int
+		runAll  bool
+		invalid bool
+	}{
+		{name: "no events", frames: 0, runAll: true},
+		{name: "given", c: config.Headless{Frames: 5, Inputs: []string{"60:a"}}, frames: 5},
+		{name: "last input", c: config.Headless{Inputs: []string{"60:a:4", "10:b"}}, frames: 63, runAll: true},
+		{name: "last event", c: config.Headless{Inputs: []string{"10:b"}, Expects: []string{"90:0"}, Searches: []string{"30:new"}}, frames: 90, runAll: true},
+		{name: "screenshot", c: config.Headless{Screenshots: []string{"120"}}, frames: 120, runAll: true},
+		{name: "record", c: config.Headless{Record: "run.gmv"}, invalid: true},
+		{name: "record given", c: config.Headless{Record: "run.gmv", Frames: 5}, frames: 5},
+		{name: "record to last event", c: config.Headless{Record: "run.gmv", Inputs: []string{"60:a"}}, frames: 60, runAll: true},
+		{name: "capture", c: config.Headless{Capture: "run.y4m"}, invalid: true},
+		{name: "capture given", c: config.Headless{Capture: "run.y4m", Frames: 5}, frames: 5},
+		{name: "play and record", c: config.Headless{Movie: "a.gmv", Record: "b.gmv", Frames: 5}, invalid: true},
+		{name: "search width", c: config.Headless{SearchWidth: 3}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		s, err := LoadScript(&tt.c, path)
+
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%s: no error", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if s.Frames != tt.frames || s.runAll != tt.runAll {
+			t.Errorf("%s: %d frames run all %t, want %d %t", tt.name, s.Frames, s.runAll, tt.frames, tt.runAll)
+		}
+	}
+}
+
+func TestMissingRom(t *testing.T) {
+	if _, err := LoadScript(&config.Headless{}, ""); err == nil {
+		t.Error("no error without a rom")
+	}
+
+	if _, err := LoadScript(&config.Headless{}, filepath.Join(t.TempDir(), "none.gba")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing rom error %v", err)
+	}
+}
+
+func TestButtons(t *testing.T) {
+	s, err := LoadScript(&config.Headless{Inputs: []string{"2:a:2", "3:b"}}, rom(t, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for frame, want := range []input.Buttons{0, 0, input.A, input.A | input.B, 0} {
+		if b := s.buttons(frame); b != want {
+			t.Errorf("frame %d: %s, want %s", frame, b, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package input
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aabalke/guac/config"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Buttons is the set of console buttons held during a frame. The low 10 bits
+// match the gba / nds KEYINPUT order, X and Y are nds only, a gb ignores
+// R, L, X and Y. Mic and Blow are the nds microphone, Lid closes the nds.
+type Buttons uint16
+
+const (
+	A Buttons = 1 << iota
+	B
+	Select
+	Start
+	Right
+	Left
+	Up
+	Down
+	R
+	L
+	X
+	Y
+	Mic
+	Blow
+	Lid
+)
+
+var buttonNames = []struct {
+	name string
+	b    Buttons
+}{
+	{"a", A},
+	{"b", B},
+	{"select", Select},
+	{"start", Start},
+	{"right", Right},
+	{"left", Left},
+	{"up", Up},
+	{"down", Down},
+	{"r", R},
+	{"l", L},
+	{"x", X},
+	{"y", Y},
+	{"mic", Mic},
+	{"blow", Blow},
+	{"lid", Lid},
+}
+
+// ParseButtons reads button names joined by "+", e.g. "a+start"
+func ParseButtons(s string) (Buttons, error) {
+	var b Buttons
+
+	for name := range strings.SplitSeq(strings.ToLower(s), "+") {
+		name = strings.TrimSpace(name)
+
+		found := false
+		for _, n := range buttonNames {
+			if n.name == name {
+				b |= n.b
+				found = true
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("unknown button %q", name)
+		}
+	}
+
+	return b, nil
+}
+
+func (b Buttons) String() string {
+	var names []string
+	for _, n := range buttonNames {
+		if b&n.b != 0 {
+			names = append(names, n.name)
+		}
+	}
+
+	return strings.Join(names, "+")
+}
+
+// Held maps pressed keys and gamepad buttons to console buttons
+func Held(keys []ebiten.Key, buttons []ebiten.StandardGamepadButton, keyCfg *config.EmulatorKeyboard, buttonCfg *config.EmulatorController) Buttons {
+	var b Buttons
+
+	for _, key := range keys {
+		switch {
+		case slices.Contains(keyCfg.A, key):
+			b |= A
+		case slices.Contains(keyCfg.B, key):
+			b |= B
+		case slices.Contains(keyCfg.Select, key):
+			b |= Select
+		case slices.Contains(keyCfg.Start, key):
+			b |= Start
+		case slices.Contains(keyCfg.Right, key):
+			b |= Right
+		case slices.Contains(keyCfg.Left, key):
+			b |= Left
+		case slices.Contains(keyCfg.Up, key):
+			b |= Up
+		case slices.Contains(keyCfg.Down, key):
+			b |= Down
+		case slices.Contains(keyCfg.R, key):
+			b |= R
+		case slices.Contains(keyCfg.L, key):
+			b |= L
+		case slices.Contains(keyCfg.X, key):
+			b |= X
+		case slices.Contains(keyCfg.Y, key):
+			b |= Y
+		case slices.Contains(keyCfg.Mic, key):
+			b |= Mic
+		case slices.Contains(keyCfg.Blow, key):
+			b |= Blow
+		case slices.Contains(keyCfg.Lid, key):
+			b |= Lid
+		}
+	}
+
+	for _, button := range buttons {
+		switch {
+		case slices.Contains(buttonCfg.A, button):
+			b |= A
+		case slices.Contains(buttonCfg.B, button):
+			b |= B
+		case slices.Contains(buttonCfg.Select, button):
+			b |= Select
+		case slices.Contains(buttonCfg.Start, button):
+			b |= Start
+		case slices.Contains(buttonCfg.Right, button):
+			b |= Right
+		case slices.Contains(buttonCfg.Left, button):
+			b |= Left
+		case slices.Contains(buttonCfg.Up, button):
+			b |= Up
+		case slices.Contains(buttonCfg.Down, button):
+			b |= Down
+		case slices.Contains(buttonCfg.R, button):
+			b |= R
+		case slices.Contains(buttonCfg.L, button):
+			b |= L
+		case slices.Contains(buttonCfg.X, button):
+			b |= X
+		case slices.Contains(buttonCfg.Y, button):
+			b |= Y
+		case slices.Contains(buttonCfg.Mic, button):
+			b |= Mic
+		case slices.Contains(buttonCfg.Blow, button):
+			b |= Blow
+		case slices.Contains(buttonCfg.Lid, button):
+			b |= Lid
+		}
+	}
+
+	return b
+}
@@ -0,0 +1,22 @@
+package input
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// rumble is renewed every frame the motor is on, a little longer than a
+// frame so it does not stutter. A kick of the nds rumble pak lasts as long.
+const RUMBLE_DURATION = time.Second / 30
+
+// Rumble shakes every gamepad for a frame
+func Rumble() {
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		ebiten.VibrateGamepad(id, &ebiten.VibrateGamepadOptions{
+			Duration:        RUMBLE_DURATION,
+			StrongMagnitude: 1,
+			WeakMagnitude:   1,
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// capturePath is a new directory of pngs beside the rom for each recording
+func (g *Game) capturePath() string {
+	return g.romPath + time.Now().Format("_20060102_150405")
+}
+
+func (g *Game) capturing() bool {
+	switch {
+	case g.nds != nil:
+		return g.nds.Capture != nil
+	case g.gba != nil:
+		return g.gba.Capture != nil
+	case g.gb != nil:
+		return g.gb.Capture != nil
+	}
+
+	return false
+}
+
+// ToggleCapture starts recording the picture and sound, or stops and saves
+func (g *Game) ToggleCapture() {
+	l := g.ui.res.localization.Toast
+
+	if g.capturing() {
+		var (
+			n   int
+			err error
+		)
+
+		switch {
+		case g.nds != nil:
+			n, err = g.nds.StopCapture()
+		case g.gba != nil:
+			n, err = g.gba.StopCapture()
+		case g.gb != nil:
+			n, err = g.gb.StopCapture()
+		}
+
+		if err != nil {
+			log.Printf("Capture Failed: %v\n", err)
+			g.ui.toast.AddMessage(fmt.Sprintf(l.CaptureFailed, err))
+			return
+		}
+
+		g.ui.toast.AddMessage(fmt.Sprintf(l.CaptureSaved, n))
+		return
+	}
+
+	var err error
+	switch path := g.capturePath(); {
+	case g.nds != nil:
+		err = g.nds.StartCapture(path)
+	case g.gba != nil:
+		err = g.gba.StartCapture(path)
+	case g.gb != nil:
+		err = g.gb.StartCapture(path)
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Printf("Capture Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(l.CaptureFailed, err))
+		return
+	}
+
+	g.ui.toast.AddMessage(l.CaptureStarted)
+}
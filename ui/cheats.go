@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aabalke/guac/emu/cheat"
+	"github.com/ebitenui/ebitenui"
+	"github.com/ebitenui/ebitenui/widget"
+)
+
+// cheats are stored beside the rom, same as save states
+func (g *Game) cheatPath() string {
+	return g.romPath + ".cht"
+}
+
+// LoadCheats reads the cheats of the rom and applies the enabled ones
+func (g *Game) LoadCheats() {
+	cheats, err := cheat.Load(g.cheatPath())
+	if err != nil {
+		log.Printf("Load Cheats Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(g.ui.res.localization.Toast.CheatFailed, err))
+		return
+	}
+
+	g.cheats = cheats
+	g.applyCheats()
+}
+
+func (g *Game) applyCheats() {
+	var err error
+	switch {
+	case g.nds != nil:
+		err = g.nds.SetCheats(g.cheats)
+	case g.gba != nil:
+		err = g.gba.SetCheats(g.cheats)
+	case g.gb != nil:
+		err = g.gb.SetCheats(g.cheats)
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Printf("Cheats Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(g.ui.res.localization.Toast.CheatFailed, err))
+	}
+}
+
+func (g *Game) ToggleCheat(i int) {
+	g.cheats[i].Enabled = !g.cheats[i].Enabled
+
+	if err := cheat.Save(g.cheatPath(), g.cheats); err != nil {
+		log.Printf("Save Cheats Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(g.ui.res.localization.Toast.CheatFailed, err))
+	}
+
+	g.applyCheats()
+}
+
+func NewCheats(g *Game) {
+
+	g.ui.focus.ClearFocus()
+
+	l := g.ui.res.localization.Cheats
+
+	root := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(g.ui.res.bg),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				HorizontalPosition: widget.AnchorLayoutPositionCenter,
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+			}),
+		),
+
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(50)),
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(16),
+		)),
+	)
+
+	if len(g.cheats) == 0 {
+		c.AddChild(NewLabel(fmt.Sprintf(l.Empty, g.cheatPath())))
+	}
+
+	for i, ch := range g.cheats {
+		state := l.Off
+		if ch.Enabled {
+			state = l.On
+		}
+
+		c.AddChild(NewCenteredButton(fmt.Sprintf("%s - %s", state, ch.Name), func() {
+			g.ToggleCheat(i)
+			NewCheats(g)
+		}))
+	}
+
+	c.AddChild(NewCenteredButton(l.Reload, func() {
+		g.LoadCheats()
+		NewCheats(g)
+	}))
+
+	c.AddChild(NewCenteredButton(l.Return, func() {
+		NewPause(g)
+	}))
+
+	root.AddChild(c)
+
+	g.ui.PageId = PAGE_CHEATS
+	g.ui.ui = &ebitenui.UI{
+		Container:    root,
+		PrimaryTheme: NewTheme(g.ui.res),
+	}
+	g.ui.focus.other = g.ui.ui.Container.GetFocusers()
+	g.ui.focus.BuildFocus(g.ui.ui)
+}
@@ -13,9 +13,11 @@ import (
 	"github.com/hajimehoshi/oto"
 
 	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/cheat"
 	"github.com/aabalke/guac/emu/gb"
 	"github.com/aabalke/guac/emu/gba"
 	"github.com/aabalke/guac/emu/nds"
+	"github.com/aabalke/guac/emu/search"
 	"github.com/aabalke/guac/input"
 	"github.com/aabalke/guac/utils"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -28,6 +30,10 @@ const (
 	PAGE_PAUSE
 	PAGE_SETTINGS
 	PAGE_KEYBOARD
+	PAGE_STATES
+	PAGE_CHEATS
+	PAGE_SEARCH
+	PAGE_MOVIE
 )
 
 type Game struct {
@@ -40,6 +46,14 @@ type Game struct {
 	mouse    *input.Mouse
 	audioCtx *oto.Context
 
+	romPath string
+	slot    int
+	cheats  []cheat.Cheat
+
+	ramSearch   *search.Search
+	searchOpt   search.Options
+	searchValue string
+
 	gamepadIdBuf []ebiten.GamepadID
 	gamepadIds   map[ebiten.GamepadID]struct{}
 
@@ -88,6 +102,8 @@ func StartEngine() {
 
 	g := NewGame(res)
 
+	powerOnClock()
+
 	if ok := g.InitConsole(config.Conf.General.RomPath); !ok {
 		NewHome(g)
 	}
@@ -135,10 +151,13 @@ func (g *Game) Update() error {
 
 	g.Profile()
 
-	justKeys, keys, _, buttons := g.GetInput()
+	justKeys, keys, justButtons, buttons := g.GetInput()
+
+	g.SetRewinding(g.ui.ui == nil && g.RewindHeld(keys, buttons))
 
 	switch {
 	case g.quit:
+		g.StopMovie()
 		return ebiten.Termination
 	case g.ui.ui != nil:
 
@@ -166,11 +185,11 @@ func (g *Game) Update() error {
 		g.nds.Update(g.TargetFps == 60)
 
 	case g.gba != nil:
-		g.gba.InputHandler(keys, buttons)
+		g.gba.InputHandler(justKeys, keys, justButtons, buttons)
 		g.gba.Update(g.TargetFps == 60)
 
 	case g.gb != nil:
-		g.gb.InputHandler(keys, buttons)
+		g.gb.InputHandler(keys, buttons, g.mouse)
 		g.gb.Update(g.TargetFps == 60)
 	}
 
@@ -231,6 +250,17 @@ func (g *Game) TogglePause() {
 	}
 }
 
+func (g *Game) SetRewinding(rewinding bool) {
+	switch {
+	case g.nds != nil:
+		g.nds.Rewinding = rewinding
+	case g.gba != nil:
+		g.gba.Rewinding = rewinding
+	case g.gb != nil:
+		g.gb.Rewinding = rewinding
+	}
+}
+
 func (g *Game) ToggleMute() {
 	g.muted = !g.muted
 
@@ -306,6 +336,9 @@ func (g *Game) Profile() {
 }
 
 func (g *Game) InitConsole(file string) bool {
+	g.romPath = file
+	g.ramSearch = nil
+
 	switch romType := utils.GetRomType(file); romType {
 	case utils.GB:
 		g.gb = gb.NewGameBoy(file, g.audioCtx)
@@ -313,6 +346,8 @@ func (g *Game) InitConsole(file string) bool {
 		if g.muted {
 			g.gb.ToggleMute()
 		}
+		g.patchFailed(g.gb.PatchErr)
+		g.LoadCheats()
 		return true
 
 	case utils.GBA:
@@ -321,6 +356,8 @@ func (g *Game) InitConsole(file string) bool {
 		if g.muted {
 			g.gba.ToggleMute()
 		}
+		g.patchFailed(g.gba.PatchErr)
+		g.LoadCheats()
 		return true
 
 	case utils.NDS:
@@ -329,8 +366,18 @@ func (g *Game) InitConsole(file string) bool {
 		if g.muted {
 			g.nds.ToggleMute()
 		}
+		g.patchFailed(g.nds.PatchErr)
+		g.LoadCheats()
 		return true
 	default:
 		return false
 	}
 }
+
+// patchFailed tells the player the rom's patch was not applied, the game runs
+// unpatched
+func (g *Game) patchFailed(err error) {
+	if err != nil {
+		g.ui.toast.AddMessage(fmt.Sprintf(g.ui.res.localization.Toast.PatchFailed, err))
+	}
+}
@@ -73,6 +73,16 @@ func (g *Game) GetInput() (justKeys, keys []ebiten.Key, justButtons, buttons []e
 			g.TogglePause()
 		case slices.Contains(keyConfig.Mute, key):
 			g.ToggleMute()
+		case slices.Contains(keyConfig.SaveState, key) && g.ui.ui == nil:
+			g.SaveState(g.slot)
+		case slices.Contains(keyConfig.LoadState, key) && g.ui.ui == nil:
+			g.LoadState(g.slot)
+		case slices.Contains(keyConfig.NextSlot, key):
+			g.ChangeSlot(1)
+		case slices.Contains(keyConfig.PrevSlot, key):
+			g.ChangeSlot(-1)
+		case slices.Contains(keyConfig.Capture, key) && g.ui.ui == nil:
+			g.ToggleCapture()
 		}
 	}
 
@@ -90,17 +100,48 @@ func (g *Game) GetInput() (justKeys, keys []ebiten.Key, justButtons, buttons []e
 			g.TogglePause()
 		case slices.Contains(buttonConfig.Mute, button):
 			g.ToggleMute()
+		case slices.Contains(buttonConfig.SaveState, button) && g.ui.ui == nil:
+			g.SaveState(g.slot)
+		case slices.Contains(buttonConfig.LoadState, button) && g.ui.ui == nil:
+			g.LoadState(g.slot)
+		case slices.Contains(buttonConfig.NextSlot, button):
+			g.ChangeSlot(1)
+		case slices.Contains(buttonConfig.PrevSlot, button):
+			g.ChangeSlot(-1)
+		case slices.Contains(buttonConfig.Capture, button) && g.ui.ui == nil:
+			g.ToggleCapture()
 		}
 	}
 
 	return justKeys, keys, justButtons, buttons
 }
 
+// RewindHeld is true while any rewind key or button is held down
+func (g *Game) RewindHeld(keys []ebiten.Key, buttons []ebiten.StandardGamepadButton) bool {
+	if !ebiten.IsFocused() {
+		return false
+	}
+
+	for _, key := range keys {
+		if slices.Contains(config.Conf.General.Keyboard.Rewind, key) {
+			return true
+		}
+	}
+
+	for _, button := range buttons {
+		if slices.Contains(config.Conf.General.Controller.Rewind, button) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (g *Game) ButtonInput(justButtons, buttons []ebiten.StandardGamepadButton) {
 	buttonConfig := config.Conf.General.Controller
 
 	switch g.ui.PageId {
-	case PAGE_HOME, PAGE_PAUSE:
+	case PAGE_HOME, PAGE_PAUSE, PAGE_STATES, PAGE_CHEATS, PAGE_SEARCH, PAGE_MOVIE:
 		for _, button := range justButtons {
 			switch {
 			case slices.Contains(buttonConfig.Up, button):
@@ -110,8 +151,11 @@ func (g *Game) ButtonInput(justButtons, buttons []ebiten.StandardGamepadButton)
 				g.ui.ui.ChangeFocus(widget.FOCUS_SOUTH)
 
 			case slices.Contains(buttonConfig.Select, button):
-				if b, ok := g.ui.ui.GetFocusedWidget().(*widget.Button); ok {
-					b.Click()
+				switch w := g.ui.ui.GetFocusedWidget().(type) {
+				case *widget.Button:
+					w.Click()
+				case *widget.TextInput:
+					w.Submit()
 				}
 			case slices.Contains(buttonConfig.Return, button):
 				g.ui.focus.FocusLast()
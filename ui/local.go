@@ -49,6 +49,10 @@ func NewLocalization(lang LangOptions) *Localization {
 type Localization struct {
 	Main     MainLocalization     `toml:"main"`
 	Pause    PauseLocalization    `toml:"pause"`
+	States   StatesLocalization   `toml:"states"`
+	Cheats   CheatsLocalization   `toml:"cheats"`
+	Search   SearchLocalization   `toml:"search"`
+	Movie    MovieLocalization    `toml:"movie"`
 	Settings SettingsLocalization `toml:"settings"`
 	Toast    ToastLocalization    `toml:"toast"`
 }
@@ -59,6 +63,19 @@ type ToastLocalization struct {
 	Unmuted                string `toml:"unmuted"`
 	ControllerConnected    string `toml:"controller_connected"`
 	ControllerDisconnected string `toml:"controller_disconnected"`
+	StateSaved             string `toml:"state_saved"`
+	StateLoaded            string `toml:"state_loaded"`
+	StateFailed            string `toml:"state_failed"`
+	StateSlot              string `toml:"state_slot"`
+	CheatFailed            string `toml:"cheat_failed"`
+	PatchFailed            string `toml:"patch_failed"`
+	MovieRecording         string `toml:"movie_recording"`
+	MoviePlaying           string `toml:"movie_playing"`
+	MovieSaved             string `toml:"movie_saved"`
+	MovieFailed            string `toml:"movie_failed"`
+	CaptureStarted         string `toml:"capture_started"`
+	CaptureSaved           string `toml:"capture_saved"`
+	CaptureFailed          string `toml:"capture_failed"`
 }
 
 type MainLocalization struct {
@@ -70,9 +87,59 @@ type MainLocalization struct {
 }
 
 type PauseLocalization struct {
-	Resume   string `toml:"resume"`
-	Settings string `toml:"settings"`
-	Main     string `toml:"main"`
+	Resume     string `toml:"resume"`
+	SaveStates string `toml:"save_states"`
+	Cheats     string `toml:"cheats"`
+	Search     string `toml:"search"`
+	Movie      string `toml:"movie"`
+	Settings   string `toml:"settings"`
+	Main       string `toml:"main"`
+}
+
+type StatesLocalization struct {
+	Slot   string `toml:"slot"`
+	Empty  string `toml:"empty"`
+	Save   string `toml:"save"`
+	Load   string `toml:"load"`
+	Return string `toml:"return"`
+}
+
+type CheatsLocalization struct {
+	On     string `toml:"on"`
+	Off    string `toml:"off"`
+	Empty  string `toml:"empty"`
+	Reload string `toml:"reload"`
+	Return string `toml:"return"`
+}
+
+type SearchLocalization struct {
+	Width     string `toml:"width"`
+	Signed    string `toml:"signed"`
+	Unsigned  string `toml:"unsigned"`
+	Value     string `toml:"value"`
+	Left      string `toml:"left"`
+	BadValue  string `toml:"bad_value"`
+	Equal     string `toml:"equal"`
+	NotEqual  string `toml:"not_equal"`
+	Increased string `toml:"increased"`
+	Decreased string `toml:"decreased"`
+	Changed   string `toml:"changed"`
+	Unchanged string `toml:"unchanged"`
+	ChangedBy string `toml:"changed_by"`
+	New       string `toml:"new"`
+	Return    string `toml:"return"`
+}
+
+type MovieLocalization struct {
+	None          string `toml:"none"`
+	Recording     string `toml:"recording"`
+	Playing       string `toml:"playing"`
+	Finished      string `toml:"finished"`
+	RecordPowerOn string `toml:"record_power_on"`
+	RecordHere    string `toml:"record_here"`
+	Play          string `toml:"play"`
+	Stop          string `toml:"stop"`
+	Return        string `toml:"return"`
 }
 
 type SettingsLocalization struct {
@@ -103,6 +170,8 @@ type GeneralLocalization struct {
 	DisableSaves         string `toml:"disable_saves"`
 	IntegerScaling       string `toml:"integer_scaling"`
 	IntegerScalingRatio  string `toml:"integer_scaling_ratio"`
+	RewindBufferSize     string `toml:"rewind_buffer_size"`
+	RewindInterval       string `toml:"rewind_interval"`
 	Keyboard             string `toml:"keyboard"`
 	Controller           string `toml:"controller"`
 	Select               string `toml:"select"`
@@ -115,6 +184,12 @@ type GeneralLocalization struct {
 	Down                 string `toml:"down"`
 	Fullscreen           string `toml:"fullscreen"`
 	Quit                 string `toml:"quit"`
+	SaveState            string `toml:"save_state"`
+	LoadState            string `toml:"load_state"`
+	NextSlot             string `toml:"next_slot"`
+	PrevSlot             string `toml:"prev_slot"`
+	Rewind               string `toml:"rewind"`
+	Capture              string `toml:"capture"`
 	KeyboardSelect       string `toml:"keyboard_select"`
 	KeyboardReturn       string `toml:"keyboard_return"`
 	KeyboardMute         string `toml:"keyboard_mute"`
@@ -125,6 +200,12 @@ type GeneralLocalization struct {
 	KeyboardDown         string `toml:"keyboard_down"`
 	KeyboardFullscreen   string `toml:"keyboard_fullscreen"`
 	KeyboardQuit         string `toml:"keyboard_quit"`
+	KeyboardSaveState    string `toml:"keyboard_save_state"`
+	KeyboardLoadState    string `toml:"keyboard_load_state"`
+	KeyboardNextSlot     string `toml:"keyboard_next_slot"`
+	KeyboardPrevSlot     string `toml:"keyboard_prev_slot"`
+	KeyboardRewind       string `toml:"keyboard_rewind"`
+	KeyboardCapture      string `toml:"keyboard_capture"`
 	ControllerSelect     string `toml:"controller_select"`
 	ControllerReturn     string `toml:"controller_return"`
 	ControllerMute       string `toml:"controller_mute"`
@@ -135,6 +216,12 @@ type GeneralLocalization struct {
 	ControllerDown       string `toml:"controller_down"`
 	ControllerFullscreen string `toml:"controller_fullscreen"`
 	ControllerQuit       string `toml:"controller_quit"`
+	ControllerSaveState  string `toml:"controller_save_state"`
+	ControllerLoadState  string `toml:"controller_load_state"`
+	ControllerNextSlot   string `toml:"controller_next_slot"`
+	ControllerPrevSlot   string `toml:"controller_prev_slot"`
+	ControllerRewind     string `toml:"controller_rewind"`
+	ControllerCapture    string `toml:"controller_capture"`
 	Save                 string `toml:"save"`
 }
 
@@ -199,42 +286,52 @@ type GbLocalization struct {
 }
 
 type GbaLocalization struct {
-	General          string `toml:"general"`
-	OptmizeIdleLoops string `toml:"optimize_idle_loops"`
-	SoundClockCycles string `toml:"sound_clock_cycles"`
-	Keyboard         string `toml:"keyboard"`
-	Controller       string `toml:"controller"`
-	A                string `toml:"a"`
-	B                string `toml:"b"`
-	Select           string `toml:"select"`
-	Start            string `toml:"start"`
-	Left             string `toml:"left"`
-	Right            string `toml:"right"`
-	Up               string `toml:"up"`
-	Down             string `toml:"down"`
-	L                string `toml:"l"`
-	R                string `toml:"r"`
-	KeyboardA        string `toml:"keyboard_a"`
-	KeyboardB        string `toml:"keyboard_b"`
-	KeyboardSelect   string `toml:"keyboard_select"`
-	KeyboardStart    string `toml:"keyboard_start"`
-	KeyboardLeft     string `toml:"keyboard_left"`
-	KeyboardRight    string `toml:"keyboard_right"`
-	KeyboardUp       string `toml:"keyboard_up"`
-	KeyboardDown     string `toml:"keyboard_down"`
-	KeyboardL        string `toml:"keyboard_l"`
-	KeyboardR        string `toml:"keyboard_r"`
-	ControllerA      string `toml:"controller_a"`
-	ControllerB      string `toml:"controller_b"`
-	ControllerSelect string `toml:"controller_select"`
-	ControllerStart  string `toml:"controller_start"`
-	ControllerLeft   string `toml:"controller_left"`
-	ControllerRight  string `toml:"controller_right"`
-	ControllerUp     string `toml:"controller_up"`
-	ControllerDown   string `toml:"controller_down"`
-	ControllerL      string `toml:"controller_l"`
-	ControllerR      string `toml:"controller_r"`
-	Save             string `toml:"save"`
+	General             string `toml:"general"`
+	OptmizeIdleLoops    string `toml:"optimize_idle_loops"`
+	SoundClockCycles    string `toml:"sound_clock_cycles"`
+	Rtc                 string `toml:"rtc"`
+	AdditionalHours     string `toml:"additional_hours"`
+	Solar               string `toml:"solar"`
+	SolarLevel          string `toml:"solar_level"`
+	Keyboard            string `toml:"keyboard"`
+	Controller          string `toml:"controller"`
+	A                   string `toml:"a"`
+	B                   string `toml:"b"`
+	Select              string `toml:"select"`
+	Start               string `toml:"start"`
+	Left                string `toml:"left"`
+	Right               string `toml:"right"`
+	Up                  string `toml:"up"`
+	Down                string `toml:"down"`
+	L                   string `toml:"l"`
+	R                   string `toml:"r"`
+	SolarUp             string `toml:"solar_up"`
+	SolarDown           string `toml:"solar_down"`
+	KeyboardA           string `toml:"keyboard_a"`
+	KeyboardB           string `toml:"keyboard_b"`
+	KeyboardSelect      string `toml:"keyboard_select"`
+	KeyboardStart       string `toml:"keyboard_start"`
+	KeyboardLeft        string `toml:"keyboard_left"`
+	KeyboardRight       string `toml:"keyboard_right"`
+	KeyboardUp          string `toml:"keyboard_up"`
+	KeyboardDown        string `toml:"keyboard_down"`
+	KeyboardL           string `toml:"keyboard_l"`
+	KeyboardR           string `toml:"keyboard_r"`
+	KeyboardSolarUp     string `toml:"keyboard_solar_up"`
+	KeyboardSolarDown   string `toml:"keyboard_solar_down"`
+	ControllerA         string `toml:"controller_a"`
+	ControllerB         string `toml:"controller_b"`
+	ControllerSelect    string `toml:"controller_select"`
+	ControllerStart     string `toml:"controller_start"`
+	ControllerLeft      string `toml:"controller_left"`
+	ControllerRight     string `toml:"controller_right"`
+	ControllerUp        string `toml:"controller_up"`
+	ControllerDown      string `toml:"controller_down"`
+	ControllerL         string `toml:"controller_l"`
+	ControllerR         string `toml:"controller_r"`
+	ControllerSolarUp   string `toml:"controller_solar_up"`
+	ControllerSolarDown string `toml:"controller_solar_down"`
+	Save                string `toml:"save"`
 }
 
 type NdsLocalization struct {
@@ -258,6 +355,16 @@ type NdsLocalization struct {
 	SceneExport     string   `toml:"scene_export"`
 	OutputDirectory string   `toml:"output_directory"`
 	ShadowPolygons  string   `toml:"shadow_polygons"`
+	Slot2           string   `toml:"slot2"`
+	Slot2Device     string   `toml:"slot2_device"`
+	Slot2Devices    []string `toml:"slot2_devices"`
+	GbaRomPath      string   `toml:"gba_rom_path"`
+	Wifi            string   `toml:"wifi"`
+	WifiAir         string   `toml:"wifi_air"`
+	WifiMac         string   `toml:"wifi_mac"`
+	Microphone      string   `toml:"microphone"`
+	MicWavPath      string   `toml:"mic_wav_path"`
+	MicLoop         string   `toml:"mic_loop"`
 
 	Keyboard       string `toml:"keyboard"`
 	Controller     string `toml:"controller"`
@@ -279,6 +386,13 @@ type NdsLocalization struct {
 	SizingToggle   string `toml:"sizing_toggle"`
 	RotationToggle string `toml:"rotation_toggle"`
 	ExportToggle   string `toml:"export_toggle"`
+	GripGreen      string `toml:"grip_green"`
+	GripRed        string `toml:"grip_red"`
+	GripYellow     string `toml:"grip_yellow"`
+	GripBlue       string `toml:"grip_blue"`
+	Mic            string `toml:"mic"`
+	Blow           string `toml:"blow"`
+	Lid            string `toml:"lid"`
 
 	KeyboardA              string `toml:"keyboard_a"`
 	KeyboardB              string `toml:"keyboard_b"`
@@ -298,18 +412,32 @@ type NdsLocalization struct {
 	KeyboardSizingToggle   string `toml:"keyboard_sizing_toggle"`
 	KeyboardRotationToggle string `toml:"keyboard_rotation_toggle"`
 	KeyboardExportToggle   string `toml:"keyboard_export_toggle"`
+	KeyboardGripGreen      string `toml:"keyboard_grip_green"`
+	KeyboardGripRed        string `toml:"keyboard_grip_red"`
+	KeyboardGripYellow     string `toml:"keyboard_grip_yellow"`
+	KeyboardGripBlue       string `toml:"keyboard_grip_blue"`
+	KeyboardMic            string `toml:"keyboard_mic"`
+	KeyboardBlow           string `toml:"keyboard_blow"`
+	KeyboardLid            string `toml:"keyboard_lid"`
 
-	ControllerA      string `toml:"controller_a"`
-	ControllerB      string `toml:"controller_b"`
-	ControllerSelect string `toml:"controller_select"`
-	ControllerStart  string `toml:"controller_start"`
-	ControllerLeft   string `toml:"controller_left"`
-	ControllerRight  string `toml:"controller_right"`
-	ControllerUp     string `toml:"controller_up"`
-	ControllerDown   string `toml:"controller_down"`
-	ControllerL      string `toml:"controller_l"`
-	ControllerR      string `toml:"controller_r"`
-	ControllerX      string `toml:"controller_x"`
-	ControllerY      string `toml:"controller_y"`
-	Save             string `toml:"save"`
+	ControllerA          string `toml:"controller_a"`
+	ControllerB          string `toml:"controller_b"`
+	ControllerSelect     string `toml:"controller_select"`
+	ControllerStart      string `toml:"controller_start"`
+	ControllerLeft       string `toml:"controller_left"`
+	ControllerRight      string `toml:"controller_right"`
+	ControllerUp         string `toml:"controller_up"`
+	ControllerDown       string `toml:"controller_down"`
+	ControllerL          string `toml:"controller_l"`
+	ControllerR          string `toml:"controller_r"`
+	ControllerX          string `toml:"controller_x"`
+	ControllerY          string `toml:"controller_y"`
+	ControllerGripGreen  string `toml:"controller_grip_green"`
+	ControllerGripRed    string `toml:"controller_grip_red"`
+	ControllerGripYellow string `toml:"controller_grip_yellow"`
+	ControllerGripBlue   string `toml:"controller_grip_blue"`
+	ControllerMic        string `toml:"controller_mic"`
+	ControllerBlow       string `toml:"controller_blow"`
+	ControllerLid        string `toml:"controller_lid"`
+	Save                 string `toml:"save"`
 }
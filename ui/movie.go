@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aabalke/guac/config"
+	"github.com/aabalke/guac/emu/clock"
+	"github.com/aabalke/guac/emu/movie"
+	"github.com/ebitenui/ebitenui"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// movies are stored beside the rom, same as save states
+func (g *Game) moviePath() string {
+	return g.romPath + ".gmv"
+}
+
+// powerOnClock sets the guest clocks for a console about to power on, the
+// epoch in deterministic mode and host time otherwise
+func powerOnClock() {
+	if config.Conf.General.Deterministic {
+		clock.Seed(config.Conf.General.Epoch)
+		return
+	}
+
+	clock.Unseed()
+}
+
+// releaseClock lets go of a movie's clock, deterministic runs keep it going
+func releaseClock() {
+	if !config.Conf.General.Deterministic {
+		clock.Unseed()
+	}
+}
+
+func (g *Game) movieSession() *movie.Session {
+	switch {
+	case g.nds != nil:
+		return g.nds.Movie
+	case g.gba != nil:
+		return g.gba.Movie
+	case g.gb != nil:
+		return g.gb.Movie
+	}
+
+	return nil
+}
+
+// restartConsole powers the rom on again, on the clock as it is seeded now
+func (g *Game) restartConsole() {
+	g.closeConsole()
+	g.InitConsole(g.romPath)
+	g.paused = false
+	g.pauseEndTick = ebiten.Tick()
+}
+
+func (g *Game) closeConsole() {
+	if g.nds != nil {
+		g.nds.Close()
+	}
+	if g.gba != nil {
+		g.gba.Close()
+	}
+	if g.gb != nil {
+		g.gb.Close()
+	}
+
+	g.nds = nil
+	g.gba = nil
+	g.gb = nil
+}
+
+// RecordMovie starts recording, from power on or from here. Both carry a
+// state, from power on it is taken before the first frame
+func (g *Game) RecordMovie(fromState bool) {
+	l := g.ui.res.localization.Toast
+
+	g.StopMovie()
+
+	if !fromState {
+		powerOnClock()
+		clock.Seed(clock.Now())
+		g.restartConsole()
+	}
+
+	var err error
+	switch {
+	case g.nds != nil:
+		err = g.nds.RecordMovie()
+	case g.gba != nil:
+		err = g.gba.RecordMovie()
+	case g.gb != nil:
+		err = g.gb.RecordMovie()
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Printf("Record Movie Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(l.MovieFailed, err))
+		releaseClock()
+		return
+	}
+
+	g.ui.toast.AddMessage(l.MovieRecording)
+}
+
+// PlayMovie replays the rom's movie, a movie without a state restarts the rom
+func (g *Game) PlayMovie() {
+	l := g.ui.res.localization.Toast
+
+	g.StopMovie()
+
+	m, err := movie.Load(g.moviePath())
+	if err == nil && m.State == nil {
+		clock.Seed(m.Seed())
+		g.restartConsole()
+	}
+
+	if err == nil {
+		switch {
+		case g.nds != nil:
+			err = g.nds.PlayMovie(m)
+		case g.gba != nil:
+			err = g.gba.PlayMovie(m)
+		case g.gb != nil:
+			err = g.gb.PlayMovie(m)
+		}
+	}
+
+	if err != nil {
+		log.Printf("Play Movie Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(l.MovieFailed, err))
+		releaseClock()
+		return
+	}
+
+	g.ui.toast.AddMessage(fmt.Sprintf(l.MoviePlaying, len(m.Frames)))
+}
+
+// StopMovie ends the movie, a recording is saved
+func (g *Game) StopMovie() {
+	var s *movie.Session
+	if s = g.movieSession(); s == nil {
+		return
+	}
+
+	var m *movie.Movie
+	switch {
+	case g.nds != nil:
+		m = g.nds.StopMovie()
+	case g.gba != nil:
+		m = g.gba.StopMovie()
+	case g.gb != nil:
+		m = g.gb.StopMovie()
+	}
+
+	releaseClock()
+
+	if !s.Recording {
+		return
+	}
+
+	l := g.ui.res.localization.Toast
+
+	if err := m.Save(g.moviePath()); err != nil {
+		log.Printf("Save Movie Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(l.MovieFailed, err))
+		return
+	}
+
+	g.ui.toast.AddMessage(fmt.Sprintf(l.MovieSaved, len(m.Frames)))
+}
+
+func NewMovie(g *Game) {
+
+	g.ui.focus.ClearFocus()
+
+	l := g.ui.res.localization.Movie
+
+	root := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(g.ui.res.bg),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				HorizontalPosition: widget.AnchorLayoutPositionCenter,
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+			}),
+		),
+
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(50)),
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(16),
+		)),
+	)
+
+	status := l.None
+	switch s := g.movieSession(); {
+	case s == nil:
+	case s.Recording:
+		status = fmt.Sprintf(l.Recording, s.Frame())
+	case s.Done():
+		status = fmt.Sprintf(l.Finished, len(s.Movie.Frames))
+	default:
+		status = fmt.Sprintf(l.Playing, s.Frame(), len(s.Movie.Frames))
+	}
+
+	c.AddChild(NewLabel(status))
+
+	c.AddChild(NewCenteredButton(l.RecordPowerOn, func() {
+		g.RecordMovie(false)
+	}))
+
+	c.AddChild(NewCenteredButton(l.RecordHere, func() {
+		g.RecordMovie(true)
+		g.TogglePause()
+	}))
+
+	c.AddChild(NewCenteredButton(l.Play, func() {
+		g.PlayMovie()
+		if g.paused {
+			g.TogglePause()
+		}
+	}))
+
+	c.AddChild(NewCenteredButton(l.Stop, func() {
+		g.StopMovie()
+		NewMovie(g)
+	}))
+
+	c.AddChild(NewCenteredButton(l.Return, func() {
+		NewPause(g)
+	}))
+
+	root.AddChild(c)
+
+	g.ui.PageId = PAGE_MOVIE
+	g.ui.ui = &ebitenui.UI{
+		Container:    root,
+		PrimaryTheme: NewTheme(g.ui.res),
+	}
+	g.ui.focus.other = g.ui.ui.Container.GetFocusers()
+	g.ui.focus.BuildFocus(g.ui.ui)
+}
@@ -17,9 +17,10 @@ func NewHome(g *Game) {
 		file := utils.OpenFile(
 			l.DialogTitle,
 			l.DialogDesc,
-			"gb", "gbc", "gba", "nds",
+			"gb", "gbc", "gba", "nds", "zip", "gz",
 		)
 
+		powerOnClock()
 		g.InitConsole(file)
 	})
 
@@ -52,30 +53,35 @@ func NewPause(g *Game) {
 		g.TogglePause()
 	})
 
-	b2 := NewCenteredButton(l.Settings, func() {
+	b2 := NewCenteredButton(l.SaveStates, func() {
+		NewStates(g)
+	})
+
+	b3 := NewCenteredButton(l.Cheats, func() {
+		NewCheats(g)
+	})
+
+	b4 := NewCenteredButton(l.Search, func() {
+		NewSearch(g)
+	})
+
+	b5 := NewCenteredButton(l.Movie, func() {
+		NewMovie(g)
+	})
+
+	b6 := NewCenteredButton(l.Settings, func() {
 		NewSettings(g, g.ui.PageId, MENU_GENERAL)
 	})
 
-	b3 := NewCenteredButton(l.Main, func() {
+	b7 := NewCenteredButton(l.Main, func() {
 		NewHome(g)
 
-		if g.nds != nil {
-			g.nds.Close()
-		}
-		if g.gba != nil {
-			g.gba.Close()
-		}
-		if g.gb != nil {
-			g.gb.Close()
-		}
-
-		g.nds = nil
-		g.gba = nil
-		g.gb = nil
+		g.StopMovie()
+		g.closeConsole()
 		g.paused = false
 	})
 
-	root := NewCenteredPage(g.ui.res.bg, b1, b2, b3)
+	root := NewCenteredPage(g.ui.res.bg, b1, b2, b3, b4, b5, b6, b7)
 	g.ui.PageId = PAGE_PAUSE
 	g.ui.ui = &ebitenui.UI{
 		Container:    root,
@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/aabalke/guac/emu/search"
+	"github.com/ebitenui/ebitenui"
+	"github.com/ebitenui/ebitenui/widget"
+)
+
+// candidates listed on the page, more are only counted
+const SEARCH_RESULTS = 16
+
+var searchWidths = []int{1, 2, 4}
+
+func (g *Game) ramRegions() []search.Region {
+	switch {
+	case g.nds != nil:
+		return g.nds.RamRegions()
+	case g.gba != nil:
+		return g.gba.RamRegions()
+	case g.gb != nil:
+		return g.gb.RamRegions()
+	}
+
+	return nil
+}
+
+// NewRamSearch starts a search with the page's options, every address is a
+// candidate again
+func (g *Game) NewRamSearch() {
+	g.searchOpt.Width = max(1, g.searchOpt.Width)
+	g.searchOpt.Unaligned = g.gb != nil
+	g.ramSearch = search.New(g.ramRegions(), g.searchOpt)
+}
+
+func NewSearch(g *Game) {
+
+	g.ui.focus.ClearFocus()
+
+	if g.ramSearch == nil {
+		g.NewRamSearch()
+	}
+
+	l := g.ui.res.localization.Search
+
+	root := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(g.ui.res.bg),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				HorizontalPosition: widget.AnchorLayoutPositionCenter,
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+			}),
+		),
+
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(50)),
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(8),
+		)),
+	)
+
+	sign := l.Unsigned
+	if g.searchOpt.Signed {
+		sign = l.Signed
+	}
+
+	// width and sign change how memory is read, they start a new search
+	c.AddChild(NewCenteredButton(fmt.Sprintf(l.Width, g.searchOpt.Width*8), func() {
+		for i, w := range searchWidths {
+			if w == g.searchOpt.Width {
+				g.searchOpt.Width = searchWidths[(i+1)%len(searchWidths)]
+				break
+			}
+		}
+
+		g.NewRamSearch()
+		NewSearch(g)
+	}))
+
+	c.AddChild(NewCenteredButton(sign, func() {
+		g.searchOpt.Signed = !g.searchOpt.Signed
+		g.NewRamSearch()
+		NewSearch(g)
+	}))
+
+	c.AddChild(NewLabel(l.Value))
+	c.AddChild(NewTextInput(g.ui, l.Value, &g.searchValue))
+
+	status := NewLabel(fmt.Sprintf(l.Left, g.ramSearch.Count()))
+	c.AddChild(status)
+
+	ops := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(4),
+			widget.GridLayoutOpts.Spacing(8, 8),
+		)),
+	)
+
+	for _, op := range []struct {
+		label string
+		op    search.Op
+	}{
+		{l.Equal, search.EQUAL},
+		{l.NotEqual, search.NOT_EQUAL},
+		{l.Increased, search.INCREASED},
+		{l.Decreased, search.DECREASED},
+		{l.Changed, search.CHANGED},
+		{l.Unchanged, search.UNCHANGED},
+		{l.ChangedBy, search.CHANGED_BY},
+	} {
+		ops.AddChild(NewCenteredButton(op.label, func() {
+			var n int64
+
+			if op.op == search.EQUAL || op.op == search.NOT_EQUAL || op.op == search.CHANGED_BY {
+				v, err := search.ParseValue(g.searchValue)
+				if err != nil {
+					status.Label = fmt.Sprintf(l.BadValue, g.searchValue)
+					return
+				}
+
+				n = v
+			}
+
+			g.ramSearch.Filter(op.op, n)
+			NewSearch(g)
+		}))
+	}
+
+	ops.AddChild(NewCenteredButton(l.New, func() {
+		g.NewRamSearch()
+		NewSearch(g)
+	}))
+
+	c.AddChild(ops)
+
+	if g.ramSearch.Count() <= SEARCH_RESULTS {
+		for _, r := range g.ramSearch.Results(0) {
+			c.AddChild(NewLabel(fmt.Sprintf("%s %08X = %d (%d)", r.Region, r.Addr, r.Value, r.Prev)))
+		}
+	}
+
+	c.AddChild(NewCenteredButton(l.Return, func() {
+		NewPause(g)
+	}))
+
+	root.AddChild(c)
+
+	g.ui.PageId = PAGE_SEARCH
+	g.ui.ui = &ebitenui.UI{
+		Container:    root,
+		PrimaryTheme: NewTheme(g.ui.res),
+	}
+	g.ui.focus.other = g.ui.ui.Container.GetFocusers()
+	g.ui.focus.BuildFocus(g.ui.ui)
+}
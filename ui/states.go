@@ -0,0 +1,285 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"os"
+
+	"github.com/ebitenui/ebitenui"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	STATE_SLOTS = 10
+
+	THUMB_WIDTH   = 128
+	THUMB_COLUMNS = 5
+)
+
+// states are stored beside the rom, same as battery saves
+func (g *Game) statePath(slot int) string {
+	return fmt.Sprintf("%s.ss%d", g.romPath, slot)
+}
+
+func (g *Game) thumbPath(slot int) string {
+	return g.statePath(slot) + ".png"
+}
+
+// stater is the running console's save states
+type stater interface {
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+}
+
+// stater returns the running console, nil without one
+func (g *Game) stater() stater {
+	switch {
+	case g.nds != nil:
+		return g.nds
+	case g.gba != nil:
+		return g.gba
+	case g.gb != nil:
+		return g.gb
+	}
+
+	return nil
+}
+
+func (g *Game) SaveState(slot int) {
+	l := g.ui.res.localization.Toast
+
+	c := g.stater()
+	if c == nil {
+		return
+	}
+
+	buf := bytes.Buffer{}
+
+	err := c.SaveState(&buf)
+	if err == nil {
+		err = os.WriteFile(g.statePath(slot), buf.Bytes(), 0644)
+	}
+
+	if err != nil {
+		log.Printf("Save State Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(l.StateFailed, err))
+		return
+	}
+
+	if err := g.writeThumbnail(slot); err != nil {
+		log.Printf("Save State Thumbnail Failed: %v\n", err)
+	}
+
+	g.ui.toast.AddMessage(fmt.Sprintf(l.StateSaved, slot))
+}
+
+// LoadState reports if the state was loaded, a failure is toasted. A state
+// failing part way leaves the console partially loaded, it is put back as it
+// was before the load.
+func (g *Game) LoadState(slot int) bool {
+	l := g.ui.res.localization.Toast
+
+	c := g.stater()
+	if c == nil {
+		return false
+	}
+
+	// a movie cannot go on from another state
+	g.StopMovie()
+
+	f, err := os.Open(g.statePath(slot))
+	if err != nil {
+		g.ui.toast.AddMessage(fmt.Sprintf(l.StateFailed, err))
+		return false
+	}
+
+	defer f.Close()
+
+	backup := bytes.Buffer{}
+	if err = c.SaveState(&backup); err == nil {
+		err = c.LoadState(f)
+	}
+
+	if err != nil {
+		log.Printf("Load State Failed: %v\n", err)
+		g.ui.toast.AddMessage(fmt.Sprintf(l.StateFailed, err))
+
+		if backup.Len() == 0 {
+			return false
+		}
+
+		if err := c.LoadState(&backup); err != nil {
+			log.Printf("Restore State Failed: %v\n", err)
+			g.restartConsole()
+		}
+
+		return false
+	}
+
+	g.ui.toast.AddMessage(fmt.Sprintf(l.StateLoaded, slot))
+	return true
+}
+
+func (g *Game) ChangeSlot(delta int) {
+	if g.nds == nil && g.gba == nil && g.gb == nil {
+		return
+	}
+
+	g.slot = (g.slot + delta + STATE_SLOTS) % STATE_SLOTS
+	g.ui.toast.AddMessage(fmt.Sprintf(g.ui.res.localization.Toast.StateSlot, g.slot))
+}
+
+// Screenshot returns a copy of the current frame, nds screens are stacked
+// top over bottom
+func (g *Game) Screenshot() *image.RGBA {
+	switch {
+	case g.nds != nil:
+		return g.nds.Screenshot()
+	case g.gba != nil:
+		return g.gba.Screenshot()
+	case g.gb != nil:
+		return g.gb.Screenshot()
+	}
+
+	return nil
+}
+
+func (g *Game) writeThumbnail(slot int) error {
+	img := g.Screenshot()
+	if img == nil {
+		return nil
+	}
+
+	f, err := os.Create(g.thumbPath(slot))
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
+
+func (g *Game) readThumbnail(slot int) *ebiten.Image {
+	f, err := os.Open(g.thumbPath(slot))
+	if err != nil {
+		return nil
+	}
+
+	defer f.Close()
+
+	src, err := png.Decode(f)
+	if err != nil {
+		return nil
+	}
+
+	b := src.Bounds()
+	scale := float64(THUMB_WIDTH) / float64(b.Dx())
+
+	thumb := ebiten.NewImage(THUMB_WIDTH, int(float64(b.Dy())*scale))
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.Filter = ebiten.FilterLinear
+	thumb.DrawImage(ebiten.NewImageFromImage(src), op)
+
+	return thumb
+}
+
+func NewStates(g *Game) {
+
+	g.ui.focus.ClearFocus()
+
+	l := g.ui.res.localization.States
+
+	root := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(g.ui.res.bg),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+	)
+
+	c := widget.NewContainer(
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				HorizontalPosition: widget.AnchorLayoutPositionCenter,
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+			}),
+		),
+
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(50)),
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(16),
+		)),
+	)
+
+	grid := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(THUMB_COLUMNS),
+			widget.GridLayoutOpts.Spacing(16, 16),
+		)),
+	)
+
+	for slot := range STATE_SLOTS {
+		grid.AddChild(g.newStateSlot(slot))
+	}
+
+	c.AddChild(grid)
+	c.AddChild(NewCenteredButton(l.Return, func() {
+		NewPause(g)
+	}))
+
+	root.AddChild(c)
+
+	g.ui.PageId = PAGE_STATES
+	g.ui.ui = &ebitenui.UI{
+		Container:    root,
+		PrimaryTheme: NewTheme(g.ui.res),
+	}
+	g.ui.focus.other = g.ui.ui.Container.GetFocusers()
+	g.ui.focus.BuildFocus(g.ui.ui)
+}
+
+func (g *Game) newStateSlot(slot int) *widget.Container {
+
+	l := g.ui.res.localization.States
+
+	cell := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(4),
+		)),
+	)
+
+	label := fmt.Sprintf("%s %d - %s", l.Slot, slot, l.Empty)
+
+	if info, err := os.Stat(g.statePath(slot)); err == nil {
+		label = fmt.Sprintf("%s %d - %s", l.Slot, slot, info.ModTime().Format("2006-01-02 15:04"))
+
+		if thumb := g.readThumbnail(slot); thumb != nil {
+			cell.AddChild(widget.NewGraphic(widget.GraphicOpts.Image(thumb)))
+		}
+	}
+
+	cell.AddChild(NewLabel(label))
+
+	cell.AddChild(NewCenteredButton(l.Save, func() {
+		g.slot = slot
+		g.SaveState(slot)
+		NewStates(g)
+	}))
+
+	cell.AddChild(NewCenteredButton(l.Load, func() {
+		g.slot = slot
+		// a failed load stays paused so its toast is seen
+		if g.LoadState(slot) {
+			g.TogglePause()
+		}
+	}))
+
+	return cell
+}
@@ -114,6 +114,9 @@ func NewGeneralMenu(g *Game, parent *widget.Container) {
 		{WIDGET_CBX, l.IntegerScaling, "", &tmp.IntegerScaling, nil},
 		{WIDGET_LNK, "", "", nil, "a ratio of zero is dynamic"},
 		{WIDGET_DEC, l.IntegerScalingRatio, "", &tmp.IntegerScalingRatio, 10},
+		{WIDGET_LNK, "", "", nil, "rewind changes apply to the next rom opened"},
+		{WIDGET_DEC, l.RewindBufferSize, l.RewindBufferSize, &tmp.RewindBufferSize, 4096},
+		{WIDGET_DEC, l.RewindInterval, l.RewindInterval, &tmp.RewindInterval, 60},
 
 		{WIDGET_HDR, l.Keyboard, "", nil, nil},
 		{WIDGET_LNK, "", "", nil, keybindsLink},
@@ -127,6 +130,12 @@ func NewGeneralMenu(g *Game, parent *widget.Container) {
 		{WIDGET_KEY, l.Down, l.KeyboardDown, &k.Down, KeyValidation()},
 		{WIDGET_KEY, l.Fullscreen, l.KeyboardFullscreen, &k.Fullscreen, KeyValidation()},
 		{WIDGET_KEY, l.Quit, l.KeyboardQuit, &k.Quit, KeyValidation()},
+		{WIDGET_KEY, l.SaveState, l.KeyboardSaveState, &k.SaveState, KeyValidation()},
+		{WIDGET_KEY, l.LoadState, l.KeyboardLoadState, &k.LoadState, KeyValidation()},
+		{WIDGET_KEY, l.NextSlot, l.KeyboardNextSlot, &k.NextSlot, KeyValidation()},
+		{WIDGET_KEY, l.PrevSlot, l.KeyboardPrevSlot, &k.PrevSlot, KeyValidation()},
+		{WIDGET_KEY, l.Rewind, l.KeyboardRewind, &k.Rewind, KeyValidation()},
+		{WIDGET_KEY, l.Capture, l.KeyboardCapture, &k.Capture, KeyValidation()},
 
 		{WIDGET_HDR, l.Controller, "", nil, nil},
 		{WIDGET_LNK, "", "", nil, controllerLink},
@@ -140,6 +149,12 @@ func NewGeneralMenu(g *Game, parent *widget.Container) {
 		{WIDGET_KEY, l.Down, l.ControllerDown, &c.Down, ControllerValidation()},
 		{WIDGET_KEY, l.Fullscreen, l.ControllerFullscreen, &c.Fullscreen, ControllerValidation()},
 		{WIDGET_KEY, l.Quit, l.ControllerQuit, &c.Quit, ControllerValidation()},
+		{WIDGET_KEY, l.SaveState, l.ControllerSaveState, &c.SaveState, ControllerValidation()},
+		{WIDGET_KEY, l.LoadState, l.ControllerLoadState, &c.LoadState, ControllerValidation()},
+		{WIDGET_KEY, l.NextSlot, l.ControllerNextSlot, &c.NextSlot, ControllerValidation()},
+		{WIDGET_KEY, l.PrevSlot, l.ControllerPrevSlot, &c.PrevSlot, ControllerValidation()},
+		{WIDGET_KEY, l.Rewind, l.ControllerRewind, &c.Rewind, ControllerValidation()},
+		{WIDGET_KEY, l.Capture, l.ControllerCapture, &c.Capture, ControllerValidation()},
 	}
 
 	parent.RemoveChildren()
@@ -298,6 +313,12 @@ func NewGbaMenu(g *Game, parent *widget.Container) {
 		{WIDGET_CBX, l.OptmizeIdleLoops, "", &tmp.IdleOptimize, nil},
 		{WIDGET_HEX, l.SoundClockCycles, l.SoundClockCycles, &tmp.SoundClockUpdateCycles, 1000},
 
+		{WIDGET_HDR, l.Rtc, "", nil, nil},
+		{WIDGET_DEC, l.AdditionalHours, l.AdditionalHours, &tmp.Rtc.AdditionalHours, 24},
+
+		{WIDGET_HDR, l.Solar, "", nil, nil},
+		{WIDGET_DEC, l.SolarLevel, l.SolarLevel, &tmp.Solar.Level, 10},
+
 		{WIDGET_HDR, l.Keyboard, "", nil, nil},
 		{WIDGET_LNK, "", "", nil, keybindsLink},
 		{WIDGET_KEY, l.A, l.KeyboardA, &k.A, KeyValidation()},
@@ -310,6 +331,8 @@ func NewGbaMenu(g *Game, parent *widget.Container) {
 		{WIDGET_KEY, l.Down, l.KeyboardDown, &k.Down, KeyValidation()},
 		{WIDGET_KEY, l.L, l.KeyboardL, &k.L, KeyValidation()},
 		{WIDGET_KEY, l.R, l.KeyboardR, &k.R, KeyValidation()},
+		{WIDGET_KEY, l.SolarUp, l.KeyboardSolarUp, &k.SolarUp, KeyValidation()},
+		{WIDGET_KEY, l.SolarDown, l.KeyboardSolarDown, &k.SolarDown, KeyValidation()},
 
 		{WIDGET_HDR, l.Controller, "", nil, nil},
 		{WIDGET_LNK, "", "", nil, controllerLink},
@@ -323,6 +346,8 @@ func NewGbaMenu(g *Game, parent *widget.Container) {
 		{WIDGET_KEY, l.Down, l.ControllerDown, &c.Down, ControllerValidation()},
 		{WIDGET_KEY, l.L, l.ControllerL, &k.L, ControllerValidation()},
 		{WIDGET_KEY, l.R, l.ControllerR, &k.R, ControllerValidation()},
+		{WIDGET_KEY, l.SolarUp, l.ControllerSolarUp, &c.SolarUp, ControllerValidation()},
+		{WIDGET_KEY, l.SolarDown, l.ControllerSolarDown, &c.SolarDown, ControllerValidation()},
 	}
 
 	parent.RemoveChildren()
@@ -378,6 +403,18 @@ func NewNdsMenu(g *Game, parent *widget.Container) {
 		{WIDGET_DIR, l.OutputDirectory, "", &tmp.Export.Directory, "./export"},
 		{WIDGET_CBX, l.ShadowPolygons, "", &tmp.Export.ShadowPolys, nil},
 
+		{WIDGET_HDR, l.Slot2, "", nil, nil},
+		{WIDGET_RAD, l.Slot2Device, "", &tmp.Slot2.Device, l.Slot2Devices},
+		{WIDGET_FLE, l.GbaRomPath, "", &tmp.Slot2.GbaRomPath, nil},
+
+		{WIDGET_HDR, l.Wifi, "", nil, nil},
+		{WIDGET_TXT, l.WifiAir, l.WifiAir, &tmp.Wifi.Air, StringValidation(64)},
+		{WIDGET_TXT, l.WifiMac, l.WifiMac, &tmp.Wifi.Mac, StringValidation(17)},
+
+		{WIDGET_HDR, l.Microphone, "", nil, nil},
+		{WIDGET_FLE, l.MicWavPath, "", &tmp.Mic.WavPath, nil},
+		{WIDGET_CBX, l.MicLoop, "", &tmp.Mic.Loop, nil},
+
 		{WIDGET_HDR, l.Keyboard, "", nil, nil},
 		{WIDGET_LNK, "", "", nil, keybindsLink},
 		{WIDGET_KEY, l.A, l.KeyboardA, &k.A, KeyValidation()},
@@ -398,6 +435,13 @@ func NewNdsMenu(g *Game, parent *widget.Container) {
 		{WIDGET_KEY, l.SizingToggle, l.KeyboardSizingToggle, &k.SizingToggle, KeyValidation()},
 		{WIDGET_KEY, l.RotationToggle, l.KeyboardRotationToggle, &k.RotationToggle, KeyValidation()},
 		{WIDGET_KEY, l.ExportToggle, l.KeyboardExportToggle, &k.ExportScene, KeyValidation()},
+		{WIDGET_KEY, l.GripGreen, l.KeyboardGripGreen, &k.GripGreen, KeyValidation()},
+		{WIDGET_KEY, l.GripRed, l.KeyboardGripRed, &k.GripRed, KeyValidation()},
+		{WIDGET_KEY, l.GripYellow, l.KeyboardGripYellow, &k.GripYellow, KeyValidation()},
+		{WIDGET_KEY, l.GripBlue, l.KeyboardGripBlue, &k.GripBlue, KeyValidation()},
+		{WIDGET_KEY, l.Mic, l.KeyboardMic, &k.Mic, KeyValidation()},
+		{WIDGET_KEY, l.Blow, l.KeyboardBlow, &k.Blow, KeyValidation()},
+		{WIDGET_KEY, l.Lid, l.KeyboardLid, &k.Lid, KeyValidation()},
 
 		{WIDGET_HDR, l.Controller, "", nil, nil},
 		{WIDGET_LNK, "", "", nil, controllerLink},
@@ -413,6 +457,13 @@ func NewNdsMenu(g *Game, parent *widget.Container) {
 		{WIDGET_KEY, l.R, l.ControllerR, &k.R, ControllerValidation()},
 		{WIDGET_KEY, l.X, l.ControllerX, &k.X, ControllerValidation()},
 		{WIDGET_KEY, l.Y, l.ControllerY, &k.Y, ControllerValidation()},
+		{WIDGET_KEY, l.GripGreen, l.ControllerGripGreen, &c.GripGreen, ControllerValidation()},
+		{WIDGET_KEY, l.GripRed, l.ControllerGripRed, &c.GripRed, ControllerValidation()},
+		{WIDGET_KEY, l.GripYellow, l.ControllerGripYellow, &c.GripYellow, ControllerValidation()},
+		{WIDGET_KEY, l.GripBlue, l.ControllerGripBlue, &c.GripBlue, ControllerValidation()},
+		{WIDGET_KEY, l.Mic, l.ControllerMic, &c.Mic, ControllerValidation()},
+		{WIDGET_KEY, l.Blow, l.ControllerBlow, &c.Blow, ControllerValidation()},
+		{WIDGET_KEY, l.Lid, l.ControllerLid, &c.Lid, ControllerValidation()},
 	}
 
 	parent.RemoveChildren()
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"image"
 	"math"
 
 	"github.com/aabalke/guac/config"
@@ -16,3 +17,20 @@ func ScaleImage(sw, sh, cw, ch float64) float64 {
 		return float64(config.Conf.General.IntegerScalingRatio)
 	}
 }
+
+// NewScreenshot copies rgba pixels into a new image, alpha is ignored by the
+// display so it is made opaque
+func NewScreenshot(w, h int, pixels ...[]byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	n := 0
+	for _, p := range pixels {
+		n += copy(img.Pix[n:], p)
+	}
+
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xFF
+	}
+
+	return img
+}
@@ -1,6 +1,6 @@
 package utils
 
-import "strings"
+import "github.com/aabalke/guac/emu/rom"
 
 type RomType int
 
@@ -11,15 +11,16 @@ const (
 	NDS
 )
 
+// GetRomType goes by the extension, of the rom inside if path is a .zip or
+// .gz, and by the header logo when the extension does not say
 func GetRomType(path string) RomType {
 
-	switch {
-	case strings.HasSuffix(path, ".gb"),
-		strings.HasSuffix(path, ".gbc"):
+	switch rom.Identify(path) {
+	case rom.GB:
 		return GB
-	case strings.HasSuffix(path, ".gba"):
+	case rom.GBA:
 		return GBA
-	case strings.HasSuffix(path, ".nds"):
+	case rom.NDS:
 		return NDS
 	default:
 		return NONE